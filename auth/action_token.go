@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Action token types, each one can only be redeemed by the flow it was issued for
+const (
+	ActionTwoFactor     = "2fa"
+	ActionPasswordReset = "password_reset"
+	ActionVerifyEmail   = "verify_email"
+	ActionUnsubscribe   = "unsubscribe_digest"
+)
+
+// ErrActionTokenInvalid is returned when an action token is missing, expired or was issued for another action
+var ErrActionTokenInvalid = errors.New("invalid or expired token")
+
+// ActionToken is a signed single purpose token, such as a two-factor sign in challenge or a password reset link.
+// Check binds the token to state the action changes, a password hash or an email, so it can only be redeemed once.
+type ActionToken struct {
+	UserId string
+	Action string
+	Check  string
+}
+
+// CreateToken signs the ActionToken with the current keyring signing key.
+// Action tokens are rejected by DecodeJWT, they can not be used as session tokens.
+func (t *ActionToken) CreateToken(exp int64) (string, error) {
+	if t.UserId == "" || t.Action == "" {
+		return "", errors.New("missing required token claims")
+	}
+	ring, err := currentKeyring()
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"id":  t.UserId,
+		"typ": t.Action,
+		"exp": exp,
+	}
+	if t.Check != "" {
+		claims["chk"] = t.Check
+	}
+	return ring.sign(claims)
+}
+
+// DecodeActionToken verifies an action token that was issued for action
+func DecodeActionToken(curToken string, action string) (*ActionToken, error) {
+	if curToken == "" {
+		return nil, ErrActionTokenInvalid
+	}
+	ring, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	token, err := jwt.Parse(curToken, ring.keyFunc)
+	if err != nil {
+		return nil, ErrActionTokenInvalid
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrActionTokenInvalid
+	}
+	if _, ok = claims["exp"]; !ok {
+		return nil, ErrActionTokenInvalid
+	}
+	t := &ActionToken{}
+	t.Action, _ = claims["typ"].(string)
+	t.UserId, _ = claims["id"].(string)
+	t.Check, _ = claims["chk"].(string)
+	if t.Action != action || t.UserId == "" {
+		return nil, ErrActionTokenInvalid
+	}
+	return t, nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActionToken(t *testing.T) {
+	t.Setenv("TOKEN_SECRET", "TESTINGSALT")
+	t.Setenv("TOKEN_KEYS_DIR", "")
+	t.Setenv("TOKEN_SIGNING_KEY", "")
+	exp := time.Now().Add(time.Minute).Unix()
+	reset := &ActionToken{UserId: "000000000000000000000001", Action: ActionPasswordReset, Check: "abc"}
+	resetToken, err := reset.CreateToken(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := reset.CreateToken(time.Now().Add(-time.Minute).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := (&TokenData{UserId: "000000000000000000000001"}).CreateToken(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string       // The name of the test
+		token   string       // The token being decoded
+		action  string       // The action the token is redeemed for
+		want    *ActionToken // What we want DecodeActionToken to return
+		wantErr bool         // whether we want an error.
+	}{
+		{"password reset", resetToken, ActionPasswordReset, reset, false},
+		{"other action", resetToken, ActionVerifyEmail, nil, true},
+		{"expired", expired, ActionPasswordReset, nil, true},
+		{"session token", session, ActionPasswordReset, nil, true},
+		{"empty", "", ActionPasswordReset, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeActionToken(tt.token, tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeActionToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && *got != *tt.want {
+				t.Errorf("DecodeActionToken() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err = DecodeJWT(resetToken); err == nil {
+		t.Errorf("DecodeJWT() accepted an action token")
+	}
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Supported token signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// defaultKeyId identifies the TOKEN_SECRET key, which also verifies tokens issued without a kid header
+const defaultKeyId = "default"
+
+// Key is a token signing or verification key identified by the kid header of the tokens it signs
+type Key struct {
+	Id        string
+	Algorithm string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// Keyring holds the key new tokens are signed with along with every key existing tokens may be verified with.
+// Keys are read from TOKEN_SECRET and from the files in TOKEN_KEYS_DIR, named after their kid:
+//   - <kid>.pem    an RSA or Ed25519 private key used to sign and verify RS256 or EdDSA tokens
+//   - <kid>.pub    an RSA or Ed25519 public key that only verifies tokens, e.g. a key being retired
+//   - <kid>.secret an HMAC secret used to sign and verify HS256 tokens
+//
+// TOKEN_SIGNING_KEY selects the kid new tokens are signed with, falling back to TOKEN_SECRET. Leaving TOKEN_SECRET
+// empty retires it, after which tokens issued without a kid are rejected.
+type Keyring struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// JWK is the JSON Web Key representation of a public verification key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set as served from a JWKS endpoint
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	keyringMu  sync.Mutex
+	keyringEnv string
+	keyring    *Keyring
+)
+
+// LoadKeyring builds a Keyring from the TOKEN_SECRET, TOKEN_KEYS_DIR and TOKEN_SIGNING_KEY environmental variables
+func LoadKeyring() (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*Key)}
+	if secret := []byte(os.Getenv("TOKEN_SECRET")); len(secret) > 0 {
+		k.keys[defaultKeyId] = &Key{Id: defaultKeyId, Algorithm: AlgorithmHS256, signKey: secret, verifyKey: secret}
+	}
+	if dir := os.Getenv("TOKEN_KEYS_DIR"); dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			key, err := loadKeyFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				continue
+			}
+			if _, ok := k.keys[key.Id]; ok {
+				return nil, fmt.Errorf("duplicate token key id %q", key.Id)
+			}
+			k.keys[key.Id] = key
+		}
+	}
+	signingId := os.Getenv("TOKEN_SIGNING_KEY")
+	if signingId == "" {
+		signingId = defaultKeyId
+	}
+	k.signing = k.keys[signingId]
+	if k.signing == nil || k.signing.signKey == nil {
+		return nil, fmt.Errorf("token signing key %q is not a private key in the keyring", signingId)
+	}
+	return k, nil
+}
+
+// loadKeyFile reads a single key file from TOKEN_KEYS_DIR, returning nil for files that are not keys
+func loadKeyFile(path string) (*Key, error) {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	key := &Key{Id: strings.TrimSuffix(name, ext)}
+	if ext != ".pem" && ext != ".pub" && ext != ".secret" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch ext {
+	case ".secret":
+		secret := bytes.TrimSpace(data)
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("token key %q is empty", key.Id)
+		}
+		key.Algorithm, key.signKey, key.verifyKey = AlgorithmHS256, secret, secret
+	case ".pem":
+		if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.Algorithm, key.signKey, key.verifyKey = AlgorithmRS256, rsaKey, &rsaKey.PublicKey
+		} else if edKey, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			key.Algorithm, key.signKey, key.verifyKey = AlgorithmEdDSA, edKey, edKey.(ed25519.PrivateKey).Public()
+		} else {
+			return nil, fmt.Errorf("token key %q is not an RSA or Ed25519 private key", key.Id)
+		}
+	case ".pub":
+		if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+			key.Algorithm, key.verifyKey = AlgorithmRS256, rsaKey
+		} else if edKey, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+			key.Algorithm, key.verifyKey = AlgorithmEdDSA, edKey
+		} else {
+			return nil, fmt.Errorf("token key %q is not an RSA or Ed25519 public key", key.Id)
+		}
+	}
+	return key, nil
+}
+
+// currentKeyring returns the Keyring for the current environment, reloading it whenever the configuration changes
+func currentKeyring() (*Keyring, error) {
+	env := os.Getenv("TOKEN_SECRET") + "\x00" + os.Getenv("TOKEN_KEYS_DIR") + "\x00" + os.Getenv("TOKEN_SIGNING_KEY")
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	if keyring != nil && keyringEnv == env {
+		return keyring, nil
+	}
+	k, err := LoadKeyring()
+	if err != nil {
+		return nil, err
+	}
+	keyring, keyringEnv = k, env
+	return keyring, nil
+}
+
+// sign creates a token carrying claims, signed with the signing key and labelled with its kid
+func (k *Keyring) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.signing.Algorithm), claims)
+	token.Header["kid"] = k.signing.Id
+	return token.SignedString(k.signing.signKey)
+}
+
+// keyFunc selects the verification key of a token from its kid header. Tokens without a kid were issued
+// before the keyring existed and are verified with TOKEN_SECRET, unless it was retired.
+func (k *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = defaultKeyId
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown token signing key")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected token signing method")
+	}
+	return key.verifyKey, nil
+}
+
+// JWKS returns the public keys of the keyring so other services can verify tokens. HMAC secrets are never exposed.
+func (k *Keyring) JWKS() *JWKSet {
+	set := &JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.Id,
+				Use: "sig",
+				Alg: key.Algorithm,
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.Id,
+				Use: "sig",
+				Alg: key.Algorithm,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// CurrentJWKS returns the public keys of the keyring for the current environment
+func CurrentJWKS() (*JWKSet, error) {
+	k, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return k.JWKS(), nil
+}
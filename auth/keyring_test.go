@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// writeTestKeys writes an RSA and an Ed25519 private key, a retired RSA public key and an HMAC secret to dir
+func writeTestKeys(t *testing.T, dir string) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDER, err := x509.MarshalPKIXPublicKey(&oldKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"rsa-1.pem":   pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+		"ed-1.pem":    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}),
+		"old.pub":     pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: oldDER}),
+		"hmac.secret": []byte("rotated-secret\n"),
+		"README":      []byte("not a key"),
+	}
+	for name, data := range files {
+		if err = os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestKeyringSigning(t *testing.T) {
+	dir := t.TempDir()
+	writeTestKeys(t, dir)
+	t.Setenv("TOKEN_SECRET", "TESTINGSALT")
+	t.Setenv("TOKEN_KEYS_DIR", dir)
+	tests := []struct {
+		name       string // The name of the test
+		signingKey string // The kid new tokens are signed with
+		wantAlg    string // The expected signing algorithm
+		wantErr    bool   // whether we want an error loading the keyring.
+	}{
+		{"default secret", "", AlgorithmHS256, false},
+		{"rsa", "rsa-1", AlgorithmRS256, false},
+		{"ed25519", "ed-1", AlgorithmEdDSA, false},
+		{"hmac file", "hmac", AlgorithmHS256, false},
+		{"public key only", "old", "", true},
+		{"unknown key", "missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TOKEN_SIGNING_KEY", tt.signingKey)
+			if _, err := LoadKeyring(); (err != nil) != tt.wantErr {
+				t.Fatalf("LoadKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			want := &TokenData{UserId: "000000000000000000000001", RootAdmin: true}
+			token, err := want.CreateToken(time.Now().Add(time.Hour).Unix())
+			if err != nil {
+				t.Fatalf("TokenData.CreateToken() error = %v", err)
+			}
+			parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Method.Alg() != tt.wantAlg {
+				t.Errorf("TokenData.CreateToken() alg = %v, want %v", parsed.Method.Alg(), tt.wantAlg)
+			}
+			got, err := DecodeJWT(token)
+			if err != nil {
+				t.Fatalf("DecodeJWT() error = %v", err)
+			}
+			if got.UserId != want.UserId || !got.RootAdmin {
+				t.Errorf("DecodeJWT() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	dir := t.TempDir()
+	writeTestKeys(t, dir)
+	t.Setenv("TOKEN_SECRET", "TESTINGSALT")
+	t.Setenv("TOKEN_KEYS_DIR", dir)
+	tData := &TokenData{UserId: "000000000000000000000001"}
+	exp := time.Now().Add(time.Hour).Unix()
+	// Tokens issued before the keyring existed carry no kid
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": tData.UserId, "root": false, "exp": exp})
+	legacyToken, err := legacy.SignedString([]byte("TESTINGSALT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOKEN_SIGNING_KEY", "rsa-1")
+	rsaToken, err := tData.CreateToken(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rotate to a new signing key, tokens signed with the previous key remain valid
+	t.Setenv("TOKEN_SIGNING_KEY", "ed-1")
+	for name, token := range map[string]string{"legacy": legacyToken, "rsa": rsaToken} {
+		if _, err = DecodeJWT(token); err != nil {
+			t.Errorf("DecodeJWT(%s) after rotation error = %v", name, err)
+		}
+	}
+	// Once the previous key is removed from the keyring its tokens are rejected
+	if err = os.Remove(filepath.Join(dir, "rsa-1.pem")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOKEN_SIGNING_KEY", "")
+	if _, err = DecodeJWT(rsaToken); err == nil {
+		t.Error("DecodeJWT() accepted a token signed with a removed key")
+	}
+	// A token can not switch algorithms to be verified with an HMAC secret
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": tData.UserId, "exp": exp})
+	forged.Header["kid"] = "ed-1"
+	forgedToken, err := forged.SignedString([]byte("TESTINGSALT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DecodeJWT(forgedToken); err == nil {
+		t.Error("DecodeJWT() accepted a token with a mismatched signing method")
+	}
+	// Retiring TOKEN_SECRET rejects tokens without a kid, including ones signed with an empty secret
+	t.Setenv("TOKEN_SECRET", "")
+	t.Setenv("TOKEN_SIGNING_KEY", "ed-1")
+	emptyToken, err := legacy.SignedString([]byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, token := range map[string]string{"legacy": legacyToken, "empty secret": emptyToken} {
+		if _, err = DecodeJWT(token); err == nil {
+			t.Errorf("DecodeJWT(%s) accepted a token without a kid after TOKEN_SECRET was retired", name)
+		}
+	}
+	t.Setenv("TOKEN_SIGNING_KEY", "")
+	if _, err = LoadKeyring(); err == nil {
+		t.Error("LoadKeyring() signed with a retired TOKEN_SECRET")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	writeTestKeys(t, dir)
+	t.Setenv("TOKEN_SECRET", "TESTINGSALT")
+	t.Setenv("TOKEN_KEYS_DIR", dir)
+	t.Setenv("TOKEN_SIGNING_KEY", "")
+	jwks, err := CurrentJWKS()
+	if err != nil {
+		t.Fatalf("CurrentJWKS() error = %v", err)
+	}
+	want := map[string]string{"ed-1": "OKP", "old": "RSA", "rsa-1": "RSA"}
+	if len(jwks.Keys) != len(want) {
+		t.Fatalf("CurrentJWKS() returned %d keys, want %d", len(jwks.Keys), len(want))
+	}
+	for _, key := range jwks.Keys {
+		if want[key.Kid] != key.Kty {
+			t.Errorf("CurrentJWKS() key %s has kty %s, want %s", key.Kid, key.Kty, want[key.Kid])
+		}
+		if key.Kty == "RSA" && (key.N == "" || key.E == "") || key.Kty == "OKP" && key.X == "" {
+			t.Errorf("CurrentJWKS() key %s is missing its public key material", key.Kid)
+		}
+	}
+}
+
+func TestDecodeJWTMissingClaims(t *testing.T) {
+	t.Setenv("TOKEN_SECRET", "TESTINGSALT")
+	t.Setenv("TOKEN_KEYS_DIR", "")
+	t.Setenv("TOKEN_SIGNING_KEY", "")
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name   string        // The name of the test
+		claims jwt.MapClaims // The claims of the decoded token
+	}{
+		{"no claims", jwt.MapClaims{}},
+		{"missing id", jwt.MapClaims{"root": true, "exp": exp}},
+		{"id of wrong type", jwt.MapClaims{"id": 1, "root": true, "exp": exp}},
+		{"missing expiration", jwt.MapClaims{"id": "000000000000000000000001", "root": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tt.claims).SignedString([]byte("TESTINGSALT"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = DecodeJWT(token); err == nil {
+				t.Errorf("DecodeJWT() accepted a token with %s", tt.name)
+			}
+		})
+	}
+}
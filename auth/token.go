@@ -1,18 +1,29 @@
 package auth
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"github.com/ablancas22/messenger-backend/models"
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v4"
 	"net/http"
-	"os"
+	"time"
 )
 
 // TokenData stores the structured data from a session token for use
 type TokenData struct {
 	UserId    string
+	Role      string
 	RootAdmin bool
+	SessionId string
+	APIKeyId  string
+}
+
+// tokenDataKey is the request context key holding TokenData verified by a middleware
+type tokenDataKey struct{}
+
+// WithTokenData returns a shallow copy of a request carrying TokenData that a middleware already verified
+func WithTokenData(r *http.Request, t *TokenData) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), tokenDataKey{}, t))
 }
 
 // InitUserToken inputs a pointer to a user and returns TokenData
@@ -23,6 +34,7 @@ func InitUserToken(u *models.User) (*TokenData, error) {
 	}
 	return &TokenData{
 		UserId:    u.Id,
+		Role:      u.Role,
 		RootAdmin: u.RootAdmin,
 	}, nil
 }
@@ -31,11 +43,12 @@ func InitUserToken(u *models.User) (*TokenData, error) {
 func (t *TokenData) ToUser() *models.User {
 	return &models.User{
 		Id:        t.UserId,
+		Role:      t.Role,
 		RootAdmin: t.RootAdmin,
 	}
 }
 
-// CreateToken is used to create a new session JWT token
+// CreateToken is used to create a new session JWT token, signed with the current keyring signing key
 func (t *TokenData) CreateToken(exp int64) (string, error) {
 	if t.UserId == "" {
 		return "", errors.New("missing required token claims")
@@ -43,13 +56,22 @@ func (t *TokenData) CreateToken(exp int64) (string, error) {
 	if exp == 0 {
 		return "", errors.New("new token must have a expiration time greater than 0")
 	}
-	var MySigningKey = []byte(os.Getenv("TOKEN_SECRET"))
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["id"] = t.UserId
-	claims["root"] = t.RootAdmin
-	claims["exp"] = exp
-	return token.SignedString(MySigningKey)
+	ring, err := currentKeyring()
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"id":   t.UserId,
+		"root": t.RootAdmin,
+		"exp":  exp,
+	}
+	if t.Role != "" {
+		claims["role"] = t.Role
+	}
+	if t.SessionId != "" {
+		claims["sid"] = t.SessionId
+	}
+	return ring.sign(claims)
 }
 
 // DecodeJWT is used to decode a JWT token
@@ -58,29 +80,56 @@ func DecodeJWT(curToken string) (*TokenData, error) {
 	if curToken == "" {
 		return &tokenData, errors.New("unauthorized")
 	}
-	var MySigningKey = []byte(os.Getenv("TOKEN_SECRET"))
+	ring, err := currentKeyring()
+	if err != nil {
+		return &tokenData, err
+	}
 	// Decode token
-	token, err := jwt.Parse(curToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("error")
-		}
-		return []byte(MySigningKey), nil
-	})
+	token, err := jwt.Parse(curToken, ring.keyFunc)
 	if err != nil {
 		return &tokenData, err
 	}
+	tokenClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return &tokenData, errors.New("invalid token")
+	}
 	// Determine user based on token
-	if token.Valid {
-		tokenClaims := token.Claims.(jwt.MapClaims)
-		tokenData.UserId = tokenClaims["id"].(string)
-		tokenData.RootAdmin = tokenClaims["root"].(bool)
-		return &tokenData, nil
+	userId, ok := tokenClaims["id"].(string)
+	if !ok || userId == "" {
+		return &tokenData, errors.New("missing required token claims")
+	}
+	if _, ok = tokenClaims["exp"]; !ok {
+		return &tokenData, errors.New("missing required token claims")
 	}
-	return &tokenData, errors.New("invalid token")
+	if _, ok = tokenClaims["typ"]; ok { // Action tokens are not session tokens
+		return &tokenData, errors.New("invalid token")
+	}
+	tokenData.UserId = userId
+	tokenData.Role, _ = tokenClaims["role"].(string)
+	tokenData.RootAdmin, _ = tokenClaims["root"].(bool)
+	tokenData.SessionId, _ = tokenClaims["sid"].(string)
+	return &tokenData, nil
+}
+
+// TokenExpiry returns the expiration time of a JWT token without verifying it, so it must only be used on a
+// token that was already decoded with DecodeJWT
+func TokenExpiry(curToken string) (time.Time, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(curToken, claims); err != nil {
+		return time.Time{}, err
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}, errors.New("token does not have an expiration time")
+	}
+	return time.Unix(int64(exp), 0), nil
 }
 
 // LoadTokenFromRequest inputs a http request and returns decrypted TokenData or an error
 func LoadTokenFromRequest(r *http.Request) (*TokenData, error) {
+	if tokenData, ok := r.Context().Value(tokenDataKey{}).(*TokenData); ok {
+		return tokenData, nil
+	}
 	authToken := r.Header.Get("Auth-Token")
 	tokenData, err := DecodeJWT(authToken)
 	if err != nil {
@@ -45,6 +45,7 @@ func Test_initUserToken(t *testing.T) {
 }
 
 func Test_createToken(t *testing.T) {
+	t.Setenv("TOKEN_SECRET", "TESTINGSALT")
 	// Defining our test slice. Each unit test should have the following properties:
 	tests := []struct {
 		name      string     // The name of the test
@@ -93,6 +94,7 @@ func Test_createToken(t *testing.T) {
 }
 
 func Test_decodeToken(t *testing.T) {
+	t.Setenv("TOKEN_SECRET", "TESTINGSALT")
 	// Defining our test slice. Each unit test should have the following properties:
 	tests := []struct {
 		name      string     // The name of the test
@@ -109,6 +111,20 @@ func Test_decodeToken(t *testing.T) {
 			false,
 			&TokenData{UserId: "000000000000000000000001", RootAdmin: false},
 		},
+		{
+			"role",
+			time.Now().Add(time.Hour * 1).Unix(),
+			&TokenData{UserId: "000000000000000000000001", Role: "support"},
+			false,
+			&TokenData{UserId: "000000000000000000000001", Role: "support"},
+		},
+		{
+			"session token",
+			time.Now().Add(time.Hour * 1).Unix(),
+			&TokenData{UserId: "000000000000000000000001", SessionId: "000000000000000000000002"},
+			false,
+			&TokenData{UserId: "000000000000000000000001", SessionId: "000000000000000000000002"},
+		},
 		{
 			"expired token",
 			time.Now().Add(time.Second * 1).Unix(),
@@ -134,3 +150,30 @@ func Test_decodeToken(t *testing.T) {
 		})
 	}
 }
+
+func Test_tokenExpiry(t *testing.T) {
+	t.Setenv("TOKEN_SECRET", "TESTINGSALT")
+	exp := time.Now().Add(time.Hour * 1).Unix()
+	validToken, _ := (&TokenData{UserId: "000000000000000000000001"}).CreateToken(exp)
+	tests := []struct {
+		name    string    // The name of the test
+		token   string    // The token to read the expiration time from
+		want    time.Time // What out instance we want our function to return.
+		wantErr bool      // whether we want an error.
+	}{
+		{"success", validToken, time.Unix(exp, 0), false},
+		{"malformed token", "not.a.token", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TokenExpiry(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TokenExpiry() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("TokenExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
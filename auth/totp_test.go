@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 encoding of the RFC 6238 SHA1 test key "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	// The RFC 6238 appendix B vectors, truncated to six digits
+	tests := []struct {
+		name   string    // The name of the test
+		at     time.Time // The time the code is checked at
+		code   string    // The code being checked
+		wantOk bool      // whether we want the code to be accepted.
+	}{
+		{"rfc 59", time.Unix(59, 0), "287082", true},
+		{"rfc 1111111109", time.Unix(1111111109, 0), "081804", true},
+		{"rfc 1111111111", time.Unix(1111111111, 0), "050471", true},
+		{"rfc 1234567890", time.Unix(1234567890, 0), "005924", true},
+		{"rfc 2000000000", time.Unix(2000000000, 0), "279037", true},
+		{"previous step", time.Unix(89, 0), "287082", true},
+		{"two steps late", time.Unix(119, 0), "287082", false},
+		{"wrong code", time.Unix(59, 0), "287083", false},
+		{"wrong length", time.Unix(59, 0), "94287082", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(rfcSecret, tt.code, tt.at); ok != tt.wantOk {
+				t.Errorf("ValidateTOTP() = %v, want %v", ok, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("GenerateTOTPSecret() = %q is not a 160 bit base32 secret", secret)
+	}
+	if _, ok := ValidateTOTP(secret, totpCode(key, now.Unix()/totpPeriod), now); !ok {
+		t.Errorf("ValidateTOTP() rejected the current code of a generated secret")
+	}
+	uri, err := url.Parse(TOTPURI("Messenger", "admin@example.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Query().Get("secret") != secret {
+		t.Errorf("TOTPURI() = %q", uri.String())
+	}
+}
@@ -2,11 +2,19 @@ package cmd
 
 import (
 	"context"
+	"github.com/ablancas22/messenger-backend/auth"
 	"github.com/ablancas22/messenger-backend/database"
+	"github.com/ablancas22/messenger-backend/jobs"
+	"github.com/ablancas22/messenger-backend/mailer"
 	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/oidc"
+	"github.com/ablancas22/messenger-backend/password"
+	"github.com/ablancas22/messenger-backend/push"
+	"github.com/ablancas22/messenger-backend/scanner"
 	"github.com/ablancas22/messenger-backend/server"
 	"github.com/ablancas22/messenger-backend/services"
 	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/ablancas22/messenger-backend/webhook"
 	"go.mongodb.org/mongo-driver/bson"
 	"os"
 	"time"
@@ -16,6 +24,8 @@ import (
 type App struct {
 	server *server.Server
 	db     database.DBClient
+	mailer mailer.Mailer
+	pusher push.Provider
 }
 
 // Initialize is a function used to initialize a new instantiation of the API Application
@@ -29,6 +39,12 @@ func (a *App) Initialize() error {
 		}
 		conf.InitializeEnvironmentalVars()
 	}
+	if _, err = auth.LoadKeyring(); err != nil {
+		return err
+	}
+	if _, err = oidc.LoadProviders(); err != nil {
+		return err
+	}
 	// 2) Initialize & Connect DB Client
 	a.db, err = database.InitializeNewClient()
 	if err != nil {
@@ -46,15 +62,108 @@ func (a *App) Initialize() error {
 	gmHandler := a.db.NewGroupMembershipHandler()
 	cHandler := a.db.NewConversationHandler()
 	coHandler := a.db.NewContactHandler()
+	fHandler := a.db.NewFileHandler()
+	upHandler := a.db.NewUploadHandler()
+	sHandler := a.db.NewStorageHandler()
+	usHandler := a.db.NewStorageUsageHandler()
+	rtHandler := a.db.NewRefreshTokenHandler()
+	seHandler := a.db.NewSessionHandler()
+	kHandler := a.db.NewAPIKeyHandler()
+	tfHandler := a.db.NewTwoFactorHandler()
+	mHandler := a.db.NewMailHandler()
+	lHandler := a.db.NewLoginAttemptHandler()
+	olHandler := a.db.NewOIDCLoginHandler()
+	iHandler := a.db.NewIdentityHandler()
+	aeHandler := a.db.NewAuditEventHandler()
+	btHandler := a.db.NewBotHandler()
+	whHandler := a.db.NewWebhookHandler()
+	wdHandler := a.db.NewWebhookDeliveryHandler()
+	ihHandler := a.db.NewIncomingWebhookHandler()
+	cmHandler := a.db.NewCommandHandler()
+	mnHandler := a.db.NewMentionHandler()
+	dvHandler := a.db.NewDeviceHandler()
+	nHandler := a.db.NewNotificationHandler()
+	dsHandler := a.db.NewDigestSettingsHandler()
+	unHandler := a.db.NewUnreadHandler()
+	smHandler := a.db.NewScheduledMessageHandler()
+	exHandler := a.db.NewMessageExpiryHandler()
 
 	gService := database.NewGroupService(a.db, gHandler)
-	uService := database.NewUserService(a.db, uHandler, gHandler)
+	policy, err := password.NewPolicy(os.Getenv("PASSWORD_MIN_LENGTH"), os.Getenv("PASSWORD_HISTORY"), os.Getenv("PASSWORD_BANNED_FILE"))
+	if err != nil {
+		return err
+	}
+	uService := database.NewUserService(a.db, uHandler, gHandler, policy)
 	bService := database.NewBlacklistService(a.db, blHandler)
-	gmService := database.NewGroupMembershipService(a.db, gmHandler)
-	tService := services.NewTokenService(uService, gService, bService)
-	ttService := database.NewMessageService(a.db, tHandler, uHandler, gHandler)
+	if err = a.db.CreateTTLIndex("blacklists", "expires_at"); err != nil {
+		return err
+	}
+	rtService := database.NewRefreshTokenService(a.db, rtHandler)
+	seService := database.NewSessionService(a.db, seHandler)
+	kService := database.NewAPIKeyService(a.db, kHandler)
+	tfService := database.NewTwoFactorService(a.db, tfHandler)
+	a.mailer, err = mailer.New(os.Getenv("MAILER"), os.Getenv("MAIL_ADDRESS"), os.Getenv("MAIL_FROM"))
+	if err != nil {
+		return err
+	}
+	mService := database.NewMailService(a.db, mHandler, a.mailer)
+	lService := database.NewLoginAttemptService(a.db, lHandler)
+	if err = a.db.CreateTTLIndex("login_attempts", "expires_at"); err != nil {
+		return err
+	}
+	oService := database.NewOIDCService(a.db, olHandler, iHandler)
+	if err = a.db.CreateTTLIndex("oidc_logins", "expires_at"); err != nil {
+		return err
+	}
+	aeService := database.NewAuditEventService(a.db, aeHandler)
+	btService := database.NewBotService(a.db, btHandler)
+	ihService := database.NewIncomingWebhookService(a.db, ihHandler)
+	whService := database.NewWebhookService(a.db, whHandler, wdHandler, webhook.NewClient(10*time.Second))
+	tService := services.NewTokenService(uService, gService, bService, rtService, seService, kService, tfService, mService, lService, oService, aeService, btService, ihService)
 	cService := database.NewConversationService(a.db, cHandler)
+	a.pusher, err = push.New(os.Getenv("PUSH_PROVIDER"), push.Config{
+		APNsKeyFile:        os.Getenv("APNS_KEY_FILE"),
+		APNsKeyID:          os.Getenv("APNS_KEY_ID"),
+		APNsTeamID:         os.Getenv("APNS_TEAM_ID"),
+		APNsTopic:          os.Getenv("APNS_TOPIC"),
+		FCMCredentialsFile: os.Getenv("FCM_CREDENTIALS_FILE"),
+		VAPIDPrivateKey:    os.Getenv("VAPID_PRIVATE_KEY"),
+		VAPIDSubject:       os.Getenv("VAPID_SUBJECT"),
+	})
+	if err != nil {
+		return err
+	}
+	dvService := database.NewDeviceService(a.db, dvHandler)
+	nService := database.NewNotificationService(a.db, nHandler, dvService, a.pusher)
+	mnService := database.NewMentionService(a.db, mnHandler)
+	dgService := database.NewDigestService(a.db, dsHandler, unHandler)
+	// message and membership events are published for the bots in a conversation, the webhooks of a group, the
+	// devices of the participants and the unread threads that email digests summarize
+	events := services.NewEventBus()
+	gmService := services.NewEventGroupMembershipService(database.NewGroupMembershipService(a.db, gmHandler), events)
+	events.Subscribe(services.NewBotNotifier(btService, cService, gmService).Notify)
+	events.Subscribe(services.NewWebhookNotifier(whService, cService).Notify)
+	events.Subscribe(services.NewPushNotifier(nService, uService, gmService, cService, seService).Notify)
+	events.Subscribe(services.NewUnreadTracker(dgService, uService, gmService, cService).Track)
+	fScanner, err := scanner.New(os.Getenv("SCANNER"), os.Getenv("CLAMD_ADDRESS"))
+	if err != nil {
+		return err
+	}
+	fService := database.NewFileService(a.db, fHandler, upHandler, sHandler, usHandler, fScanner)
+	// attachments are checked, mentions resolved and expiries set before a message is stored so that its events
+	// carry them
+	exService := database.NewMessageExpiryService(a.db, exHandler)
+	ttService := services.NewAttachmentMessageService(database.NewMessageService(a.db, tHandler, uHandler, gHandler), fService)
+	ttService = services.NewDisappearingMessageService(ttService, cService, exService)
+	ttService = services.NewMentionMessageService(ttService, uService, gmService, cService, seService, mnService)
+	ttService = services.NewEventMessageService(ttService, events)
+	cmService := database.NewCommandService(a.db, cmHandler)
+	cmdDispatcher := services.NewCommandDispatcher(uService, gmService, cService, cmService, btService, ttService)
 	coService := database.NewContactService(a.db, coHandler)
+	digester := services.NewDigester(uService, gService, ttService, mnService, dgService, mService)
+	smService := database.NewScheduledMessageService(a.db, smHandler)
+	scheduler := services.NewScheduler(smService, ttService)
+	sweeper := services.NewMessageSweeper(exService, ttService, fService)
 
 	// 4) Create RootAdmin user if database is empty
 	var group models.Group
@@ -80,12 +189,26 @@ func (a *App) Initialize() error {
 		}
 	}
 	// 5) Initialize Server
-	a.server = server.NewServer(uService, gService, ttService, tService, gmService, cService, coService)
+	a.server = server.NewServer(uService, gService, ttService, tService, gmService, cService, coService, fService, mService, whService, cmdDispatcher, mnService, dvService, nService, dgService, digester, smService, scheduler, sweeper)
 	return nil
 }
 
 // Run is a function used to run a previously initialized API Application
 func (a *App) Run() {
 	defer a.db.Close()
+	// background jobs garbage collect abandoned uploads, retry the work that failed when it was first attempted,
+	// including the outboxes left over from before a restart, deliver scheduled messages once they are due, delete
+	// disappearing messages once they expire and email digests to users who have been away
+	runner := jobs.NewRunner()
+	runner.Every("purge expired uploads", time.Hour, a.server.FileService.PurgeExpiredUploads)
+	runner.Every("scan pending files", 10*time.Minute, a.server.FileService.ScanPendingFiles)
+	runner.Every("deliver pending mail", time.Minute, a.server.MailService.MailDeliverPending)
+	runner.Every("deliver pending webhooks", 15*time.Second, a.server.WebhookService.WebhookDeliverPending)
+	runner.Every("deliver pending notifications", 30*time.Second, a.server.NotificationService.NotificationDeliverPending)
+	runner.Every("send digests", time.Hour, a.server.Digester.SendDigests)
+	runner.Every("deliver scheduled messages", 10*time.Second, a.server.Scheduler.DeliverDue)
+	runner.Every("purge expired messages", time.Minute, a.server.MessageSweeper.PurgeExpiredMessages)
+	runner.Start()
+	defer runner.Stop()
 	a.server.Start()
 }
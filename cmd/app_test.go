@@ -2,10 +2,26 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/database"
+	"github.com/ablancas22/messenger-backend/mailer"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/oidc"
+	"github.com/ablancas22/messenger-backend/push"
+	"github.com/ablancas22/messenger-backend/scanner"
+	"github.com/ablancas22/messenger-backend/services"
+	"github.com/ablancas22/messenger-backend/webhook"
+	"image"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 var ta App
@@ -233,34 +249,1116 @@ func TestTokenRefresh(t *testing.T) {
 	checkResponseCode(t, http.StatusCreated, testResponse.Code)
 }
 
-// TestGenerateAPIKey Test
-func TestGenerateAPIKey(t *testing.T) {
+// TestRefreshSessionless Test
+func TestRefreshSessionless(t *testing.T) {
 	// Test Setup
 	setup()
 	createTestGroup(ta, 1)
 	authResponse := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
 	authToken := authResponse.Header().Get("Auth-Token")
-	// Refresh Auth-Token Test
-	reqAPIKey, err := http.NewRequest("GET", "/auth/api-key", nil)
-	if err != nil {
-		t.Errorf("TestGenerateAPIKey() error = %v", err)
+	// Session tokens are refreshed
+	req, _ := http.NewRequest("GET", "/auth", nil)
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	// Tokens without a session are never extended
+	req, _ = http.NewRequest("GET", "/auth", nil)
+	req.Header.Add("Auth-Token", sessionlessToken(t, authToken))
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+	// Long lived tokens are no longer minted, API keys are created as records instead
+	req, _ = http.NewRequest("GET", "/auth/api-key", nil)
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(ta, req).Code)
+}
+
+// TestJWKS Test
+func TestJWKS(t *testing.T) {
+	setup()
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	response := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &jwks); err != nil {
+		t.Fatalf("TestJWKS() error = %v", err)
+	}
+	if jwks.Keys == nil || len(jwks.Keys) != 0 {
+		t.Errorf("TestJWKS() exposed keys for an HMAC only keyring: %v", jwks.Keys)
+	}
+}
+
+// TestSessionlessToken Test
+func TestSessionlessToken(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	authResponse := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	token := sessionlessToken(t, authResponse.Header().Get("Auth-Token"))
+	// Tokens without a session can not be revoked, so they are not accepted
+	req, _ := http.NewRequest("GET", "/users", nil)
+	req.Header.Add("Auth-Token", token)
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("DELETE", "/auth", nil)
+	req.Header.Add("Auth-Token", token)
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+}
+
+// TestRefreshTokenRotation Test
+func TestRefreshTokenRotation(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	authResponse := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	firstRefresh := authResponse.Header().Get("Refresh-Token")
+	if firstRefresh == "" {
+		t.Fatal("TestRefreshTokenRotation() sign in did not return a Refresh-Token")
+	}
+	// Exchange the refresh token for a new session
+	refreshResponse := refreshSession(ta, firstRefresh)
+	checkResponseCode(t, http.StatusOK, refreshResponse.Code)
+	authToken := refreshResponse.Header().Get("Auth-Token")
+	secondRefresh := refreshResponse.Header().Get("Refresh-Token")
+	if secondRefresh == "" || secondRefresh == firstRefresh {
+		t.Fatal("TestRefreshTokenRotation() refresh token was not rotated")
 	}
-	reqAPIKey.Header.Add("Content-Type", "application/json")
-	reqAPIKey.Header.Add("Auth-Token", authToken)
-	apiKeyTestResponse := executeRequest(ta, reqAPIKey)
-	checkResponseCode(t, http.StatusOK, apiKeyTestResponse.Code)
-	apiKey := apiKeyTestResponse.Header().Get("API-Key")
-	// Test new token to ensure it works by creating a new group
 	payload := getTestUserPayload("CREATE")
 	req, err := http.NewRequest("POST", "/users", bytes.NewBuffer(payload))
 	if err != nil {
-		t.Errorf("TestGenerateAPIKey() error = %v", err)
+		t.Errorf("TestRefreshTokenRotation() error = %v", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Auth-Token", apiKey)
-	testResponse := executeRequest(ta, req)
-	// Clean database and do final status check
-	checkResponseCode(t, http.StatusCreated, testResponse.Code)
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusCreated, executeRequest(ta, req).Code)
+	// Reusing the rotated refresh token revokes the whole family
+	checkResponseCode(t, http.StatusUnauthorized, refreshSession(ta, firstRefresh).Code)
+	checkResponseCode(t, http.StatusUnauthorized, refreshSession(ta, secondRefresh).Code)
+	// Signing out revokes the refresh token of the session
+	authResponse = signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	reqSignOut, err := http.NewRequest("DELETE", "/auth", nil)
+	if err != nil {
+		t.Errorf("TestRefreshTokenRotation() error = %v", err)
+	}
+	reqSignOut.Header.Add("Auth-Token", authResponse.Header().Get("Auth-Token"))
+	reqSignOut.Header.Add("Refresh-Token", authResponse.Header().Get("Refresh-Token"))
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, reqSignOut).Code)
+	checkResponseCode(t, http.StatusUnauthorized, refreshSession(ta, authResponse.Header().Get("Refresh-Token")).Code)
+}
+
+// TestDeviceSessions Test
+func TestDeviceSessions(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	phone := signInDevice(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"), "phone")
+	laptop := signInDevice(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"), "laptop")
+	laptopToken := laptop.Header().Get("Auth-Token")
+	// List the signed in devices
+	req, _ := http.NewRequest("GET", "/auth/sessions", nil)
+	req.Header.Add("Auth-Token", laptopToken)
+	response := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var sessions []*models.Session
+	if err := json.Unmarshal(response.Body.Bytes(), &sessions); err != nil {
+		t.Fatalf("TestDeviceSessions() error = %v", err)
+	}
+	phoneSession := ""
+	for _, session := range sessions {
+		if session.DeviceName == "phone" {
+			phoneSession = session.Id
+			if session.Current || session.UserAgent != "phone-agent" {
+				t.Errorf("TestDeviceSessions() unexpected phone session %+v", session)
+			}
+		}
+		if session.DeviceName == "laptop" && !session.Current {
+			t.Errorf("TestDeviceSessions() laptop session is not marked as current")
+		}
+	}
+	if phoneSession == "" {
+		t.Fatal("TestDeviceSessions() phone session not listed")
+	}
+	// Remotely sign out the phone
+	req, _ = http.NewRequest("DELETE", "/auth/sessions/"+phoneSession, nil)
+	req.Header.Add("Auth-Token", laptopToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.Header.Add("Auth-Token", phone.Header().Get("Auth-Token"))
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+	checkResponseCode(t, http.StatusUnauthorized, refreshSession(ta, phone.Header().Get("Refresh-Token")).Code)
+	// Sign out everywhere
+	req, _ = http.NewRequest("DELETE", "/auth/sessions", nil)
+	req.Header.Add("Auth-Token", laptopToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.Header.Add("Auth-Token", laptopToken)
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+	checkResponseCode(t, http.StatusUnauthorized, refreshSession(ta, laptop.Header().Get("Refresh-Token")).Code)
+}
+
+// TestScopedAPIKeys Test
+func TestScopedAPIKeys(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	authResponse := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	authToken := authResponse.Header().Get("Auth-Token")
+	// Create a group administration key
+	req, _ := http.NewRequest("POST", "/auth/api-keys", bytes.NewBuffer([]byte(`{"name":"provisioner","scopes":["groups:admin"]}`)))
+	req.Header.Add("Auth-Token", authToken)
+	response := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var created models.APIKey
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+		t.Fatalf("TestScopedAPIKeys() error = %v", err)
+	}
+	if created.Key == "" || created.Id == "" {
+		t.Fatal("TestScopedAPIKeys() created key was not returned")
+	}
+	req, _ = http.NewRequest("POST", "/auth/api-keys", bytes.NewBuffer([]byte(`{"name":"bad","scopes":["everything"]}`)))
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(ta, req).Code)
+	// The key is accepted on routes within its scope only
+	req, _ = http.NewRequest("GET", "/groups", nil)
+	req.Header.Add("API-Key", created.Key)
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("GET", "/messages", nil)
+	req.Header.Add("API-Key", created.Key)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.Header.Add("API-Key", created.Key)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(ta, req).Code)
+	// Listing never exposes the key itself
+	req, _ = http.NewRequest("GET", "/auth/api-keys", nil)
+	req.Header.Add("Auth-Token", authToken)
+	response = executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var keys []*models.APIKey
+	if err := json.Unmarshal(response.Body.Bytes(), &keys); err != nil {
+		t.Fatalf("TestScopedAPIKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].Key != "" || keys[0].Name != "provisioner" {
+		t.Errorf("TestScopedAPIKeys() unexpected key listing %+v", keys)
+	}
+	// Revoked keys are rejected
+	req, _ = http.NewRequest("DELETE", "/auth/api-keys/"+created.Id, nil)
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("GET", "/groups", nil)
+	req.Header.Add("API-Key", created.Key)
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+}
+
+// TestTwoFactorSignIn Test
+func TestTwoFactorSignIn(t *testing.T) {
+	// Test Setup
+	setup()
+	t.Setenv("REQUIRE_ADMIN_2FA", "true")
+	createTestGroup(ta, 1)
+	authResponse := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	authToken := authResponse.Header().Get("Auth-Token")
+	// Root admins are kept off admin routes until they enable 2FA
+	req, _ := http.NewRequest("POST", "/users", bytes.NewBuffer(getTestUserPayload("CREATE")))
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(ta, req).Code)
+	// Enroll an authenticator app
+	req, _ = http.NewRequest("POST", "/auth/2fa/enroll", nil)
+	req.Header.Add("Auth-Token", authToken)
+	response := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var enrollment models.TwoFactor
+	if err := json.Unmarshal(response.Body.Bytes(), &enrollment); err != nil {
+		t.Fatalf("TestTwoFactorSignIn() error = %v", err)
+	}
+	if enrollment.Secret == "" || !strings.HasPrefix(enrollment.URI, "otpauth://totp/") {
+		t.Fatalf("TestTwoFactorSignIn() unexpected enrollment %+v", enrollment)
+	}
+	now := time.Now()
+	code, _ := auth.TOTPCode(enrollment.Secret, now)
+	req, _ = http.NewRequest("POST", "/auth/2fa/confirm", bytes.NewBuffer([]byte(`{"code":"000000x"}`)))
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("POST", "/auth/2fa/confirm", bytes.NewBuffer([]byte(`{"code":"`+code+`"}`)))
+	req.Header.Add("Auth-Token", authToken)
+	response = executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var confirmed models.TwoFactor
+	if err := json.Unmarshal(response.Body.Bytes(), &confirmed); err != nil {
+		t.Fatalf("TestTwoFactorSignIn() error = %v", err)
+	}
+	if !confirmed.Enabled || len(confirmed.RecoveryCodes) != 10 || confirmed.Secret != "" {
+		t.Fatalf("TestTwoFactorSignIn() unexpected confirmation %+v", confirmed)
+	}
+	req, _ = http.NewRequest("POST", "/users", bytes.NewBuffer(getTestUserPayload("CREATE")))
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusCreated, executeRequest(ta, req).Code)
+	// Signing in now returns a challenge instead of a session
+	response = signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	checkResponseCode(t, http.StatusAccepted, response.Code)
+	var challenge struct {
+		ChallengeToken string `json:"challenge_token"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &challenge); err != nil {
+		t.Fatalf("TestTwoFactorSignIn() error = %v", err)
+	}
+	if response.Header().Get("Auth-Token") != "" || challenge.ChallengeToken == "" {
+		t.Fatal("TestTwoFactorSignIn() sign in skipped the second factor")
+	}
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.Header.Add("Auth-Token", challenge.ChallengeToken)
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+	// A TOTP code is only accepted once
+	checkResponseCode(t, http.StatusUnauthorized, signInTwoFactor(ta, challenge.ChallengeToken, code).Code)
+	nextCode, _ := auth.TOTPCode(enrollment.Secret, now.Add(30*time.Second))
+	response = signInTwoFactor(ta, challenge.ChallengeToken, nextCode)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if response.Header().Get("Auth-Token") == "" || response.Header().Get("Refresh-Token") == "" {
+		t.Fatal("TestTwoFactorSignIn() second factor did not start a session")
+	}
+	// A challenge only starts one session
+	checkResponseCode(t, http.StatusUnauthorized, signInTwoFactor(ta, challenge.ChallengeToken, confirmed.RecoveryCodes[0]).Code)
+	// Recovery codes are single use
+	response = signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	if err := json.Unmarshal(response.Body.Bytes(), &challenge); err != nil {
+		t.Fatalf("TestTwoFactorSignIn() error = %v", err)
+	}
+	checkResponseCode(t, http.StatusOK, signInTwoFactor(ta, challenge.ChallengeToken, confirmed.RecoveryCodes[0]).Code)
+	response = signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	if err := json.Unmarshal(response.Body.Bytes(), &challenge); err != nil {
+		t.Fatalf("TestTwoFactorSignIn() error = %v", err)
+	}
+	checkResponseCode(t, http.StatusUnauthorized, signInTwoFactor(ta, challenge.ChallengeToken, confirmed.RecoveryCodes[0]).Code)
+	// Too many wrong codes invalidate the challenge, even for a valid code
+	for i := 0; i < 4; i++ {
+		checkResponseCode(t, http.StatusUnauthorized, signInTwoFactor(ta, challenge.ChallengeToken, "000000").Code)
+	}
+	checkResponseCode(t, http.StatusUnauthorized, signInTwoFactor(ta, challenge.ChallengeToken, confirmed.RecoveryCodes[1]).Code)
+	response = signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	checkResponseCode(t, http.StatusAccepted, response.Code)
+	if err := json.Unmarshal(response.Body.Bytes(), &challenge); err != nil {
+		t.Fatalf("TestTwoFactorSignIn() error = %v", err)
+	}
+	checkResponseCode(t, http.StatusOK, signInTwoFactor(ta, challenge.ChallengeToken, confirmed.RecoveryCodes[1]).Code)
+	// The admin policy does not let root admins turn 2FA off
+	req, _ = http.NewRequest("DELETE", "/auth/2fa", bytes.NewBuffer([]byte(`{"code":"`+confirmed.RecoveryCodes[2]+`"}`)))
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(ta, req).Code)
+}
+
+// TestPasswordReset Test
+func TestPasswordReset(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	user := createTestUser(ta, 1)
+	authToken := signIn(ta, user.Email, "abc123").Header().Get("Auth-Token")
+	// Unknown emails are accepted without sending anything
+	req, _ := http.NewRequest("POST", "/auth/password/forgot", bytes.NewBuffer([]byte(`{"email":"nobody@example.com"}`)))
+	checkResponseCode(t, http.StatusAccepted, executeRequest(ta, req).Code)
+	if mailedToken(ta, "nobody@example.com") != "" {
+		t.Fatal("TestPasswordReset() emailed an unknown address")
+	}
+	req, _ = http.NewRequest("POST", "/auth/password/forgot", bytes.NewBuffer([]byte(`{"email":"`+user.Email+`"}`)))
+	checkResponseCode(t, http.StatusAccepted, executeRequest(ta, req).Code)
+	// The email is only queued while the request is handled, its token is created when it is sent
+	if len(ta.mailer.(*mailer.FakeMailer).Sent(user.Email)) != 0 {
+		t.Fatal("TestPasswordReset() sent the reset email while handling the request")
+	}
+	token := mailedToken(ta, user.Email)
+	if token == "" {
+		t.Fatal("TestPasswordReset() reset email was not sent")
+	}
+	// Reset tokens can not be used as session tokens
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.Header.Add("Auth-Token", token)
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+	payload := []byte(`{"token":"` + token + `","new_password":"789test124"}`)
+	req, _ = http.NewRequest("POST", "/auth/password/reset", bytes.NewBuffer(payload))
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	// The reset signs out every device and the token is single use
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+	checkResponseCode(t, http.StatusUnauthorized, signIn(ta, user.Email, "abc123").Code)
+	checkResponseCode(t, http.StatusOK, signIn(ta, user.Email, "789test124").Code)
+	req, _ = http.NewRequest("POST", "/auth/password/reset", bytes.NewBuffer(payload))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(ta, req).Code)
+}
+
+// TestVerifyEmail Test
+func TestVerifyEmail(t *testing.T) {
+	// Test Setup
+	setup()
+	t.Setenv("REQUIRE_VERIFIED_EMAIL", "true")
+	createTestGroup(ta, 1)
+	payload := []byte(`{"username":"new_user","password":"abc123","email":"new_user@example.com","phone":"5555555555"}`)
+	req, _ := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(payload))
+	response := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	authToken := response.Header().Get("Auth-Token")
+	// Unverified users can not send messages
+	req, _ = http.NewRequest("POST", "/messages", bytes.NewBuffer(getTestTaskPayload("CREATE")))
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(ta, req).Code)
+	token := mailedToken(ta, "new_user@example.com")
+	if token == "" {
+		t.Fatal("TestVerifyEmail() verification email was not sent")
+	}
+	req, _ = http.NewRequest("POST", "/auth/verify-email", bytes.NewBuffer([]byte(`{"token":"bad"}`)))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("POST", "/auth/verify-email", bytes.NewBuffer([]byte(`{"token":"`+token+`"}`)))
+	response = executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var verified models.User
+	if err := json.Unmarshal(response.Body.Bytes(), &verified); err != nil {
+		t.Fatalf("TestVerifyEmail() error = %v", err)
+	}
+	if !verified.EmailVerified() || verified.Password != "" {
+		t.Errorf("TestVerifyEmail() unexpected user %+v", verified)
+	}
+	req, _ = http.NewRequest("POST", "/messages", bytes.NewBuffer(getTestTaskPayload("CREATE")))
+	req.Header.Add("Auth-Token", authToken)
+	if code := executeRequest(ta, req).Code; code == http.StatusForbidden {
+		t.Errorf("TestVerifyEmail() verified user was kept from sending messages")
+	}
+	req, _ = http.NewRequest("POST", "/auth/verify-email/resend", nil)
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusConflict, executeRequest(ta, req).Code)
+}
+
+// TestTrustedProxies Test
+func TestTrustedProxies(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	user := createTestUser(ta, 1)
+	sessionIP := func(forwarded string) string {
+		req, _ := http.NewRequest("POST", "/auth", bytes.NewBuffer([]byte(`{"email":"`+user.Email+`","password":"abc123"}`)))
+		req.RemoteAddr = "10.0.0.2:41000"
+		req.Header.Add("X-Forwarded-For", forwarded)
+		response := executeRequest(ta, req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+		req, _ = http.NewRequest("GET", "/auth/sessions", nil)
+		req.Header.Add("Auth-Token", response.Header().Get("Auth-Token"))
+		response = executeRequest(ta, req)
+		var sessions []*models.Session
+		if err := json.Unmarshal(response.Body.Bytes(), &sessions); err != nil {
+			t.Fatalf("TestTrustedProxies() error = %v", err)
+		}
+		for _, s := range sessions {
+			if s.Current {
+				return s.IPAddress
+			}
+		}
+		return ""
+	}
+	// X-Forwarded-For is ignored unless the request came from a trusted proxy
+	if ip := sessionIP("203.0.113.9"); ip != "10.0.0.2" {
+		t.Errorf("TestTrustedProxies() untrusted proxy recorded ip %q, want 10.0.0.2", ip)
+	}
+	// Behind trusted proxies the client is the last address that is not one of them
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.7")
+	if ip := sessionIP("198.51.100.1, 203.0.113.9, 192.0.2.7"); ip != "203.0.113.9" {
+		t.Errorf("TestTrustedProxies() trusted proxy recorded ip %q, want 203.0.113.9", ip)
+	}
+}
+
+// TestSignInLockout Test
+func TestSignInLockout(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	user := createTestUser(ta, 1)
+	// Wrong passwords and unknown emails fail the same way
+	wrongPassword := signIn(ta, user.Email, "wrong-password")
+	checkResponseCode(t, http.StatusUnauthorized, wrongPassword.Code)
+	unknownEmail := signIn(ta, "nobody@email.com", "wrong-password")
+	checkResponseCode(t, http.StatusUnauthorized, unknownEmail.Code)
+	if wrongPassword.Body.String() != unknownEmail.Body.String() {
+		t.Fatalf("TestSignInLockout() responses differ: %s != %s", wrongPassword.Body.String(), unknownEmail.Body.String())
+	}
+	for i := 0; i < 4; i++ {
+		signIn(ta, user.Email, "wrong-password")
+		signIn(ta, "nobody@email.com", "wrong-password")
+	}
+	// Both accounts are locked out, even with the right password
+	response := signIn(ta, user.Email, "abc123")
+	checkResponseCode(t, http.StatusTooManyRequests, response.Code)
+	if response.Header().Get("Retry-After") == "" || response.Header().Get("Auth-Token") != "" {
+		t.Fatal("TestSignInLockout() locked out sign in is missing Retry-After or started a session")
+	}
+	checkResponseCode(t, http.StatusTooManyRequests, signIn(ta, "nobody@email.com", "wrong-password").Code)
+	// Admins can list and clear lockouts
+	adminToken := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD")).Header().Get("Auth-Token")
+	req, _ := http.NewRequest("GET", "/auth/lockouts", nil)
+	req.Header.Add("Auth-Token", adminToken)
+	response = executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var lockouts []*models.LoginAttempt
+	if err := json.Unmarshal(response.Body.Bytes(), &lockouts); err != nil {
+		t.Fatalf("TestSignInLockout() error = %v", err)
+	}
+	if len(lockouts) != 2 {
+		t.Fatalf("TestSignInLockout() expected 2 lockouts, got %d", len(lockouts))
+	}
+	var lockoutId string
+	for _, lockout := range lockouts {
+		if lockout.Kind == models.LoginAttemptAccount && lockout.Key == user.Email {
+			lockoutId = lockout.Id
+		}
+	}
+	req, _ = http.NewRequest("DELETE", "/auth/lockouts/"+lockoutId, nil)
+	req.Header.Add("Auth-Token", adminToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	checkResponseCode(t, http.StatusOK, signIn(ta, user.Email, "abc123").Code)
+	checkResponseCode(t, http.StatusTooManyRequests, signIn(ta, "nobody@email.com", "wrong-password").Code)
+	// Members can not see lockouts
+	req, _ = http.NewRequest("GET", "/auth/lockouts", nil)
+	req.Header.Add("Auth-Token", signIn(ta, user.Email, "abc123").Header().Get("Auth-Token"))
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+}
+
+// TestPasswordPolicy Test
+func TestPasswordPolicy(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	// New accounts have to follow the policy
+	for _, pw := range []string{"abc", "password"} {
+		payload := []byte(`{"username":"new_user","password":"` + pw + `","email":"new_user@example.com","phone":"5555555555"}`)
+		req, _ := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(payload))
+		checkResponseCode(t, http.StatusBadRequest, executeRequest(ta, req).Code)
+	}
+	// Old bcrypt hashes are replaced with argon2id hashes on sign in
+	user := createTestUser(ta, 1)
+	checkResponseCode(t, http.StatusOK, signIn(ta, user.Email, "abc123").Code)
+	stored, err := ta.server.UserService.UserFind(&models.User{Id: user.Id})
+	if err != nil {
+		t.Fatalf("TestPasswordPolicy() error = %v", err)
+	}
+	if !strings.HasPrefix(stored.Password, "$argon2id$") {
+		t.Fatalf("TestPasswordPolicy() password hash was not upgraded: %s", stored.Password)
+	}
+	authToken := signIn(ta, user.Email, "abc123").Header().Get("Auth-Token")
+	if authToken == "" {
+		t.Fatal("TestPasswordPolicy() sign in failed after the password hash was upgraded")
+	}
+	// Password changes follow the policy and can not reuse the last 3 passwords
+	changePassword := func(current string, next string) int {
+		payload := []byte(`{"current_password":"` + current + `","new_password":"` + next + `"}`)
+		req, _ := http.NewRequest("POST", "/auth/password", bytes.NewBuffer(payload))
+		req.Header.Add("Auth-Token", authToken)
+		return executeRequest(ta, req).Code
+	}
+	checkResponseCode(t, http.StatusBadRequest, changePassword("abc123", "abc123"))
+	checkResponseCode(t, http.StatusBadRequest, changePassword("abc123", "qwerty123"))
+	checkResponseCode(t, http.StatusAccepted, changePassword("abc123", "newpass1"))
+	checkResponseCode(t, http.StatusAccepted, changePassword("newpass1", "newpass2"))
+	checkResponseCode(t, http.StatusBadRequest, changePassword("newpass2", "abc123"))
+	checkResponseCode(t, http.StatusAccepted, changePassword("newpass2", "newpass3"))
+	checkResponseCode(t, http.StatusAccepted, changePassword("newpass3", "abc123"))
+	checkResponseCode(t, http.StatusOK, signIn(ta, user.Email, "abc123").Code)
+}
+
+// TestOIDCSignIn Test
+func TestOIDCSignIn(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	mock := setupTestOIDC(t)
+	req, _ := http.NewRequest("GET", "/auth/oidc", nil)
+	response := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if strings.TrimSpace(response.Body.String()) != `["mock"]` {
+		t.Fatalf("TestOIDCSignIn() providers = %s", response.Body.String())
+	}
+	req, _ = http.NewRequest("POST", "/auth/oidc/unknown", nil)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(ta, req).Code)
+	// Unknown provider accounts get a new verified account while registration is on
+	mock.SetUser(oidc.MockUser{Subject: "sub-1", Email: "sso_user@example.com", EmailVerified: true, Name: "SSO User"})
+	response = signInOIDC(ta, mock)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if response.Header().Get("Auth-Token") == "" || response.Header().Get("Refresh-Token") == "" {
+		t.Fatal("TestOIDCSignIn() sign in did not start a session")
+	}
+	var created models.User
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+		t.Fatalf("TestOIDCSignIn() error = %v", err)
+	}
+	if created.Username != "sso_user" || created.EmailVerifiedAt.IsZero() || created.Password != "" {
+		t.Fatalf("TestOIDCSignIn() created user = %+v", created)
+	}
+	// The linked identity signs in to the same account, even after its email changes at the provider
+	mock.SetUser(oidc.MockUser{Subject: "sub-1", Email: "renamed@example.com", EmailVerified: true})
+	response = signInOIDC(ta, mock)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var user models.User
+	_ = json.Unmarshal(response.Body.Bytes(), &user)
+	if user.Id != created.Id {
+		t.Fatalf("TestOIDCSignIn() linked sign in returned user %s, want %s", user.Id, created.Id)
+	}
+	// Existing accounts are only linked when both the provider and the account verified the email
+	local := createTestUser(ta, 1)
+	mock.SetUser(oidc.MockUser{Subject: "sub-2", Email: local.Email, EmailVerified: false})
+	checkResponseCode(t, http.StatusConflict, signInOIDC(ta, mock).Code)
+	mock.SetUser(oidc.MockUser{Subject: "sub-2", Email: local.Email, EmailVerified: true})
+	checkResponseCode(t, http.StatusConflict, signInOIDC(ta, mock).Code)
+	if _, err := ta.server.UserService.UserVerifyEmail(&models.User{Id: local.Id, Email: local.Email}); err != nil {
+		t.Fatalf("TestOIDCSignIn() error = %v", err)
+	}
+	response = signInOIDC(ta, mock)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	_ = json.Unmarshal(response.Body.Bytes(), &user)
+	if user.Id != local.Id {
+		t.Fatalf("TestOIDCSignIn() linked user %s, want %s", user.Id, local.Id)
+	}
+	// States are single use and checked against the provider
+	req, _ = http.NewRequest("POST", "/auth/oidc/mock", nil)
+	response = executeRequest(ta, req)
+	var login struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}
+	_ = json.Unmarshal(response.Body.Bytes(), &login)
+	code, state, err := mock.Authorize(login.AuthorizationURL)
+	if err != nil {
+		t.Fatalf("TestOIDCSignIn() error = %v", err)
+	}
+	checkResponseCode(t, http.StatusUnauthorized, oidcCallback(ta, "mock", code, "wrong-state").Code)
+	checkResponseCode(t, http.StatusOK, oidcCallback(ta, "mock", code, state).Code)
+	checkResponseCode(t, http.StatusUnauthorized, oidcCallback(ta, "mock", code, state).Code)
+	// New accounts are refused while registration is off
+	t.Setenv("REGISTRATION", "OFF")
+	mock.SetUser(oidc.MockUser{Subject: "sub-3", Email: "closed@example.com", EmailVerified: true})
+	checkResponseCode(t, http.StatusForbidden, signInOIDC(ta, mock).Code)
+}
+
+// TestUserRoles Test
+func TestUserRoles(t *testing.T) {
+	// Test Setup
+	setup()
+	createTestGroup(ta, 1)
+	// Self-registered users are plain members
+	payload := []byte(`{"username":"new_user","password":"abc123","email":"new_user@example.com","phone":"5555555555"}`)
+	req, _ := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(payload))
+	response := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var member models.User
+	if err := json.Unmarshal(response.Body.Bytes(), &member); err != nil {
+		t.Fatalf("TestUserRoles() error = %v", err)
+	}
+	if member.Role != models.RoleMember || member.RootAdmin {
+		t.Fatalf("TestUserRoles() registered user role = %q, root_admin = %v", member.Role, member.RootAdmin)
+	}
+	memberToken := response.Header().Get("Auth-Token")
+	get := func(path string, authToken string) int {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Add("Auth-Token", authToken)
+		return executeRequest(ta, req).Code
+	}
+	changeRole := func(userId string, role string, authToken string) int {
+		req, _ := http.NewRequest("PUT", "/users/"+userId+"/role", bytes.NewBuffer([]byte(`{"role":"`+role+`"}`)))
+		req.Header.Add("Auth-Token", authToken)
+		return executeRequest(ta, req).Code
+	}
+	checkResponseCode(t, http.StatusUnauthorized, get("/auth/lockouts", memberToken))
+	checkResponseCode(t, http.StatusUnauthorized, changeRole(member.Id, models.RoleSupport, memberToken))
+	// Root admins promote users, which applies to the tokens they already hold
+	adminResponse := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	adminToken := adminResponse.Header().Get("Auth-Token")
+	var admin models.User
+	_ = json.Unmarshal(adminResponse.Body.Bytes(), &admin)
+	if admin.Role != models.RoleRootAdmin {
+		t.Fatalf("TestUserRoles() root admin role = %q", admin.Role)
+	}
+	checkResponseCode(t, http.StatusAccepted, changeRole(member.Id, models.RoleSupport, adminToken))
+	checkResponseCode(t, http.StatusOK, get("/auth/lockouts", memberToken))
+	checkResponseCode(t, http.StatusUnauthorized, get("/audit-events", memberToken))
+	checkResponseCode(t, http.StatusUnauthorized, changeRole(admin.Id, models.RoleMember, memberToken))
+	// Roles have to exist, admins can not change their own role and bots stay bots
+	checkResponseCode(t, http.StatusBadRequest, changeRole(member.Id, "owner", adminToken))
+	checkResponseCode(t, http.StatusBadRequest, changeRole(member.Id, models.RoleBot, adminToken))
+	checkResponseCode(t, http.StatusForbidden, changeRole(admin.Id, models.RoleMember, adminToken))
+	checkResponseCode(t, http.StatusNotFound, changeRole("000000000000000000000099", models.RoleSupport, adminToken))
+	// Demotions apply right away as well
+	checkResponseCode(t, http.StatusAccepted, changeRole(member.Id, models.RoleMember, adminToken))
+	checkResponseCode(t, http.StatusUnauthorized, get("/auth/lockouts", memberToken))
+	// Every change is in the audit trail
+	req, _ = http.NewRequest("GET", "/audit-events?target_id="+member.Id, nil)
+	req.Header.Add("Auth-Token", adminToken)
+	response = executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var events []*models.AuditEvent
+	if err := json.Unmarshal(response.Body.Bytes(), &events); err != nil {
+		t.Fatalf("TestUserRoles() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("TestUserRoles() expected 2 audit events, got %d", len(events))
+	}
+	for _, event := range events {
+		if event.Action != models.AuditRoleChange || event.ActorId != admin.Id || event.TargetId != member.Id {
+			t.Fatalf("TestUserRoles() audit event = %+v", event)
+		}
+	}
+	if events[0].From != models.RoleSupport || events[0].To != models.RoleMember {
+		t.Fatalf("TestUserRoles() latest audit event = %+v", events[0])
+	}
+}
+
+// TestBots Test
+func TestBots(t *testing.T) {
+	// Test Setup
+	setup()
+	group := createTestGroup(ta, 1)
+	owner := createTestUser(ta, 1)
+	createTestUser(ta, 2)
+	ownerToken := signIn(ta, owner.Email, "abc123").Header().Get("Auth-Token")
+	otherToken := signIn(ta, "test3@email.com.com", "abc123").Header().Get("Auth-Token")
+	srv, received := startTestWebhook(t)
+	send := func(header string, token string, payload string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/messages", bytes.NewBuffer([]byte(payload)))
+		req.Header.Add(header, token)
+		return executeRequest(ta, req)
+	}
+	// Bots are created with a bot token and a webhook secret
+	req, _ := http.NewRequest("POST", "/bots", bytes.NewBuffer([]byte(`{"username":"helper_bot","webhook_url":"ftp://example.com"}`)))
+	req.Header.Add("Auth-Token", ownerToken)
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("POST", "/bots", bytes.NewBuffer([]byte(`{"username":"helper_bot","webhook_url":"`+srv.URL+`"}`)))
+	req.Header.Add("Auth-Token", ownerToken)
+	response := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var created struct {
+		Bot   models.Bot    `json:"bot"`
+		Token models.APIKey `json:"token"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+		t.Fatalf("TestBots() error = %v", err)
+	}
+	bot := created.Bot
+	if bot.OwnerId != owner.Id || bot.WebhookSecret == "" || created.Token.Key == "" {
+		t.Fatalf("TestBots() unexpected bot %+v", created)
+	}
+	// Bots never sign in with a password
+	checkResponseCode(t, http.StatusUnauthorized, signIn(ta, "bot-"+bot.UserId+"@bots.invalid", "abc123").Code)
+	// Messages sent to a bot are delivered to its webhook, signed with its secret
+	checkResponseCode(t, http.StatusCreated, send("Auth-Token", ownerToken, `{"receiver_id":"`+bot.UserId+`","content":"hello bot"}`).Code)
+	d := awaitWebhook(t, received)
+	if err := webhook.Verify(bot.WebhookSecret, d.header, d.body); err != nil {
+		t.Fatalf("TestBots() delivery error = %v", err)
+	}
+	var event models.Event
+	if err := json.Unmarshal(d.body, &event); err != nil {
+		t.Fatalf("TestBots() error = %v", err)
+	}
+	if event.Type != models.EventMessageCreated || event.Message.SenderID != owner.Id || event.Message.Content != "hello bot" {
+		t.Errorf("TestBots() unexpected event %+v", event)
+	}
+	// Bots post through the normal message routes with their token
+	response = send("API-Key", created.Token.Key, `{"receiver_id":"`+owner.Id+`","sender_id":"`+owner.Id+`","content":"hello human"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var reply models.Message
+	_ = json.Unmarshal(response.Body.Bytes(), &reply)
+	if reply.SenderID != bot.UserId {
+		t.Errorf("TestBots() bot message sender = %q", reply.SenderID)
+	}
+	// Bots added to a group receive the events of the group
+	_, err := ta.server.GroupMembershipsService.GroupMembershipDocInsert(&models.GroupMembership{Id: "000000000000000000000051", GroupId: group.Id, UserId: bot.UserId})
+	if err != nil {
+		t.Fatalf("TestBots() error = %v", err)
+	}
+	checkResponseCode(t, http.StatusCreated, send("Auth-Token", ownerToken, `{"receiver_id":"`+group.Id+`","group":true,"content":"hello group"}`).Code)
+	d = awaitWebhook(t, received)
+	_ = json.Unmarshal(d.body, &event)
+	if event.Message.ReceiverID != group.Id || d.header.Get(webhook.EventHeader) != models.EventMessageCreated {
+		t.Errorf("TestBots() unexpected group event %+v", event)
+	}
+	// Only the owner manages a bot
+	req, _ = http.NewRequest("PATCH", "/bots/"+bot.UserId, bytes.NewBuffer([]byte(`{"webhook_url":""}`)))
+	req.Header.Add("Auth-Token", otherToken)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("PATCH", "/bots/"+bot.UserId, bytes.NewBuffer([]byte(`{"webhook_url":""}`)))
+	req.Header.Add("Auth-Token", ownerToken)
+	checkResponseCode(t, http.StatusAccepted, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("GET", "/bots", nil)
+	req.Header.Add("Auth-Token", ownerToken)
+	response = executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if !strings.Contains(response.Body.String(), bot.UserId) || strings.Contains(response.Body.String(), srv.URL) {
+		t.Errorf("TestBots() unexpected bots %s", response.Body.String())
+	}
+	// Rotating the token revokes the old one
+	req, _ = http.NewRequest("POST", "/bots/"+bot.UserId+"/token", nil)
+	req.Header.Add("Auth-Token", ownerToken)
+	response = executeRequest(ta, req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var rotated struct {
+		Token models.APIKey `json:"token"`
+	}
+	_ = json.Unmarshal(response.Body.Bytes(), &rotated)
+	checkResponseCode(t, http.StatusUnauthorized, send("API-Key", created.Token.Key, `{"receiver_id":"`+owner.Id+`","content":"old token"}`).Code)
+	checkResponseCode(t, http.StatusCreated, send("API-Key", rotated.Token.Key, `{"receiver_id":"`+owner.Id+`","content":"new token"}`).Code)
+	// Deleting a bot removes it along with its token
+	req, _ = http.NewRequest("DELETE", "/bots/"+bot.UserId, nil)
+	req.Header.Add("Auth-Token", otherToken)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("DELETE", "/bots/"+bot.UserId, nil)
+	req.Header.Add("Auth-Token", ownerToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	checkResponseCode(t, http.StatusUnauthorized, send("API-Key", rotated.Token.Key, `{"receiver_id":"`+owner.Id+`","content":"deleted"}`).Code)
+}
+
+// TestGroupWebhooks Test
+func TestGroupWebhooks(t *testing.T) {
+	// Test Setup
+	setup()
+	t.Setenv("WEBHOOK_RETRY_BACKOFF", "1ms")
+	group := createTestGroup(ta, 1)
+	admin := createTestUser(ta, 1)
+	member := createTestUser(ta, 2)
+	_, err := ta.server.GroupMembershipsService.GroupMembershipDocInsert(&models.GroupMembership{Id: "000000000000000000000061", GroupId: group.Id, UserId: admin.Id, Admin: true})
+	if err != nil {
+		t.Fatalf("TestGroupWebhooks() error = %v", err)
+	}
+	adminToken := signIn(ta, admin.Email, "abc123").Header().Get("Auth-Token")
+	memberToken := signIn(ta, member.Email, "abc123").Header().Get("Auth-Token")
+	srv, received := startTestWebhook(t)
+	hooksPath := "/groups/" + group.Id + "/webhooks"
+	// Group admins register webhooks for the events they want
+	checkResponseCode(t, http.StatusUnauthorized, sendRequest(ta, "POST", hooksPath, memberToken, `{"url":"`+srv.URL+`"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", hooksPath, adminToken, `{"url":"not a url"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", hooksPath, adminToken, `{"url":"`+srv.URL+`","events":["message.read"]}`).Code)
+	response := sendRequest(ta, "POST", hooksPath, adminToken, `{"url":"`+srv.URL+`","events":["message.created","message.edited","member.joined"]}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var hook models.Webhook
+	if err = json.Unmarshal(response.Body.Bytes(), &hook); err != nil || hook.Secret == "" {
+		t.Fatalf("TestGroupWebhooks() unexpected webhook %s", response.Body.String())
+	}
+	// Group events are delivered signed with the secret of the webhook
+	response = sendRequest(ta, "POST", "/messages", adminToken, `{"receiver_id":"`+group.Id+`","group":true,"content":"hello"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var message models.Message
+	_ = json.Unmarshal(response.Body.Bytes(), &message)
+	d := awaitWebhook(t, received)
+	if err = webhook.Verify(hook.Secret, d.header, d.body); err != nil {
+		t.Fatalf("TestGroupWebhooks() delivery error = %v", err)
+	}
+	var event models.Event
+	_ = json.Unmarshal(d.body, &event)
+	if event.Type != models.EventMessageCreated || event.GroupId != group.Id || event.Message.Id != message.Id {
+		t.Errorf("TestGroupWebhooks() unexpected event %+v", event)
+	}
+	checkResponseCode(t, http.StatusForbidden, sendRequest(ta, "PATCH", "/messages/"+message.Id, memberToken, `{"content":"edited"}`).Code)
+	checkResponseCode(t, http.StatusAccepted, sendRequest(ta, "PATCH", "/messages/"+message.Id, adminToken, `{"content":"edited"}`).Code)
+	d = awaitWebhook(t, received)
+	_ = json.Unmarshal(d.body, &event)
+	if event.Type != models.EventMessageEdited || event.Message.Content != "edited" {
+		t.Errorf("TestGroupWebhooks() unexpected event %+v", event)
+	}
+	checkResponseCode(t, http.StatusCreated, sendRequest(ta, "POST", "/groups/"+group.Id+"/users/"+member.Id, adminToken, `{}`).Code)
+	d = awaitWebhook(t, received)
+	_ = json.Unmarshal(d.body, &event)
+	if event.Type != models.EventMemberJoined || event.Member.UserId != member.Id {
+		t.Errorf("TestGroupWebhooks() unexpected event %+v", event)
+	}
+	// Every delivery and its attempts are recorded, once the attempts that claimed them finished
+	var deliveries webhookDeliveries
+	for i := 0; i < 100; i++ {
+		response = sendRequest(ta, "GET", hooksPath+"/"+hook.Id+"/deliveries", adminToken, "")
+		checkResponseCode(t, http.StatusOK, response.Code)
+		_ = json.Unmarshal(response.Body.Bytes(), &deliveries)
+		finished := true
+		for _, delivery := range deliveries.Deliveries {
+			finished = finished && delivery.Status != models.DeliveryDelivering
+		}
+		if finished {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(deliveries.Deliveries) != 3 {
+		t.Fatalf("TestGroupWebhooks() expected 3 deliveries, got %s", response.Body.String())
+	}
+	for _, delivery := range deliveries.Deliveries {
+		if delivery.Status != models.DeliveryDelivered || len(delivery.Attempts) != 1 {
+			t.Errorf("TestGroupWebhooks() unexpected delivery %+v", delivery)
+		}
+	}
+	// Endpoints that keep failing are retried with backoff and then disabled
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	response = sendRequest(ta, "POST", hooksPath, adminToken, `{"url":"`+failing.URL+`","events":["message.created"]}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var failingHook models.Webhook
+	_ = json.Unmarshal(response.Body.Bytes(), &failingHook)
+	for i := 0; i < 2; i++ {
+		checkResponseCode(t, http.StatusCreated, sendRequest(ta, "POST", "/messages", adminToken, `{"receiver_id":"`+group.Id+`","group":true,"content":"retry"}`).Code)
+		awaitWebhook(t, received)
+	}
+	// wait for the first attempts, which are made as the messages are sent, to finish
+	for i := 0; i < 100; i++ {
+		response = sendRequest(ta, "GET", hooksPath+"/"+failingHook.Id+"/deliveries", adminToken, "")
+		_ = json.Unmarshal(response.Body.Bytes(), &deliveries)
+		attempted := len(deliveries.Deliveries) == 2
+		for _, delivery := range deliveries.Deliveries {
+			attempted = attempted && delivery.Status != models.DeliveryDelivering && len(delivery.Attempts) > 0
+		}
+		if attempted {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 50; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if _, err = ta.server.WebhookService.WebhookDeliverPending(); err != nil {
+			t.Fatalf("TestGroupWebhooks() error = %v", err)
+		}
+	}
+	response = sendRequest(ta, "GET", hooksPath+"/"+failingHook.Id+"/deliveries", adminToken, "")
+	_ = json.Unmarshal(response.Body.Bytes(), &deliveries)
+	if len(deliveries.Deliveries) != 2 {
+		t.Fatalf("TestGroupWebhooks() expected 2 deliveries, got %s", response.Body.String())
+	}
+	attempts := 0
+	for _, delivery := range deliveries.Deliveries {
+		if delivery.Status != models.DeliveryFailed || delivery.Attempts[0].Error == "" {
+			t.Errorf("TestGroupWebhooks() unexpected delivery %+v", delivery)
+		}
+		attempts += len(delivery.Attempts)
+	}
+	if attempts != 10 {
+		t.Errorf("TestGroupWebhooks() expected 10 attempts before the webhook was disabled, got %d", attempts)
+	}
+	response = sendRequest(ta, "GET", hooksPath, adminToken, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var hooks struct {
+		Webhooks []*models.Webhook `json:"webhooks"`
+	}
+	_ = json.Unmarshal(response.Body.Bytes(), &hooks)
+	for _, h := range hooks.Webhooks {
+		if h.Secret != "" || (h.Id == failingHook.Id) != h.Disabled {
+			t.Errorf("TestGroupWebhooks() unexpected webhook %+v", h)
+		}
+	}
+	// Admins enable a disabled webhook again once its endpoint is fixed
+	response = sendRequest(ta, "PATCH", hooksPath+"/"+failingHook.Id, adminToken, `{"disabled":false}`)
+	checkResponseCode(t, http.StatusAccepted, response.Code)
+	_ = json.Unmarshal(response.Body.Bytes(), &failingHook)
+	if failingHook.Disabled || failingHook.Failures != 0 {
+		t.Errorf("TestGroupWebhooks() unexpected webhook %+v", failingHook)
+	}
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "DELETE", hooksPath+"/"+failingHook.Id, adminToken, "").Code)
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "GET", hooksPath+"/"+failingHook.Id+"/deliveries", adminToken, "").Code)
+}
+
+// TestIncomingWebhooks Test
+func TestIncomingWebhooks(t *testing.T) {
+	// Test Setup
+	setup()
+	group := createTestGroup(ta, 1)
+	admin := createTestUser(ta, 1)
+	member := createTestUser(ta, 2)
+	_, err := ta.server.GroupMembershipsService.GroupMembershipDocInsert(&models.GroupMembership{Id: "000000000000000000000061", GroupId: group.Id, UserId: admin.Id, Admin: true})
+	if err != nil {
+		t.Fatalf("TestIncomingWebhooks() error = %v", err)
+	}
+	conversation, err := ta.server.ConversationService.ConversationDocInsert(&models.Conversation{Id: "000000000000000000000071", ParticipantsIds: []string{group.Id}, Group: true})
+	if err != nil {
+		t.Fatalf("TestIncomingWebhooks() error = %v", err)
+	}
+	adminToken := signIn(ta, admin.Email, "abc123").Header().Get("Auth-Token")
+	memberToken := signIn(ta, member.Email, "abc123").Header().Get("Auth-Token")
+	hooksPath := "/conversations/" + conversation.Id + "/incoming-webhooks"
+	// Group admins add incoming webhooks to the conversations of their group
+	checkResponseCode(t, http.StatusUnauthorized, sendRequest(ta, "POST", hooksPath, memberToken, `{"name":"ci"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", hooksPath, adminToken, `{}`).Code)
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "POST", "/conversations/000000000000000000000079/incoming-webhooks", adminToken, `{"name":"ci"}`).Code)
+	response := sendRequest(ta, "POST", hooksPath, adminToken, `{"name":"ci"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var hook models.IncomingWebhook
+	if err = json.Unmarshal(response.Body.Bytes(), &hook); err != nil || hook.Token == "" || hook.UserId == "" {
+		t.Fatalf("TestIncomingWebhooks() unexpected incoming webhook %s", response.Body.String())
+	}
+	response = sendRequest(ta, "GET", hooksPath, adminToken, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var hooks struct {
+		IncomingWebhooks []*models.IncomingWebhook `json:"incoming_webhooks"`
+	}
+	_ = json.Unmarshal(response.Body.Bytes(), &hooks)
+	if len(hooks.IncomingWebhooks) != 1 || hooks.IncomingWebhooks[0].Token != "" {
+		t.Fatalf("TestIncomingWebhooks() unexpected incoming webhooks %s", response.Body.String())
+	}
+	// Posting to the token of the webhook creates a message from its integration user
+	checkResponseCode(t, http.StatusUnauthorized, sendRequest(ta, "POST", "/hooks/ihk_invalid", "", `{"text":"build passed"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", "/hooks/"+hook.Token, "", `{}`).Code)
+	response = sendRequest(ta, "POST", "/hooks/"+hook.Token, "", `{"text":"build passed"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var message models.Message
+	_ = json.Unmarshal(response.Body.Bytes(), &message)
+	if message.SenderID != hook.UserId || message.ConversationID != conversation.Id || message.Content != "build passed" {
+		t.Errorf("TestIncomingWebhooks() unexpected message %+v", message)
+	}
+	// Each webhook is rate limited
+	t.Setenv("INCOMING_WEBHOOK_RATE_LIMIT", "3")
+	checkResponseCode(t, http.StatusCreated, sendRequest(ta, "POST", "/hooks/"+hook.Token, "", `{"text":"build passed"}`).Code)
+	response = sendRequest(ta, "POST", "/hooks/"+hook.Token, "", `{"text":"build passed"}`)
+	checkResponseCode(t, http.StatusTooManyRequests, response.Code)
+	if response.Header().Get("Retry-After") == "" {
+		t.Errorf("TestIncomingWebhooks() expected a Retry-After header")
+	}
+	// Revoked webhooks stop accepting posts
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "DELETE", hooksPath+"/000000000000000000000079", adminToken, "").Code)
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "DELETE", hooksPath+"/"+hook.Id, adminToken, "").Code)
+	checkResponseCode(t, http.StatusUnauthorized, sendRequest(ta, "POST", "/hooks/"+hook.Token, "", `{"text":"build passed"}`).Code)
+}
+
+// TestSlashCommands Test
+func TestSlashCommands(t *testing.T) {
+	// Test Setup
+	setup()
+	group := createTestGroup(ta, 1)
+	admin := createTestUser(ta, 1)
+	member := createTestUser(ta, 2)
+	_, err := ta.server.GroupMembershipsService.GroupMembershipDocInsert(&models.GroupMembership{Id: "000000000000000000000061", GroupId: group.Id, UserId: admin.Id, Admin: true})
+	if err != nil {
+		t.Fatalf("TestSlashCommands() error = %v", err)
+	}
+	adminToken := signIn(ta, admin.Email, "abc123").Header().Get("Auth-Token")
+	memberToken := signIn(ta, member.Email, "abc123").Header().Get("Auth-Token")
+	run := func(token string, content string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(map[string]interface{}{"receiver_id": group.Id, "group": true, "content": content})
+		return sendRequest(ta, "POST", "/messages", token, string(payload))
+	}
+	reply := func(response *httptest.ResponseRecorder) models.CommandResponse {
+		var r models.CommandResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &r); err != nil || !r.Ephemeral {
+			t.Fatalf("TestSlashCommands() unexpected command response %s", response.Body.String())
+		}
+		return r
+	}
+	// Commands are run by the members of a group and answered only to them
+	checkResponseCode(t, http.StatusForbidden, run(memberToken, "/mute 1h").Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", "/messages", adminToken, `{"receiver_id":"`+member.Id+`","content":"/mute 1h"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, run(adminToken, "/invite").Code)
+	checkResponseCode(t, http.StatusBadRequest, run(adminToken, "/invite @nobody").Code)
+	response := run(adminToken, "/invite @"+member.Username)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if r := reply(response); r.Command != "invite" || r.Text != "Invited @"+member.Username {
+		t.Errorf("TestSlashCommands() unexpected invite response %+v", r)
+	}
+	checkResponseCode(t, http.StatusBadRequest, run(adminToken, "/invite @"+member.Username).Code)
+	checkResponseCode(t, http.StatusForbidden, run(memberToken, "/invite @"+admin.Username).Code)
+	// Members mute and unmute the group for themselves
+	checkResponseCode(t, http.StatusBadRequest, run(memberToken, "/mute soon").Code)
+	checkResponseCode(t, http.StatusOK, run(memberToken, "/mute 1h").Code)
+	gm, err := ta.server.GroupMembershipsService.GroupMembershipFind(&models.GroupMembership{UserId: member.Id, GroupId: group.Id})
+	if err != nil || !gm.Muted(time.Now().Add(59*time.Minute)) || gm.Muted(time.Now().Add(61*time.Minute)) {
+		t.Fatalf("TestSlashCommands() unexpected membership %+v, %v", gm, err)
+	}
+	checkResponseCode(t, http.StatusOK, run(memberToken, "/mute off").Code)
+	gm, _ = ta.server.GroupMembershipsService.GroupMembershipFind(&models.GroupMembership{UserId: member.Id, GroupId: group.Id})
+	if gm.Muted(time.Now()) {
+		t.Errorf("TestSlashCommands() expected the group to be unmuted, got %+v", gm)
+	}
+	// Polls are posted to the group, commands themselves are never stored
+	checkResponseCode(t, http.StatusBadRequest, run(adminToken, `/poll "Lunch?" "Pizza"`).Code)
+	response = run(adminToken, `/poll "Lunch today?" "Pizza" "Sushi bar"`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	poll := reply(response).Message
+	if poll == nil || poll.Content != "Poll: Lunch today?\n1. Pizza\n2. Sushi bar" {
+		t.Fatalf("TestSlashCommands() unexpected poll %s", response.Body.String())
+	}
+	if _, err = ta.server.MessageService.MessageFind(&models.Message{Id: poll.Id}); err != nil {
+		t.Errorf("TestSlashCommands() poll was not stored, error = %v", err)
+	}
+	response = run(adminToken, "//shrug")
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var message models.Message
+	_ = json.Unmarshal(response.Body.Bytes(), &message)
+	if message.Content != "/shrug" {
+		t.Errorf("TestSlashCommands() unexpected message %+v", message)
+	}
+	checkResponseCode(t, http.StatusBadRequest, run(adminToken, "/echo hi").Code)
+	// Group admins register custom commands that forward to a URL or a bot
+	var secret string
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var invocation models.CommandInvocation
+		_ = json.Unmarshal(body, &invocation)
+		if webhook.Verify(secret, r.Header, body) != nil || r.Header.Get(webhook.EventHeader) != models.CommandEventType {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if invocation.Text == "raw" {
+			_, _ = w.Write([]byte("<html>not a command reply</html>"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"text":"` + invocation.UserId + ` said ` + invocation.Text + `"}`))
+	}))
+	defer echo.Close()
+	commandsPath := "/groups/" + group.Id + "/commands"
+	checkResponseCode(t, http.StatusUnauthorized, sendRequest(ta, "POST", commandsPath, memberToken, `{"name":"echo","url":"`+echo.URL+`"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", commandsPath, adminToken, `{"name":"mute","url":"`+echo.URL+`"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", commandsPath, adminToken, `{"name":"Echo!","url":"`+echo.URL+`"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", commandsPath, adminToken, `{"name":"echo"}`).Code)
+	response = sendRequest(ta, "POST", commandsPath, adminToken, `{"name":"echo","description":"repeats you","url":"`+echo.URL+`"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var echoCommand models.Command
+	_ = json.Unmarshal(response.Body.Bytes(), &echoCommand)
+	if secret = echoCommand.Secret; secret == "" {
+		t.Fatalf("TestSlashCommands() unexpected command %s", response.Body.String())
+	}
+	checkResponseCode(t, http.StatusConflict, sendRequest(ta, "POST", commandsPath, adminToken, `{"name":"echo","url":"`+echo.URL+`"}`).Code)
+	response = run(memberToken, "/echo hi there")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if r := reply(response); r.Text != member.Id+" said hi there" {
+		t.Errorf("TestSlashCommands() unexpected echo response %+v", r)
+	}
+	// Replies without a JSON text field are not passed on
+	checkResponseCode(t, http.StatusBadGateway, run(memberToken, "/echo raw").Code)
+	pong := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"text":"pong"}`))
+	}))
+	defer pong.Close()
+	response = sendRequest(ta, "POST", "/bots", adminToken, `{"username":"ping_bot","webhook_url":"`+pong.URL+`"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var created struct {
+		Bot models.Bot `json:"bot"`
+	}
+	_ = json.Unmarshal(response.Body.Bytes(), &created)
+	checkResponseCode(t, http.StatusCreated, sendRequest(ta, "POST", commandsPath, adminToken, `{"name":"ping","bot_id":"`+created.Bot.UserId+`"}`).Code)
+	// Bots of other users can only be used once they are members of the group
+	response = sendRequest(ta, "POST", "/bots", memberToken, `{"username":"other_bot","webhook_url":"`+pong.URL+`"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var other struct {
+		Bot models.Bot `json:"bot"`
+	}
+	_ = json.Unmarshal(response.Body.Bytes(), &other)
+	checkResponseCode(t, http.StatusForbidden, sendRequest(ta, "POST", commandsPath, adminToken, `{"name":"other","bot_id":"`+other.Bot.UserId+`"}`).Code)
+	_, err = ta.server.GroupMembershipsService.GroupMembershipDocInsert(&models.GroupMembership{Id: "000000000000000000000062", GroupId: group.Id, UserId: other.Bot.UserId})
+	if err != nil {
+		t.Fatalf("TestSlashCommands() error = %v", err)
+	}
+	checkResponseCode(t, http.StatusCreated, sendRequest(ta, "POST", commandsPath, adminToken, `{"name":"other","bot_id":"`+other.Bot.UserId+`"}`).Code)
+	response = run(memberToken, "/ping")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if r := reply(response); r.Command != "ping" || r.Text != "pong" {
+		t.Errorf("TestSlashCommands() unexpected ping response %+v", r)
+	}
+	// Commands whose endpoint fails report it, removed commands are unknown again
+	pong.Close()
+	checkResponseCode(t, http.StatusBadGateway, run(memberToken, "/ping").Code)
+	response = sendRequest(ta, "GET", commandsPath, adminToken, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var commands struct {
+		Commands []*models.Command `json:"commands"`
+	}
+	_ = json.Unmarshal(response.Body.Bytes(), &commands)
+	if len(commands.Commands) != 3 || commands.Commands[0].Secret != "" {
+		t.Fatalf("TestSlashCommands() unexpected commands %s", response.Body.String())
+	}
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "DELETE", commandsPath+"/"+echoCommand.Id, adminToken, "").Code)
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "DELETE", commandsPath+"/"+echoCommand.Id, adminToken, "").Code)
+	checkResponseCode(t, http.StatusBadRequest, run(memberToken, "/echo hi").Code)
 }
 
 /*
@@ -481,3 +1579,832 @@ func TestDeleteMessage(t *testing.T) {
 	// Clean database and do final status check
 	checkResponseCode(t, http.StatusOK, testResponse.Code)
 }
+
+/*
+UPLOAD TESTS
+*/
+
+// TestResumableUpload Test
+func TestResumableUpload(t *testing.T) {
+	// Test Setup
+	setup()
+	authResponse := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	authToken := authResponse.Header().Get("Auth-Token")
+	content := []byte("hello world!")
+	// Create upload session test
+	req, err := http.NewRequest("POST", "/uploads", bytes.NewBuffer(getTestUploadPayload(content, len(content))))
+	if err != nil {
+		t.Errorf("TestResumableUpload() error = %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Auth-Token", authToken)
+	testResponse := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusCreated, testResponse.Code)
+	var upload models.Upload
+	if err = json.Unmarshal(testResponse.Body.Bytes(), &upload); err != nil {
+		t.Fatalf("TestResumableUpload() error = %v", err)
+	}
+	// Upload chunks out of order, with one corrupted chunk
+	checkResponseCode(t, http.StatusAccepted, uploadTestChunk(ta, authToken, upload.Id, 0, content[0:4], content[0:4]).Code)
+	checkResponseCode(t, http.StatusBadRequest, uploadTestChunk(ta, authToken, upload.Id, 1, content[4:8], content[0:4]).Code)
+	checkResponseCode(t, http.StatusAccepted, uploadTestChunk(ta, authToken, upload.Id, 2, content[8:12], content[8:12]).Code)
+	// Resending a chunk replaces the copy received before
+	checkResponseCode(t, http.StatusAccepted, uploadTestChunk(ta, authToken, upload.Id, 0, content[0:4], content[0:4]).Code)
+	// Query received offset test
+	reqShow, _ := http.NewRequest("GET", "/uploads/"+upload.Id, nil)
+	reqShow.Header.Add("Auth-Token", authToken)
+	showResponse := executeRequest(ta, reqShow)
+	checkResponseCode(t, http.StatusOK, showResponse.Code)
+	if offset := showResponse.Header().Get("Upload-Offset"); offset != "4" {
+		t.Errorf("Expected upload offset 4. Got %s\n", offset)
+	}
+	// Completing an unfinished upload fails
+	reqComplete, _ := http.NewRequest("POST", "/uploads/"+upload.Id+"/complete", nil)
+	reqComplete.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(ta, reqComplete).Code)
+	// Resume and complete the upload
+	checkResponseCode(t, http.StatusAccepted, uploadTestChunk(ta, authToken, upload.Id, 1, content[4:8], content[4:8]).Code)
+	reqComplete, _ = http.NewRequest("POST", "/uploads/"+upload.Id+"/complete", nil)
+	reqComplete.Header.Add("Auth-Token", authToken)
+	completeResponse := executeRequest(ta, reqComplete)
+	checkResponseCode(t, http.StatusCreated, completeResponse.Code)
+	var file models.File
+	if err = json.Unmarshal(completeResponse.Body.Bytes(), &file); err != nil {
+		t.Fatalf("TestResumableUpload() error = %v", err)
+	}
+	// A completed upload takes no more chunks and completing it again returns the same file
+	checkResponseCode(t, http.StatusBadRequest, uploadTestChunk(ta, authToken, upload.Id, 1, content[4:8], content[4:8]).Code)
+	reqComplete, _ = http.NewRequest("POST", "/uploads/"+upload.Id+"/complete", nil)
+	reqComplete.Header.Add("Auth-Token", authToken)
+	completeResponse = executeRequest(ta, reqComplete)
+	var again models.File
+	_ = json.Unmarshal(completeResponse.Body.Bytes(), &again)
+	if again.Id != file.Id {
+		t.Errorf("Expected completing again to return file %s. Got %s\n", file.Id, completeResponse.Body.String())
+	}
+	// Download the assembled file
+	reqDownload, _ := http.NewRequest("GET", "/files/"+file.Id+"/download", nil)
+	reqDownload.Header.Add("Auth-Token", authToken)
+	downloadResponse := executeRequest(ta, reqDownload)
+	checkResponseCode(t, http.StatusOK, downloadResponse.Code)
+	if !bytes.Equal(downloadResponse.Body.Bytes(), content) {
+		t.Errorf("Expected downloaded content %q. Got %q\n", content, downloadResponse.Body.Bytes())
+	}
+	// Images are processed by their contents, whatever type they are declared as
+	imageResponse := uploadTestFile(ta, authToken, getTestImage(300, 200))
+	checkResponseCode(t, http.StatusCreated, imageResponse.Code)
+	var imageFile models.File
+	if err = json.Unmarshal(imageResponse.Body.Bytes(), &imageFile); err != nil {
+		t.Fatalf("TestResumableUpload() error = %v", err)
+	}
+	if imageFile.FileType != "image/png" || imageFile.Width != 300 || len(imageFile.Thumbnails) == 0 {
+		t.Errorf("Expected a processed 300px wide png. Got %s %dpx with %d thumbnails\n", imageFile.FileType, imageFile.Width, len(imageFile.Thumbnails))
+	}
+}
+
+// TestUploadSizeLimit Test
+func TestUploadSizeLimit(t *testing.T) {
+	// Test Setup
+	setup()
+	authResponse := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	authToken := authResponse.Header().Get("Auth-Token")
+	// Create an upload session larger than MAX_FILE_SIZE
+	req, err := http.NewRequest("POST", "/uploads", bytes.NewBuffer(getTestUploadPayload([]byte("large"), 1048577)))
+	if err != nil {
+		t.Errorf("TestUploadSizeLimit() error = %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Auth-Token", authToken)
+	testResponse := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusRequestEntityTooLarge, testResponse.Code)
+}
+
+// TestUserImage Test
+func TestUserImage(t *testing.T) {
+	// Test Setup
+	setup()
+	user := createTestUser(ta, 1)
+	other := createTestUser(ta, 2)
+	authResponse := signIn(ta, user.Email, "abc123")
+	authToken := authResponse.Header().Get("Auth-Token")
+	otherResponse := signIn(ta, other.Email, "abc123")
+	otherToken := otherResponse.Header().Get("Auth-Token")
+	imagePath := "/users/" + user.Id + "/image"
+	// Non image bodies and other users are rejected
+	req, _ := http.NewRequest("PUT", imagePath, bytes.NewBuffer([]byte("hello")))
+	req.Header.Add("Content-Type", "text/plain")
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusUnsupportedMediaType, executeRequest(ta, req).Code)
+	checkResponseCode(t, http.StatusUnauthorized, putTestImage(ta, otherToken, imagePath, getTestImage(300, 300)).Code)
+	// Upload, then replace the avatar
+	checkResponseCode(t, http.StatusOK, putTestImage(ta, authToken, imagePath, getTestImage(300, 300)).Code)
+	testResponse := putTestImage(ta, authToken, imagePath, getTestImage(400, 200))
+	checkResponseCode(t, http.StatusOK, testResponse.Code)
+	var updated models.User
+	if err := json.Unmarshal(testResponse.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("TestUserImage() error = %v", err)
+	}
+	if updated.ImageURL != imagePath {
+		t.Errorf("Expected image_url %s. Got %s\n", imagePath, updated.ImageURL)
+	}
+	// Fetch a resized version of the avatar
+	reqShow, _ := http.NewRequest("GET", imagePath+"?size=64", nil)
+	reqShow.Header.Add("Auth-Token", otherToken)
+	showResponse := executeRequest(ta, reqShow)
+	checkResponseCode(t, http.StatusOK, showResponse.Code)
+	cfg, _, err := image.DecodeConfig(showResponse.Body)
+	if err != nil || cfg.Width != 64 || cfg.Height != 32 {
+		t.Errorf("Expected a 64x32 thumbnail. Got %dx%d (%v)\n", cfg.Width, cfg.Height, err)
+	}
+	// Remove the avatar
+	reqDelete, _ := http.NewRequest("DELETE", imagePath, nil)
+	reqDelete.Header.Add("Auth-Token", authToken)
+	deleteResponse := executeRequest(ta, reqDelete)
+	checkResponseCode(t, http.StatusOK, deleteResponse.Code)
+	if bytes.Contains(deleteResponse.Body.Bytes(), []byte("image_url")) {
+		t.Errorf("Expected image_url to be removed. Got %s\n", deleteResponse.Body.String())
+	}
+	reqShow, _ = http.NewRequest("GET", imagePath, nil)
+	reqShow.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(ta, reqShow).Code)
+}
+
+// TestStorageQuota Test
+func TestStorageQuota(t *testing.T) {
+	// Test Setup
+	setup()
+	user := createTestUser(ta, 1)
+	adminResponse := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD"))
+	adminToken := adminResponse.Header().Get("Auth-Token")
+	authResponse := signIn(ta, user.Email, "abc123")
+	authToken := authResponse.Header().Get("Auth-Token")
+	// Only root admins can adjust quotas
+	quotaPayload := []byte(`{"quota":20}`)
+	req, _ := http.NewRequest("PUT", "/users/"+user.Id+"/storage", bytes.NewBuffer(quotaPayload))
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("PUT", "/users/"+user.Id+"/storage", bytes.NewBuffer(quotaPayload))
+	req.Header.Add("Auth-Token", adminToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	// Start two uploads that each fit within the quota, but not together
+	content := []byte("hello world!")
+	uploads := make([]models.Upload, 2)
+	for u := range uploads {
+		req, _ = http.NewRequest("POST", "/uploads", bytes.NewBuffer(getTestUploadPayload(content, len(content))))
+		req.Header.Add("Auth-Token", authToken)
+		testResponse := executeRequest(ta, req)
+		checkResponseCode(t, http.StatusCreated, testResponse.Code)
+		if err := json.Unmarshal(testResponse.Body.Bytes(), &uploads[u]); err != nil {
+			t.Fatalf("TestStorageQuota() error = %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			chunk := content[i*4 : i*4+4]
+			checkResponseCode(t, http.StatusAccepted, uploadTestChunk(ta, authToken, uploads[u].Id, i, chunk, chunk).Code)
+		}
+	}
+	// Only the first upload to complete is stored
+	req, _ = http.NewRequest("POST", "/uploads/"+uploads[0].Id+"/complete", nil)
+	req.Header.Add("Auth-Token", authToken)
+	testResponse := executeRequest(ta, req)
+	checkResponseCode(t, http.StatusCreated, testResponse.Code)
+	var file models.File
+	if err := json.Unmarshal(testResponse.Body.Bytes(), &file); err != nil {
+		t.Fatalf("TestStorageQuota() error = %v", err)
+	}
+	req, _ = http.NewRequest("POST", "/uploads/"+uploads[1].Id+"/complete", nil)
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusRequestEntityTooLarge, executeRequest(ta, req).Code)
+	// Usage reflects the stored file
+	req, _ = http.NewRequest("GET", "/users/me/storage", nil)
+	req.Header.Add("Auth-Token", authToken)
+	testResponse = executeRequest(ta, req)
+	checkResponseCode(t, http.StatusOK, testResponse.Code)
+	var storage models.Storage
+	if err := json.Unmarshal(testResponse.Body.Bytes(), &storage); err != nil {
+		t.Fatalf("TestStorageQuota() error = %v", err)
+	}
+	if storage.Quota != 20 || storage.Used != 12 || storage.Available != 8 {
+		t.Errorf("Expected quota 20, used 12, available 8. Got %d, %d, %d\n", storage.Quota, storage.Used, storage.Available)
+	}
+	// A third upload would exceed the quota
+	req, _ = http.NewRequest("POST", "/uploads", bytes.NewBuffer(getTestUploadPayload(content, len(content))))
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusRequestEntityTooLarge, executeRequest(ta, req).Code)
+	// Deleting the stored file frees its bytes for the second upload
+	req, _ = http.NewRequest("DELETE", "/files/"+file.Id, nil)
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(ta, req).Code)
+	req, _ = http.NewRequest("POST", "/uploads/"+uploads[1].Id+"/complete", nil)
+	req.Header.Add("Auth-Token", authToken)
+	checkResponseCode(t, http.StatusCreated, executeRequest(ta, req).Code)
+}
+
+// TestAttachmentScanning Test
+func TestAttachmentScanning(t *testing.T) {
+	// Test Setup
+	setup()
+	user := createTestUser(ta, 1)
+	other := createTestUser(ta, 2)
+	authResponse := signIn(ta, user.Email, "abc123")
+	authToken := authResponse.Header().Get("Auth-Token")
+	otherResponse := signIn(ta, other.Email, "abc123")
+	otherToken := otherResponse.Header().Get("Auth-Token")
+	tests := []struct {
+		name     string // The name of the test
+		content  []byte // The uploaded file contents
+		status   string // The expected scan status
+		download int    // The expected response code when the user the file is sent to downloads it
+	}{
+		{"clean", []byte("hello world!"), models.ScanClean, http.StatusOK},
+		{"infected", []byte(scanner.EICAR), models.ScanInfected, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testResponse := uploadTestFile(ta, authToken, tt.content)
+			checkResponseCode(t, http.StatusCreated, testResponse.Code)
+			var file models.File
+			if err := json.Unmarshal(testResponse.Body.Bytes(), &file); err != nil {
+				t.Fatalf("TestAttachmentScanning() error = %v", err)
+			}
+			if file.ScanStatus != tt.status {
+				t.Errorf("Expected scan_status %s. Got %s\n", tt.status, file.ScanStatus)
+			}
+			payload := map[string]interface{}{"receiver_id": other.Id, "content": "see attached", "file_ids": file.Id}
+			checkResponseCode(t, http.StatusCreated, sendRequest(ta, "POST", "/messages", authToken, payload).Code)
+			req, _ := http.NewRequest("GET", "/files/"+file.Id+"/download", nil)
+			req.Header.Add("Auth-Token", otherToken)
+			checkResponseCode(t, tt.download, executeRequest(ta, req).Code)
+		})
+	}
+}
+
+// TestFileAccess Test
+func TestFileAccess(t *testing.T) {
+	// Test Setup
+	setup()
+	owner := createTestUser(ta, 1)
+	stranger := createTestUser(ta, 2)
+	receiver := &models.User{Id: "000000000000000000000014", Username: "receiver", Password: "abc123", Email: "receiver@email.com"}
+	if _, err := ta.server.UserService.UserDocInsert(receiver); err != nil {
+		t.Fatalf("TestFileAccess() error = %v", err)
+	}
+	ownerToken := signIn(ta, owner.Email, "abc123").Header().Get("Auth-Token")
+	strangerToken := signIn(ta, stranger.Email, "abc123").Header().Get("Auth-Token")
+	receiverToken := signIn(ta, receiver.Email, "abc123").Header().Get("Auth-Token")
+	rootToken := signIn(ta, os.Getenv("ROOT_EMAIL"), os.Getenv("ROOT_PASSWORD")).Header().Get("Auth-Token")
+	response := uploadTestFile(ta, ownerToken, []byte("private notes"))
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var file models.File
+	_ = json.Unmarshal(response.Body.Bytes(), &file)
+	filePath := "/files/" + file.Id
+	// Files are only readable by their owner and root admins until they are attached to a message
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "GET", filePath, ownerToken, "").Code)
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "GET", filePath+"/download", rootToken, "").Code)
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "GET", filePath, receiverToken, "").Code)
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "GET", filePath+"/download", receiverToken, "").Code)
+	// Attaching the files of other users is ignored
+	response = sendRequest(ta, "POST", "/messages", strangerToken, map[string]interface{}{"receiver_id": receiver.Id, "content": "look", "file_ids": file.Id})
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var message models.Message
+	_ = json.Unmarshal(response.Body.Bytes(), &message)
+	if message.FileIds != "" {
+		t.Errorf("TestFileAccess() expected the attachment to be dropped, got %q", message.FileIds)
+	}
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "GET", filePath+"/download", receiverToken, "").Code)
+	// The participants of the message a file is attached to can read it, other users can not
+	response = sendRequest(ta, "POST", "/messages", ownerToken, map[string]interface{}{"receiver_id": receiver.Id, "content": "here", "file_ids": file.Id})
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	response = sendRequest(ta, "GET", filePath+"/download", receiverToken, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if response.Body.String() != "private notes" {
+		t.Errorf("TestFileAccess() unexpected download %q", response.Body.String())
+	}
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "GET", filePath, receiverToken, "").Code)
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "GET", filePath+"/download", strangerToken, "").Code)
+}
+
+func TestMentions(t *testing.T) {
+	// Test Setup
+	setup()
+	group := createTestGroup(ta, 1)
+	admin := createTestUser(ta, 1)
+	member := createTestUser(ta, 2)
+	idle := &models.User{Id: "000000000000000000000014", Username: "idle_user", Password: "abc123", Email: "idle@email.com"}
+	outsider := &models.User{Id: "000000000000000000000015", Username: "outsider", Password: "abc123", Email: "outsider@email.com"}
+	for _, u := range []*models.User{idle, outsider} {
+		if _, err := ta.server.UserService.UserDocInsert(u); err != nil {
+			t.Fatalf("TestMentions() error = %v", err)
+		}
+	}
+	for _, u := range []*models.User{admin, member, idle} {
+		gm := &models.GroupMembership{Id: "00000000000000000000007" + u.Id[23:], GroupId: group.Id, UserId: u.Id, Admin: u == admin}
+		if _, err := ta.server.GroupMembershipsService.GroupMembershipDocInsert(gm); err != nil {
+			t.Fatalf("TestMentions() error = %v", err)
+		}
+	}
+	adminToken := signIn(ta, admin.Email, "abc123").Header().Get("Auth-Token")
+	memberToken := signIn(ta, member.Email, "abc123").Header().Get("Auth-Token")
+	post := func(token string, content string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(map[string]interface{}{"receiver_id": group.Id, "group": true, "content": content})
+		req, _ := http.NewRequest("POST", "/messages", bytes.NewBuffer(payload))
+		req.Header.Add("Auth-Token", token)
+		return executeRequest(ta, req)
+	}
+	mentioned := func(response *httptest.ResponseRecorder) *models.Mentions {
+		checkResponseCode(t, http.StatusCreated, response.Code)
+		var message models.Message
+		if err := json.Unmarshal(response.Body.Bytes(), &message); err != nil {
+			t.Fatalf("TestMentions() unexpected message %s", response.Body.String())
+		}
+		return message.Mentions
+	}
+	// Usernames are only resolved for participants, email addresses are not mentions
+	m := mentioned(post(adminToken, "hi @test_user2 and @outsider, mail test_user2@email.com or @nobody"))
+	if m == nil || len(m.UserIds) != 1 || m.UserIds[0] != member.Id || len(m.Usernames) != 1 || m.Here || m.All {
+		t.Errorf("TestMentions() unexpected mentions %+v", m)
+	}
+	if m = mentioned(post(adminToken, "mail test_user2@email.com or @outsider")); m != nil {
+		t.Errorf("TestMentions() expected no mentions, got %+v", m)
+	}
+	// @here only reaches the participants with a recently used session
+	m = mentioned(post(adminToken, "@here standup"))
+	if m == nil || !m.Here || len(m.UserIds) != 1 || m.UserIds[0] != member.Id {
+		t.Errorf("TestMentions() unexpected @here mentions %+v", m)
+	}
+	// @all is restricted to group admins unless MENTION_ALL_ROLE allows every member
+	checkResponseCode(t, http.StatusForbidden, post(memberToken, "@all look").Code)
+	t.Setenv("MENTION_ALL_ROLE", "member")
+	m = mentioned(post(memberToken, "@all look"))
+	if m == nil || !m.All || len(m.UserIds) != 2 || !m.Includes(admin.Id) || !m.Includes(idle.Id) || m.Includes(member.Id) {
+		t.Errorf("TestMentions() unexpected @all mentions %+v", m)
+	}
+	// Users list the messages that mention them
+	mentionsOf := func(token string) []*models.Message {
+		req, _ := http.NewRequest("GET", "/messages?mentions=me", nil)
+		req.Header.Add("Auth-Token", token)
+		response := executeRequest(ta, req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+		var messages struct {
+			Messages []*models.Message `json:"messages"`
+		}
+		_ = json.Unmarshal(response.Body.Bytes(), &messages)
+		return messages.Messages
+	}
+	if messages := mentionsOf(memberToken); len(messages) != 2 {
+		t.Errorf("TestMentions() expected 2 messages mentioning the member, got %d", len(messages))
+	}
+	if messages := mentionsOf(adminToken); len(messages) != 1 || messages[0].Content != "@all look" {
+		t.Errorf("TestMentions() unexpected messages mentioning the admin %+v", messages)
+	}
+}
+
+func TestPushNotifications(t *testing.T) {
+	// Test Setup
+	setup()
+	t.Setenv("NOTIFICATION_RETRY_BACKOFF", "1ms")
+	pusher := ta.pusher.(*push.FakeProvider)
+	group := createTestGroup(ta, 1)
+	admin := createTestUser(ta, 1)
+	member := createTestUser(ta, 2)
+	idle := &models.User{Id: "000000000000000000000014", Username: "idle_user", Password: "abc123", Email: "idle@email.com"}
+	muted := &models.User{Id: "000000000000000000000015", Username: "muted_user", Password: "abc123", Email: "muted@email.com"}
+	for _, u := range []*models.User{idle, muted} {
+		if _, err := ta.server.UserService.UserDocInsert(u); err != nil {
+			t.Fatalf("TestPushNotifications() error = %v", err)
+		}
+	}
+	for _, u := range []*models.User{admin, member, idle, muted} {
+		gm := &models.GroupMembership{Id: "00000000000000000000008" + u.Id[23:], GroupId: group.Id, UserId: u.Id, Admin: u == admin}
+		if u == muted {
+			gm.MutedUntil = time.Now().Add(time.Hour)
+		}
+		if _, err := ta.server.GroupMembershipsService.GroupMembershipDocInsert(gm); err != nil {
+			t.Fatalf("TestPushNotifications() error = %v", err)
+		}
+	}
+	adminToken := signIn(ta, admin.Email, "abc123").Header().Get("Auth-Token")
+	memberToken := signIn(ta, member.Email, "abc123").Header().Get("Auth-Token")
+	sharedPhone, idlePhone := strings.Repeat("5a", 32), strings.Repeat("1d", 32) // APNs device tokens
+	devices := func(token string) []*models.Device {
+		response := sendRequest(ta, "GET", "/devices", token, "")
+		checkResponseCode(t, http.StatusOK, response.Code)
+		var list struct {
+			Devices []*models.Device `json:"devices"`
+		}
+		_ = json.Unmarshal(response.Body.Bytes(), &list)
+		return list.Devices
+	}
+	// Users register the devices they receive notifications on, a token moves to the last user signed in on it
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", "/devices", adminToken, `{"platform":"sms","token":"abc"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", "/devices", adminToken, `{"platform":"webpush","token":"https://push.example.com/abc"}`).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", "/devices", adminToken, `{"platform":"apns","token":"shared-phone/../x"}`).Code)
+	checkResponseCode(t, http.StatusCreated, sendRequest(ta, "POST", "/devices", adminToken, `{"platform":"apns","token":"`+sharedPhone+`"}`).Code)
+	if list := devices(adminToken); len(list) != 1 || list[0].Token != sharedPhone {
+		t.Fatalf("TestPushNotifications() unexpected devices %+v", list)
+	}
+	response := sendRequest(ta, "POST", "/devices", memberToken, `{"platform":"apns","token":"`+sharedPhone+`"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var shared models.Device
+	_ = json.Unmarshal(response.Body.Bytes(), &shared)
+	if len(devices(adminToken)) != 0 || len(devices(memberToken)) != 1 {
+		t.Errorf("TestPushNotifications() expected the device to move to the member")
+	}
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "DELETE", "/devices/"+shared.Id, adminToken, "").Code)
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "DELETE", "/devices/"+shared.Id, memberToken, "").Code)
+	checkResponseCode(t, http.StatusCreated, sendRequest(ta, "POST", "/devices", memberToken, `{"platform":"fcm","token":"member-phone"}`).Code)
+	for _, d := range []*models.Device{
+		{UserId: idle.Id, Platform: models.DeviceAPNs, Token: idlePhone},
+		{UserId: idle.Id, Platform: models.DeviceFCM, Token: "idle-tablet"},
+		{UserId: muted.Id, Platform: models.DeviceFCM, Token: "muted-phone"},
+	} {
+		if _, err := ta.server.DeviceService.DeviceRegister(d); err != nil {
+			t.Fatalf("TestPushNotifications() error = %v", err)
+		}
+	}
+	// post sends a message and waits until the notifications queued for reader in the background have been attempted
+	post := func(payload map[string]interface{}, reader string) {
+		body, _ := json.Marshal(payload)
+		response := sendRequest(ta, "POST", "/messages", adminToken, string(body))
+		checkResponseCode(t, http.StatusCreated, response.Code)
+		var m models.Message
+		_ = json.Unmarshal(response.Body.Bytes(), &m)
+		for i := 0; i < 500; i++ {
+			queued, err := ta.server.NotificationService.NotificationsFind(&models.Notification{UserId: reader})
+			if err != nil {
+				t.Fatalf("TestPushNotifications() error = %v", err)
+			}
+			found, attempted := 0, 0
+			for _, n := range queued {
+				if n.MessageId != m.Id {
+					continue
+				}
+				found++
+				if n.Attempts > 0 && n.Status != models.NotificationSending {
+					attempted++
+				}
+			}
+			if found > 0 && attempted == found {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("TestPushNotifications() no notification was attempted for %q", payload["content"])
+	}
+	// Participants using the app and members who muted the group are not notified
+	post(map[string]interface{}{"receiver_id": group.Id, "group": true, "content": "hello team"}, idle.Id)
+	if sent := pusher.Sent(idlePhone); len(sent) != 1 || sent[0].Title != admin.Username || sent[0].Body != "hello team" || sent[0].Platform != push.APNs {
+		t.Errorf("TestPushNotifications() unexpected notifications %+v", sent)
+	}
+	if len(pusher.Sent("idle-tablet")) != 1 || len(pusher.Sent("member-phone")) != 0 || len(pusher.Sent("muted-phone")) != 0 {
+		t.Errorf("TestPushNotifications() notified the wrong devices")
+	}
+	// Mentions by name reach members who muted the group
+	post(map[string]interface{}{"receiver_id": group.Id, "group": true, "content": "@muted_user ping"}, muted.Id)
+	if sent := pusher.Sent("muted-phone"); len(sent) != 1 || sent[0].Title != admin.Username+" mentioned you" {
+		t.Errorf("TestPushNotifications() unexpected notifications %+v", sent)
+	}
+	post(map[string]interface{}{"receiver_id": idle.Id, "content": "direct"}, idle.Id)
+	if sent := pusher.Sent(idlePhone); len(sent) != 3 || sent[2].Body != "direct" || sent[2].Data["message_id"] == "" {
+		t.Errorf("TestPushNotifications() unexpected notifications %+v", sent)
+	}
+	// Notifications stay in the outbox while the push service is unavailable
+	pusher.Err = errors.New("push service unavailable")
+	post(map[string]interface{}{"receiver_id": idle.Id, "content": "are you there?"}, idle.Id)
+	queued, err := ta.server.NotificationService.NotificationsFind(&models.Notification{UserId: idle.Id})
+	if err != nil {
+		t.Fatalf("TestPushNotifications() error = %v", err)
+	}
+	pending := 0
+	for _, n := range queued {
+		if n.Status == models.NotificationPending && n.Attempts > 0 && n.LastError == "push service unavailable" && !n.NextAttemptAt.IsZero() {
+			pending++
+		}
+	}
+	if pending != 2 {
+		t.Fatalf("TestPushNotifications() expected 2 pending notifications, got %d", pending)
+	}
+	pusher.Err = nil
+	time.Sleep(10 * time.Millisecond) // the retries are due after the backoff
+	if _, err = ta.server.NotificationService.NotificationDeliverPending(); err != nil {
+		t.Fatalf("TestPushNotifications() error = %v", err)
+	}
+	if sent := pusher.Sent(idlePhone); len(sent) != 4 || sent[3].Body != "are you there?" {
+		t.Errorf("TestPushNotifications() pending notification was not delivered %+v", sent)
+	}
+	// Devices the push service no longer accepts are unregistered
+	pusher.Unregister("idle-tablet")
+	post(map[string]interface{}{"receiver_id": idle.Id, "content": "still there?"}, idle.Id)
+	list, err := ta.server.DeviceService.DevicesFind(&models.Device{UserId: idle.Id})
+	if err != nil || len(list) != 1 || list[0].Token != idlePhone {
+		t.Errorf("TestPushNotifications() unexpected devices %+v, %v", list, err)
+	}
+}
+
+func TestEmailDigest(t *testing.T) {
+	// Test Setup
+	setup()
+	fake := ta.mailer.(*mailer.FakeMailer)
+	group := createTestGroup(ta, 1)
+	admin := createTestUser(ta, 1)
+	idle := &models.User{Id: "000000000000000000000014", Username: "idle_user", Password: "abc123", Email: "idle@email.com", LastActive: time.Now().UTC()}
+	if _, err := ta.server.UserService.UserDocInsert(idle); err != nil {
+		t.Fatalf("TestEmailDigest() error = %v", err)
+	}
+	for _, u := range []*models.User{admin, idle} {
+		gm := &models.GroupMembership{Id: "00000000000000000000008" + u.Id[23:], GroupId: group.Id, UserId: u.Id, Admin: u == admin}
+		if _, err := ta.server.GroupMembershipsService.GroupMembershipDocInsert(gm); err != nil {
+			t.Fatalf("TestEmailDigest() error = %v", err)
+		}
+	}
+	adminToken := signIn(ta, admin.Email, "abc123").Header().Get("Auth-Token")
+	post := func(payload map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		checkResponseCode(t, http.StatusCreated, sendRequest(ta, "POST", "/messages", adminToken, string(body)).Code)
+	}
+	post(map[string]interface{}{"receiver_id": group.Id, "group": true, "content": "hello team"})
+	post(map[string]interface{}{"receiver_id": group.Id, "group": true, "content": "@idle_user lunch?"})
+	post(map[string]interface{}{"receiver_id": idle.Id, "content": "direct hi"})
+	// Users who were active more recently than DIGEST_INACTIVE_AFTER are not sent digests
+	t.Setenv("DIGEST_INACTIVE_AFTER", "1h")
+	if _, err := ta.server.Digester.SendDigests(); err != nil {
+		t.Fatalf("TestEmailDigest() error = %v", err)
+	}
+	if _, err := ta.server.MailService.MailDeliverPending(); err != nil {
+		t.Fatalf("TestEmailDigest() error = %v", err)
+	}
+	if len(fake.Sent(idle.Email)) != 0 {
+		t.Fatalf("TestEmailDigest() sent a digest to an active user")
+	}
+	// Digests summarize the unread threads and mentions of users who have been away
+	t.Setenv("DIGEST_INACTIVE_AFTER", "1ms")
+	time.Sleep(5 * time.Millisecond)
+	if _, err := ta.server.Digester.SendDigests(); err != nil {
+		t.Fatalf("TestEmailDigest() error = %v", err)
+	}
+	if _, err := ta.server.MailService.MailDeliverPending(); err != nil {
+		t.Fatalf("TestEmailDigest() error = %v", err)
+	}
+	sent := fake.Sent(idle.Email)
+	if len(sent) != 1 {
+		t.Fatalf("TestEmailDigest() expected 1 digest, got %d", len(sent))
+	}
+	for _, want := range []string{
+		"You have 3 unread messages",
+		group.Name + ": 2 new messages, the latest from @" + admin.Username + ": \"@idle_user lunch?\"",
+		"@" + admin.Username + ": 1 new message, the latest from @" + admin.Username + ": \"direct hi\"",
+		"@" + admin.Username + " mentioned you in " + group.Name,
+		"/unsubscribe?token=",
+	} {
+		if !strings.Contains(sent[0].Subject+"\n"+sent[0].Body, want) {
+			t.Errorf("TestEmailDigest() digest is missing %q:\n%s", want, sent[0].Body)
+		}
+	}
+	if len(fake.Sent(admin.Email)) != 0 {
+		t.Errorf("TestEmailDigest() sent a digest to the sender")
+	}
+	// Digests are sent at most once per period of their frequency
+	post(map[string]interface{}{"receiver_id": idle.Id, "content": "still there?"})
+	if _, err := ta.server.Digester.SendDigests(); err != nil {
+		t.Fatalf("TestEmailDigest() error = %v", err)
+	}
+	if _, err := ta.server.MailService.MailDeliverPending(); err != nil {
+		t.Fatalf("TestEmailDigest() error = %v", err)
+	}
+	if len(fake.Sent(idle.Email)) != 1 {
+		t.Errorf("TestEmailDigest() sent a second digest within a day")
+	}
+	// Users choose how often they receive digests
+	idleToken := signIn(ta, idle.Email, "abc123").Header().Get("Auth-Token")
+	settings := func() *models.DigestSettings {
+		response := sendRequest(ta, "GET", "/digest", idleToken, "")
+		checkResponseCode(t, http.StatusOK, response.Code)
+		var s models.DigestSettings
+		_ = json.Unmarshal(response.Body.Bytes(), &s)
+		return &s
+	}
+	if s := settings(); s.Frequency != models.DigestDaily || s.LastSentAt.IsZero() {
+		t.Errorf("TestEmailDigest() unexpected settings %+v", s)
+	}
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "PUT", "/digest", idleToken, `{"frequency":"hourly"}`).Code)
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "PUT", "/digest", idleToken, `{"frequency":"weekly"}`).Code)
+	if s := settings(); s.Frequency != models.DigestWeekly {
+		t.Errorf("TestEmailDigest() unexpected settings %+v", s)
+	}
+	// The link in a digest unsubscribes from them without signing in
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", "/digest/unsubscribe", "", `{"token":"invalid"}`).Code)
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "POST", "/digest/unsubscribe", "", `{"token":"`+mailedToken(ta, idle.Email)+`"}`).Code)
+	if s := settings(); s.Frequency != models.DigestOff {
+		t.Errorf("TestEmailDigest() unexpected settings %+v", s)
+	}
+}
+
+func TestScheduledMessages(t *testing.T) {
+	// Test Setup
+	setup()
+	group := createTestGroup(ta, 1)
+	admin := createTestUser(ta, 1)
+	member := createTestUser(ta, 2)
+	for _, u := range []*models.User{admin, member} {
+		gm := &models.GroupMembership{Id: "00000000000000000000008" + u.Id[23:], GroupId: group.Id, UserId: u.Id, Admin: u == admin}
+		if _, err := ta.server.GroupMembershipsService.GroupMembershipDocInsert(gm); err != nil {
+			t.Fatalf("TestScheduledMessages() error = %v", err)
+		}
+	}
+	adminToken := signIn(ta, admin.Email, "abc123").Header().Get("Auth-Token")
+	memberToken := signIn(ta, member.Email, "abc123").Header().Get("Auth-Token")
+	schedule := func(payload map[string]interface{}) *models.ScheduledMessage {
+		response := sendRequest(ta, "POST", "/scheduled-messages", adminToken, payload)
+		checkResponseCode(t, http.StatusCreated, response.Code)
+		var s models.ScheduledMessage
+		_ = json.Unmarshal(response.Body.Bytes(), &s)
+		return &s
+	}
+	list := func(token string) []*models.ScheduledMessage {
+		response := sendRequest(ta, "GET", "/scheduled-messages", token, nil)
+		checkResponseCode(t, http.StatusOK, response.Code)
+		var l struct {
+			ScheduledMessages []*models.ScheduledMessage `json:"scheduled_messages"`
+		}
+		_ = json.Unmarshal(response.Body.Bytes(), &l)
+		return l.ScheduledMessages
+	}
+	groupUnreads := func() int {
+		unreads, _ := ta.server.DigestService.UnreadsFind(&models.Unread{UserId: member.Id})
+		for _, u := range unreads {
+			if u.ThreadId == group.Id {
+				return u.Count
+			}
+		}
+		return 0
+	}
+	later := time.Now().Add(time.Hour)
+	// Messages are scheduled for the future and can not be slash commands
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", "/scheduled-messages", adminToken, map[string]interface{}{"receiver_id": group.Id, "group": true, "content": "late", "send_at": time.Now().Add(-time.Minute)}).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", "/scheduled-messages", adminToken, map[string]interface{}{"receiver_id": group.Id, "group": true, "send_at": later}).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "POST", "/scheduled-messages", adminToken, map[string]interface{}{"receiver_id": group.Id, "group": true, "content": "/mute 1h", "send_at": later}).Code)
+	groupMessage := schedule(map[string]interface{}{"receiver_id": group.Id, "group": true, "content": "good morning", "send_at": later})
+	direct := schedule(map[string]interface{}{"receiver_id": member.Id, "content": "see you later", "send_at": later})
+	if groupMessage.Status != models.ScheduledPending || groupMessage.SendAt.Sub(later).Abs() > time.Millisecond {
+		t.Errorf("TestScheduledMessages() unexpected scheduled message %+v", groupMessage)
+	}
+	// Senders list, edit and cancel their pending scheduled messages
+	if l := list(adminToken); len(l) != 2 || l[0].Id != groupMessage.Id {
+		t.Errorf("TestScheduledMessages() unexpected scheduled messages %+v", l)
+	}
+	if len(list(memberToken)) != 0 {
+		t.Errorf("TestScheduledMessages() listed the scheduled messages of another user")
+	}
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "GET", "/scheduled-messages/"+groupMessage.Id, memberToken, nil).Code)
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "PATCH", "/scheduled-messages/"+groupMessage.Id, memberToken, map[string]interface{}{"content": "hijacked"}).Code)
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "PATCH", "/scheduled-messages/"+groupMessage.Id, adminToken, map[string]interface{}{"send_at": time.Now().Add(-time.Minute)}).Code)
+	checkResponseCode(t, http.StatusAccepted, sendRequest(ta, "PATCH", "/scheduled-messages/"+groupMessage.Id, adminToken, map[string]interface{}{"content": "good afternoon", "send_at": time.Now().Add(100 * time.Millisecond)}).Code)
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "DELETE", "/scheduled-messages/"+direct.Id, adminToken, nil).Code)
+	if l := list(adminToken); len(l) != 1 || l[0].Content != "good afternoon" {
+		t.Errorf("TestScheduledMessages() unexpected scheduled messages %+v", l)
+	}
+	// Scheduled messages are delivered through the message service once they are due
+	scheduler := ta.server.Scheduler
+	if delivered, err := scheduler.DeliverDue(); err != nil || delivered != 0 {
+		t.Fatalf("TestScheduledMessages() delivered %d messages early, error = %v", delivered, err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if delivered, err := scheduler.DeliverDue(); err != nil || delivered != 1 {
+		t.Fatalf("TestScheduledMessages() delivered %d messages, error = %v", delivered, err)
+	}
+	m, err := ta.server.MessageService.MessageFind(&models.Message{Id: groupMessage.Id})
+	if err != nil || m.Content != "good afternoon" || m.SenderID != admin.Id {
+		t.Errorf("TestScheduledMessages() unexpected message %+v, %v", m, err)
+	}
+	if groupUnreads() != 1 {
+		t.Errorf("TestScheduledMessages() expected the message to reach the group")
+	}
+	response := sendRequest(ta, "GET", "/scheduled-messages/"+groupMessage.Id, adminToken, nil)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var sent models.ScheduledMessage
+	_ = json.Unmarshal(response.Body.Bytes(), &sent)
+	if sent.Status != models.ScheduledSent || sent.SentAt.IsZero() || len(list(adminToken)) != 0 {
+		t.Errorf("TestScheduledMessages() unexpected scheduled message %+v", sent)
+	}
+	checkResponseCode(t, http.StatusConflict, sendRequest(ta, "PATCH", "/scheduled-messages/"+groupMessage.Id, adminToken, map[string]interface{}{"content": "too late"}).Code)
+	checkResponseCode(t, http.StatusConflict, sendRequest(ta, "DELETE", "/scheduled-messages/"+groupMessage.Id, adminToken, nil).Code)
+	if delivered, _ := scheduler.DeliverDue(); delivered != 0 {
+		t.Errorf("TestScheduledMessages() delivered a sent message again")
+	}
+	// A message another instance delivered before it could record it, or before a restart, is not delivered twice
+	again := schedule(map[string]interface{}{"receiver_id": group.Id, "group": true, "content": "once", "send_at": time.Now().Add(50 * time.Millisecond)})
+	time.Sleep(100 * time.Millisecond)
+	if _, err = ta.server.MessageService.MessageCreate(again.Message()); err != nil {
+		t.Fatalf("TestScheduledMessages() error = %v", err)
+	}
+	restarted := services.NewScheduler(ta.server.ScheduledMessageService, ta.server.MessageService)
+	if delivered, err := restarted.DeliverDue(); err != nil || delivered != 1 {
+		t.Fatalf("TestScheduledMessages() delivered %d messages, error = %v", delivered, err)
+	}
+	if groupUnreads() != 2 {
+		t.Errorf("TestScheduledMessages() delivered a message twice")
+	}
+	// Messages that can not be delivered are retried, then marked as failed
+	failing := schedule(map[string]interface{}{"receiver_id": "000000000000000000000099", "content": "anyone?", "send_at": time.Now().Add(50 * time.Millisecond)})
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		_, _ = scheduler.DeliverDue()
+	}
+	if l := list(adminToken); len(l) != 1 || l[0].Id != failing.Id || l[0].Status != models.ScheduledFailed || l[0].Attempts != 5 || l[0].LastError == "" {
+		t.Errorf("TestScheduledMessages() unexpected scheduled messages %+v", l)
+	}
+	checkResponseCode(t, http.StatusOK, sendRequest(ta, "DELETE", "/scheduled-messages/"+failing.Id, adminToken, nil).Code)
+}
+
+func TestDisappearingMessages(t *testing.T) {
+	// Test Setup
+	setup()
+	sender := createTestUser(ta, 1)
+	receiver := createTestUser(ta, 2)
+	senderToken := signIn(ta, sender.Email, "abc123").Header().Get("Auth-Token")
+	response := sendRequest(ta, "POST", "/conversations", senderToken, map[string]interface{}{"participants_ids": []string{sender.Id, receiver.Id}})
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var conversation models.Conversation
+	_ = json.Unmarshal(response.Body.Bytes(), &conversation)
+	lastMessage := func() *models.Message {
+		unreads, _ := ta.server.DigestService.UnreadsFind(&models.Unread{UserId: receiver.Id})
+		for _, u := range unreads {
+			if u.ThreadId == conversation.Id {
+				m, err := ta.server.MessageService.MessageFind(&models.Message{Id: u.LastMessageId})
+				if err != nil {
+					t.Fatalf("TestDisappearingMessages() error = %v", err)
+				}
+				return m
+			}
+		}
+		return nil
+	}
+	disappearing := func(ttl int64) *models.Conversation {
+		response := sendRequest(ta, "PUT", "/conversations/"+conversation.Id+"/disappearing", senderToken, map[string]interface{}{"message_ttl": ttl})
+		checkResponseCode(t, http.StatusOK, response.Code)
+		var c models.Conversation
+		_ = json.Unmarshal(response.Body.Bytes(), &c)
+		return &c
+	}
+	// Only the supported lifetimes can be set
+	checkResponseCode(t, http.StatusBadRequest, sendRequest(ta, "PUT", "/conversations/"+conversation.Id+"/disappearing", senderToken, map[string]interface{}{"message_ttl": 90}).Code)
+	checkResponseCode(t, http.StatusNotFound, sendRequest(ta, "PUT", "/conversations/000000000000000000000099/disappearing", senderToken, map[string]interface{}{"message_ttl": models.MessageTTLDay}).Code)
+	// Participants get a system message when the setting changes
+	if c := disappearing(models.MessageTTLDay); c.MessageTTL != models.MessageTTLDay {
+		t.Errorf("TestDisappearingMessages() unexpected conversation %+v", c)
+	}
+	notice := lastMessage()
+	if notice == nil || notice.ContentType != models.ContentTypeSystem || notice.Content != "test_user set messages to disappear after 1 day" {
+		t.Fatalf("TestDisappearingMessages() unexpected notice %+v", notice)
+	}
+	// Messages sent while it is on expire, and are deleted along with their attachments once they do
+	upload := uploadTestFile(ta, senderToken, []byte("disappearing attachment"))
+	checkResponseCode(t, http.StatusCreated, upload.Code)
+	var file models.File
+	_ = json.Unmarshal(upload.Body.Bytes(), &file)
+	m, err := ta.server.MessageService.MessageCreate(&models.Message{ConversationID: conversation.Id, SenderID: sender.Id, ReceiverID: receiver.Id, Content: "self destructing", FileIds: file.Id})
+	if err != nil {
+		t.Fatalf("TestDisappearingMessages() error = %v", err)
+	}
+	if expiresIn := time.Until(m.ExpiresAt); expiresIn < 23*time.Hour || expiresIn > 24*time.Hour {
+		t.Errorf("TestDisappearingMessages() unexpected expiry %v", m.ExpiresAt)
+	}
+	sweeper := ta.server.MessageSweeper
+	if purged, err := sweeper.PurgeExpiredMessages(); err != nil || purged != 0 {
+		t.Fatalf("TestDisappearingMessages() purged %d messages early, error = %v", purged, err)
+	}
+	expiries := database.NewMessageExpiryService(ta.db, ta.db.NewMessageExpiryHandler())
+	if _, err = expiries.MessageExpiryDelete(&models.MessageExpiry{Id: m.Id}); err != nil {
+		t.Fatalf("TestDisappearingMessages() error = %v", err)
+	}
+	if _, err = expiries.MessageExpiryCreate(&models.MessageExpiry{Id: m.Id, ConversationId: conversation.Id, ExpiresAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("TestDisappearingMessages() error = %v", err)
+	}
+	if purged, err := sweeper.PurgeExpiredMessages(); err != nil || purged != 1 {
+		t.Fatalf("TestDisappearingMessages() purged %d messages, error = %v", purged, err)
+	}
+	if _, err = ta.server.MessageService.MessageFind(&models.Message{Id: m.Id}); err == nil {
+		t.Errorf("TestDisappearingMessages() expired message was not deleted")
+	}
+	if _, err = ta.server.FileService.FileFind(&models.File{Id: file.Id}); err == nil {
+		t.Errorf("TestDisappearingMessages() attachment of an expired message was not deleted")
+	}
+	if _, err = ta.server.MessageService.MessageFind(&models.Message{Id: notice.Id}); err != nil {
+		t.Errorf("TestDisappearingMessages() deleted a message that has not expired")
+	}
+	// Turning it off is announced too, and later messages are kept
+	if c := disappearing(models.MessageTTLOff); c.MessageTTL != models.MessageTTLOff {
+		t.Errorf("TestDisappearingMessages() unexpected conversation %+v", c)
+	}
+	off := lastMessage()
+	if off == nil || off.Content != "test_user turned off disappearing messages" || !off.ExpiresAt.IsZero() {
+		t.Fatalf("TestDisappearingMessages() unexpected notice %+v", off)
+	}
+	disappearing(models.MessageTTLOff)
+	if unchanged := lastMessage(); unchanged.Id != off.Id {
+		t.Errorf("TestDisappearingMessages() posted a notice for an unchanged setting")
+	}
+	kept, err := ta.server.MessageService.MessageCreate(&models.Message{ConversationID: conversation.Id, SenderID: sender.Id, ReceiverID: receiver.Id, Content: "for the record"})
+	if err != nil || !kept.ExpiresAt.IsZero() {
+		t.Errorf("TestDisappearingMessages() unexpected message %+v, %v", kept, err)
+	}
+}
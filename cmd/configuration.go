@@ -7,19 +7,55 @@ import (
 
 // configuration is a struct designed to hold the applications variable configuration settings
 type configuration struct {
-	MongoURI     string
-	Database     string
-	TokenSecret  string
-	RootAdmin    string
-	RootPassword string
-	RootEmail    string
-	RootGroup    string
-	Registration string
-	Port         string
-	HTTPS        string
-	Cert         string
-	Key          string
-	ENV          string
+	MongoURI                    string
+	Database                    string
+	TokenSecret                 string
+	RootAdmin                   string
+	RootPassword                string
+	RootEmail                   string
+	RootGroup                   string
+	Registration                string
+	Port                        string
+	HTTPS                       string
+	Cert                        string
+	Key                         string
+	ENV                         string
+	MaxFileSize                 string
+	UploadChunkSize             string
+	UserStorageQuota            string
+	GroupStorageQuota           string
+	Scanner                     string
+	ClamdAddress                string
+	AccessTokenTTL              string
+	RefreshTokenTTL             string
+	TokenKeysDir                string
+	TokenSigningKey             string
+	TOTPIssuer                  string
+	RequireAdmin2FA             string
+	Mailer                      string
+	MailAddress                 string
+	MailFrom                    string
+	AppURL                      string
+	RequireVerifiedEmail        string
+	PasswordMinLength           string
+	PasswordHistory             string
+	PasswordBannedFile          string
+	OIDCProvidersFile           string
+	WebhookRetryBackoff         string
+	IncomingWebhookRateLimit    string
+	MentionAllRole              string
+	PushProvider                string
+	APNsKeyFile                 string
+	APNsKeyID                   string
+	APNsTeamID                  string
+	APNsTopic                   string
+	FCMCredentialsFile          string
+	VAPIDPrivateKey             string
+	VAPIDSubject                string
+	NotificationRetryBackoff    string
+	DigestInactiveAfter         string
+	TrustedProxies              string
+	WebhookAllowPrivateNetworks string
 }
 
 // getConfigurations is a function that reads a json configuration file and outputs a Configuration struct
@@ -56,4 +92,40 @@ func (c *configuration) InitializeEnvironmentalVars() {
 	os.Setenv("CERT", c.Cert)
 	os.Setenv("KEY", c.Key)
 	os.Setenv("ENV", c.ENV)
+	os.Setenv("MAX_FILE_SIZE", c.MaxFileSize)
+	os.Setenv("UPLOAD_CHUNK_SIZE", c.UploadChunkSize)
+	os.Setenv("USER_STORAGE_QUOTA", c.UserStorageQuota)
+	os.Setenv("GROUP_STORAGE_QUOTA", c.GroupStorageQuota)
+	os.Setenv("SCANNER", c.Scanner)
+	os.Setenv("CLAMD_ADDRESS", c.ClamdAddress)
+	os.Setenv("ACCESS_TOKEN_TTL", c.AccessTokenTTL)
+	os.Setenv("REFRESH_TOKEN_TTL", c.RefreshTokenTTL)
+	os.Setenv("TOKEN_KEYS_DIR", c.TokenKeysDir)
+	os.Setenv("TOKEN_SIGNING_KEY", c.TokenSigningKey)
+	os.Setenv("TOTP_ISSUER", c.TOTPIssuer)
+	os.Setenv("REQUIRE_ADMIN_2FA", c.RequireAdmin2FA)
+	os.Setenv("MAILER", c.Mailer)
+	os.Setenv("MAIL_ADDRESS", c.MailAddress)
+	os.Setenv("MAIL_FROM", c.MailFrom)
+	os.Setenv("APP_URL", c.AppURL)
+	os.Setenv("REQUIRE_VERIFIED_EMAIL", c.RequireVerifiedEmail)
+	os.Setenv("PASSWORD_MIN_LENGTH", c.PasswordMinLength)
+	os.Setenv("PASSWORD_HISTORY", c.PasswordHistory)
+	os.Setenv("PASSWORD_BANNED_FILE", c.PasswordBannedFile)
+	os.Setenv("OIDC_PROVIDERS_FILE", c.OIDCProvidersFile)
+	os.Setenv("WEBHOOK_RETRY_BACKOFF", c.WebhookRetryBackoff)
+	os.Setenv("INCOMING_WEBHOOK_RATE_LIMIT", c.IncomingWebhookRateLimit)
+	os.Setenv("MENTION_ALL_ROLE", c.MentionAllRole)
+	os.Setenv("PUSH_PROVIDER", c.PushProvider)
+	os.Setenv("APNS_KEY_FILE", c.APNsKeyFile)
+	os.Setenv("APNS_KEY_ID", c.APNsKeyID)
+	os.Setenv("APNS_TEAM_ID", c.APNsTeamID)
+	os.Setenv("APNS_TOPIC", c.APNsTopic)
+	os.Setenv("FCM_CREDENTIALS_FILE", c.FCMCredentialsFile)
+	os.Setenv("VAPID_PRIVATE_KEY", c.VAPIDPrivateKey)
+	os.Setenv("VAPID_SUBJECT", c.VAPIDSubject)
+	os.Setenv("NOTIFICATION_RETRY_BACKOFF", c.NotificationRetryBackoff)
+	os.Setenv("DIGEST_INACTIVE_AFTER", c.DigestInactiveAfter)
+	os.Setenv("TRUSTED_PROXIES", c.TrustedProxies)
+	os.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", c.WebhookAllowPrivateNetworks)
 }
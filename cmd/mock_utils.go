@@ -2,10 +2,23 @@ package cmd
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/mailer"
 	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/oidc"
+	"image"
+	"image/png"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -17,6 +30,21 @@ func executeRequest(ta App, req *http.Request) *httptest.ResponseRecorder {
 	return rr
 }
 
+// sendRequest executes a test http request authenticated with token, a string payload is sent as is and any other
+// payload is encoded as JSON
+func sendRequest(ta App, method string, path string, token string, payload interface{}) *httptest.ResponseRecorder {
+	body, ok := payload.(string)
+	if !ok {
+		b, _ := json.Marshal(payload)
+		body = string(b)
+	}
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	if token != "" {
+		req.Header.Add("Auth-Token", token)
+	}
+	return executeRequest(ta, req)
+}
+
 // Check response code returned from a test http request
 func checkResponseCode(t *testing.T, expected, actual int) {
 	if expected != actual {
@@ -33,6 +61,109 @@ func signIn(ta App, email string, password string) *httptest.ResponseRecorder {
 	return response
 }
 
+// signInDevice signs in from a named device
+func signInDevice(ta App, email string, password string, device string) *httptest.ResponseRecorder {
+	payload := []byte(`{"email":"` + email + `","password":"` + password + `","device_name":"` + device + `"}`)
+	req, _ := http.NewRequest("POST", "/auth", bytes.NewBuffer(payload))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("User-Agent", device+"-agent")
+	return executeRequest(ta, req)
+}
+
+// refreshSession exchanges a refresh token for a new session
+func refreshSession(ta App, refreshToken string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/auth/refresh", nil)
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Refresh-Token", refreshToken)
+	return executeRequest(ta, req)
+}
+
+// sessionlessToken re-issues a session token without its session, like the tokens the app issued before sessions
+func sessionlessToken(t *testing.T, authToken string) string {
+	tokenData, err := auth.DecodeJWT(authToken)
+	if err != nil {
+		t.Fatalf("sessionlessToken() error = %v", err)
+	}
+	tokenData.SessionId = ""
+	token, err := tokenData.CreateToken(time.Now().Add(time.Hour).Unix())
+	if err != nil {
+		t.Fatalf("sessionlessToken() error = %v", err)
+	}
+	return token
+}
+
+// signInTwoFactor completes a two-factor sign in challenge with a TOTP or recovery code
+func signInTwoFactor(ta App, challengeToken string, code string) *httptest.ResponseRecorder {
+	payload := []byte(`{"challenge_token":"` + challengeToken + `","code":"` + code + `"}`)
+	req, _ := http.NewRequest("POST", "/auth/2fa", bytes.NewBuffer(payload))
+	req.Header.Add("Content-Type", "application/json")
+	return executeRequest(ta, req)
+}
+
+// mailedToken delivers the queued emails and returns the action token linked in the last email sent to a recipient by
+// the fake mailer
+func mailedToken(ta App, to string) string {
+	_, _ = ta.server.MailService.MailDeliverPending()
+	sent := ta.mailer.(*mailer.FakeMailer).Sent(to)
+	if len(sent) == 0 {
+		return ""
+	}
+	match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(sent[len(sent)-1].Body)
+	if match == nil {
+		return ""
+	}
+	token, _ := url.QueryUnescape(match[1])
+	return token
+}
+
+// setupTestOIDC starts a mock OpenID Connect provider and configures it as the "mock" sign in provider,
+// it has to be called after setup() since setup() resets the environment
+func setupTestOIDC(t *testing.T) *oidc.MockProvider {
+	mock, err := oidc.NewMockProvider()
+	if err != nil {
+		t.Fatalf("setupTestOIDC() error = %v", err)
+	}
+	t.Cleanup(mock.Close)
+	data, err := json.Marshal([]oidc.Config{mock.Config("mock", "http://localhost:3000/sso/callback")})
+	if err != nil {
+		t.Fatalf("setupTestOIDC() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "oidc_providers.json")
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("setupTestOIDC() error = %v", err)
+	}
+	t.Setenv("OIDC_PROVIDERS_FILE", path)
+	return mock
+}
+
+// oidcCallback completes a sign in with an external provider using the code and state it redirected back with
+func oidcCallback(ta App, provider string, code string, state string) *httptest.ResponseRecorder {
+	payload := []byte(`{"code":"` + code + `","state":"` + state + `"}`)
+	req, _ := http.NewRequest("POST", "/auth/oidc/"+provider+"/callback", bytes.NewBuffer(payload))
+	req.Header.Add("Content-Type", "application/json")
+	return executeRequest(ta, req)
+}
+
+// signInOIDC signs in with the mock provider as the user it is set to, the way a browser would
+func signInOIDC(ta App, mock *oidc.MockProvider) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/auth/oidc/mock", nil)
+	response := executeRequest(ta, req)
+	if response.Code != http.StatusOK {
+		return response
+	}
+	var login struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &login); err != nil {
+		panic(err)
+	}
+	code, state, err := mock.Authorize(login.AuthorizationURL)
+	if err != nil {
+		panic(err)
+	}
+	return oidcCallback(ta, "mock", code, state)
+}
+
 // CreateTestGroup creates a group doc for test setup
 func createTestGroup(ta App, groupType int) *models.Group {
 	group := models.Group{}
@@ -187,3 +318,94 @@ func getTestTaskPayload(tCase string) []byte {
 	}
 	return nil
 }
+
+// getTestUploadPayload
+func getTestUploadPayload(content []byte, size int) []byte {
+	sum := sha256.Sum256(content)
+	return []byte(`{"name":"test.txt","file_type":"text/plain","size":` + strconv.Itoa(size) + `,"checksum":"` + hex.EncodeToString(sum[:]) + `"}`)
+}
+
+// uploadTestChunk sends a numbered chunk of an upload session
+func uploadTestChunk(ta App, authToken string, uploadId string, number int, chunk []byte, chunkSum []byte) *httptest.ResponseRecorder {
+	sum := sha256.Sum256(chunkSum)
+	req, _ := http.NewRequest("POST", "/uploads/"+uploadId+"/chunks/"+strconv.Itoa(number), bytes.NewBuffer(chunk))
+	req.Header.Add("Auth-Token", authToken)
+	req.Header.Add("Upload-Checksum", hex.EncodeToString(sum[:]))
+	return executeRequest(ta, req)
+}
+
+// getTestImage returns a png encoded image of the given dimensions
+func getTestImage(w, h int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// putTestImage uploads a png image to an avatar endpoint
+func putTestImage(ta App, authToken string, path string, data []byte) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PUT", path, bytes.NewBuffer(data))
+	req.Header.Add("Content-Type", "image/png")
+	req.Header.Add("Auth-Token", authToken)
+	return executeRequest(ta, req)
+}
+
+// uploadTestFile runs a complete upload session for content and returns the completion response
+func uploadTestFile(ta App, authToken string, content []byte) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/uploads", bytes.NewBuffer(getTestUploadPayload(content, len(content))))
+	req.Header.Add("Auth-Token", authToken)
+	response := executeRequest(ta, req)
+	if response.Code != http.StatusCreated {
+		return response
+	}
+	var upload models.Upload
+	if err := json.Unmarshal(response.Body.Bytes(), &upload); err != nil {
+		panic(err)
+	}
+	for i := int64(0); i*upload.ChunkSize < upload.Size; i++ {
+		end := (i + 1) * upload.ChunkSize
+		if end > upload.Size {
+			end = upload.Size
+		}
+		chunk := content[i*upload.ChunkSize : end]
+		uploadTestChunk(ta, authToken, upload.Id, int(i), chunk, chunk)
+	}
+	req, _ = http.NewRequest("POST", "/uploads/"+upload.Id+"/complete", nil)
+	req.Header.Add("Auth-Token", authToken)
+	return executeRequest(ta, req)
+}
+
+// webhookDeliveries is the response of the webhook deliveries route
+type webhookDeliveries struct {
+	Deliveries []*models.WebhookDelivery `json:"deliveries"`
+}
+
+// receivedWebhook is a delivery received by the test webhook receiver
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// startTestWebhook starts a webhook receiver that accepts every delivery and passes it on to the returned channel
+func startTestWebhook(t *testing.T) (*httptest.Server, <-chan receivedWebhook) {
+	received := make(chan receivedWebhook, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+// awaitWebhook waits for the next delivery of a test webhook receiver, failing the test when none arrives in time
+func awaitWebhook(t *testing.T, received <-chan receivedWebhook) receivedWebhook {
+	select {
+	case d := <-received:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook delivery received")
+	}
+	return receivedWebhook{}
+}
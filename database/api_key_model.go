@@ -0,0 +1,168 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// apiKeyModel structures an api key BSON document to save in an api_keys collection
+type apiKeyModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	UserId       primitive.ObjectID `bson:"user_id,omitempty"`
+	Name         string             `bson:"name,omitempty"`
+	Scopes       []string           `bson:"scopes,omitempty"`
+	Prefix       string             `bson:"prefix,omitempty"`
+	KeyHash      string             `bson:"key_hash,omitempty"`
+	LastUsedAt   time.Time          `bson:"last_used_at,omitempty"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newAPIKeyModel initializes a new pointer to an apiKeyModel struct from a pointer to a JSON APIKey struct
+func newAPIKeyModel(k *models.APIKey) (km *apiKeyModel, err error) {
+	km = &apiKeyModel{
+		Name:         k.Name,
+		Scopes:       k.Scopes,
+		Prefix:       k.Prefix,
+		KeyHash:      k.KeyHash,
+		LastUsedAt:   k.LastUsedAt,
+		LastModified: k.LastModified,
+		CreatedAt:    k.CreatedAt,
+	}
+	if k.CheckID("id") {
+		km.Id, err = primitive.ObjectIDFromHex(k.Id)
+		if err != nil {
+			return
+		}
+	}
+	if k.CheckID("user_id") {
+		km.UserId, err = primitive.ObjectIDFromHex(k.UserId)
+	}
+	return
+}
+
+// update the apiKeyModel using an overwrite bson.D doc
+func (k *apiKeyModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	km := apiKeyModel{}
+	err = bson.Unmarshal(data, &km)
+	if !km.LastUsedAt.IsZero() {
+		k.LastUsedAt = km.LastUsedAt
+	}
+	if !km.LastModified.IsZero() {
+		k.LastModified = km.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the apiKeyModel
+func (k *apiKeyModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, k)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the apiKeyModel
+func (k *apiKeyModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	km := apiKeyModel{}
+	err = bson.Unmarshal(data, &km)
+	if !km.Id.IsZero() {
+		return k.Id == km.Id
+	}
+	if km.KeyHash != "" {
+		return k.KeyHash == km.KeyHash
+	}
+	if !km.UserId.IsZero() {
+		return k.UserId == km.UserId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the apiKeyModel
+func (k *apiKeyModel) getID() (id interface{}) {
+	return k.Id
+}
+
+// addTimeStamps updates an apiKeyModel struct with a timestamp
+func (k *apiKeyModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	k.LastModified = currentTime
+	if newRecord {
+		k.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if an apiKeyModel has a value assigned for Id, if no value a new one is generated and assigned
+func (k *apiKeyModel) addObjectID() {
+	if k.Id.IsZero() {
+		k.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates an apiKeyModel struct postProcess
+func (k *apiKeyModel) postProcess() (err error) {
+	if k.KeyHash == "" {
+		err = errors.New("api key record does not have a key_hash")
+	}
+	return
+}
+
+// toDoc converts the bson apiKeyModel into a bson.D
+func (k *apiKeyModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(k)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the apiKeyModel data
+func (k *apiKeyModel) bsonFilter() (doc bson.D, err error) {
+	if !k.Id.IsZero() {
+		doc = bson.D{{"_id", k.Id}}
+	} else if k.KeyHash != "" {
+		doc = bson.D{{"key_hash", k.KeyHash}}
+	} else if !k.UserId.IsZero() {
+		doc = bson.D{{"user_id", k.UserId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the apiKeyModel data
+func (k *apiKeyModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := k.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to an APIKey JSON struct from a pointer to a BSON apiKeyModel
+func (k *apiKeyModel) toRoot() *models.APIKey {
+	return &models.APIKey{
+		Id:           k.Id.Hex(),
+		UserId:       k.UserId.Hex(),
+		Name:         k.Name,
+		Scopes:       k.Scopes,
+		Prefix:       k.Prefix,
+		KeyHash:      k.KeyHash,
+		LastUsedAt:   k.LastUsedAt,
+		LastModified: k.LastModified,
+		CreatedAt:    k.CreatedAt,
+	}
+}
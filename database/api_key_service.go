@@ -0,0 +1,128 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+const (
+	apiKeyPrefix        = "msk_"
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService is used by the app to manage all api key related controllers and functionality
+type APIKeyService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*apiKeyModel]
+}
+
+// NewAPIKeyService is an exported function used to initialize a new APIKeyService struct
+func NewAPIKeyService(db DBClient, handler *DBHandler[*apiKeyModel]) *APIKeyService {
+	collection := db.GetCollection("api_keys")
+	return &APIKeyService{collection, db, handler}
+}
+
+// hashAPIKey returns the hex encoded sha256 sum of an api key, which is what gets stored
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyCreate is used to create a new api key, the returned APIKey is the only one that carries the key itself
+func (p *APIKeyService) APIKeyCreate(k *models.APIKey) (*models.APIKey, error) {
+	err := k.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	km, err := newAPIKeyModel(&models.APIKey{
+		UserId:  k.UserId,
+		Name:    k.Name,
+		Scopes:  k.Scopes,
+		Prefix:  key[:len(apiKeyPrefix)+6],
+		KeyHash: hashAPIKey(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	km, err = p.handler.InsertOne(km)
+	if err != nil {
+		return nil, err
+	}
+	created := km.toRoot()
+	created.Key = key
+	return created, nil
+}
+
+// APIKeysFind is used to list the api keys of a user
+func (p *APIKeyService) APIKeysFind(k *models.APIKey) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	err := k.Validate("find")
+	if err != nil {
+		return keys, err
+	}
+	km, err := newAPIKeyModel(&models.APIKey{UserId: k.UserId})
+	if err != nil {
+		return keys, err
+	}
+	kms, err := p.handler.FindMany(km)
+	if err != nil {
+		return keys, err
+	}
+	for _, m := range kms {
+		keys = append(keys, m.toRoot())
+	}
+	return keys, nil
+}
+
+// APIKeyDelete is used to revoke an api key belonging to a user
+func (p *APIKeyService) APIKeyDelete(k *models.APIKey) (*models.APIKey, error) {
+	err := k.Validate("delete")
+	if err != nil {
+		return nil, err
+	}
+	km, err := newAPIKeyModel(&models.APIKey{Id: k.Id, UserId: k.UserId})
+	if err != nil {
+		return nil, err
+	}
+	found, err := p.handler.FindOne(&apiKeyModel{Id: km.Id})
+	if err != nil {
+		return nil, err
+	}
+	if found.UserId != km.UserId {
+		return nil, errors.New("api key not found")
+	}
+	found, err = p.handler.DeleteOne(&apiKeyModel{Id: km.Id})
+	if err != nil {
+		return nil, err
+	}
+	return found.toRoot(), nil
+}
+
+// APIKeyVerify looks up the record of an api key and records that it was used
+func (p *APIKeyService) APIKeyVerify(key string) (*models.APIKey, error) {
+	if key == "" {
+		return nil, models.ErrAPIKeyInvalid
+	}
+	km, err := p.handler.FindOne(&apiKeyModel{KeyHash: hashAPIKey(key)})
+	if err != nil {
+		return nil, models.ErrAPIKeyInvalid
+	}
+	if time.Since(km.LastUsedAt) >= apiKeyTouchInterval {
+		km.LastUsedAt = time.Now().UTC()
+		if km, err = p.handler.UpdateOne(&apiKeyModel{Id: km.Id}, km); err != nil {
+			return nil, err
+		}
+	}
+	return km.toRoot(), nil
+}
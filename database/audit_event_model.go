@@ -0,0 +1,156 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// auditEventModel structures an audit event BSON document to save in an audit_events collection
+type auditEventModel struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty"`
+	Action    string             `bson:"action,omitempty"`
+	ActorId   primitive.ObjectID `bson:"actor_id,omitempty"`
+	TargetId  primitive.ObjectID `bson:"target_id,omitempty"`
+	From      string             `bson:"from,omitempty"`
+	To        string             `bson:"to,omitempty"`
+	CreatedAt time.Time          `bson:"created_at,omitempty"`
+}
+
+// newAuditEventModel initializes a new pointer to an auditEventModel struct from a pointer to a JSON AuditEvent struct
+func newAuditEventModel(a *models.AuditEvent) (am *auditEventModel, err error) {
+	am = &auditEventModel{
+		Action:    a.Action,
+		From:      a.From,
+		To:        a.To,
+		CreatedAt: a.CreatedAt,
+	}
+	if a.Id != "" && a.Id != "000000000000000000000000" {
+		am.Id, err = primitive.ObjectIDFromHex(a.Id)
+		if err != nil {
+			return
+		}
+	}
+	if a.ActorId != "" && a.ActorId != "000000000000000000000000" {
+		am.ActorId, err = primitive.ObjectIDFromHex(a.ActorId)
+		if err != nil {
+			return
+		}
+	}
+	if a.TargetId != "" && a.TargetId != "000000000000000000000000" {
+		am.TargetId, err = primitive.ObjectIDFromHex(a.TargetId)
+	}
+	return
+}
+
+// update the auditEventModel using an overwrite bson.D doc, audit events are never changed once recorded
+func (a *auditEventModel) update(doc interface{}) (err error) {
+	return errors.New("audit events can not be updated")
+}
+
+// bsonLoad loads a bson doc into the auditEventModel
+func (a *auditEventModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, a)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the auditEventModel
+func (a *auditEventModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	am := auditEventModel{}
+	err = bson.Unmarshal(data, &am)
+	if !am.Id.IsZero() {
+		return a.Id == am.Id
+	}
+	if !am.TargetId.IsZero() && a.TargetId != am.TargetId {
+		return false
+	}
+	if am.Action != "" && a.Action != am.Action {
+		return false
+	}
+	return true
+}
+
+// getID returns the unique identifier of the auditEventModel
+func (a *auditEventModel) getID() (id interface{}) {
+	return a.Id
+}
+
+// addTimeStamps updates an auditEventModel struct with a timestamp
+func (a *auditEventModel) addTimeStamps(newRecord bool) {
+	if newRecord {
+		a.CreatedAt = time.Now().UTC()
+	}
+}
+
+// addObjectID checks if an auditEventModel has a value assigned for Id, if no value a new one is generated and assigned
+func (a *auditEventModel) addObjectID() {
+	if a.Id.IsZero() {
+		a.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates an auditEventModel struct postProcess
+func (a *auditEventModel) postProcess() (err error) {
+	if a.Action == "" {
+		err = errors.New("audit event record does not have an action")
+	}
+	return
+}
+
+// toDoc converts the bson auditEventModel into a bson.D
+func (a *auditEventModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(a)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the auditEventModel data
+func (a *auditEventModel) bsonFilter() (doc bson.D, err error) {
+	if !a.Id.IsZero() {
+		return bson.D{{"_id", a.Id}}, nil
+	}
+	doc = bson.D{}
+	if !a.TargetId.IsZero() {
+		doc = append(doc, bson.E{Key: "target_id", Value: a.TargetId})
+	}
+	if a.Action != "" {
+		doc = append(doc, bson.E{Key: "action", Value: a.Action})
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the auditEventModel data
+func (a *auditEventModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := a.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to an AuditEvent JSON struct from a pointer to a BSON auditEventModel
+func (a *auditEventModel) toRoot() *models.AuditEvent {
+	return &models.AuditEvent{
+		Id:        a.Id.Hex(),
+		Action:    a.Action,
+		ActorId:   a.ActorId.Hex(),
+		TargetId:  a.TargetId.Hex(),
+		From:      a.From,
+		To:        a.To,
+		CreatedAt: a.CreatedAt,
+	}
+}
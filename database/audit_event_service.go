@@ -0,0 +1,66 @@
+package database
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"sort"
+)
+
+// AuditEventService is used by the app to record privileged changes and list them for admins
+type AuditEventService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*auditEventModel]
+}
+
+// NewAuditEventService is an exported function used to initialize a new AuditEventService struct
+func NewAuditEventService(db DBClient, handler *DBHandler[*auditEventModel]) *AuditEventService {
+	collection := db.GetCollection("audit_events")
+	return &AuditEventService{collection, db, handler}
+}
+
+// AuditEventCreate records an AuditEvent
+func (p *AuditEventService) AuditEventCreate(a *models.AuditEvent) (*models.AuditEvent, error) {
+	err := a.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	am, err := newAuditEventModel(&models.AuditEvent{
+		Action:   a.Action,
+		ActorId:  a.ActorId,
+		TargetId: a.TargetId,
+		From:     a.From,
+		To:       a.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+	am, err = p.handler.InsertOne(am)
+	if err != nil {
+		return nil, err
+	}
+	return am.toRoot(), nil
+}
+
+// AuditEventsFind lists the AuditEvents matching the target and action of a filter, newest first
+func (p *AuditEventService) AuditEventsFind(a *models.AuditEvent) ([]*models.AuditEvent, error) {
+	events := []*models.AuditEvent{}
+	am, err := newAuditEventModel(&models.AuditEvent{TargetId: a.TargetId, Action: a.Action})
+	if err != nil {
+		return events, err
+	}
+	ams, err := p.handler.FindMany(am)
+	if err != nil {
+		return events, err
+	}
+	for _, am = range ams {
+		events = append(events, am.toRoot())
+	}
+	// ids break ties between events recorded in the same millisecond, they increase within a process
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].Id > events[j].Id
+		}
+		return events[i].CreatedAt.After(events[j].CreatedAt)
+	})
+	return events, nil
+}
@@ -2,16 +2,19 @@ package database
 
 import (
 	"errors"
-	"fmt"
 	"github.com/ablancas22/messenger-backend/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"time"
 )
 
+// blacklistModel structures a revoked token BSON document. Raw tokens are no longer stored, AuthToken is only
+// read from records written before tokens were hashed.
 type blacklistModel struct {
 	Id           primitive.ObjectID `bson:"_id,omitempty"`
 	AuthToken    string             `bson:"auth_token,omitempty"`
+	TokenHash    string             `bson:"token_hash,omitempty"`
+	ExpiresAt    time.Time          `bson:"expires_at,omitempty"`
 	LastModified time.Time          `bson:"last_modified,omitempty"`
 	CreatedAt    time.Time          `bson:"created_at,omitempty"`
 }
@@ -19,7 +22,8 @@ type blacklistModel struct {
 // newBlacklistModel initializes a new pointer to a blacklistModel struct from a pointer to a JSON Blacklist struct
 func newBlacklistModel(bl *models.Blacklist) (bm *blacklistModel, err error) {
 	bm = &blacklistModel{
-		AuthToken: bl.AuthToken,
+		TokenHash: bl.TokenHash,
+		ExpiresAt: bl.ExpiresAt,
 		CreatedAt: bl.CreatedAt,
 	}
 	if bl.Id != "" && bl.Id != "000000000000000000000000" {
@@ -36,8 +40,11 @@ func (b *blacklistModel) update(doc interface{}) (err error) {
 	}
 	bm := blacklistModel{}
 	err = bson.Unmarshal(data, &bm)
-	if len(bm.AuthToken) > 0 {
-		b.AuthToken = bm.AuthToken
+	if len(bm.TokenHash) > 0 {
+		b.TokenHash = bm.TokenHash
+	}
+	if !bm.ExpiresAt.IsZero() {
+		b.ExpiresAt = bm.ExpiresAt
 	}
 	if !bm.LastModified.IsZero() {
 		b.LastModified = bm.LastModified
@@ -63,12 +70,11 @@ func (b *blacklistModel) match(doc interface{}) bool {
 	}
 	bm := blacklistModel{}
 	err = bson.Unmarshal(data, &bm)
-	fmt.Println("\nCHECK BLACKLIST MATCH: ", bm, b)
-	if b.Id == bm.Id {
-		return true
+	if !bm.Id.IsZero() {
+		return b.Id == bm.Id
 	}
-	if b.AuthToken == bm.AuthToken {
-		return true
+	if bm.TokenHash != "" {
+		return b.TokenHash == bm.TokenHash
 	}
 	return false
 }
@@ -96,8 +102,8 @@ func (b *blacklistModel) addObjectID() {
 
 // postProcess updates an blacklistModel struct postProcess to do things such as removing the password field's value
 func (b *blacklistModel) postProcess() (err error) {
-	if b.AuthToken == "" {
-		err = errors.New("blacklist record does not have an AuthToken")
+	if b.TokenHash == "" && b.AuthToken == "" {
+		err = errors.New("blacklist record does not have a token_hash")
 	}
 	return
 }
@@ -114,8 +120,8 @@ func (b *blacklistModel) toDoc() (doc bson.D, err error) {
 
 // bsonFilter generates a bson filter for MongoDB queries from the blacklistModel data
 func (b *blacklistModel) bsonFilter() (doc bson.D, err error) {
-	if b.AuthToken != "" {
-		doc = bson.D{{"auth_token", b.AuthToken}}
+	if b.TokenHash != "" {
+		doc = bson.D{{"token_hash", b.TokenHash}}
 	} else if b.Id.Hex() != "" && b.Id.Hex() != "000000000000000000000000" {
 		doc = bson.D{{"_id", b.Id}}
 	}
@@ -136,7 +142,8 @@ func (b *blacklistModel) bsonUpdate() (doc bson.D, err error) {
 func (b *blacklistModel) toRoot() *models.Blacklist {
 	return &models.Blacklist{
 		Id:        b.Id.Hex(),
-		AuthToken: b.AuthToken,
+		TokenHash: b.TokenHash,
+		ExpiresAt: b.ExpiresAt,
 		CreatedAt: b.CreatedAt,
 	}
 }
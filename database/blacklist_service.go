@@ -1,32 +1,138 @@
 package database
 
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.mongodb.org/mongo-driver/bson"
+	"log"
+	"sync"
+	"time"
+)
+
+// blacklistCacheTTL is how long the in-memory revocation cache is trusted before it is reloaded, which bounds
+// how long a token revoked by another instance of the app stays usable here
+const blacklistCacheTTL = 30 * time.Second
+
+// revocationCache is an in-memory copy of the unexpired blacklist keyed by token hash
+type revocationCache struct {
+	mu       sync.RWMutex
+	entries  map[string]time.Time
+	loadedAt time.Time
+}
+
 // BlacklistService is used by the app to manage all group related controllers and functionality
 type BlacklistService struct {
 	collection DBCollection
 	db         DBClient
 	handler    *DBHandler[*blacklistModel]
+	cache      *revocationCache
 }
 
 // NewBlacklistService is an exported function used to initialize a new GroupService struct
 func NewBlacklistService(db DBClient, handler *DBHandler[*blacklistModel]) *BlacklistService {
 	collection := db.GetCollection("blacklists")
-	return &BlacklistService{collection, db, handler}
+	return &BlacklistService{collection, db, handler, &revocationCache{}}
+}
+
+// hashAuthToken returns the hex encoded sha256 sum of an auth token, which is what gets blacklisted
+func hashAuthToken(authToken string) string {
+	sum := sha256.Sum256([]byte(authToken))
+	return hex.EncodeToString(sum[:])
 }
 
-// BlacklistAuthToken is used during sign-out to add the now invalid auth-token/api key to the blacklist collection
-func (a *BlacklistService) BlacklistAuthToken(authToken string) error {
-	_, err := a.handler.InsertOne(&blacklistModel{AuthToken: authToken})
+// BlacklistAuthToken is used during sign-out to add the now invalid auth-token/api key to the blacklist collection.
+// The record is removed by a TTL index once the token would have expired anyway.
+func (a *BlacklistService) BlacklistAuthToken(authToken string, expiresAt time.Time) error {
+	tokenHash := hashAuthToken(authToken)
+	_, err := a.handler.InsertOne(&blacklistModel{TokenHash: tokenHash, ExpiresAt: expiresAt.UTC()})
 	if err != nil {
 		return err
 	}
+	a.cache.mu.Lock()
+	if a.cache.entries != nil {
+		a.cache.entries[tokenHash] = expiresAt
+	}
+	a.cache.mu.Unlock()
 	return nil
 }
 
 // CheckTokenBlacklist to determine if the submitted Auth-Token or API-Key with what's in the blacklist collection
 func (a *BlacklistService) CheckTokenBlacklist(authToken string) bool {
-	_, err := a.handler.FindOne(&blacklistModel{AuthToken: authToken})
+	tokenHash := hashAuthToken(authToken)
+	if err := a.loadCache(); err != nil {
+		log.Println("Blacklist cache reload failed:", err)
+		_, err = a.handler.FindOne(&blacklistModel{TokenHash: tokenHash})
+		return err == nil
+	}
+	a.cache.mu.RLock()
+	expiresAt, ok := a.cache.entries[tokenHash]
+	a.cache.mu.RUnlock()
+	return ok && (expiresAt.IsZero() || time.Now().Before(expiresAt))
+}
+
+// loadCache refreshes the revocation cache once it is older than blacklistCacheTTL. The whole collection is only read
+// the first time, after that only the entries created since the last load are, with some slack for the clocks of other
+// instances of the app, and expired entries are dropped from the cache.
+func (a *BlacklistService) loadCache() error {
+	a.cache.mu.RLock()
+	loaded, loadedAt := a.cache.entries != nil, a.cache.loadedAt
+	a.cache.mu.RUnlock()
+	if loaded && time.Since(loadedAt) < blacklistCacheTTL {
+		return nil
+	}
+	now := time.Now()
+	var bms []*blacklistModel
+	var err error
+	if loaded {
+		bms, err = a.createdSince(loadedAt.Add(-blacklistCacheTTL))
+	} else {
+		bms, err = a.handler.FindMany(&blacklistModel{})
+	}
 	if err != nil {
-		return false
+		return err
+	}
+	a.cache.mu.Lock()
+	defer a.cache.mu.Unlock()
+	if a.cache.entries == nil {
+		a.cache.entries = make(map[string]time.Time, len(bms))
+	}
+	for tokenHash, expiresAt := range a.cache.entries {
+		if !expiresAt.IsZero() && now.After(expiresAt) {
+			delete(a.cache.entries, tokenHash)
+		}
+	}
+	for _, bm := range bms {
+		if !bm.ExpiresAt.IsZero() && now.After(bm.ExpiresAt) {
+			continue
+		}
+		if bm.TokenHash != "" {
+			a.cache.entries[bm.TokenHash] = bm.ExpiresAt
+		} else {
+			a.cache.entries[hashAuthToken(bm.AuthToken)] = bm.ExpiresAt
+		}
+	}
+	a.cache.loadedAt = now
+	return nil
+}
+
+// createdSince returns the blacklist entries created at or after a time
+func (a *BlacklistService) createdSince(since time.Time) ([]*blacklistModel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cur, err := a.collection.Find(ctx, bson.D{{"created_at", bson.D{{"$gte", since.UTC()}}}})
+	if err != nil {
+		return nil, err
+	}
+	cursor := checkCursorENV(cur)
+	defer cursor.Close(ctx)
+	var bms []*blacklistModel
+	for cursor.Next(ctx) {
+		var bm blacklistModel
+		if err = cursor.Decode(&bm); err != nil {
+			return nil, err
+		}
+		bms = append(bms, &bm)
 	}
-	return true
+	return bms, nil
 }
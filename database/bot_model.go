@@ -0,0 +1,168 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// botModel structures a bot BSON document to save in a bots collection
+type botModel struct {
+	Id            primitive.ObjectID `bson:"_id,omitempty"`
+	UserId        primitive.ObjectID `bson:"user_id,omitempty"`
+	OwnerId       primitive.ObjectID `bson:"owner_id,omitempty"`
+	WebhookURL    string             `bson:"webhook_url,omitempty"`
+	WebhookSecret string             `bson:"webhook_secret,omitempty"`
+	LastModified  time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty"`
+}
+
+// newBotModel initializes a new pointer to a botModel struct from a pointer to a JSON Bot struct
+func newBotModel(b *models.Bot) (bm *botModel, err error) {
+	bm = &botModel{
+		WebhookURL:    b.WebhookURL,
+		WebhookSecret: b.WebhookSecret,
+		LastModified:  b.LastModified,
+		CreatedAt:     b.CreatedAt,
+	}
+	if b.Id != "" && b.Id != "000000000000000000000000" {
+		if bm.Id, err = primitive.ObjectIDFromHex(b.Id); err != nil {
+			return
+		}
+	}
+	if b.UserId != "" && b.UserId != "000000000000000000000000" {
+		if bm.UserId, err = primitive.ObjectIDFromHex(b.UserId); err != nil {
+			return
+		}
+	}
+	if b.OwnerId != "" && b.OwnerId != "000000000000000000000000" {
+		bm.OwnerId, err = primitive.ObjectIDFromHex(b.OwnerId)
+	}
+	return
+}
+
+// update the botModel using an overwrite bson.D doc
+func (b *botModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	bm := botModel{}
+	err = bson.Unmarshal(data, &bm)
+	if len(bm.WebhookURL) > 0 {
+		b.WebhookURL = bm.WebhookURL
+	}
+	if len(bm.WebhookSecret) > 0 {
+		b.WebhookSecret = bm.WebhookSecret
+	}
+	if !bm.LastModified.IsZero() {
+		b.LastModified = bm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the botModel
+func (b *botModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, b)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the botModel
+func (b *botModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	bm := botModel{}
+	err = bson.Unmarshal(data, &bm)
+	if !bm.Id.IsZero() {
+		return b.Id == bm.Id
+	}
+	if !bm.UserId.IsZero() {
+		return b.UserId == bm.UserId
+	}
+	if !bm.OwnerId.IsZero() {
+		return b.OwnerId == bm.OwnerId
+	}
+	return true
+}
+
+// getID returns the unique identifier of the botModel
+func (b *botModel) getID() (id interface{}) {
+	return b.Id
+}
+
+// addTimeStamps updates a botModel struct with a timestamp
+func (b *botModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	b.LastModified = currentTime
+	if newRecord {
+		b.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a botModel has a value assigned for Id, if no value a new one is generated and assigned
+func (b *botModel) addObjectID() {
+	if b.Id.IsZero() {
+		b.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a botModel struct postProcess
+func (b *botModel) postProcess() (err error) {
+	if b.UserId.IsZero() {
+		err = errors.New("bot record does not have a user_id")
+	}
+	return
+}
+
+// toDoc converts the bson botModel into a bson.D
+func (b *botModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(b)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the botModel data
+func (b *botModel) bsonFilter() (doc bson.D, err error) {
+	if !b.Id.IsZero() {
+		doc = bson.D{{"_id", b.Id}}
+	} else if !b.UserId.IsZero() {
+		doc = bson.D{{"user_id", b.UserId}}
+	} else if !b.OwnerId.IsZero() {
+		doc = bson.D{{"owner_id", b.OwnerId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the botModel data
+func (b *botModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := b.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a Bot JSON struct from a pointer to a BSON botModel
+func (b *botModel) toRoot() *models.Bot {
+	return &models.Bot{
+		Id:            b.Id.Hex(),
+		UserId:        b.UserId.Hex(),
+		OwnerId:       b.OwnerId.Hex(),
+		WebhookURL:    b.WebhookURL,
+		WebhookSecret: b.WebhookSecret,
+		LastModified:  b.LastModified,
+		CreatedAt:     b.CreatedAt,
+	}
+}
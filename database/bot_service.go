@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+// BotService is used by the app to manage the settings of bot users
+type BotService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*botModel]
+}
+
+// NewBotService is an exported function used to initialize a new BotService struct
+func NewBotService(db DBClient, handler *DBHandler[*botModel]) *BotService {
+	collection := db.GetCollection("bots")
+	return &BotService{collection, db, handler}
+}
+
+// BotCreate stores the settings of a new bot User
+func (p *BotService) BotCreate(b *models.Bot) (*models.Bot, error) {
+	err := b.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	bm, err := newBotModel(&models.Bot{
+		UserId:        b.UserId,
+		OwnerId:       b.OwnerId,
+		WebhookURL:    b.WebhookURL,
+		WebhookSecret: b.WebhookSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	bm, err = p.handler.InsertOne(bm)
+	if err != nil {
+		return nil, err
+	}
+	return bm.toRoot(), nil
+}
+
+// BotFind looks up the settings of a bot User by its user id
+func (p *BotService) BotFind(b *models.Bot) (*models.Bot, error) {
+	if !b.CheckID("user_id") {
+		return nil, errors.New("missing the following bot fields: user_id")
+	}
+	bm, err := newBotModel(&models.Bot{UserId: b.UserId})
+	if err != nil {
+		return nil, err
+	}
+	bms, err := p.handler.FindMany(bm)
+	if err != nil {
+		return nil, err
+	}
+	if len(bms) == 0 {
+		return nil, models.ErrBotNotFound
+	}
+	return bms[0].toRoot(), nil
+}
+
+// BotsFind lists the bots of an owner, or every bot when the filter has no owner
+func (p *BotService) BotsFind(b *models.Bot) ([]*models.Bot, error) {
+	bots := []*models.Bot{}
+	bm, err := newBotModel(&models.Bot{OwnerId: b.OwnerId})
+	if err != nil {
+		return bots, err
+	}
+	bms, err := p.handler.FindMany(bm)
+	if err != nil {
+		return bots, err
+	}
+	for _, bm = range bms {
+		bots = append(bots, bm.toRoot())
+	}
+	return bots, nil
+}
+
+// BotUpdate sets the webhook URL and secret of a bot, an empty webhook URL turns event delivery off
+func (p *BotService) BotUpdate(b *models.Bot) (*models.Bot, error) {
+	err := b.Validate("update")
+	if err != nil {
+		return nil, err
+	}
+	cur, err := p.BotFind(b)
+	if err != nil {
+		return nil, err
+	}
+	bm, err := newBotModel(cur)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.D{{"last_modified", time.Now().UTC()}}
+	if b.WebhookSecret != "" {
+		set = append(set, bson.E{Key: "webhook_secret", Value: b.WebhookSecret})
+		cur.WebhookSecret = b.WebhookSecret
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if b.WebhookURL != "" {
+		set = append(set, bson.E{Key: "webhook_url", Value: b.WebhookURL})
+	} else if cur.WebhookURL != "" {
+		_, err = p.collection.UpdateOne(ctx, bson.D{{"_id", bm.Id}}, bson.D{{"$unset", bson.D{{"webhook_url", ""}}}})
+		if err != nil {
+			return nil, err
+		}
+	}
+	cur.WebhookURL = b.WebhookURL
+	_, err = p.collection.UpdateOne(ctx, bson.D{{"_id", bm.Id}}, bson.D{{"$set", set}})
+	if err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// BotDelete removes the settings of a bot User
+func (p *BotService) BotDelete(b *models.Bot) (*models.Bot, error) {
+	cur, err := p.BotFind(b)
+	if err != nil {
+		return nil, err
+	}
+	bm, err := newBotModel(&models.Bot{Id: cur.Id})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.handler.DeleteOne(bm); err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlCacheMaxEntries bounds the size of a ttlCache, expired entries are dropped once it is reached
+const ttlCacheMaxEntries = 10000
+
+// ttlCacheEntry is a cached value along with when it stops being trusted
+type ttlCacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// ttlCache is an in-memory cache of docs keyed by id that are trusted for a fixed time after they were loaded. It is
+// used on the hot path of authenticating requests, writers remove the entries of the docs they change.
+type ttlCache[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]ttlCacheEntry[T]
+}
+
+// newTTLCache initializes a new ttlCache whose entries are trusted for ttl
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{ttl: ttl, entries: make(map[string]ttlCacheEntry[T])}
+}
+
+// get returns the cached value for a key unless it is missing or expired
+func (c *ttlCache[T]) get(key string) (value T, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return value, false
+	}
+	return entry.value, true
+}
+
+// put caches a value for a key
+func (c *ttlCache[T]) put(key string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= ttlCacheMaxEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= ttlCacheMaxEntries {
+			c.entries = make(map[string]ttlCacheEntry[T])
+		}
+	}
+	c.entries[key] = ttlCacheEntry[T]{value, now.Add(c.ttl)}
+}
+
+// remove drops the cached value for a key
+func (c *ttlCache[T]) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// commandModel structures a custom command BSON document to save in a commands collection
+type commandModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	GroupId      primitive.ObjectID `bson:"group_id,omitempty"`
+	Name         string             `bson:"name,omitempty"`
+	Description  string             `bson:"description,omitempty"`
+	BotId        primitive.ObjectID `bson:"bot_id,omitempty"`
+	URL          string             `bson:"url,omitempty"`
+	Secret       string             `bson:"secret,omitempty"`
+	CreatedBy    primitive.ObjectID `bson:"created_by,omitempty"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newCommandModel initializes a new pointer to a commandModel struct from a pointer to a JSON Command struct
+func newCommandModel(c *models.Command) (cm *commandModel, err error) {
+	cm = &commandModel{
+		Name:         c.Name,
+		Description:  c.Description,
+		URL:          c.URL,
+		Secret:       c.Secret,
+		LastModified: c.LastModified,
+		CreatedAt:    c.CreatedAt,
+	}
+	if c.Id != "" && c.Id != "000000000000000000000000" {
+		if cm.Id, err = primitive.ObjectIDFromHex(c.Id); err != nil {
+			return
+		}
+	}
+	if c.GroupId != "" && c.GroupId != "000000000000000000000000" {
+		if cm.GroupId, err = primitive.ObjectIDFromHex(c.GroupId); err != nil {
+			return
+		}
+	}
+	if c.BotId != "" && c.BotId != "000000000000000000000000" {
+		if cm.BotId, err = primitive.ObjectIDFromHex(c.BotId); err != nil {
+			return
+		}
+	}
+	if c.CreatedBy != "" && c.CreatedBy != "000000000000000000000000" {
+		cm.CreatedBy, err = primitive.ObjectIDFromHex(c.CreatedBy)
+	}
+	return
+}
+
+// update the commandModel using an overwrite bson.D doc
+func (c *commandModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	cm := commandModel{}
+	err = bson.Unmarshal(data, &cm)
+	if len(cm.Description) > 0 {
+		c.Description = cm.Description
+	}
+	if len(cm.URL) > 0 {
+		c.URL = cm.URL
+	}
+	if !cm.LastModified.IsZero() {
+		c.LastModified = cm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the commandModel
+func (c *commandModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, c)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the commandModel
+func (c *commandModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	cm := commandModel{}
+	err = bson.Unmarshal(data, &cm)
+	if !cm.Id.IsZero() {
+		return c.Id == cm.Id
+	}
+	if !cm.GroupId.IsZero() {
+		if cm.Name != "" {
+			return c.GroupId == cm.GroupId && c.Name == cm.Name
+		}
+		return c.GroupId == cm.GroupId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the commandModel
+func (c *commandModel) getID() (id interface{}) {
+	return c.Id
+}
+
+// addTimeStamps updates a commandModel struct with a timestamp
+func (c *commandModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	c.LastModified = currentTime
+	if newRecord {
+		c.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a commandModel has a value assigned for Id, if no value a new one is generated and assigned
+func (c *commandModel) addObjectID() {
+	if c.Id.IsZero() {
+		c.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a commandModel struct postProcess
+func (c *commandModel) postProcess() (err error) {
+	if c.GroupId.IsZero() {
+		err = errors.New("command record does not have a group_id")
+	}
+	return
+}
+
+// toDoc converts the bson commandModel into a bson.D
+func (c *commandModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(c)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the commandModel data
+func (c *commandModel) bsonFilter() (doc bson.D, err error) {
+	if !c.Id.IsZero() {
+		doc = bson.D{{"_id", c.Id}}
+	} else if !c.GroupId.IsZero() && c.Name != "" {
+		doc = bson.D{{"group_id", c.GroupId}, {"name", c.Name}}
+	} else if !c.GroupId.IsZero() {
+		doc = bson.D{{"group_id", c.GroupId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the commandModel data
+func (c *commandModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := c.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a Command JSON struct from a pointer to a BSON commandModel
+func (c *commandModel) toRoot() *models.Command {
+	cmd := &models.Command{
+		Id:           c.Id.Hex(),
+		GroupId:      c.GroupId.Hex(),
+		Name:         c.Name,
+		Description:  c.Description,
+		URL:          c.URL,
+		Secret:       c.Secret,
+		CreatedBy:    c.CreatedBy.Hex(),
+		LastModified: c.LastModified,
+		CreatedAt:    c.CreatedAt,
+	}
+	if !c.BotId.IsZero() {
+		cmd.BotId = c.BotId.Hex()
+	}
+	return cmd
+}
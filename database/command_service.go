@@ -0,0 +1,115 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/webhook"
+)
+
+// CommandService is used by the app to manage the custom slash commands of groups
+type CommandService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*commandModel]
+}
+
+// NewCommandService is an exported function used to initialize a new CommandService struct
+func NewCommandService(db DBClient, handler *DBHandler[*commandModel]) *CommandService {
+	collection := db.GetCollection("commands")
+	return &CommandService{collection, db, handler}
+}
+
+// CommandCreate registers a custom command for a group, commands forwarded to a URL get a generated signing secret
+func (p *CommandService) CommandCreate(c *models.Command) (*models.Command, error) {
+	err := c.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.CommandFind(&models.Command{GroupId: c.GroupId, Name: c.Name}); err == nil {
+		return nil, models.ErrCommandExists
+	}
+	cmd := &models.Command{
+		GroupId:     c.GroupId,
+		Name:        c.Name,
+		Description: c.Description,
+		BotId:       c.BotId,
+		URL:         c.URL,
+		CreatedBy:   c.CreatedBy,
+	}
+	if cmd.URL != "" {
+		if cmd.Secret, err = webhook.NewSecret(); err != nil {
+			return nil, err
+		}
+	}
+	cm, err := newCommandModel(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cm, err = p.handler.InsertOne(cm)
+	if err != nil {
+		return nil, err
+	}
+	return cm.toRoot(), nil
+}
+
+// CommandsFind lists the custom commands of a group
+func (p *CommandService) CommandsFind(c *models.Command) ([]*models.Command, error) {
+	commands := []*models.Command{}
+	if !c.CheckID("group_id") {
+		return commands, errors.New("missing the following command fields: group_id")
+	}
+	cm, err := newCommandModel(&models.Command{GroupId: c.GroupId})
+	if err != nil {
+		return commands, err
+	}
+	cms, err := p.handler.FindMany(cm)
+	if err != nil {
+		return commands, err
+	}
+	for _, cm = range cms {
+		commands = append(commands, cm.toRoot())
+	}
+	return commands, nil
+}
+
+// CommandFind looks up a custom command by its id, or by the name it is run with in a group
+func (p *CommandService) CommandFind(c *models.Command) (*models.Command, error) {
+	filter := &models.Command{Id: c.Id}
+	if !c.CheckID("id") {
+		if !c.CheckID("group_id") || c.Name == "" {
+			return nil, models.ErrCommandNotFound
+		}
+		filter = &models.Command{GroupId: c.GroupId, Name: c.Name}
+	}
+	cm, err := newCommandModel(filter)
+	if err != nil {
+		return nil, err
+	}
+	cms, err := p.handler.FindMany(cm)
+	if err != nil {
+		return nil, err
+	}
+	if len(cms) == 0 {
+		return nil, models.ErrCommandNotFound
+	}
+	return cms[0].toRoot(), nil
+}
+
+// CommandDelete removes a custom command of a group
+func (p *CommandService) CommandDelete(c *models.Command) (*models.Command, error) {
+	cur, err := p.CommandFind(&models.Command{Id: c.Id})
+	if err != nil {
+		return nil, err
+	}
+	if cur.GroupId != c.GroupId {
+		return nil, models.ErrCommandNotFound
+	}
+	cm, err := newCommandModel(&models.Command{Id: cur.Id})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.handler.DeleteOne(cm); err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
@@ -11,6 +11,7 @@ type conversationModel struct {
 	Id              primitive.ObjectID   `bson:"_id,omitempty"`
 	ParticipantsIds []primitive.ObjectID `bson:"participants_ids,omitempty"`
 	Group           bool                 `bson:"group,omitempty"` //if group, only ParticipantID is group id
+	MessageTTL      int64                `bson:"message_ttl"`     // not omitted, so turning disappearing messages off is stored
 	DeletedAt       time.Time            `bson:"deleted_at,omitempty"`
 	UpdatedAt       time.Time            `bson:"updatedAt,omitempty"`
 	CreatedAt       time.Time            `bson:"createdAt,omitempty"`
@@ -19,10 +20,11 @@ type conversationModel struct {
 // newConversationModel initializes a new pointer to a userModel struct from a pointer to a JSON User struct
 func newConversationModel(c *models.Conversation) (cm *conversationModel, err error) {
 	cm = &conversationModel{
-		Group:     c.Group,
-		DeletedAt: c.DeletedAt,
-		UpdatedAt: c.UpdatedAt,
-		CreatedAt: c.CreatedAt,
+		Group:      c.Group,
+		MessageTTL: c.MessageTTL,
+		DeletedAt:  c.DeletedAt,
+		UpdatedAt:  c.UpdatedAt,
+		CreatedAt:  c.CreatedAt,
 	}
 	if c.Id != "" && c.Id != "000000000000000000000000" {
 		cm.Id, err = primitive.ObjectIDFromHex(c.Id)
@@ -43,12 +45,18 @@ func newConversationModel(c *models.Conversation) (cm *conversationModel, err er
 
 // toRoot creates and return a new pointer to a Group JSON struct from a pointer to a BSON groupModel
 func (c *conversationModel) toRoot() *models.Conversation {
+	var participantsIds []string
+	for _, id := range c.ParticipantsIds {
+		participantsIds = append(participantsIds, id.Hex())
+	}
 	return &models.Conversation{
-		Id: c.Id.Hex(),
-		//ParticipantsIds: c.ParticipantsIds.Hex(), //Todo: fix this
-		UpdatedAt: c.UpdatedAt,
-		CreatedAt: c.CreatedAt,
-		DeletedAt: c.DeletedAt,
+		Id:              c.Id.Hex(),
+		ParticipantsIds: participantsIds,
+		Group:           c.Group,
+		MessageTTL:      c.MessageTTL,
+		UpdatedAt:       c.UpdatedAt,
+		CreatedAt:       c.CreatedAt,
+		DeletedAt:       c.DeletedAt,
 	}
 }
 
@@ -57,12 +65,15 @@ func (c *conversationModel) update(doc interface{}) (err error) {
 	if err != nil {
 		return
 	}
-	um := userModel{}
-	err = bson.Unmarshal(data, &um)
-	if len(um.Id.Hex()) > 0 && um.Id.Hex() != "000000000000000000000000" {
-		c.Id = um.Id
+	cm := conversationModel{}
+	err = bson.Unmarshal(data, &cm)
+	if len(cm.Id.Hex()) > 0 && cm.Id.Hex() != "000000000000000000000000" {
+		c.Id = cm.Id
+	}
+	c.MessageTTL = cm.MessageTTL
+	if !cm.UpdatedAt.IsZero() {
+		c.UpdatedAt = cm.UpdatedAt
 	}
-
 	return
 }
 
@@ -103,6 +114,7 @@ func (c *conversationModel) getID() (id interface{}) {
 // addTimeStamps updates an userModel struct with a timestamp
 func (c *conversationModel) addTimeStamps(newRecord bool) {
 	currentTime := time.Now().UTC()
+	c.UpdatedAt = currentTime
 	if newRecord {
 		c.CreatedAt = currentTime
 	}
@@ -79,28 +79,31 @@ func (c *ConversationService) ConversationDelete(g *models.Conversation) (*model
 	return cm.toRoot(), err
 }
 
-// ConversationUpdate is used to update an existing group
+// ConversationUpdate is used to change the settings of an existing conversation, such as its message lifetime
 func (c *ConversationService) ConversationUpdate(g *models.Conversation) (*models.Conversation, error) {
-	var filter models.Conversation
-	err := g.Validate("create")
+	err := g.Validate("update")
 	if err != nil {
-		return nil, errors.New("missing valid query filter")
+		return nil, err
 	}
-	filter.Id = g.Id
-	f, err := newConversationModel(&filter)
+	f, err := newConversationModel(&models.Conversation{Id: g.Id})
 	if err != nil {
 		return nil, err
 	}
-	cm, err := newConversationModel(g)
+	if _, err = c.handler.FindOne(f); err != nil {
+		return nil, errors.New("conversation not found")
+	}
+	cm, err := newConversationModel(&models.Conversation{MessageTTL: g.MessageTTL})
 	if err != nil {
 		return nil, err
 	}
-	_, groupErr := c.handler.FindOne(f)
-	if groupErr != nil {
-		return nil, errors.New("group not found")
+	if _, err = c.handler.UpdateOne(f, cm); err != nil {
+		return nil, err
 	}
-	cm, err = c.handler.UpdateOne(f, cm)
-	return cm.toRoot(), err
+	cm, err = c.handler.FindOne(f)
+	if err != nil {
+		return nil, err
+	}
+	return cm.toRoot(), nil
 }
 
 // ConversationDocInsert is used to insert a group doc directly into mongodb for testing purposes
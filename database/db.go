@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
 	"log"
 	"os"
 	"sync"
@@ -31,6 +33,8 @@ type DBClient interface {
 	Connect() error
 	Close() error
 	GetCollection(collectionName string) DBCollection
+	GetBucket(bucketName string) (DBBucket, error)
+	CreateTTLIndex(collectionName string, field string) error
 	NewDBHandler(collectionName string) *DBHandler[dbModel]
 	NewUserHandler() *DBHandler[*userModel]
 	NewGroupHandler() *DBHandler[*groupModel]
@@ -39,6 +43,31 @@ type DBClient interface {
 	NewGroupMembershipHandler() *DBHandler[*groupMembershipModel]
 	NewConversationHandler() *DBHandler[*conversationModel]
 	NewContactHandler() *DBHandler[*contactModel]
+	NewFileHandler() *DBHandler[*fileModel]
+	NewUploadHandler() *DBHandler[*uploadModel]
+	NewStorageHandler() *DBHandler[*storageModel]
+	NewStorageUsageHandler() *DBHandler[*storageUsageModel]
+	NewRefreshTokenHandler() *DBHandler[*refreshTokenModel]
+	NewSessionHandler() *DBHandler[*sessionModel]
+	NewAPIKeyHandler() *DBHandler[*apiKeyModel]
+	NewTwoFactorHandler() *DBHandler[*twoFactorModel]
+	NewMailHandler() *DBHandler[*mailModel]
+	NewLoginAttemptHandler() *DBHandler[*loginAttemptModel]
+	NewOIDCLoginHandler() *DBHandler[*oidcLoginModel]
+	NewIdentityHandler() *DBHandler[*identityModel]
+	NewAuditEventHandler() *DBHandler[*auditEventModel]
+	NewBotHandler() *DBHandler[*botModel]
+	NewWebhookHandler() *DBHandler[*webhookModel]
+	NewWebhookDeliveryHandler() *DBHandler[*webhookDeliveryModel]
+	NewIncomingWebhookHandler() *DBHandler[*incomingWebhookModel]
+	NewCommandHandler() *DBHandler[*commandModel]
+	NewMentionHandler() *DBHandler[*mentionModel]
+	NewDeviceHandler() *DBHandler[*deviceModel]
+	NewNotificationHandler() *DBHandler[*notificationModel]
+	NewDigestSettingsHandler() *DBHandler[*digestSettingsModel]
+	NewUnreadHandler() *DBHandler[*unreadModel]
+	NewScheduledMessageHandler() *DBHandler[*scheduledMessageModel]
+	NewMessageExpiryHandler() *DBHandler[*messageExpiryModel]
 }
 
 // DBCursor is an abstraction of the dbClient and testDBClient types
@@ -62,6 +91,7 @@ type DBCollection interface {
 	InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error)
 	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
 	FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult
+	FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
 	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
 	UpdateByID(ctx context.Context, id interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
 	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (cur *mongo.Cursor, err error)
@@ -69,6 +99,13 @@ type DBCollection interface {
 	CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
 }
 
+// DBBucket is an abstraction of the GridFS bucket and testMongoBucket types
+type DBBucket interface {
+	UploadFromStreamWithID(fileID interface{}, filename string, source io.Reader, opts ...*options.UploadOptions) error
+	DownloadToStream(fileID interface{}, stream io.Writer) (int64, error)
+	Delete(fileID interface{}) error
+}
+
 // DBClient manages a database connection
 type dbClient struct {
 	connectionURI string
@@ -114,6 +151,26 @@ func (db *dbClient) GetCollection(collectionName string) DBCollection {
 	return db.client.Database(os.Getenv("DATABASE")).Collection(collectionName)
 }
 
+// GetBucket returns a GridFS bucket based on the input bucket name
+func (db *dbClient) GetBucket(bucketName string) (DBBucket, error) {
+	bucket, err := gridfs.NewBucket(db.client.Database(os.Getenv("DATABASE")), options.GridFSBucket().SetName(bucketName))
+	if err != nil {
+		return nil, err
+	}
+	return bucket, nil
+}
+
+// CreateTTLIndex ensures documents of a collection are removed by MongoDB once the time stored in field has passed
+func (db *dbClient) CreateTTLIndex(collectionName string, field string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := db.client.Database(os.Getenv("DATABASE")).Collection(collectionName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{field, 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
 // NewDBHandler returns a new DBHandler generic interface
 func (db *dbClient) NewDBHandler(collectionName string) *DBHandler[dbModel] {
 	col := db.GetCollection(collectionName)
@@ -180,6 +237,231 @@ func (db *dbClient) NewContactHandler() *DBHandler[*contactModel] {
 	}
 }
 
+// NewFileHandler returns a new DBHandler files interface
+func (db *dbClient) NewFileHandler() *DBHandler[*fileModel] {
+	col := db.GetCollection("files")
+	return &DBHandler[*fileModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewUploadHandler returns a new DBHandler uploads interface
+func (db *dbClient) NewUploadHandler() *DBHandler[*uploadModel] {
+	col := db.GetCollection("uploads")
+	return &DBHandler[*uploadModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewStorageHandler returns a new DBHandler storage quotas interface
+func (db *dbClient) NewStorageHandler() *DBHandler[*storageModel] {
+	col := db.GetCollection("storage")
+	return &DBHandler[*storageModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewStorageUsageHandler returns a new DBHandler storage usage interface
+func (db *dbClient) NewStorageUsageHandler() *DBHandler[*storageUsageModel] {
+	col := db.GetCollection("storage_usage")
+	return &DBHandler[*storageUsageModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewRefreshTokenHandler returns a new DBHandler refresh tokens interface
+func (db *dbClient) NewRefreshTokenHandler() *DBHandler[*refreshTokenModel] {
+	col := db.GetCollection("refresh_tokens")
+	return &DBHandler[*refreshTokenModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewSessionHandler returns a new DBHandler device sessions interface
+func (db *dbClient) NewSessionHandler() *DBHandler[*sessionModel] {
+	col := db.GetCollection("sessions")
+	return &DBHandler[*sessionModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewAPIKeyHandler returns a new DBHandler api keys interface
+func (db *dbClient) NewAPIKeyHandler() *DBHandler[*apiKeyModel] {
+	col := db.GetCollection("api_keys")
+	return &DBHandler[*apiKeyModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewTwoFactorHandler returns a new DBHandler two factor interface
+func (db *dbClient) NewTwoFactorHandler() *DBHandler[*twoFactorModel] {
+	col := db.GetCollection("two_factors")
+	return &DBHandler[*twoFactorModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewMailHandler returns a new DBHandler mail outbox interface
+func (db *dbClient) NewMailHandler() *DBHandler[*mailModel] {
+	col := db.GetCollection("mail_outbox")
+	return &DBHandler[*mailModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewLoginAttemptHandler returns a new DBHandler login attempts interface
+func (db *dbClient) NewLoginAttemptHandler() *DBHandler[*loginAttemptModel] {
+	col := db.GetCollection("login_attempts")
+	return &DBHandler[*loginAttemptModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewOIDCLoginHandler returns a new DBHandler oidc logins interface
+func (db *dbClient) NewOIDCLoginHandler() *DBHandler[*oidcLoginModel] {
+	col := db.GetCollection("oidc_logins")
+	return &DBHandler[*oidcLoginModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewIdentityHandler returns a new DBHandler identities interface
+func (db *dbClient) NewIdentityHandler() *DBHandler[*identityModel] {
+	col := db.GetCollection("identities")
+	return &DBHandler[*identityModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewAuditEventHandler returns a new DBHandler audit events interface
+func (db *dbClient) NewAuditEventHandler() *DBHandler[*auditEventModel] {
+	col := db.GetCollection("audit_events")
+	return &DBHandler[*auditEventModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewBotHandler returns a new DBHandler bots interface
+func (db *dbClient) NewBotHandler() *DBHandler[*botModel] {
+	col := db.GetCollection("bots")
+	return &DBHandler[*botModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewWebhookHandler returns a new DBHandler webhooks interface
+func (db *dbClient) NewWebhookHandler() *DBHandler[*webhookModel] {
+	col := db.GetCollection("webhooks")
+	return &DBHandler[*webhookModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewWebhookDeliveryHandler returns a new DBHandler webhook deliveries interface
+func (db *dbClient) NewWebhookDeliveryHandler() *DBHandler[*webhookDeliveryModel] {
+	col := db.GetCollection("webhook_deliveries")
+	return &DBHandler[*webhookDeliveryModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewIncomingWebhookHandler returns a new DBHandler incoming webhooks interface
+func (db *dbClient) NewIncomingWebhookHandler() *DBHandler[*incomingWebhookModel] {
+	col := db.GetCollection("incoming_webhooks")
+	return &DBHandler[*incomingWebhookModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewCommandHandler returns a new DBHandler custom commands interface
+func (db *dbClient) NewCommandHandler() *DBHandler[*commandModel] {
+	col := db.GetCollection("commands")
+	return &DBHandler[*commandModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewMentionHandler returns a new DBHandler mentions interface
+func (db *dbClient) NewMentionHandler() *DBHandler[*mentionModel] {
+	col := db.GetCollection("mentions")
+	return &DBHandler[*mentionModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewDeviceHandler returns a new DBHandler devices interface
+func (db *dbClient) NewDeviceHandler() *DBHandler[*deviceModel] {
+	col := db.GetCollection("devices")
+	return &DBHandler[*deviceModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewNotificationHandler returns a new DBHandler notification outbox interface
+func (db *dbClient) NewNotificationHandler() *DBHandler[*notificationModel] {
+	col := db.GetCollection("notification_outbox")
+	return &DBHandler[*notificationModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewDigestSettingsHandler returns a new DBHandler digest settings interface
+func (db *dbClient) NewDigestSettingsHandler() *DBHandler[*digestSettingsModel] {
+	col := db.GetCollection("digest_settings")
+	return &DBHandler[*digestSettingsModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewUnreadHandler returns a new DBHandler unreads interface
+func (db *dbClient) NewUnreadHandler() *DBHandler[*unreadModel] {
+	col := db.GetCollection("unreads")
+	return &DBHandler[*unreadModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewScheduledMessageHandler returns a new DBHandler scheduled messages interface
+func (db *dbClient) NewScheduledMessageHandler() *DBHandler[*scheduledMessageModel] {
+	col := db.GetCollection("scheduled_messages")
+	return &DBHandler[*scheduledMessageModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewMessageExpiryHandler returns a new DBHandler message expiries interface
+func (db *dbClient) NewMessageExpiryHandler() *DBHandler[*messageExpiryModel] {
+	col := db.GetCollection("message_expiries")
+	return &DBHandler[*messageExpiryModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
 // DBHandler is a Generic type struct for organizing dbModel methods
 type DBHandler[T dbModel] struct {
 	db         DBClient
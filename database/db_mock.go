@@ -1,17 +1,21 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/password"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.mongodb.org/mongo-driver/x/bsonx"
+	"io"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -33,6 +37,304 @@ func cleanUpdateBSON(bsonData interface{}) (data interface{}, err error) {
 	return bsonData, nil
 }
 
+// unsetFields returns the field names of an $unset update doc
+func unsetFields(bsonData interface{}) ([]string, bool) {
+	t, ok := bsonData.(bson.D)
+	if !ok || len(t) == 0 || t[0].Key != "$unset" {
+		return nil, false
+	}
+	inner, ok := t[0].Value.(bson.D)
+	if !ok {
+		return nil, false
+	}
+	var fields []string
+	for _, e := range inner {
+		fields = append(fields, e.Key)
+	}
+	return fields, true
+}
+
+// toBsonD converts a bson filter or update of any type into a bson.D, so that its values have the types they are
+// stored with
+func toBsonD(bsonData interface{}) (doc bson.D, err error) {
+	data, err := bsonMarshall(bsonData)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// lookupField returns the value of a top level field of a bson doc and whether it is set
+func lookupField(doc bson.D, key string) (interface{}, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, e.Value != nil
+		}
+	}
+	return nil, false
+}
+
+// lookupValues returns the values a dotted field path resolves to in a bson doc, descending into arrays of sub
+// documents and returning the elements of arrays like mongo does when matching a filter
+func lookupValues(doc bson.D, path string) []interface{} {
+	key, rest, nested := strings.Cut(path, ".")
+	value, set := lookupField(doc, key)
+	if !set {
+		return nil
+	}
+	var values []interface{}
+	items, isArray := value.(primitive.A)
+	if !isArray {
+		items = primitive.A{value}
+	}
+	for _, item := range items {
+		if !nested {
+			values = append(values, item)
+			continue
+		}
+		if sub, ok := item.(bson.D); ok {
+			values = append(values, lookupValues(sub, rest)...)
+		}
+	}
+	return values
+}
+
+// compareValues orders two bson values of a comparable type, returning false when they can not be compared
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	number := func(v interface{}) (float64, bool) {
+		switch n := v.(type) {
+		case int32:
+			return float64(n), true
+		case int64:
+			return float64(n), true
+		case float64:
+			return n, true
+		case primitive.DateTime:
+			return float64(n), true
+		}
+		return 0, false
+	}
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		switch {
+		case !ok:
+			return 0, false
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case primitive.ObjectID:
+		if y, ok := b.(primitive.ObjectID); ok {
+			return strings.Compare(x.Hex(), y.Hex()), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok && x == y {
+			return 0, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+// matchCondition determines whether any of the values of a field satisfies a single filter condition
+func matchCondition(values []interface{}, op string, operand interface{}) bool {
+	switch op {
+	case "$exists":
+		exists, _ := operand.(bool)
+		return exists == (len(values) > 0)
+	case "$ne":
+		return !matchCondition(values, "$eq", operand)
+	case "$in":
+		options, _ := operand.(primitive.A)
+		for _, o := range options {
+			if matchCondition(values, "$eq", o) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range values {
+		cmp, ok := compareValues(v, operand)
+		if !ok {
+			continue
+		}
+		switch op {
+		case "$eq":
+			ok = cmp == 0
+		case "$gt":
+			ok = cmp > 0
+		case "$gte":
+			ok = cmp >= 0
+		case "$lt":
+			ok = cmp < 0
+		case "$lte":
+			ok = cmp <= 0
+		default:
+			ok = false
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFilter determines whether a bson doc matches a filter of field values and $exists, $eq, $ne, $in, $gt, $gte,
+// $lt and $lte conditions, dotted field paths and array fields are matched like mongo does
+func matchFilter(doc bson.D, filter bson.D) bool {
+	for _, e := range filter {
+		values := lookupValues(doc, e.Key)
+		conditions, ok := e.Value.(bson.D)
+		if !ok || len(conditions) == 0 || !strings.HasPrefix(conditions[0].Key, "$") {
+			conditions = bson.D{{"$eq", e.Value}}
+		}
+		for _, c := range conditions {
+			if !matchCondition(values, c.Key, c.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hasConditions determines whether a filter uses query operators, which the dbModel match functions do not support
+func hasConditions(filter bson.D) bool {
+	for _, e := range filter {
+		if conditions, ok := e.Value.(bson.D); ok && len(conditions) > 0 && strings.HasPrefix(conditions[0].Key, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// positionalIndex returns the index of the first element of an array field that matches the conditions a filter has
+// on that field, the element a "field.$" path refers to
+func positionalIndex(items primitive.A, field string, filter bson.D) int {
+	for i, item := range items {
+		matched := false
+		for _, e := range filter {
+			if e.Key != field && !strings.HasPrefix(e.Key, field+".") {
+				continue
+			}
+			cond := bson.D{{"v" + strings.TrimPrefix(e.Key, field), e.Value}}
+			if matched = matchFilter(bson.D{{"v", item}}, cond); !matched {
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyUpdate returns a copy of a bson doc with the $set, $inc, $unset, $push, $pull and $addToSet operators of an
+// update applied, a "field.$" path sets the array element matched by filter
+func applyUpdate(doc bson.D, update bson.D, filter bson.D) (bson.D, error) {
+	updated := append(bson.D{}, doc...)
+	set := func(key string, value interface{}) {
+		for i, e := range updated {
+			if e.Key == key {
+				updated[i].Value = value
+				return
+			}
+		}
+		updated = append(updated, bson.E{Key: key, Value: value})
+	}
+	array := func(key string) primitive.A {
+		cur, _ := lookupField(updated, key)
+		items, _ := cur.(primitive.A)
+		return append(primitive.A{}, items...)
+	}
+	for _, op := range update {
+		fields, ok := op.Value.(bson.D)
+		if !ok {
+			return nil, errors.New("unsupported test update: " + op.Key)
+		}
+		for _, f := range fields {
+			switch op.Key {
+			case "$set":
+				if strings.HasSuffix(f.Key, ".$") {
+					field := strings.TrimSuffix(f.Key, ".$")
+					items := array(field)
+					i := positionalIndex(items, field, filter)
+					if i < 0 {
+						return nil, errors.New("the positional operator did not find the match needed from the query")
+					}
+					items[i] = f.Value
+					set(field, items)
+					continue
+				}
+				set(f.Key, f.Value)
+			case "$inc":
+				cur, _ := lookupField(updated, f.Key)
+				switch n := f.Value.(type) {
+				case int:
+					set(f.Key, toInt64(cur)+int64(n))
+				case int32:
+					set(f.Key, toInt64(cur)+int64(n))
+				case int64:
+					set(f.Key, toInt64(cur)+n)
+				default:
+					return nil, errors.New("unsupported test $inc value for " + f.Key)
+				}
+			case "$unset":
+				var kept bson.D
+				for _, e := range updated {
+					if e.Key != f.Key {
+						kept = append(kept, e)
+					}
+				}
+				updated = kept
+			case "$push":
+				set(f.Key, append(array(f.Key), f.Value))
+			case "$addToSet":
+				items := array(f.Key)
+				if !matchCondition(items, "$eq", f.Value) {
+					items = append(items, f.Value)
+				}
+				set(f.Key, items)
+			case "$pull":
+				var kept primitive.A
+				for _, item := range array(f.Key) {
+					cond, isCond := f.Value.(bson.D)
+					sub, isDoc := item.(bson.D)
+					switch {
+					case isCond && isDoc && matchFilter(sub, cond):
+					case !isCond && matchCondition([]interface{}{item}, "$eq", f.Value):
+					default:
+						kept = append(kept, item)
+					}
+				}
+				set(f.Key, kept)
+			default:
+				return nil, errors.New("unsupported test update: " + op.Key)
+			}
+		}
+	}
+	return updated, nil
+}
+
+// toInt64 returns the value of an integer bson field, 0 when it is not set
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	}
+	return 0
+}
+
 // standardizeID ensures that a dbModels unique identified is returned as a string
 func standardizeID(dbDoc dbModel) (string, error) {
 	var docId string
@@ -122,6 +424,214 @@ func bsonUnmarshall(colName string, bsonData interface{}) (data dbModel, err err
 		gmm := groupMembershipModel{}
 		err = bson.Unmarshal(bData, &gmm)
 		return &gmm, nil
+	case "files":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		fm := fileModel{}
+		err = bson.Unmarshal(bData, &fm)
+		return &fm, nil
+	case "uploads":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		um := uploadModel{}
+		err = bson.Unmarshal(bData, &um)
+		return &um, nil
+	case "storage":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		sm := storageModel{}
+		err = bson.Unmarshal(bData, &sm)
+		return &sm, nil
+	case "storage_usage":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		um := storageUsageModel{}
+		err = bson.Unmarshal(bData, &um)
+		return &um, nil
+	case "refresh_tokens":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		rm := refreshTokenModel{}
+		err = bson.Unmarshal(bData, &rm)
+		return &rm, nil
+	case "sessions":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		sm := sessionModel{}
+		err = bson.Unmarshal(bData, &sm)
+		return &sm, nil
+	case "api_keys":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		km := apiKeyModel{}
+		err = bson.Unmarshal(bData, &km)
+		return &km, nil
+	case "two_factors":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		tm := twoFactorModel{}
+		err = bson.Unmarshal(bData, &tm)
+		return &tm, nil
+	case "mail_outbox":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		mm := mailModel{}
+		err = bson.Unmarshal(bData, &mm)
+		return &mm, nil
+	case "login_attempts":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		lm := loginAttemptModel{}
+		err = bson.Unmarshal(bData, &lm)
+		return &lm, nil
+	case "oidc_logins":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		lm := oidcLoginModel{}
+		err = bson.Unmarshal(bData, &lm)
+		return &lm, nil
+	case "identities":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		im := identityModel{}
+		err = bson.Unmarshal(bData, &im)
+		return &im, nil
+	case "audit_events":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		am := auditEventModel{}
+		err = bson.Unmarshal(bData, &am)
+		return &am, nil
+	case "bots":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		bm := botModel{}
+		err = bson.Unmarshal(bData, &bm)
+		return &bm, nil
+	case "webhooks":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		wm := webhookModel{}
+		err = bson.Unmarshal(bData, &wm)
+		return &wm, nil
+	case "webhook_deliveries":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		dm := webhookDeliveryModel{}
+		err = bson.Unmarshal(bData, &dm)
+		return &dm, nil
+	case "incoming_webhooks":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		hm := incomingWebhookModel{}
+		err = bson.Unmarshal(bData, &hm)
+		return &hm, nil
+	case "conversations":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		cm := conversationModel{}
+		err = bson.Unmarshal(bData, &cm)
+		return &cm, nil
+	case "commands":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		cm := commandModel{}
+		err = bson.Unmarshal(bData, &cm)
+		return &cm, nil
+	case "mentions":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		mm := mentionModel{}
+		err = bson.Unmarshal(bData, &mm)
+		return &mm, nil
+	case "devices":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		dm := deviceModel{}
+		err = bson.Unmarshal(bData, &dm)
+		return &dm, nil
+	case "notification_outbox":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		nm := notificationModel{}
+		err = bson.Unmarshal(bData, &nm)
+		return &nm, nil
+	case "digest_settings":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		sm := digestSettingsModel{}
+		err = bson.Unmarshal(bData, &sm)
+		return &sm, nil
+	case "unreads":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		um := unreadModel{}
+		err = bson.Unmarshal(bData, &um)
+		return &um, nil
+	case "scheduled_messages":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		sm := scheduledMessageModel{}
+		err = bson.Unmarshal(bData, &sm)
+		return &sm, nil
+	case "message_expiries":
+		bData, err := bsonMarshall(bsonData)
+		if err != nil {
+			return nil, err
+		}
+		em := messageExpiryModel{}
+		err = bson.Unmarshal(bData, &em)
+		return &em, nil
 	}
 	return nil, errors.New("invalid test collection type")
 }
@@ -130,6 +640,12 @@ func bsonUnmarshall(colName string, bsonData interface{}) (data dbModel, err err
 ================ Test Data ==================
 */
 
+// testPasswordPolicy returns a password policy loose enough for the test user passwords
+func testPasswordPolicy() *password.Policy {
+	policy, _ := password.NewPolicy("6", "", "")
+	return policy
+}
+
 func getTestGroupModels(root bool) []*groupModel {
 	var gms []*groupModel
 	var gm *groupModel
@@ -241,6 +757,8 @@ func initTestMessageService() *MessageService {
 		db,
 		uHandler,
 		gHandler,
+		testPasswordPolicy(),
+		newTTLCache[models.User](userCacheTTL),
 	}
 	tu := getTestUsersModels(true)
 	for _, d := range tu {
@@ -286,6 +804,8 @@ func setupTestMessages() *MessageService {
 		db,
 		uHandler,
 		gHandler,
+		testPasswordPolicy(),
+		newTTLCache[models.User](userCacheTTL),
 	}
 	tu := getTestUsersModels(true)
 	for _, d := range tu {
@@ -328,6 +848,7 @@ func initTestBlacklistService() *BlacklistService {
 		collection,
 		db,
 		gHandler,
+		&revocationCache{},
 	}
 }
 
@@ -342,10 +863,11 @@ func setupTestBlacklists() *BlacklistService {
 		collection,
 		db,
 		gHandler,
+		&revocationCache{},
 	}
 	td := getTestTokens()
 	for _, d := range td {
-		err := gs.BlacklistAuthToken(d)
+		err := gs.BlacklistAuthToken(d, time.Now().Add(time.Hour))
 		if err != nil {
 			panic(err)
 		}
@@ -424,6 +946,8 @@ func initTestUserService() *UserService {
 		db,
 		uHandler,
 		gHandler,
+		testPasswordPolicy(),
+		newTTLCache[models.User](userCacheTTL),
 	}
 }
 
@@ -454,6 +978,8 @@ func setupTestUsers() *UserService {
 		db,
 		uHandler,
 		gHandler,
+		testPasswordPolicy(),
+		newTTLCache[models.User](userCacheTTL),
 	}
 	tu := getTestUsersModels(true)
 	for _, d := range tu {
@@ -628,6 +1154,43 @@ func (coll *testMongoCollection) deleteById(findId string) (reDoc dbModel, err e
 	return reDoc, nil
 }
 
+// unsetById removes fields from a document in the test collection by ID
+func (coll *testMongoCollection) unsetById(findId string, fields []string) (reDoc dbModel, err error) {
+	for i, doc := range coll.docs {
+		var docId string
+		docId, err = standardizeID(doc)
+		if err != nil {
+			return
+		}
+		if docId != findId {
+			continue
+		}
+		var cur, kept bson.D
+		cur, err = doc.toDoc()
+		if err != nil {
+			return
+		}
+		for _, e := range cur {
+			unset := false
+			for _, f := range fields {
+				if e.Key == f {
+					unset = true
+				}
+			}
+			if !unset {
+				kept = append(kept, e)
+			}
+		}
+		reDoc, err = coll.unmarshallBSON(kept)
+		if err != nil {
+			return
+		}
+		coll.docs[i] = reDoc
+		return reDoc, nil
+	}
+	return reDoc, errors.New("document not found in test collection: " + findId)
+}
+
 // updateById a document in the test collection
 func (coll *testMongoCollection) updateById(findId string, upDoc dbModel) (reDoc dbModel, err error) {
 	var dbDocs []dbModel
@@ -786,6 +1349,52 @@ func (coll *testMongoCollection) FindOneAndDelete(ctx context.Context, filter in
 	return res
 }
 
+// FindOneAndUpdate updates the first document of the test collection that matches filter and returns it as it was
+// before the update, or after it when the ReturnDocument option is options.After
+func (coll *testMongoCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	coll.ctx = ctx
+	fmt.Println("\n--->FIND ONE AND UPDATE: ", filter, update, opts)
+	f, err := toBsonD(filter)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	u, err := toBsonD(update)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	after := false
+	for _, o := range opts {
+		if o != nil && o.ReturnDocument != nil {
+			after = *o.ReturnDocument == options.After
+		}
+	}
+	for i, doc := range coll.docs {
+		cur, err := doc.toDoc()
+		if err != nil || !matchFilter(cur, f) {
+			continue
+		}
+		updated, err := applyUpdate(cur, u, f)
+		if err != nil {
+			return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+		}
+		reDoc, err := coll.unmarshallBSON(updated)
+		if err != nil {
+			return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+		}
+		coll.docs[i] = reDoc
+		if after {
+			cur = updated
+		}
+		rawResult, err := bsonMarshall(cur)
+		if err != nil {
+			return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+		}
+		result, err := bsonx.ReadDoc(rawResult)
+		return mongo.NewSingleResultFromDocument(result, err, nil)
+	}
+	return mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+}
+
 // UpdateOne a document in the test collection
 func (coll *testMongoCollection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
 	coll.ctx = ctx
@@ -798,6 +1407,13 @@ func (coll *testMongoCollection) UpdateOne(ctx context.Context, filter interface
 	if err != nil {
 		return nil, err
 	}
+	if fields, ok := unsetFields(update); ok {
+		reDoc, err := coll.unsetById(docId, fields)
+		if err != nil {
+			return nil, err
+		}
+		return &mongo.UpdateResult{UpsertedID: reDoc.getID()}, nil
+	}
 	update, err = cleanUpdateBSON(update)
 	if err != nil {
 		panic(err)
@@ -834,11 +1450,20 @@ func (coll *testMongoCollection) Find(ctx context.Context, filter interface{}, o
 	var rawResults []byte
 	coll.ctx = ctx
 	fmt.Println("\n--->FIND: ", filter, opts)
-	filterDoc, err := coll.unmarshallBSON(filter)
-	if err != nil {
-		return nil, err
+	var reDocs []dbModel
+	if f, fErr := toBsonD(filter); fErr == nil && hasConditions(f) {
+		for _, doc := range coll.docs {
+			if d, dErr := doc.toDoc(); dErr == nil && matchFilter(d, f) {
+				reDocs = append(reDocs, doc)
+			}
+		}
+	} else {
+		filterDoc, err := coll.unmarshallBSON(filter)
+		if err != nil {
+			return nil, err
+		}
+		reDocs, err = coll.find(filterDoc)
 	}
-	reDocs, err := coll.find(filterDoc)
 	cd := initTestCursorData(reDocs)
 	bsonData, err := cd.toDoc()
 	if err != nil {
@@ -904,6 +1529,7 @@ type testMongoDatabase struct {
 	ctx             context.Context
 	name            string
 	testCollections []*testMongoCollection
+	testBuckets     []*testMongoBucket
 }
 
 // newTestMongoDatabase
@@ -942,6 +1568,162 @@ func newTestMongoDatabase(databaseName string) (*testMongoDatabase, error) {
 		return &testMongoDatabase{}, err
 	}
 	testsColls = append(testsColls, testMessageCollection, testGroupMembershipsCollection)
+	testFileCollection, err := newTestMongoCollection("files")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT FILE ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testFileCollection)
+	testUploadCollection, err := newTestMongoCollection("uploads")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT UPLOAD ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testUploadCollection)
+	testStorageCollection, err := newTestMongoCollection("storage")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT STORAGE ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testStorageCollection)
+	testStorageUsageCollection, err := newTestMongoCollection("storage_usage")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT STORAGE USAGE ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testStorageUsageCollection)
+	testRefreshTokenCollection, err := newTestMongoCollection("refresh_tokens")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT REFRESH TOKEN ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testRefreshTokenCollection)
+	testSessionCollection, err := newTestMongoCollection("sessions")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT SESSION ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testSessionCollection)
+	testAPIKeyCollection, err := newTestMongoCollection("api_keys")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT API KEY ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testAPIKeyCollection)
+	testTwoFactorCollection, err := newTestMongoCollection("two_factors")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT TWO FACTOR ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testTwoFactorCollection)
+	testMailCollection, err := newTestMongoCollection("mail_outbox")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT MAIL ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testMailCollection)
+	testLoginAttemptCollection, err := newTestMongoCollection("login_attempts")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT LOGIN ATTEMPT ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testLoginAttemptCollection)
+	testOIDCLoginCollection, err := newTestMongoCollection("oidc_logins")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT OIDC LOGIN ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testOIDCLoginCollection)
+	testIdentityCollection, err := newTestMongoCollection("identities")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT IDENTITY ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testIdentityCollection)
+	testAuditEventCollection, err := newTestMongoCollection("audit_events")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT AUDIT EVENT ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testAuditEventCollection)
+	testBotCollection, err := newTestMongoCollection("bots")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT BOT ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testBotCollection)
+	testWebhookCollection, err := newTestMongoCollection("webhooks")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT WEBHOOK ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testWebhookCollection)
+	testWebhookDeliveryCollection, err := newTestMongoCollection("webhook_deliveries")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT WEBHOOK DELIVERY ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testWebhookDeliveryCollection)
+	testIncomingWebhookCollection, err := newTestMongoCollection("incoming_webhooks")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT INCOMING WEBHOOK ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testIncomingWebhookCollection)
+	testConversationCollection, err := newTestMongoCollection("conversations")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT CONVERSATION ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testConversationCollection)
+	testCommandCollection, err := newTestMongoCollection("commands")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT COMMAND ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testCommandCollection)
+	testMentionCollection, err := newTestMongoCollection("mentions")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT MENTION ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testMentionCollection)
+	testDeviceCollection, err := newTestMongoCollection("devices")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT DEVICE ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testDeviceCollection)
+	testNotificationCollection, err := newTestMongoCollection("notification_outbox")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT NOTIFICATION ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testNotificationCollection)
+	testDigestSettingsCollection, err := newTestMongoCollection("digest_settings")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT DIGEST SETTINGS ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testDigestSettingsCollection)
+	testUnreadCollection, err := newTestMongoCollection("unreads")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT UNREAD ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testUnreadCollection)
+	testScheduledMessageCollection, err := newTestMongoCollection("scheduled_messages")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT SCHEDULED MESSAGE ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testScheduledMessageCollection)
+	testMessageExpiryCollection, err := newTestMongoCollection("message_expiries")
+	if err != nil {
+		fmt.Println("\nCOLLECTION INIT MESSAGE EXPIRY ERROR: ", err.Error())
+		return &testMongoDatabase{}, err
+	}
+	testsColls = append(testsColls, testMessageExpiryCollection)
 	return &testMongoDatabase{
 		name:            databaseName,
 		testCollections: testsColls,
@@ -958,6 +1740,75 @@ func (c *testMongoDatabase) Collection(colName string) *testMongoCollection {
 	return nil
 }
 
+// Bucket returns a test GridFS bucket from the test client, creating it on first use
+func (c *testMongoDatabase) Bucket(bucketName string) *testMongoBucket {
+	for _, tBucket := range c.testBuckets {
+		if tBucket.name == bucketName {
+			return tBucket
+		}
+	}
+	tBucket := newTestMongoBucket(bucketName)
+	c.testBuckets = append(c.testBuckets, tBucket)
+	return tBucket
+}
+
+/*
+================ testMongoBucket ==================
+*/
+
+// testMongoBucket is an in-memory stand in for a GridFS bucket
+type testMongoBucket struct {
+	name  string
+	files map[string][]byte
+}
+
+// newTestMongoBucket initiates and returns a testMongoBucket
+func newTestMongoBucket(name string) *testMongoBucket {
+	return &testMongoBucket{name: name, files: map[string][]byte{}}
+}
+
+// bucketKey converts a GridFS file id into a test bucket map key
+func bucketKey(fileID interface{}) string {
+	switch t := fileID.(type) {
+	case primitive.ObjectID:
+		return t.Hex()
+	case string:
+		return t
+	}
+	return fmt.Sprint(fileID)
+}
+
+// UploadFromStreamWithID stores the contents of source in the test bucket
+func (b *testMongoBucket) UploadFromStreamWithID(fileID interface{}, filename string, source io.Reader, opts ...*options.UploadOptions) error {
+	fmt.Println("\n--->BUCKET UPLOAD: ", b.name, fileID, filename, opts)
+	data, err := io.ReadAll(source)
+	if err != nil {
+		return err
+	}
+	b.files[bucketKey(fileID)] = data
+	return nil
+}
+
+// DownloadToStream writes the contents of a test bucket file to stream
+func (b *testMongoBucket) DownloadToStream(fileID interface{}, stream io.Writer) (int64, error) {
+	fmt.Println("\n--->BUCKET DOWNLOAD: ", b.name, fileID)
+	data, ok := b.files[bucketKey(fileID)]
+	if !ok {
+		return 0, errors.New("file not found in test bucket: " + bucketKey(fileID))
+	}
+	return io.Copy(stream, bytes.NewReader(data))
+}
+
+// Delete removes a file from the test bucket
+func (b *testMongoBucket) Delete(fileID interface{}) error {
+	fmt.Println("\n--->BUCKET DELETE: ", b.name, fileID)
+	if _, ok := b.files[bucketKey(fileID)]; !ok {
+		return errors.New("file not found in test bucket: " + bucketKey(fileID))
+	}
+	delete(b.files, bucketKey(fileID))
+	return nil
+}
+
 /*
 ================ testMongoClient ==================
 */
@@ -1064,6 +1915,16 @@ func (db *testDBClient) GetCollection(collectionName string) DBCollection {
 	return db.client.Database("test").Collection(collectionName)
 }
 
+// GetBucket returns a test GridFS bucket based on the input bucket name
+func (db *testDBClient) GetBucket(bucketName string) (DBBucket, error) {
+	return db.client.Database("test").Bucket(bucketName), nil
+}
+
+// CreateTTLIndex is a no-op for the test client, expired test documents are simply left in place
+func (db *testDBClient) CreateTTLIndex(collectionName string, field string) error {
+	return nil
+}
+
 // NewDBHandler returns a new DBHandler generic interface
 func (db *testDBClient) NewDBHandler(collectionName string) *DBHandler[dbModel] {
 	col := db.GetCollection(collectionName)
@@ -1135,3 +1996,228 @@ func (db *testDBClient) NewMessageHandler() *DBHandler[*messageModel] {
 		collection: col,
 	}
 }
+
+// NewFileHandler returns a new DBHandler files interface
+func (db *testDBClient) NewFileHandler() *DBHandler[*fileModel] {
+	col := db.GetCollection("files")
+	return &DBHandler[*fileModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewUploadHandler returns a new DBHandler uploads interface
+func (db *testDBClient) NewUploadHandler() *DBHandler[*uploadModel] {
+	col := db.GetCollection("uploads")
+	return &DBHandler[*uploadModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewStorageHandler returns a new DBHandler storage quotas interface
+func (db *testDBClient) NewStorageHandler() *DBHandler[*storageModel] {
+	col := db.GetCollection("storage")
+	return &DBHandler[*storageModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewStorageUsageHandler returns a new DBHandler storage usage interface
+func (db *testDBClient) NewStorageUsageHandler() *DBHandler[*storageUsageModel] {
+	col := db.GetCollection("storage_usage")
+	return &DBHandler[*storageUsageModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewRefreshTokenHandler returns a new DBHandler refresh tokens interface
+func (db *testDBClient) NewRefreshTokenHandler() *DBHandler[*refreshTokenModel] {
+	col := db.GetCollection("refresh_tokens")
+	return &DBHandler[*refreshTokenModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewSessionHandler returns a new DBHandler device sessions interface
+func (db *testDBClient) NewSessionHandler() *DBHandler[*sessionModel] {
+	col := db.GetCollection("sessions")
+	return &DBHandler[*sessionModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewAPIKeyHandler returns a new DBHandler api keys interface
+func (db *testDBClient) NewAPIKeyHandler() *DBHandler[*apiKeyModel] {
+	col := db.GetCollection("api_keys")
+	return &DBHandler[*apiKeyModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewTwoFactorHandler returns a new DBHandler two factor interface
+func (db *testDBClient) NewTwoFactorHandler() *DBHandler[*twoFactorModel] {
+	col := db.GetCollection("two_factors")
+	return &DBHandler[*twoFactorModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewMailHandler returns a new DBHandler mail outbox interface
+func (db *testDBClient) NewMailHandler() *DBHandler[*mailModel] {
+	col := db.GetCollection("mail_outbox")
+	return &DBHandler[*mailModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewLoginAttemptHandler returns a new DBHandler login attempts interface
+func (db *testDBClient) NewLoginAttemptHandler() *DBHandler[*loginAttemptModel] {
+	col := db.GetCollection("login_attempts")
+	return &DBHandler[*loginAttemptModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewOIDCLoginHandler returns a new DBHandler oidc logins interface
+func (db *testDBClient) NewOIDCLoginHandler() *DBHandler[*oidcLoginModel] {
+	col := db.GetCollection("oidc_logins")
+	return &DBHandler[*oidcLoginModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewIdentityHandler returns a new DBHandler identities interface
+func (db *testDBClient) NewIdentityHandler() *DBHandler[*identityModel] {
+	col := db.GetCollection("identities")
+	return &DBHandler[*identityModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewAuditEventHandler returns a new DBHandler audit events interface
+func (db *testDBClient) NewAuditEventHandler() *DBHandler[*auditEventModel] {
+	col := db.GetCollection("audit_events")
+	return &DBHandler[*auditEventModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewBotHandler returns a new DBHandler bots interface
+func (db *testDBClient) NewBotHandler() *DBHandler[*botModel] {
+	col := db.GetCollection("bots")
+	return &DBHandler[*botModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewWebhookHandler returns a new DBHandler webhooks interface
+func (db *testDBClient) NewWebhookHandler() *DBHandler[*webhookModel] {
+	col := db.GetCollection("webhooks")
+	return &DBHandler[*webhookModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewWebhookDeliveryHandler returns a new DBHandler webhook deliveries interface
+func (db *testDBClient) NewWebhookDeliveryHandler() *DBHandler[*webhookDeliveryModel] {
+	col := db.GetCollection("webhook_deliveries")
+	return &DBHandler[*webhookDeliveryModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewIncomingWebhookHandler returns a new DBHandler incoming webhooks interface
+func (db *testDBClient) NewIncomingWebhookHandler() *DBHandler[*incomingWebhookModel] {
+	col := db.GetCollection("incoming_webhooks")
+	return &DBHandler[*incomingWebhookModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewCommandHandler returns a new DBHandler custom commands interface
+func (db *testDBClient) NewCommandHandler() *DBHandler[*commandModel] {
+	col := db.GetCollection("commands")
+	return &DBHandler[*commandModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewMentionHandler returns a new DBHandler mentions interface
+func (db *testDBClient) NewMentionHandler() *DBHandler[*mentionModel] {
+	col := db.GetCollection("mentions")
+	return &DBHandler[*mentionModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewDeviceHandler returns a new DBHandler devices interface
+func (db *testDBClient) NewDeviceHandler() *DBHandler[*deviceModel] {
+	col := db.GetCollection("devices")
+	return &DBHandler[*deviceModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewNotificationHandler returns a new DBHandler notification outbox interface
+func (db *testDBClient) NewNotificationHandler() *DBHandler[*notificationModel] {
+	col := db.GetCollection("notification_outbox")
+	return &DBHandler[*notificationModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewDigestSettingsHandler returns a new DBHandler digest settings interface
+func (db *testDBClient) NewDigestSettingsHandler() *DBHandler[*digestSettingsModel] {
+	col := db.GetCollection("digest_settings")
+	return &DBHandler[*digestSettingsModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewUnreadHandler returns a new DBHandler unreads interface
+func (db *testDBClient) NewUnreadHandler() *DBHandler[*unreadModel] {
+	col := db.GetCollection("unreads")
+	return &DBHandler[*unreadModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewScheduledMessageHandler returns a new DBHandler scheduled messages interface
+func (db *testDBClient) NewScheduledMessageHandler() *DBHandler[*scheduledMessageModel] {
+	col := db.GetCollection("scheduled_messages")
+	return &DBHandler[*scheduledMessageModel]{
+		db:         db,
+		collection: col,
+	}
+}
+
+// NewMessageExpiryHandler returns a new DBHandler message expiries interface
+func (db *testDBClient) NewMessageExpiryHandler() *DBHandler[*messageExpiryModel] {
+	col := db.GetCollection("message_expiries")
+	return &DBHandler[*messageExpiryModel]{
+		db:         db,
+		collection: col,
+	}
+}
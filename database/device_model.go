@@ -0,0 +1,170 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// deviceModel structures a device BSON document to save in a devices collection
+type deviceModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	UserId       primitive.ObjectID `bson:"user_id,omitempty"`
+	Platform     string             `bson:"platform,omitempty"`
+	Token        string             `bson:"token,omitempty"`
+	PublicKey    string             `bson:"public_key,omitempty"`
+	AuthSecret   string             `bson:"auth_secret,omitempty"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newDeviceModel initializes a new pointer to a deviceModel struct from a pointer to a JSON Device struct
+func newDeviceModel(d *models.Device) (dm *deviceModel, err error) {
+	dm = &deviceModel{
+		Platform:     d.Platform,
+		Token:        d.Token,
+		PublicKey:    d.PublicKey,
+		AuthSecret:   d.AuthSecret,
+		LastModified: d.LastModified,
+		CreatedAt:    d.CreatedAt,
+	}
+	if d.Id != "" && d.Id != "000000000000000000000000" {
+		if dm.Id, err = primitive.ObjectIDFromHex(d.Id); err != nil {
+			return
+		}
+	}
+	if d.UserId != "" && d.UserId != "000000000000000000000000" {
+		dm.UserId, err = primitive.ObjectIDFromHex(d.UserId)
+	}
+	return
+}
+
+// update the deviceModel using an overwrite bson.D doc
+func (d *deviceModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	dm := deviceModel{}
+	err = bson.Unmarshal(data, &dm)
+	if !dm.UserId.IsZero() {
+		d.UserId = dm.UserId
+	}
+	if len(dm.PublicKey) > 0 {
+		d.PublicKey = dm.PublicKey
+	}
+	if len(dm.AuthSecret) > 0 {
+		d.AuthSecret = dm.AuthSecret
+	}
+	if !dm.LastModified.IsZero() {
+		d.LastModified = dm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the deviceModel
+func (d *deviceModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, d)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the deviceModel
+func (d *deviceModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	dm := deviceModel{}
+	err = bson.Unmarshal(data, &dm)
+	if !dm.Id.IsZero() {
+		return d.Id == dm.Id
+	}
+	if dm.Token != "" {
+		return d.Token == dm.Token
+	}
+	if !dm.UserId.IsZero() {
+		return d.UserId == dm.UserId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the deviceModel
+func (d *deviceModel) getID() (id interface{}) {
+	return d.Id
+}
+
+// addTimeStamps updates a deviceModel struct with a timestamp
+func (d *deviceModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	d.LastModified = currentTime
+	if newRecord {
+		d.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a deviceModel has a value assigned for Id, if no value a new one is generated and assigned
+func (d *deviceModel) addObjectID() {
+	if d.Id.IsZero() {
+		d.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a deviceModel struct postProcess
+func (d *deviceModel) postProcess() (err error) {
+	if d.Token == "" {
+		err = errors.New("device record does not have a token")
+	}
+	return
+}
+
+// toDoc converts the bson deviceModel into a bson.D
+func (d *deviceModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(d)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the deviceModel data
+func (d *deviceModel) bsonFilter() (doc bson.D, err error) {
+	if !d.Id.IsZero() {
+		doc = bson.D{{"_id", d.Id}}
+	} else if d.Token != "" {
+		doc = bson.D{{"token", d.Token}}
+	} else if !d.UserId.IsZero() {
+		doc = bson.D{{"user_id", d.UserId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the deviceModel data
+func (d *deviceModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := d.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a Device JSON struct from a pointer to a BSON deviceModel
+func (d *deviceModel) toRoot() *models.Device {
+	return &models.Device{
+		Id:           d.Id.Hex(),
+		UserId:       d.UserId.Hex(),
+		Platform:     d.Platform,
+		Token:        d.Token,
+		PublicKey:    d.PublicKey,
+		AuthSecret:   d.AuthSecret,
+		LastModified: d.LastModified,
+		CreatedAt:    d.CreatedAt,
+	}
+}
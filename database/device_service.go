@@ -0,0 +1,100 @@
+package database
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// DeviceService is used by the app to manage the devices users receive push notifications on
+type DeviceService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*deviceModel]
+}
+
+// NewDeviceService is an exported function used to initialize a new DeviceService struct
+func NewDeviceService(db DBClient, handler *DBHandler[*deviceModel]) *DeviceService {
+	collection := db.GetCollection("devices")
+	return &DeviceService{collection, db, handler}
+}
+
+// DeviceRegister registers a device for a user, a token that is already registered is moved to the user since
+// an app install or browser only receives the notifications of the user signed in on it
+func (p *DeviceService) DeviceRegister(d *models.Device) (*models.Device, error) {
+	err := d.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	dm, err := newDeviceModel(&models.Device{UserId: d.UserId, Platform: d.Platform, Token: d.Token, PublicKey: d.PublicKey, AuthSecret: d.AuthSecret})
+	if err != nil {
+		return nil, err
+	}
+	existing, err := p.handler.FindMany(&deviceModel{Token: d.Token})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		dm.Id = existing[0].Id
+		dm, err = p.handler.UpdateOne(&deviceModel{Id: dm.Id}, dm)
+	} else {
+		dm, err = p.handler.InsertOne(dm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dm.toRoot(), nil
+}
+
+// DevicesFind lists the devices of a user
+func (p *DeviceService) DevicesFind(d *models.Device) ([]*models.Device, error) {
+	devices := []*models.Device{}
+	if !d.CheckID("user_id") {
+		return devices, models.ErrDeviceNotFound
+	}
+	dm, err := newDeviceModel(&models.Device{UserId: d.UserId})
+	if err != nil {
+		return devices, err
+	}
+	dms, err := p.handler.FindMany(dm)
+	if err != nil {
+		return devices, err
+	}
+	for _, dm = range dms {
+		devices = append(devices, dm.toRoot())
+	}
+	return devices, nil
+}
+
+// DeviceFind finds a device by its id, a device of another user than the one set is not found
+func (p *DeviceService) DeviceFind(d *models.Device) (*models.Device, error) {
+	if !d.CheckID("id") {
+		return nil, models.ErrDeviceNotFound
+	}
+	dm, err := newDeviceModel(&models.Device{Id: d.Id})
+	if err != nil {
+		return nil, err
+	}
+	dms, err := p.handler.FindMany(dm)
+	if err != nil {
+		return nil, err
+	}
+	if len(dms) == 0 || (d.UserId != "" && dms[0].UserId.Hex() != d.UserId) {
+		return nil, models.ErrDeviceNotFound
+	}
+	return dms[0].toRoot(), nil
+}
+
+// DeviceDelete unregisters a device
+func (p *DeviceService) DeviceDelete(d *models.Device) (*models.Device, error) {
+	cur, err := p.DeviceFind(d)
+	if err != nil {
+		return nil, err
+	}
+	dm, err := newDeviceModel(&models.Device{Id: cur.Id})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.handler.DeleteOne(dm); err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
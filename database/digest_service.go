@@ -0,0 +1,167 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"sort"
+	"time"
+)
+
+// DigestService is used by the app to manage the email digest settings of users and the unread threads digests summarize
+type DigestService struct {
+	collection    DBCollection
+	db            DBClient
+	handler       *DBHandler[*digestSettingsModel]
+	unreadHandler *DBHandler[*unreadModel]
+}
+
+// NewDigestService is an exported function used to initialize a new DigestService struct
+func NewDigestService(db DBClient, handler *DBHandler[*digestSettingsModel], unreadHandler *DBHandler[*unreadModel]) *DigestService {
+	collection := db.GetCollection("digest_settings")
+	return &DigestService{collection, db, handler, unreadHandler}
+}
+
+// DigestSettingsFind finds the digest settings of a user, users who never saved any receive daily digests
+func (p *DigestService) DigestSettingsFind(s *models.DigestSettings) (*models.DigestSettings, error) {
+	if !s.CheckID("user_id") {
+		return nil, errors.New("missing the following digest settings fields: user_id")
+	}
+	sm, err := newDigestSettingsModel(&models.DigestSettings{UserId: s.UserId})
+	if err != nil {
+		return nil, err
+	}
+	sms, err := p.handler.FindMany(sm)
+	if err != nil {
+		return nil, err
+	}
+	if len(sms) == 0 {
+		return &models.DigestSettings{UserId: s.UserId, Frequency: models.DigestDaily}, nil
+	}
+	return sms[0].toRoot(), nil
+}
+
+// DigestSettingsUpdate saves the digest settings of a user
+func (p *DigestService) DigestSettingsUpdate(s *models.DigestSettings) (*models.DigestSettings, error) {
+	err := s.Validate("update")
+	if err != nil {
+		return nil, err
+	}
+	sm, err := newDigestSettingsModel(&models.DigestSettings{UserId: s.UserId, Frequency: s.Frequency, LastSentAt: s.LastSentAt})
+	if err != nil {
+		return nil, err
+	}
+	existing, err := p.handler.FindMany(&digestSettingsModel{UserId: sm.UserId})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		sm, err = p.handler.InsertOne(sm)
+		if err != nil {
+			return nil, err
+		}
+		return sm.toRoot(), nil
+	}
+	sm.Id = existing[0].Id
+	if _, err = p.handler.UpdateOne(&digestSettingsModel{Id: sm.Id}, sm); err != nil {
+		return nil, err
+	}
+	doc, err := sm.toDoc()
+	if err != nil {
+		return nil, err
+	}
+	if err = existing[0].update(doc); err != nil {
+		return nil, err
+	}
+	return existing[0].toRoot(), nil
+}
+
+// UnreadRecord counts a message in the unread thread of a user, the count restarts with the first message received
+// after since, the time the user was last active
+func (p *DigestService) UnreadRecord(u *models.Unread, since time.Time) (*models.Unread, error) {
+	err := u.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	um, err := newUnreadModel(&models.Unread{
+		UserId:        u.UserId,
+		ThreadId:      u.ThreadId,
+		Kind:          u.Kind,
+		Count:         1,
+		LastMessageId: u.LastMessageId,
+		LastSenderId:  u.LastSenderId,
+		LastMessageAt: u.LastMessageAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	existing, err := p.unreadHandler.FindMany(&unreadModel{UserId: um.UserId, ThreadId: um.ThreadId})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		um, err = p.unreadHandler.InsertOne(um)
+		if err != nil {
+			return nil, err
+		}
+		return um.toRoot(), nil
+	}
+	if !existing[0].LastMessageAt.Before(since) {
+		um.Count = existing[0].Count + 1
+	}
+	um.Id = existing[0].Id
+	if _, err = p.unreadHandler.UpdateOne(&unreadModel{Id: um.Id}, um); err != nil {
+		return nil, err
+	}
+	doc, err := um.toDoc()
+	if err != nil {
+		return nil, err
+	}
+	if err = existing[0].update(doc); err != nil {
+		return nil, err
+	}
+	return existing[0].toRoot(), nil
+}
+
+// UnreadsFind lists the unread threads of a user, most recently active first
+func (p *DigestService) UnreadsFind(u *models.Unread) ([]*models.Unread, error) {
+	unreads := []*models.Unread{}
+	if !u.CheckID("user_id") {
+		return unreads, errors.New("missing the following unread fields: user_id")
+	}
+	um, err := newUnreadModel(&models.Unread{UserId: u.UserId})
+	if err != nil {
+		return unreads, err
+	}
+	ums, err := p.unreadHandler.FindMany(um)
+	if err != nil {
+		return unreads, err
+	}
+	for _, um = range ums {
+		unreads = append(unreads, um.toRoot())
+	}
+	sort.Slice(unreads, func(i, j int) bool {
+		return unreads[i].LastMessageAt.After(unreads[j].LastMessageAt)
+	})
+	return unreads, nil
+}
+
+// UnreadsClear deletes the unread threads of a user once a digest summarized them
+func (p *DigestService) UnreadsClear(u *models.Unread) error {
+	if !u.CheckID("user_id") {
+		return errors.New("missing the following unread fields: user_id")
+	}
+	um, err := newUnreadModel(&models.Unread{UserId: u.UserId})
+	if err != nil {
+		return err
+	}
+	ums, err := p.unreadHandler.FindMany(um)
+	if err != nil {
+		return err
+	}
+	for _, um = range ums {
+		if _, err = p.unreadHandler.DeleteOne(&unreadModel{Id: um.Id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
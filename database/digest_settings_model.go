@@ -0,0 +1,156 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// digestSettingsModel structures a digest settings BSON document to save in a digest_settings collection
+type digestSettingsModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	UserId       primitive.ObjectID `bson:"user_id,omitempty"`
+	Frequency    string             `bson:"frequency,omitempty"`
+	LastSentAt   time.Time          `bson:"last_sent_at,omitempty"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newDigestSettingsModel initializes a new pointer to a digestSettingsModel struct from a pointer to a JSON DigestSettings struct
+func newDigestSettingsModel(s *models.DigestSettings) (sm *digestSettingsModel, err error) {
+	sm = &digestSettingsModel{
+		Frequency:    s.Frequency,
+		LastSentAt:   s.LastSentAt,
+		LastModified: s.LastModified,
+		CreatedAt:    s.CreatedAt,
+	}
+	if s.Id != "" && s.Id != "000000000000000000000000" {
+		if sm.Id, err = primitive.ObjectIDFromHex(s.Id); err != nil {
+			return
+		}
+	}
+	if s.UserId != "" && s.UserId != "000000000000000000000000" {
+		sm.UserId, err = primitive.ObjectIDFromHex(s.UserId)
+	}
+	return
+}
+
+// update the digestSettingsModel using an overwrite bson.D doc
+func (s *digestSettingsModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	sm := digestSettingsModel{}
+	err = bson.Unmarshal(data, &sm)
+	if len(sm.Frequency) > 0 {
+		s.Frequency = sm.Frequency
+	}
+	if !sm.LastSentAt.IsZero() {
+		s.LastSentAt = sm.LastSentAt
+	}
+	if !sm.LastModified.IsZero() {
+		s.LastModified = sm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the digestSettingsModel
+func (s *digestSettingsModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, s)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the digestSettingsModel
+func (s *digestSettingsModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	sm := digestSettingsModel{}
+	err = bson.Unmarshal(data, &sm)
+	if !sm.Id.IsZero() {
+		return s.Id == sm.Id
+	}
+	if !sm.UserId.IsZero() {
+		return s.UserId == sm.UserId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the digestSettingsModel
+func (s *digestSettingsModel) getID() (id interface{}) {
+	return s.Id
+}
+
+// addTimeStamps updates a digestSettingsModel struct with a timestamp
+func (s *digestSettingsModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	s.LastModified = currentTime
+	if newRecord {
+		s.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a digestSettingsModel has a value assigned for Id, if no value a new one is generated and assigned
+func (s *digestSettingsModel) addObjectID() {
+	if s.Id.IsZero() {
+		s.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a digestSettingsModel struct postProcess
+func (s *digestSettingsModel) postProcess() (err error) {
+	if s.UserId.IsZero() {
+		err = errors.New("digest settings record does not have a user_id")
+	}
+	return
+}
+
+// toDoc converts the bson digestSettingsModel into a bson.D
+func (s *digestSettingsModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(s)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the digestSettingsModel data
+func (s *digestSettingsModel) bsonFilter() (doc bson.D, err error) {
+	if !s.Id.IsZero() {
+		doc = bson.D{{"_id", s.Id}}
+	} else if !s.UserId.IsZero() {
+		doc = bson.D{{"user_id", s.UserId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the digestSettingsModel data
+func (s *digestSettingsModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := s.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a DigestSettings JSON struct from a pointer to a BSON digestSettingsModel
+func (s *digestSettingsModel) toRoot() *models.DigestSettings {
+	return &models.DigestSettings{
+		Id:           s.Id.Hex(),
+		UserId:       s.UserId.Hex(),
+		Frequency:    s.Frequency,
+		LastSentAt:   s.LastSentAt,
+		LastModified: s.LastModified,
+		CreatedAt:    s.CreatedAt,
+	}
+}
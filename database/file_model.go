@@ -0,0 +1,257 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// fileModel structures a file BSON document to save in a files collection
+type fileModel struct {
+	Id           primitive.ObjectID    `bson:"_id,omitempty"`
+	OwnerId      primitive.ObjectID    `bson:"owner_id,omitempty"`
+	OwnerType    string                `bson:"owner_type,omitempty"`
+	GridFSId     primitive.ObjectID    `bson:"gridfs_id,omitempty"`
+	BucketName   string                `bson:"bucket_name,omitempty"`
+	BucketType   string                `bson:"bucket_type,omitempty"`
+	Name         string                `bson:"name,omitempty"`
+	FileType     string                `bson:"file_type,omitempty"`
+	Size         int64                 `bson:"size,omitempty"`
+	Checksum     string                `bson:"checksum,omitempty"`
+	Width        int                   `bson:"width,omitempty"`
+	Height       int                   `bson:"height,omitempty"`
+	Thumbnails   []*fileThumbnailModel `bson:"thumbnails,omitempty"`
+	ScanStatus   string                `bson:"scan_status,omitempty"`
+	Signature    string                `bson:"signature,omitempty"`
+	MessageIds   []primitive.ObjectID  `bson:"message_ids,omitempty"`
+	LastModified time.Time             `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time             `bson:"created_at,omitempty"`
+	DeletedAt    time.Time             `bson:"deleted_at,omitempty"`
+}
+
+// fileThumbnailModel structures a thumbnail BSON sub document stored with a fileModel
+type fileThumbnailModel struct {
+	Size     int                `bson:"size"`
+	Width    int                `bson:"width"`
+	Height   int                `bson:"height"`
+	FileType string             `bson:"file_type"`
+	GridFSId primitive.ObjectID `bson:"gridfs_id"`
+}
+
+// newFileModel initializes a new pointer to a fileModel struct from a pointer to a JSON File struct
+func newFileModel(f *models.File) (fm *fileModel, err error) {
+	fm = &fileModel{
+		OwnerType:    f.OwnerType,
+		BucketName:   f.BucketName,
+		BucketType:   f.BucketType,
+		Name:         f.Name,
+		FileType:     f.FileType,
+		Size:         f.Size,
+		Checksum:     f.Checksum,
+		Width:        f.Width,
+		Height:       f.Height,
+		ScanStatus:   f.ScanStatus,
+		Signature:    f.Signature,
+		LastModified: f.LastModified,
+		CreatedAt:    f.CreatedAt,
+		DeletedAt:    f.DeletedAt,
+	}
+	for _, t := range f.Thumbnails {
+		tm := &fileThumbnailModel{Size: t.Size, Width: t.Width, Height: t.Height, FileType: t.FileType}
+		tm.GridFSId, err = primitive.ObjectIDFromHex(t.GridFSId)
+		if err != nil {
+			return
+		}
+		fm.Thumbnails = append(fm.Thumbnails, tm)
+	}
+	if f.CheckID("id") {
+		fm.Id, err = primitive.ObjectIDFromHex(f.Id)
+		if err != nil {
+			return
+		}
+	}
+	if f.CheckID("owner_id") {
+		fm.OwnerId, err = primitive.ObjectIDFromHex(f.OwnerId)
+		if err != nil {
+			return
+		}
+	}
+	if f.CheckID("gridfs_id") {
+		fm.GridFSId, err = primitive.ObjectIDFromHex(f.GridFSId)
+	}
+	return
+}
+
+// update the fileModel using an overwrite bson.D doc
+func (f *fileModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	fm := fileModel{}
+	err = bson.Unmarshal(data, &fm)
+	if len(fm.Name) > 0 {
+		f.Name = fm.Name
+	}
+	if len(fm.FileType) > 0 {
+		f.FileType = fm.FileType
+	}
+	if len(fm.BucketType) > 0 {
+		f.BucketType = fm.BucketType
+	}
+	if !fm.GridFSId.IsZero() {
+		f.GridFSId = fm.GridFSId
+	}
+	if fm.Size > 0 {
+		f.Size = fm.Size
+	}
+	if len(fm.Checksum) > 0 {
+		f.Checksum = fm.Checksum
+	}
+	if fm.Width > 0 {
+		f.Width = fm.Width
+		f.Height = fm.Height
+	}
+	if len(fm.Thumbnails) > 0 {
+		f.Thumbnails = fm.Thumbnails
+	}
+	if len(fm.ScanStatus) > 0 {
+		f.ScanStatus = fm.ScanStatus
+		f.Signature = fm.Signature
+	}
+	if !fm.LastModified.IsZero() {
+		f.LastModified = fm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the fileModel
+func (f *fileModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, f)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the fileModel
+func (f *fileModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	fm := fileModel{}
+	err = bson.Unmarshal(data, &fm)
+	if !fm.Id.IsZero() {
+		return f.Id == fm.Id
+	}
+	if !fm.OwnerId.IsZero() {
+		return f.OwnerId == fm.OwnerId
+	}
+	if !fm.GridFSId.IsZero() {
+		return f.GridFSId == fm.GridFSId
+	}
+	if len(fm.ScanStatus) > 0 {
+		return f.ScanStatus == fm.ScanStatus
+	}
+	return false
+}
+
+// getID returns the unique identifier of the fileModel
+func (f *fileModel) getID() (id interface{}) {
+	return f.Id
+}
+
+// addTimeStamps updates a fileModel struct with a timestamp
+func (f *fileModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	f.LastModified = currentTime
+	if newRecord {
+		f.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a fileModel has a value assigned for Id, if no value a new one is generated and assigned
+func (f *fileModel) addObjectID() {
+	if f.Id.IsZero() {
+		f.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a fileModel struct postProcess
+func (f *fileModel) postProcess() (err error) {
+	if f.GridFSId.IsZero() {
+		err = errors.New("file record does not have a gridfs_id")
+	}
+	return
+}
+
+// toDoc converts the bson fileModel into a bson.D
+func (f *fileModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(f)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the fileModel data
+func (f *fileModel) bsonFilter() (doc bson.D, err error) {
+	if !f.Id.IsZero() {
+		doc = bson.D{{"_id", f.Id}}
+	} else if !f.OwnerId.IsZero() {
+		doc = bson.D{{"owner_id", f.OwnerId}}
+	} else if !f.GridFSId.IsZero() {
+		doc = bson.D{{"gridfs_id", f.GridFSId}}
+	} else if len(f.ScanStatus) > 0 {
+		doc = bson.D{{"scan_status", f.ScanStatus}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the fileModel data
+func (f *fileModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := f.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a File JSON struct from a pointer to a BSON fileModel
+func (f *fileModel) toRoot() *models.File {
+	var thumbnails []*models.FileThumbnail
+	for _, t := range f.Thumbnails {
+		thumbnails = append(thumbnails, &models.FileThumbnail{Size: t.Size, Width: t.Width, Height: t.Height, FileType: t.FileType, GridFSId: t.GridFSId.Hex()})
+	}
+	var messageIds []string
+	for _, id := range f.MessageIds {
+		messageIds = append(messageIds, id.Hex())
+	}
+	return &models.File{
+		Id:           f.Id.Hex(),
+		OwnerId:      f.OwnerId.Hex(),
+		OwnerType:    f.OwnerType,
+		GridFSId:     f.GridFSId.Hex(),
+		BucketName:   f.BucketName,
+		BucketType:   f.BucketType,
+		Name:         f.Name,
+		FileType:     f.FileType,
+		Size:         f.Size,
+		Checksum:     f.Checksum,
+		Width:        f.Width,
+		Height:       f.Height,
+		Thumbnails:   thumbnails,
+		ScanStatus:   f.ScanStatus,
+		Signature:    f.Signature,
+		MessageIds:   messageIds,
+		LastModified: f.LastModified,
+		CreatedAt:    f.CreatedAt,
+		DeletedAt:    f.DeletedAt,
+	}
+}
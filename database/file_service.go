@@ -0,0 +1,748 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ablancas22/messenger-backend/media"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/scanner"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxFileSize int64 = 25 << 20 // 25 MB
+	defaultChunkSize   int64 = 1 << 20  // 1 MB
+	defaultUserQuota   int64 = 1 << 30  // 1 GB
+	defaultGroupQuota  int64 = 5 << 30  // 5 GB
+	uploadSessionTTL         = 24 * time.Hour
+	uploadChunksBucket       = "upload_chunks"
+)
+
+// FileService is used by the app to manage all file and upload related controllers and functionality
+type FileService struct {
+	collection     DBCollection
+	db             DBClient
+	handler        *DBHandler[*fileModel]
+	uploadHandler  *DBHandler[*uploadModel]
+	storageHandler *DBHandler[*storageModel]
+	usageHandler   *DBHandler[*storageUsageModel]
+	scanner        scanner.Scanner
+}
+
+// NewFileService is an exported function used to initialize a new FileService struct
+func NewFileService(db DBClient, handler *DBHandler[*fileModel], uHandler *DBHandler[*uploadModel], sHandler *DBHandler[*storageModel], usHandler *DBHandler[*storageUsageModel], s scanner.Scanner) *FileService {
+	collection := db.GetCollection("files")
+	return &FileService{collection, db, handler, uHandler, sHandler, usHandler, s}
+}
+
+// envSize reads a byte size from an environmental variable, falling back to def when unset or invalid
+func envSize(key string, def int64) int64 {
+	size, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || size <= 0 {
+		return def
+	}
+	return size
+}
+
+// MaxFileSize returns the maximum number of bytes a single file may contain
+func (p *FileService) MaxFileSize() int64 {
+	return envSize("MAX_FILE_SIZE", defaultMaxFileSize)
+}
+
+// defaultQuota returns the configured storage quota for a type of file owner
+func defaultQuota(ownerType string) int64 {
+	if ownerType == "group" {
+		return envSize("GROUP_STORAGE_QUOTA", defaultGroupQuota)
+	}
+	return envSize("USER_STORAGE_QUOTA", defaultUserQuota)
+}
+
+// checksum returns the hex encoded sha256 sum of data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// UploadCreate is used to start a new resumable upload session
+func (p *FileService) UploadCreate(u *models.Upload) (*models.Upload, error) {
+	err := u.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	if u.Size > p.MaxFileSize() {
+		return nil, models.ErrFileTooLarge
+	}
+	err = p.checkQuota(u.OwnerId, u.OwnerType, u.Size)
+	if err != nil {
+		return nil, err
+	}
+	u.Checksum = strings.ToLower(u.Checksum)
+	u.ChunkSize = envSize("UPLOAD_CHUNK_SIZE", defaultChunkSize)
+	u.Chunks = nil
+	u.FileId = ""
+	u.ExpiresAt = time.Now().UTC().Add(uploadSessionTTL)
+	um, err := newUploadModel(u)
+	if err != nil {
+		return nil, err
+	}
+	um, err = p.uploadHandler.InsertOne(um)
+	if err != nil {
+		return nil, err
+	}
+	return um.toRoot(), nil
+}
+
+// UploadFind is used to find a specific upload session
+func (p *FileService) UploadFind(u *models.Upload) (*models.Upload, error) {
+	um, err := newUploadModel(u)
+	if err != nil {
+		return nil, err
+	}
+	um, err = p.uploadHandler.FindOne(um)
+	if err != nil {
+		return nil, err
+	}
+	return um.toRoot(), nil
+}
+
+// UploadChunkWrite stores a numbered chunk of an upload session after verifying its checksum
+func (p *FileService) UploadChunkWrite(u *models.Upload, c *models.UploadChunk, data []byte) (*models.Upload, error) {
+	um, err := newUploadModel(&models.Upload{Id: u.Id})
+	if err != nil {
+		return nil, err
+	}
+	um, err = p.uploadHandler.FindOne(um)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().UTC().After(um.ExpiresAt) {
+		return nil, models.ErrUploadExpired
+	}
+	if !um.FileId.IsZero() {
+		return nil, errors.New("upload is already complete")
+	}
+	upload := um.toRoot()
+	expected := upload.ExpectedChunkSize(c.Number)
+	if expected == 0 {
+		return nil, fmt.Errorf("chunk number must be between 0 and %d", upload.ChunkCount()-1)
+	}
+	if int64(len(data)) != expected {
+		return nil, fmt.Errorf("chunk %d must contain %d bytes", c.Number, expected)
+	}
+	sum := checksum(data)
+	if c.Checksum == "" || !strings.EqualFold(c.Checksum, sum) {
+		return nil, models.ErrChecksumMismatch
+	}
+	bucket, err := p.db.GetBucket(uploadChunksBucket)
+	if err != nil {
+		return nil, err
+	}
+	gridId := primitive.NewObjectID()
+	err = bucket.UploadFromStreamWithID(gridId, um.Id.Hex()+"_"+strconv.Itoa(c.Number), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	um, err = p.recordChunk(um.Id, &uploadChunkModel{Number: c.Number, Size: int64(len(data)), Checksum: sum, GridFSId: gridId})
+	if err != nil {
+		_ = bucket.Delete(gridId)
+		return nil, err
+	}
+	return um.toRoot(), nil
+}
+
+// recordChunk atomically adds a received chunk to an upload session that is not complete, replacing a previously
+// received copy of the chunk, so that chunks uploaded in parallel are all kept
+func (p *FileService) recordChunk(uploadId primitive.ObjectID, cm *uploadChunkModel) (*uploadModel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	set := bson.D{{"expires_at", now.Add(uploadSessionTTL)}, {"last_modified", now}}
+	var um uploadModel
+	filter := bson.D{{"_id", uploadId}, {"file_id", bson.D{{"$exists", false}}}, {"chunks.number", bson.D{{"$ne", cm.Number}}}}
+	update := bson.D{{"$push", bson.D{{"chunks", cm}}}, {"$set", set}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := p.uploadHandler.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&um)
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return &um, err
+	}
+	// the chunk was received before, swap in the new copy and delete the old one
+	filter = bson.D{{"_id", uploadId}, {"file_id", bson.D{{"$exists", false}}}, {"chunks.number", cm.Number}}
+	update = bson.D{{"$set", append(bson.D{{"chunks.$", cm}}, set...)}}
+	opts = options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err = p.uploadHandler.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&um)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errors.New("upload is already complete")
+	} else if err != nil {
+		return nil, err
+	}
+	bucket, err := p.db.GetBucket(uploadChunksBucket)
+	if err != nil {
+		return nil, err
+	}
+	for i, old := range um.Chunks {
+		if old.Number == cm.Number {
+			_ = bucket.Delete(old.GridFSId)
+			um.Chunks[i] = cm
+		}
+	}
+	return &um, nil
+}
+
+// UploadComplete assembles the chunks of a finished upload session into a new File. The session is claimed by
+// setting the id of the file before it is assembled, so concurrent requests do not assemble it twice.
+func (p *FileService) UploadComplete(u *models.Upload) (*models.File, error) {
+	um, err := newUploadModel(&models.Upload{Id: u.Id})
+	if err != nil {
+		return nil, err
+	}
+	um, err = p.uploadHandler.FindOne(um)
+	if err != nil {
+		return nil, err
+	}
+	if !um.FileId.IsZero() {
+		return p.completedFile(um)
+	}
+	upload := um.toRoot()
+	if !upload.Complete() {
+		return nil, fmt.Errorf("upload is incomplete, received %d of %d bytes", upload.Offset, upload.Size)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	fileId := primitive.NewObjectID()
+	filter := bson.D{{"_id", um.Id}, {"file_id", bson.D{{"$exists", false}}}}
+	update := bson.D{{"$set", bson.D{{"file_id", fileId}, {"last_modified", time.Now().UTC()}}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = p.uploadHandler.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(um)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if um, err = p.uploadHandler.FindOne(&uploadModel{Id: um.Id}); err != nil {
+			return nil, err
+		}
+		return p.completedFile(um)
+	} else if err != nil {
+		return nil, err
+	}
+	upload = um.toRoot()
+	if err = p.reserveStorage(upload.OwnerId, upload.OwnerType, upload.Size); err != nil {
+		p.releaseUpload(um.Id, fileId)
+		return nil, err
+	}
+	fm, err := p.assembleUpload(um, upload)
+	if err != nil {
+		p.releaseStorage(upload.OwnerId, upload.Size)
+		p.releaseUpload(um.Id, fileId)
+		return nil, err
+	}
+	p.releaseStorage(upload.OwnerId, upload.Size-fm.Size) // processed images may shrink
+	return fm.toRoot(), nil
+}
+
+// completedFile returns the file an upload session was assembled into
+func (p *FileService) completedFile(um *uploadModel) (*models.File, error) {
+	f, err := p.FileFind(&models.File{Id: um.FileId.Hex()})
+	if err != nil {
+		return nil, errors.New("upload is being completed")
+	}
+	return f, nil
+}
+
+// releaseUpload gives up the claim on an upload session whose assembly failed, so that completing it can be retried
+func (p *FileService) releaseUpload(uploadId primitive.ObjectID, fileId primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	filter := bson.D{{"_id", uploadId}, {"file_id", fileId}}
+	_ = p.uploadHandler.collection.FindOneAndUpdate(ctx, filter, bson.D{{"$unset", bson.D{{"file_id", ""}}}}).Err()
+}
+
+// assembleUpload streams the chunks of a complete upload session into a new file and records it
+func (p *FileService) assembleUpload(um *uploadModel, upload *models.Upload) (*fileModel, error) {
+	chunkBucket, err := p.db.GetBucket(uploadChunksBucket)
+	if err != nil {
+		return nil, err
+	}
+	file := upload.ToFile()
+	file.Id = um.FileId.Hex()
+	file.GridFSId = primitive.NewObjectID().Hex()
+	file.BucketType = "upload"
+	err = file.BuildBucketName()
+	if err != nil {
+		return nil, err
+	}
+	fm, err := newFileModel(file)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := p.db.GetBucket(fm.BucketName)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(um.Chunks, func(i, j int) bool { return um.Chunks[i].Number < um.Chunks[j].Number })
+	pr, pw := io.Pipe()
+	go func() {
+		for _, cm := range um.Chunks {
+			if _, err := chunkBucket.DownloadToStream(cm.GridFSId, pw); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.Close()
+	}()
+	hash := sha256.New()
+	err = bucket.UploadFromStreamWithID(fm.GridFSId, fm.Name, io.TeeReader(pr, hash))
+	pr.Close()
+	if err != nil {
+		return nil, err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != um.Checksum {
+		_ = bucket.Delete(fm.GridFSId)
+		return nil, models.ErrChecksumMismatch
+	}
+	fm, err = p.insertFile(bucket, fm)
+	if err != nil {
+		return nil, err
+	}
+	for _, cm := range um.Chunks {
+		_ = chunkBucket.Delete(cm.GridFSId)
+	}
+	return fm, nil
+}
+
+// FileCreate stores data as a new File in its owner's bucket
+func (p *FileService) FileCreate(f *models.File, data []byte) (*models.File, error) {
+	err := f.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > p.MaxFileSize() {
+		return nil, models.ErrFileTooLarge
+	}
+	err = p.reserveStorage(f.OwnerId, f.OwnerType, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	fm, err := p.storeFile(f, data)
+	if err != nil {
+		p.releaseStorage(f.OwnerId, int64(len(data)))
+		return nil, err
+	}
+	p.releaseStorage(f.OwnerId, int64(len(data))-fm.Size) // processed images may shrink
+	return fm.toRoot(), nil
+}
+
+// storeFile writes data to the bucket of a new file and records it
+func (p *FileService) storeFile(f *models.File, data []byte) (*fileModel, error) {
+	f.Id = primitive.NewObjectID().Hex()
+	f.GridFSId = primitive.NewObjectID().Hex()
+	f.Size = int64(len(data))
+	f.Checksum = checksum(data)
+	err := f.BuildBucketName()
+	if err != nil {
+		return nil, err
+	}
+	fm, err := newFileModel(f)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := p.db.GetBucket(fm.BucketName)
+	if err != nil {
+		return nil, err
+	}
+	err = bucket.UploadFromStreamWithID(fm.GridFSId, fm.Name, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return p.insertFile(bucket, fm)
+}
+
+// insertFile records a file whose contents are already stored in bucket, scanning it and processing images first.
+// Images are recognized by their contents rather than their declared type, so an image uploaded as another type still
+// has its metadata stripped. The stored contents are removed again if the file can not be recorded.
+func (p *FileService) insertFile(bucket DBBucket, fm *fileModel) (*fileModel, error) {
+	_ = p.scanFile(bucket, fm) // an unavailable scanner leaves the file pending for ScanPendingFiles
+	if media.IsImage(p.sniffFileType(bucket, fm)) && fm.ScanStatus != models.ScanInfected {
+		if err := p.processImage(bucket, fm); err != nil {
+			_ = bucket.Delete(fm.GridFSId)
+			return nil, err
+		}
+	}
+	inserted, err := p.handler.InsertOne(fm)
+	if err != nil {
+		p.deleteThumbnails(bucket, fm)
+		_ = bucket.Delete(fm.GridFSId)
+		return nil, err
+	}
+	return inserted, nil
+}
+
+// sniffFileType detects the mime type of the stored contents of a file from their first bytes
+func (p *FileService) sniffFileType(bucket DBBucket, fm *fileModel) string {
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := bucket.DownloadToStream(fm.GridFSId, pw)
+		pw.CloseWithError(err)
+	}()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(pr, head)
+	pr.Close()
+	return http.DetectContentType(head[:n])
+}
+
+// scanFile hands the stored contents of a file to the scanner and records its verdict on the fileModel
+func (p *FileService) scanFile(bucket DBBucket, fm *fileModel) error {
+	fm.ScanStatus = models.ScanPending
+	fm.Signature = ""
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := bucket.DownloadToStream(fm.GridFSId, pw)
+		pw.CloseWithError(err)
+	}()
+	result, err := p.scanner.Scan(pr)
+	pr.Close()
+	if err != nil {
+		return err
+	}
+	fm.ScanStatus = models.ScanClean
+	if result.Infected {
+		fm.ScanStatus = models.ScanInfected
+		fm.Signature = result.Signature
+	}
+	return nil
+}
+
+// ScanPendingFiles retries scanning files whose scan is still pending and returns how many were scanned
+func (p *FileService) ScanPendingFiles() (int, error) {
+	pending, err := p.handler.FindMany(&fileModel{ScanStatus: models.ScanPending})
+	if err != nil {
+		return 0, err
+	}
+	scanned := 0
+	for _, fm := range pending {
+		bucket, err := p.db.GetBucket(fm.BucketName)
+		if err != nil {
+			return scanned, err
+		}
+		if err = p.scanFile(bucket, fm); err != nil {
+			return scanned, err
+		}
+		if _, err = p.handler.UpdateOne(&fileModel{Id: fm.Id}, fm); err != nil {
+			return scanned, err
+		}
+		scanned++
+	}
+	return scanned, nil
+}
+
+// processImage replaces a stored image with a copy stripped of its metadata and stores its thumbnails
+func (p *FileService) processImage(bucket DBBucket, fm *fileModel) error {
+	var buf bytes.Buffer
+	if _, err := bucket.DownloadToStream(fm.GridFSId, &buf); err != nil {
+		return err
+	}
+	img, err := media.ProcessImage(buf.Bytes(), fm.FileType)
+	if err != nil {
+		return err
+	}
+	gridId := primitive.NewObjectID()
+	if err = bucket.UploadFromStreamWithID(gridId, fm.Name, bytes.NewReader(img.Data)); err != nil {
+		return err
+	}
+	_ = bucket.Delete(fm.GridFSId)
+	fm.GridFSId = gridId
+	fm.FileType = img.FileType
+	fm.Size = int64(len(img.Data))
+	fm.Checksum = checksum(img.Data)
+	fm.Width = img.Width
+	fm.Height = img.Height
+	fm.Thumbnails = nil
+	for _, t := range img.Thumbnails {
+		tm := &fileThumbnailModel{Size: t.Size, Width: t.Width, Height: t.Height, FileType: t.FileType, GridFSId: primitive.NewObjectID()}
+		name := strconv.Itoa(t.Size) + "_" + fm.Name
+		if err = bucket.UploadFromStreamWithID(tm.GridFSId, name, bytes.NewReader(t.Data)); err != nil {
+			p.deleteThumbnails(bucket, fm)
+			return err
+		}
+		fm.Thumbnails = append(fm.Thumbnails, tm)
+	}
+	return nil
+}
+
+// deleteThumbnails removes the stored thumbnails of a fileModel
+func (p *FileService) deleteThumbnails(bucket DBBucket, fm *fileModel) {
+	for _, tm := range fm.Thumbnails {
+		_ = bucket.Delete(tm.GridFSId)
+	}
+}
+
+// UploadDelete is used to abort an upload session and discard any chunks it has received
+func (p *FileService) UploadDelete(u *models.Upload) (*models.Upload, error) {
+	um, err := newUploadModel(&models.Upload{Id: u.Id})
+	if err != nil {
+		return nil, err
+	}
+	um, err = p.uploadHandler.FindOne(um)
+	if err != nil {
+		return nil, err
+	}
+	err = p.discardUpload(um)
+	if err != nil {
+		return nil, err
+	}
+	return um.toRoot(), nil
+}
+
+// discardUpload removes the stored chunks of an upload session along with the session itself
+func (p *FileService) discardUpload(um *uploadModel) error {
+	bucket, err := p.db.GetBucket(uploadChunksBucket)
+	if err != nil {
+		return err
+	}
+	for _, cm := range um.Chunks {
+		_ = bucket.Delete(cm.GridFSId)
+	}
+	_, err = p.uploadHandler.DeleteOne(&uploadModel{Id: um.Id})
+	return err
+}
+
+// PurgeExpiredUploads garbage collects abandoned upload sessions and returns how many were removed
+func (p *FileService) PurgeExpiredUploads() (int, error) {
+	now := time.Now().UTC()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cur, err := p.uploadHandler.collection.Find(ctx, bson.D{{"expires_at", bson.D{{"$lt", now}}}})
+	if err != nil {
+		return 0, err
+	}
+	cursor := checkCursorENV(cur)
+	defer cursor.Close(ctx)
+	var expired []*uploadModel
+	for cursor.Next(ctx) {
+		var um uploadModel
+		if err = cursor.Decode(&um); err != nil {
+			return 0, err
+		}
+		if um.ExpiresAt.Before(now) {
+			expired = append(expired, &um)
+		}
+	}
+	purged := 0
+	for _, um := range expired {
+		if err = p.discardUpload(um); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// FileFind is used to find a specific file doc
+func (p *FileService) FileFind(f *models.File) (*models.File, error) {
+	fm, err := newFileModel(f)
+	if err != nil {
+		return nil, err
+	}
+	fm, err = p.handler.FindOne(fm)
+	if err != nil {
+		return nil, err
+	}
+	return fm.toRoot(), nil
+}
+
+// FileAttach records that a file is attached to a message, so that the participants of the message can read it
+func (p *FileService) FileAttach(f *models.File, messageId string) error {
+	id, err := primitive.ObjectIDFromHex(f.Id)
+	if err != nil {
+		return err
+	}
+	mId, err := primitive.ObjectIDFromHex(messageId)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	update := bson.D{{"$addToSet", bson.D{{"message_ids", mId}}}, {"$set", bson.D{{"last_modified", time.Now().UTC()}}}}
+	return p.handler.collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}}, update).Err()
+}
+
+// FileDownload writes the contents of a stored file to w
+func (p *FileService) FileDownload(f *models.File, w io.Writer) (*models.File, error) {
+	fm, err := newFileModel(f)
+	if err != nil {
+		return nil, err
+	}
+	fm, err = p.handler.FindOne(fm)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := p.db.GetBucket(fm.BucketName)
+	if err != nil {
+		return nil, err
+	}
+	_, err = bucket.DownloadToStream(fm.GridFSId, w)
+	if err != nil {
+		return nil, err
+	}
+	return fm.toRoot(), nil
+}
+
+// ThumbnailDownload writes the contents of a stored image thumbnail to w
+func (p *FileService) ThumbnailDownload(f *models.File, t *models.FileThumbnail, w io.Writer) error {
+	gridId, err := primitive.ObjectIDFromHex(t.GridFSId)
+	if err != nil {
+		return err
+	}
+	bucket, err := p.db.GetBucket(f.BucketName)
+	if err != nil {
+		return err
+	}
+	_, err = bucket.DownloadToStream(gridId, w)
+	return err
+}
+
+// FileDelete is used to delete a file doc along with its stored contents
+func (p *FileService) FileDelete(f *models.File) (*models.File, error) {
+	fm, err := newFileModel(f)
+	if err != nil {
+		return nil, err
+	}
+	fm, err = p.handler.DeleteOne(fm)
+	if err != nil {
+		return nil, err
+	}
+	p.releaseStorage(fm.OwnerId.Hex(), fm.Size)
+	bucket, err := p.db.GetBucket(fm.BucketName)
+	if err != nil {
+		return nil, err
+	}
+	p.deleteThumbnails(bucket, fm)
+	err = bucket.Delete(fm.GridFSId)
+	if err != nil {
+		return nil, err
+	}
+	return fm.toRoot(), nil
+}
+
+// StorageFind returns the number of bytes stored by a file owner along with their quota
+func (p *FileService) StorageFind(s *models.Storage) (*models.Storage, error) {
+	err := s.Validate("retrieve")
+	if err != nil {
+		return nil, err
+	}
+	sm, err := newStorageModel(&models.Storage{OwnerId: s.OwnerId})
+	if err != nil {
+		return nil, err
+	}
+	storage := &models.Storage{OwnerId: s.OwnerId, OwnerType: s.OwnerType, Quota: defaultQuota(s.OwnerType)}
+	usage, err := p.storageUsage(sm.OwnerId)
+	if err != nil {
+		return nil, err
+	}
+	if sm, err = p.storageHandler.FindOne(sm); err == nil {
+		storage = sm.toRoot()
+	}
+	storage.Used = usage.Used
+	storage.CalculateAvailable()
+	return storage, nil
+}
+
+// StorageUpdate is used to adjust the storage quota of a file owner
+func (p *FileService) StorageUpdate(s *models.Storage) (*models.Storage, error) {
+	err := s.Validate("update")
+	if err != nil {
+		return nil, err
+	}
+	sm, err := newStorageModel(&models.Storage{OwnerId: s.OwnerId, OwnerType: s.OwnerType, Quota: s.Quota})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := p.storageHandler.FindOne(&storageModel{OwnerId: sm.OwnerId})
+	if err == nil {
+		sm.Id = cur.Id
+		_, err = p.storageHandler.UpdateOne(&storageModel{Id: cur.Id}, sm)
+	} else {
+		_, err = p.storageHandler.InsertOne(sm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.StorageFind(s)
+}
+
+// checkQuota ensures a file owner has room to store size more bytes. It is only advisory, the bytes are reserved by
+// reserveStorage once they are stored.
+func (p *FileService) checkQuota(ownerId string, ownerType string, size int64) error {
+	storage, err := p.StorageFind(&models.Storage{OwnerId: ownerId, OwnerType: ownerType})
+	if err != nil {
+		return err
+	}
+	if size > storage.Available {
+		return models.ErrQuotaExceeded
+	}
+	return nil
+}
+
+// storageUsage returns the usage ledger of a file owner, seeding it from the sizes of their files the first time
+func (p *FileService) storageUsage(ownerId primitive.ObjectID) (*storageUsageModel, error) {
+	um, err := p.usageHandler.FindOne(&storageUsageModel{Id: ownerId})
+	if err == nil {
+		return um, nil
+	}
+	files, err := p.handler.FindMany(&fileModel{OwnerId: ownerId})
+	if err != nil {
+		return nil, err
+	}
+	um = &storageUsageModel{Id: ownerId}
+	for _, fm := range files {
+		um.Used += fm.Size
+	}
+	if _, err = p.usageHandler.InsertOne(um); err != nil {
+		return p.usageHandler.FindOne(&storageUsageModel{Id: ownerId}) // seeded by a concurrent request
+	}
+	return um, nil
+}
+
+// reserveStorage atomically adds size bytes to the usage of a file owner. The ledger is only incremented while the
+// bytes fit in the quota, so concurrent uploads can not take the owner over it.
+func (p *FileService) reserveStorage(ownerId string, ownerType string, size int64) error {
+	storage, err := p.StorageFind(&models.Storage{OwnerId: ownerId, OwnerType: ownerType})
+	if err != nil {
+		return err
+	}
+	id, err := primitive.ObjectIDFromHex(ownerId)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	filter := bson.D{{"_id", id}, {"used", bson.D{{"$lte", storage.Quota - size}}}}
+	update := bson.D{{"$inc", bson.D{{"used", size}}}, {"$set", bson.D{{"last_modified", time.Now().UTC()}}}}
+	err = p.usageHandler.collection.FindOneAndUpdate(ctx, filter, update).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.ErrQuotaExceeded
+	}
+	return err
+}
+
+// releaseStorage returns size bytes reserved by a file owner, a negative size reserves the difference
+func (p *FileService) releaseStorage(ownerId string, size int64) {
+	id, err := primitive.ObjectIDFromHex(ownerId)
+	if err != nil || size == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	update := bson.D{{"$inc", bson.D{{"used", -size}}}, {"$set", bson.D{{"last_modified", time.Now().UTC()}}}}
+	_ = p.usageHandler.collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}}, update).Err()
+}
@@ -9,22 +9,24 @@ import (
 )
 
 type groupMembershipModel struct {
-	Id        primitive.ObjectID `bson:"id,omitempty"`
-	UserId    primitive.ObjectID `bson:"user_id,omitempty"`
-	GroupId   primitive.ObjectID `bson:"group_id,omitempty"`
-	Admin     bool               `bson:"admin,omitempty"`
-	UpdatedAt time.Time          `bson:"updated_at,omitempty"`
-	CreatedAt time.Time          `bson:"created_at,omitempty"`
-	DeletedAt time.Time          `bson:"deleted_at,omitempty"`
+	Id         primitive.ObjectID `bson:"id,omitempty"`
+	UserId     primitive.ObjectID `bson:"user_id,omitempty"`
+	GroupId    primitive.ObjectID `bson:"group_id,omitempty"`
+	Admin      bool               `bson:"admin,omitempty"`
+	MutedUntil time.Time          `bson:"muted_until,omitempty"`
+	UpdatedAt  time.Time          `bson:"updated_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at,omitempty"`
+	DeletedAt  time.Time          `bson:"deleted_at,omitempty"`
 }
 
 // newGroupModel initializes a new pointer to a groupModel struct from a pointer to a JSON Group struct
 func newGroupMembershipModel(g *models.GroupMembership) (gm *groupMembershipModel, err error) {
 	gm = &groupMembershipModel{
-		Admin:     g.Admin,
-		UpdatedAt: g.UpdatedAt,
-		CreatedAt: g.CreatedAt,
-		DeletedAt: g.DeletedAt,
+		Admin:      g.Admin,
+		MutedUntil: g.MutedUntil,
+		UpdatedAt:  g.UpdatedAt,
+		CreatedAt:  g.CreatedAt,
+		DeletedAt:  g.DeletedAt,
 	}
 	if g.Id != "" && g.Id != "000000000000000000000000" {
 		gm.Id, err = primitive.ObjectIDFromHex(g.Id)
@@ -41,13 +43,14 @@ func newGroupMembershipModel(g *models.GroupMembership) (gm *groupMembershipMode
 // toRoot creates and return a new pointer to a Group JSON struct from a pointer to a BSON groupModel
 func (g *groupMembershipModel) toRoot() *models.GroupMembership {
 	return &models.GroupMembership{
-		Id:        g.Id.Hex(),
-		UserId:    g.UserId.Hex(),
-		GroupId:   g.GroupId.Hex(),
-		Admin:     g.Admin,
-		UpdatedAt: g.UpdatedAt,
-		CreatedAt: g.CreatedAt,
-		DeletedAt: g.DeletedAt,
+		Id:         g.Id.Hex(),
+		UserId:     g.UserId.Hex(),
+		GroupId:    g.GroupId.Hex(),
+		Admin:      g.Admin,
+		MutedUntil: g.MutedUntil,
+		UpdatedAt:  g.UpdatedAt,
+		CreatedAt:  g.CreatedAt,
+		DeletedAt:  g.DeletedAt,
 	}
 }
 
@@ -61,6 +64,9 @@ func (g *groupMembershipModel) update(doc interface{}) (err error) {
 	if len(gmm.Id.Hex()) > 0 && gmm.Id.Hex() != "000000000000000000000000" {
 		g.Id = gmm.Id
 	}
+	if !gmm.MutedUntil.IsZero() {
+		g.MutedUntil = gmm.MutedUntil
+	}
 
 	return
 }
@@ -95,6 +101,8 @@ func (g *groupMembershipModel) match(doc interface{}) bool {
 			if g.UserId == gmm.UserId && g.GroupId == gmm.GroupId {
 				return true
 			}
+		} else if g.GroupId == gmm.GroupId {
+			return true
 		}
 	}
 
@@ -140,8 +148,9 @@ func (g *groupMembershipModel) toDoc() (doc bson.D, err error) {
 
 // bsonFilter generates a bson filter for MongoDB queries from the userModel data
 func (g *groupMembershipModel) bsonFilter() (doc bson.D, err error) {
+	// memberships store their id under "id" rather than "_id"
 	if g.Id.Hex() != "" && g.Id.Hex() != "000000000000000000000000" {
-		doc = bson.D{{"_id", g.Id}}
+		doc = bson.D{{"id", g.Id}}
 	}
 	if g.GroupId.Hex() != "" && g.GroupId.Hex() != "000000000000000000000000" {
 		if g.UserId.Hex() != "" && g.UserId.Hex() != "000000000000000000000000" {
@@ -48,8 +48,11 @@ func (p *GroupMembershipService) GroupMembershipCreate(g *models.GroupMembership
 // GroupsFind is used to find all group docs in a MongoDB Collection
 func (p *GroupMembershipService) GroupMembershipsFind(g *models.GroupMembership) ([]*models.GroupMembership, error) {
 	var groups []*models.GroupMembership
-	//Todo: build filter using g
-	gms, err := p.handler.FindMany(&groupMembershipModel{})
+	filter, err := newGroupMembershipModel(&models.GroupMembership{GroupId: g.GroupId})
+	if err != nil {
+		return groups, err
+	}
+	gms, err := p.handler.FindMany(filter)
 	if err != nil {
 		return groups, err
 	}
@@ -13,6 +13,7 @@ type groupModel struct {
 	Id           primitive.ObjectID `bson:"_id,omitempty"`
 	Name         string             `bson:"name,omitempty"`
 	Visibility   bool               `bson:"visibility,omitempty"` //invite only, visible for Member requests
+	ImageId      string             `bson:"image_id,omitempty"`
 	LastModified time.Time          `bson:"last_modified,omitempty"`
 	CreatedAt    time.Time          `bson:"created_at,omitempty"`
 	DeletedAt    time.Time          `bson:"deleted_at,omitempty"`
@@ -22,6 +23,7 @@ type groupModel struct {
 func newGroupModel(g *models.Group) (gm *groupModel, err error) {
 	gm = &groupModel{
 		Name:         g.Name,
+		ImageId:      g.ImageId,
 		LastModified: g.LastModified,
 		CreatedAt:    g.CreatedAt,
 		DeletedAt:    g.DeletedAt,
@@ -43,6 +45,9 @@ func (g *groupModel) update(doc interface{}) (err error) {
 	if len(gm.Name) > 0 {
 		g.Name = gm.Name
 	}
+	if len(gm.ImageId) > 0 {
+		g.ImageId = gm.ImageId
+	}
 	if !gm.LastModified.IsZero() {
 		g.LastModified = gm.LastModified
 	}
@@ -139,11 +144,14 @@ func (g *groupModel) bsonUpdate() (doc bson.D, err error) {
 
 // toRoot creates and return a new pointer to a Group JSON struct from a pointer to a BSON groupModel
 func (g *groupModel) toRoot() *models.Group {
-	return &models.Group{
+	group := &models.Group{
 		Id:           g.Id.Hex(),
 		Name:         g.Name,
+		ImageId:      g.ImageId,
 		LastModified: g.LastModified,
 		CreatedAt:    g.CreatedAt,
 		DeletedAt:    g.DeletedAt,
 	}
+	group.BuildImageURL()
+	return group
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
 	"time"
 )
 
@@ -109,6 +110,7 @@ func (p *GroupService) GroupUpdate(g *models.Group) (*models.Group, error) {
 	if err != nil {
 		return nil, err
 	}
+	g.ImageId = "" // group pictures are only changed through GroupImageSet
 	gm, err := newGroupModel(g)
 	if err != nil {
 		return nil, err
@@ -121,6 +123,33 @@ func (p *GroupService) GroupUpdate(g *models.Group) (*models.Group, error) {
 	return gm.toRoot(), err
 }
 
+// GroupImageSet associates an image file with a group, an empty ImageId removes the group's current image
+func (p *GroupService) GroupImageSet(g *models.Group) (*models.Group, error) {
+	gm, err := newGroupModel(&models.Group{Id: g.Id})
+	if err != nil {
+		return nil, err
+	}
+	f, err := gm.bsonFilter()
+	if err != nil {
+		return nil, err
+	}
+	update := bson.D{{"$set", bson.D{{"image_id", g.ImageId}}}}
+	if g.ImageId == "" {
+		update = bson.D{{"$unset", bson.D{{"image_id", ""}}}}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = p.collection.UpdateOne(ctx, f, update)
+	if err != nil {
+		return nil, err
+	}
+	gm, err = p.handler.FindOne(gm)
+	if err != nil {
+		return nil, err
+	}
+	return gm.toRoot(), nil
+}
+
 // GroupDocInsert is used to insert a group doc directly into mongodb for testing purposes
 func (p *GroupService) GroupDocInsert(g *models.Group) (*models.Group, error) {
 	insertGroup, err := newGroupModel(g)
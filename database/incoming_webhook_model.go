@@ -0,0 +1,185 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// incomingWebhookModel structures an incoming webhook BSON document to save in an incoming_webhooks collection
+type incomingWebhookModel struct {
+	Id             primitive.ObjectID `bson:"_id,omitempty"`
+	ConversationId primitive.ObjectID `bson:"conversation_id,omitempty"`
+	GroupId        primitive.ObjectID `bson:"group_id,omitempty"`
+	UserId         primitive.ObjectID `bson:"user_id,omitempty"`
+	Name           string             `bson:"name,omitempty"`
+	Prefix         string             `bson:"prefix,omitempty"`
+	TokenHash      string             `bson:"token_hash,omitempty"`
+	CreatedBy      primitive.ObjectID `bson:"created_by,omitempty"`
+	LastUsedAt     time.Time          `bson:"last_used_at,omitempty"`
+	LastModified   time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at,omitempty"`
+}
+
+// newIncomingWebhookModel initializes a new pointer to an incomingWebhookModel struct from a pointer to a JSON IncomingWebhook struct
+func newIncomingWebhookModel(h *models.IncomingWebhook) (hm *incomingWebhookModel, err error) {
+	hm = &incomingWebhookModel{
+		Name:         h.Name,
+		Prefix:       h.Prefix,
+		TokenHash:    h.TokenHash,
+		LastUsedAt:   h.LastUsedAt,
+		LastModified: h.LastModified,
+		CreatedAt:    h.CreatedAt,
+	}
+	if h.Id != "" && h.Id != "000000000000000000000000" {
+		if hm.Id, err = primitive.ObjectIDFromHex(h.Id); err != nil {
+			return
+		}
+	}
+	if h.ConversationId != "" && h.ConversationId != "000000000000000000000000" {
+		if hm.ConversationId, err = primitive.ObjectIDFromHex(h.ConversationId); err != nil {
+			return
+		}
+	}
+	if h.GroupId != "" && h.GroupId != "000000000000000000000000" {
+		if hm.GroupId, err = primitive.ObjectIDFromHex(h.GroupId); err != nil {
+			return
+		}
+	}
+	if h.UserId != "" && h.UserId != "000000000000000000000000" {
+		if hm.UserId, err = primitive.ObjectIDFromHex(h.UserId); err != nil {
+			return
+		}
+	}
+	if h.CreatedBy != "" && h.CreatedBy != "000000000000000000000000" {
+		hm.CreatedBy, err = primitive.ObjectIDFromHex(h.CreatedBy)
+	}
+	return
+}
+
+// update the incomingWebhookModel using an overwrite bson.D doc
+func (h *incomingWebhookModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	hm := incomingWebhookModel{}
+	err = bson.Unmarshal(data, &hm)
+	if !hm.LastUsedAt.IsZero() {
+		h.LastUsedAt = hm.LastUsedAt
+	}
+	if !hm.LastModified.IsZero() {
+		h.LastModified = hm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the incomingWebhookModel
+func (h *incomingWebhookModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, h)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the incomingWebhookModel
+func (h *incomingWebhookModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	hm := incomingWebhookModel{}
+	err = bson.Unmarshal(data, &hm)
+	if !hm.Id.IsZero() {
+		return h.Id == hm.Id
+	}
+	if hm.TokenHash != "" {
+		return h.TokenHash == hm.TokenHash
+	}
+	if !hm.ConversationId.IsZero() {
+		return h.ConversationId == hm.ConversationId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the incomingWebhookModel
+func (h *incomingWebhookModel) getID() (id interface{}) {
+	return h.Id
+}
+
+// addTimeStamps updates an incomingWebhookModel struct with a timestamp
+func (h *incomingWebhookModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	h.LastModified = currentTime
+	if newRecord {
+		h.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if an incomingWebhookModel has a value assigned for Id, if no value a new one is generated and assigned
+func (h *incomingWebhookModel) addObjectID() {
+	if h.Id.IsZero() {
+		h.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates an incomingWebhookModel struct postProcess
+func (h *incomingWebhookModel) postProcess() (err error) {
+	if h.UserId.IsZero() {
+		err = errors.New("incoming webhook record does not have a user_id")
+	}
+	return
+}
+
+// toDoc converts the bson incomingWebhookModel into a bson.D
+func (h *incomingWebhookModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(h)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the incomingWebhookModel data
+func (h *incomingWebhookModel) bsonFilter() (doc bson.D, err error) {
+	if !h.Id.IsZero() {
+		doc = bson.D{{"_id", h.Id}}
+	} else if h.TokenHash != "" {
+		doc = bson.D{{"token_hash", h.TokenHash}}
+	} else if !h.ConversationId.IsZero() {
+		doc = bson.D{{"conversation_id", h.ConversationId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the incomingWebhookModel data
+func (h *incomingWebhookModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := h.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to an IncomingWebhook JSON struct from a pointer to a BSON incomingWebhookModel
+func (h *incomingWebhookModel) toRoot() *models.IncomingWebhook {
+	return &models.IncomingWebhook{
+		Id:             h.Id.Hex(),
+		ConversationId: h.ConversationId.Hex(),
+		GroupId:        h.GroupId.Hex(),
+		UserId:         h.UserId.Hex(),
+		Name:           h.Name,
+		Prefix:         h.Prefix,
+		TokenHash:      h.TokenHash,
+		CreatedBy:      h.CreatedBy.Hex(),
+		LastUsedAt:     h.LastUsedAt,
+		LastModified:   h.LastModified,
+		CreatedAt:      h.CreatedAt,
+	}
+}
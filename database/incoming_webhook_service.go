@@ -0,0 +1,114 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+const incomingWebhookPrefix = "ihk_"
+
+// IncomingWebhookService is used by the app to manage the incoming webhooks of conversations
+type IncomingWebhookService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*incomingWebhookModel]
+}
+
+// NewIncomingWebhookService is an exported function used to initialize a new IncomingWebhookService struct
+func NewIncomingWebhookService(db DBClient, handler *DBHandler[*incomingWebhookModel]) *IncomingWebhookService {
+	collection := db.GetCollection("incoming_webhooks")
+	return &IncomingWebhookService{collection, db, handler}
+}
+
+// IncomingWebhookCreate is used to create a new incoming webhook, the returned IncomingWebhook is the only one that
+// carries the token itself
+func (p *IncomingWebhookService) IncomingWebhookCreate(h *models.IncomingWebhook) (*models.IncomingWebhook, error) {
+	err := h.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := incomingWebhookPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	hm, err := newIncomingWebhookModel(&models.IncomingWebhook{
+		ConversationId: h.ConversationId,
+		GroupId:        h.GroupId,
+		UserId:         h.UserId,
+		Name:           h.Name,
+		Prefix:         token[:len(incomingWebhookPrefix)+6],
+		TokenHash:      hashAPIKey(token),
+		CreatedBy:      h.CreatedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	hm, err = p.handler.InsertOne(hm)
+	if err != nil {
+		return nil, err
+	}
+	created := hm.toRoot()
+	created.Token = token
+	return created, nil
+}
+
+// IncomingWebhooksFind is used to list the incoming webhooks of a conversation
+func (p *IncomingWebhookService) IncomingWebhooksFind(h *models.IncomingWebhook) ([]*models.IncomingWebhook, error) {
+	hooks := []*models.IncomingWebhook{}
+	hm, err := newIncomingWebhookModel(&models.IncomingWebhook{ConversationId: h.ConversationId})
+	if err != nil {
+		return hooks, err
+	}
+	if hm.ConversationId.IsZero() {
+		return hooks, nil
+	}
+	hms, err := p.handler.FindMany(hm)
+	if err != nil {
+		return hooks, err
+	}
+	for _, hm = range hms {
+		hooks = append(hooks, hm.toRoot())
+	}
+	return hooks, nil
+}
+
+// IncomingWebhookDelete is used to revoke an incoming webhook of a conversation
+func (p *IncomingWebhookService) IncomingWebhookDelete(h *models.IncomingWebhook) (*models.IncomingWebhook, error) {
+	if !h.CheckID("id") {
+		return nil, models.ErrIncomingWebhookInvalid
+	}
+	hm, err := newIncomingWebhookModel(&models.IncomingWebhook{Id: h.Id})
+	if err != nil {
+		return nil, err
+	}
+	found, err := p.handler.FindOne(hm)
+	if err != nil || found.ConversationId.Hex() != h.ConversationId {
+		return nil, models.ErrIncomingWebhookInvalid
+	}
+	found, err = p.handler.DeleteOne(&incomingWebhookModel{Id: found.Id})
+	if err != nil {
+		return nil, err
+	}
+	return found.toRoot(), nil
+}
+
+// IncomingWebhookVerify looks up the incoming webhook of a token and records when it was last used
+func (p *IncomingWebhookService) IncomingWebhookVerify(token string) (*models.IncomingWebhook, error) {
+	if token == "" {
+		return nil, models.ErrIncomingWebhookInvalid
+	}
+	hm, err := p.handler.FindOne(&incomingWebhookModel{TokenHash: hashAPIKey(token)})
+	if err != nil {
+		return nil, models.ErrIncomingWebhookInvalid
+	}
+	if time.Since(hm.LastUsedAt) >= apiKeyTouchInterval {
+		hm.LastUsedAt = time.Now().UTC()
+		if hm, err = p.handler.UpdateOne(&incomingWebhookModel{Id: hm.Id}, hm); err != nil {
+			return nil, err
+		}
+	}
+	return hm.toRoot(), nil
+}
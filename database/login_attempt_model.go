@@ -0,0 +1,167 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// loginAttemptModel structures a login attempt BSON document to save in a login_attempts collection
+type loginAttemptModel struct {
+	Id            primitive.ObjectID `bson:"_id,omitempty"`
+	Kind          string             `bson:"kind,omitempty"`
+	Key           string             `bson:"key,omitempty"`
+	Failures      int                `bson:"failures,omitempty"`
+	LockedUntil   time.Time          `bson:"locked_until,omitempty"`
+	LastFailureAt time.Time          `bson:"last_failure_at,omitempty"`
+	ExpiresAt     time.Time          `bson:"expires_at,omitempty"`
+	LastModified  time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty"`
+}
+
+// newLoginAttemptModel initializes a new pointer to a loginAttemptModel struct from a pointer to a JSON LoginAttempt struct
+func newLoginAttemptModel(l *models.LoginAttempt) (lm *loginAttemptModel, err error) {
+	lm = &loginAttemptModel{
+		Kind:          l.Kind,
+		Key:           l.Key,
+		Failures:      l.Failures,
+		LockedUntil:   l.LockedUntil,
+		LastFailureAt: l.LastFailureAt,
+		ExpiresAt:     l.ExpiresAt,
+		LastModified:  l.LastModified,
+		CreatedAt:     l.CreatedAt,
+	}
+	if l.Id != "" && l.Id != "000000000000000000000000" {
+		lm.Id, err = primitive.ObjectIDFromHex(l.Id)
+	}
+	return
+}
+
+// update the loginAttemptModel using an overwrite bson.D doc
+func (l *loginAttemptModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	lm := loginAttemptModel{}
+	err = bson.Unmarshal(data, &lm)
+	if lm.Failures > 0 {
+		l.Failures = lm.Failures
+	}
+	if !lm.LockedUntil.IsZero() {
+		l.LockedUntil = lm.LockedUntil
+	}
+	if !lm.LastFailureAt.IsZero() {
+		l.LastFailureAt = lm.LastFailureAt
+	}
+	if !lm.ExpiresAt.IsZero() {
+		l.ExpiresAt = lm.ExpiresAt
+	}
+	if !lm.LastModified.IsZero() {
+		l.LastModified = lm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the loginAttemptModel
+func (l *loginAttemptModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, l)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the loginAttemptModel
+func (l *loginAttemptModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	lm := loginAttemptModel{}
+	err = bson.Unmarshal(data, &lm)
+	if !lm.Id.IsZero() {
+		return l.Id == lm.Id
+	}
+	if lm.Key != "" {
+		return l.Kind == lm.Kind && l.Key == lm.Key
+	}
+	return false
+}
+
+// getID returns the unique identifier of the loginAttemptModel
+func (l *loginAttemptModel) getID() (id interface{}) {
+	return l.Id
+}
+
+// addTimeStamps updates a loginAttemptModel struct with a timestamp
+func (l *loginAttemptModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	l.LastModified = currentTime
+	if newRecord {
+		l.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a loginAttemptModel has a value assigned for Id, if no value a new one is generated and assigned
+func (l *loginAttemptModel) addObjectID() {
+	if l.Id.IsZero() {
+		l.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a loginAttemptModel struct postProcess
+func (l *loginAttemptModel) postProcess() (err error) {
+	if l.Key == "" {
+		err = errors.New("login attempt record does not have a key")
+	}
+	return
+}
+
+// toDoc converts the bson loginAttemptModel into a bson.D
+func (l *loginAttemptModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(l)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the loginAttemptModel data
+func (l *loginAttemptModel) bsonFilter() (doc bson.D, err error) {
+	if !l.Id.IsZero() {
+		doc = bson.D{{"_id", l.Id}}
+	} else if l.Key != "" {
+		doc = bson.D{{"kind", l.Kind}, {"key", l.Key}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the loginAttemptModel data
+func (l *loginAttemptModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := l.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a LoginAttempt JSON struct from a pointer to a BSON loginAttemptModel
+func (l *loginAttemptModel) toRoot() *models.LoginAttempt {
+	return &models.LoginAttempt{
+		Id:            l.Id.Hex(),
+		Kind:          l.Kind,
+		Key:           l.Key,
+		Failures:      l.Failures,
+		LockedUntil:   l.LockedUntil,
+		LastFailureAt: l.LastFailureAt,
+		ExpiresAt:     l.ExpiresAt,
+		LastModified:  l.LastModified,
+		CreatedAt:     l.CreatedAt,
+	}
+}
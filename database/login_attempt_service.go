@@ -0,0 +1,164 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+const (
+	// accountFreeAttempts and ipFreeAttempts are how many failed sign ins are allowed before lockouts start
+	accountFreeAttempts = 5
+	ipFreeAttempts      = 20
+	// maxLockout caps the exponential backoff between sign in attempts
+	maxLockout = 15 * time.Minute
+	// loginAttemptTTL is how long failed sign ins are remembered after the last one
+	loginAttemptTTL = 24 * time.Hour
+)
+
+// LoginAttemptService is used by the app to track failed sign ins and lock out brute-force attempts
+type LoginAttemptService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*loginAttemptModel]
+}
+
+// NewLoginAttemptService is an exported function used to initialize a new LoginAttemptService struct
+func NewLoginAttemptService(db DBClient, handler *DBHandler[*loginAttemptModel]) *LoginAttemptService {
+	collection := db.GetCollection("login_attempts")
+	return &LoginAttemptService{collection, db, handler}
+}
+
+// lockoutDuration returns how long sign ins are refused after a number of failures, doubling with every failure
+// past the free attempts
+func lockoutDuration(kind string, failures int) time.Duration {
+	free := accountFreeAttempts
+	if kind == models.LoginAttemptIP {
+		free = ipFreeAttempts
+	}
+	if failures < free {
+		return 0
+	}
+	exp := failures - free
+	if exp > 10 {
+		return maxLockout
+	}
+	lockout := time.Second << exp
+	if lockout > maxLockout {
+		return maxLockout
+	}
+	return lockout
+}
+
+// find looks up the failed sign ins of an account or address, returning nil without an error when there are none.
+// Records past their expiry that have not been removed by the TTL index yet are deleted.
+func (p *LoginAttemptService) find(l *models.LoginAttempt) (*loginAttemptModel, error) {
+	if l.Key == "" {
+		return nil, errors.New("missing the following login attempt fields: key")
+	}
+	lms, err := p.handler.FindMany(&loginAttemptModel{Kind: l.Kind, Key: l.Key})
+	if err != nil {
+		return nil, err
+	}
+	var found *loginAttemptModel
+	for _, lm := range lms {
+		if found == nil && time.Now().UTC().Before(lm.ExpiresAt) {
+			found = lm
+			continue
+		}
+		if _, err = p.handler.DeleteOne(&loginAttemptModel{Id: lm.Id}); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// LoginAttemptsLocked returns how long until sign ins are accepted again when any of the accounts or addresses is locked out
+func (p *LoginAttemptService) LoginAttemptsLocked(ls ...*models.LoginAttempt) (time.Duration, error) {
+	var retryAfter time.Duration
+	for _, l := range ls {
+		if l.Key == "" {
+			continue
+		}
+		lm, err := p.find(l)
+		if err != nil {
+			return 0, err
+		}
+		if lm == nil {
+			continue
+		}
+		if wait := time.Until(lm.LockedUntil); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	return retryAfter, nil
+}
+
+// LoginAttemptFailed records a failed sign in against an account or address, locking it out once it runs out of free attempts
+func (p *LoginAttemptService) LoginAttemptFailed(l *models.LoginAttempt) (*models.LoginAttempt, error) {
+	lm, err := p.find(l)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if lm == nil {
+		lm = &loginAttemptModel{Kind: l.Kind, Key: l.Key}
+	}
+	lm.Failures++
+	lm.LastFailureAt = now
+	lm.ExpiresAt = now.Add(loginAttemptTTL)
+	if lockout := lockoutDuration(lm.Kind, lm.Failures); lockout > 0 {
+		lm.LockedUntil = now.Add(lockout)
+	}
+	if lm.Id.IsZero() {
+		lm, err = p.handler.InsertOne(lm)
+	} else {
+		lm, err = p.handler.UpdateOne(&loginAttemptModel{Id: lm.Id}, lm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lm.toRoot(), nil
+}
+
+// LoginAttemptsClear forgets the failed sign ins of an account or address
+func (p *LoginAttemptService) LoginAttemptsClear(l *models.LoginAttempt) error {
+	lm, err := p.find(l)
+	if err != nil || lm == nil {
+		return err
+	}
+	_, err = p.handler.DeleteOne(&loginAttemptModel{Id: lm.Id})
+	return err
+}
+
+// LoginAttemptsFind lists the accounts and addresses that are currently locked out
+func (p *LoginAttemptService) LoginAttemptsFind() ([]*models.LoginAttempt, error) {
+	var attempts []*models.LoginAttempt
+	lms, err := p.handler.FindMany(&loginAttemptModel{})
+	if err != nil {
+		return attempts, err
+	}
+	now := time.Now().UTC()
+	for _, lm := range lms {
+		if now.Before(lm.LockedUntil) {
+			attempts = append(attempts, lm.toRoot())
+		}
+	}
+	return attempts, nil
+}
+
+// LoginAttemptDelete clears a lockout by its id
+func (p *LoginAttemptService) LoginAttemptDelete(l *models.LoginAttempt) (*models.LoginAttempt, error) {
+	lm, err := newLoginAttemptModel(&models.LoginAttempt{Id: l.Id})
+	if err != nil {
+		return nil, err
+	}
+	if lm.Id.IsZero() {
+		return nil, errors.New("missing the following login attempt fields: id")
+	}
+	lm, err = p.handler.DeleteOne(lm)
+	if err != nil {
+		return nil, err
+	}
+	return lm.toRoot(), nil
+}
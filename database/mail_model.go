@@ -0,0 +1,196 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// mailModel structures a queued email BSON document to save in a mail_outbox collection
+type mailModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	To           string             `bson:"to,omitempty"`
+	Subject      string             `bson:"subject,omitempty"`
+	Body         string             `bson:"body,omitempty"`
+	Link         *mailLinkModel     `bson:"link,omitempty"`
+	Status       string             `bson:"status,omitempty"`
+	Attempts     int                `bson:"attempts,omitempty"`
+	LastError    string             `bson:"last_error,omitempty"`
+	SentAt       time.Time          `bson:"sent_at,omitempty"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// mailLinkModel structures the action link of a queued email, stored instead of its token
+type mailLinkModel struct {
+	Path      string             `bson:"path,omitempty"`
+	UserId    primitive.ObjectID `bson:"user_id,omitempty"`
+	Action    string             `bson:"action,omitempty"`
+	Check     string             `bson:"check,omitempty"`
+	ExpiresAt time.Time          `bson:"expires_at,omitempty"`
+}
+
+// newMailModel initializes a new pointer to a mailModel struct from a pointer to a JSON Mail struct
+func newMailModel(m *models.Mail) (mm *mailModel, err error) {
+	mm = &mailModel{
+		To:           m.To,
+		Subject:      m.Subject,
+		Body:         m.Body,
+		Status:       m.Status,
+		Attempts:     m.Attempts,
+		LastError:    m.LastError,
+		SentAt:       m.SentAt,
+		LastModified: m.LastModified,
+		CreatedAt:    m.CreatedAt,
+	}
+	if m.Id != "" && m.Id != "000000000000000000000000" {
+		mm.Id, err = primitive.ObjectIDFromHex(m.Id)
+		if err != nil {
+			return
+		}
+	}
+	if m.Link != nil {
+		mm.Link = &mailLinkModel{Path: m.Link.Path, Action: m.Link.Action, Check: m.Link.Check, ExpiresAt: m.Link.ExpiresAt}
+		mm.Link.UserId, err = primitive.ObjectIDFromHex(m.Link.UserId)
+	}
+	return
+}
+
+// update the mailModel using an overwrite bson.D doc
+func (m *mailModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	mm := mailModel{}
+	err = bson.Unmarshal(data, &mm)
+	if len(mm.Status) > 0 {
+		m.Status = mm.Status
+	}
+	if mm.Attempts > 0 {
+		m.Attempts = mm.Attempts
+	}
+	if len(mm.LastError) > 0 {
+		m.LastError = mm.LastError
+	}
+	if !mm.SentAt.IsZero() {
+		m.SentAt = mm.SentAt
+	}
+	if !mm.LastModified.IsZero() {
+		m.LastModified = mm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the mailModel
+func (m *mailModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, m)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the mailModel
+func (m *mailModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	mm := mailModel{}
+	err = bson.Unmarshal(data, &mm)
+	if !mm.Id.IsZero() {
+		return m.Id == mm.Id
+	}
+	if mm.Status != "" {
+		return m.Status == mm.Status
+	}
+	return false
+}
+
+// getID returns the unique identifier of the mailModel
+func (m *mailModel) getID() (id interface{}) {
+	return m.Id
+}
+
+// addTimeStamps updates a mailModel struct with a timestamp
+func (m *mailModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	m.LastModified = currentTime
+	if newRecord {
+		m.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a mailModel has a value assigned for Id, if no value a new one is generated and assigned
+func (m *mailModel) addObjectID() {
+	if m.Id.IsZero() {
+		m.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a mailModel struct postProcess
+func (m *mailModel) postProcess() (err error) {
+	if m.To == "" {
+		err = errors.New("mail record does not have a recipient")
+	}
+	return
+}
+
+// toDoc converts the bson mailModel into a bson.D
+func (m *mailModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(m)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the mailModel data
+func (m *mailModel) bsonFilter() (doc bson.D, err error) {
+	if !m.Id.IsZero() {
+		doc = bson.D{{"_id", m.Id}}
+	} else if m.Status != "" {
+		doc = bson.D{{"status", m.Status}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the mailModel data
+func (m *mailModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := m.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a Mail JSON struct from a pointer to a BSON mailModel
+func (m *mailModel) toRoot() *models.Mail {
+	return &models.Mail{
+		Id:           m.Id.Hex(),
+		To:           m.To,
+		Subject:      m.Subject,
+		Body:         m.Body,
+		Link:         m.Link.toRoot(),
+		Status:       m.Status,
+		Attempts:     m.Attempts,
+		LastError:    m.LastError,
+		SentAt:       m.SentAt,
+		LastModified: m.LastModified,
+		CreatedAt:    m.CreatedAt,
+	}
+}
+
+// toRoot creates and return a new pointer to a MailLink JSON struct from a pointer to a BSON mailLinkModel
+func (l *mailLinkModel) toRoot() *models.MailLink {
+	if l == nil {
+		return nil
+	}
+	return &models.MailLink{Path: l.Path, UserId: l.UserId.Hex(), Action: l.Action, Check: l.Check, ExpiresAt: l.ExpiresAt}
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/mailer"
+	"github.com/ablancas22/messenger-backend/models"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// mailMaxAttempts is how many times an email is tried before it is marked as failed
+const mailMaxAttempts = 5
+
+// MailService is used by the app to manage the mail outbox
+type MailService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*mailModel]
+	mailer     mailer.Mailer
+}
+
+// NewMailService is an exported function used to initialize a new MailService struct
+func NewMailService(db DBClient, handler *DBHandler[*mailModel], m mailer.Mailer) *MailService {
+	collection := db.GetCollection("mail_outbox")
+	return &MailService{collection, db, handler, m}
+}
+
+// errMailLinkExpired is recorded on a queued email whose action link expired before it could be sent
+var errMailLinkExpired = errors.New("the link of the email expired before it was sent")
+
+// render returns the body of a queued email with its action link, whose token is created now rather than stored
+func (p *MailService) render(mm *mailModel) (string, error) {
+	if mm.Link == nil {
+		return mm.Body, nil
+	}
+	if !time.Now().Before(mm.Link.ExpiresAt) {
+		return "", errMailLinkExpired
+	}
+	t := &auth.ActionToken{UserId: mm.Link.UserId.Hex(), Action: mm.Link.Action, Check: mm.Link.Check}
+	token, err := t.CreateToken(mm.Link.ExpiresAt.Unix())
+	if err != nil {
+		return "", err
+	}
+	link := strings.TrimRight(os.Getenv("APP_URL"), "/") + mm.Link.Path + "?token=" + url.QueryEscape(token)
+	return strings.Replace(mm.Body, models.MailLinkPlaceholder, link, 1), nil
+}
+
+// deliver hands a queued email to the mailer and records the outcome of the attempt on the mailModel
+func (p *MailService) deliver(mm *mailModel) error {
+	mm.Attempts++
+	body, err := p.render(mm)
+	if err != nil {
+		mm.LastError = err.Error()
+		mm.Status = models.MailFailed
+		return err
+	}
+	err = p.mailer.Send(&mailer.Message{To: mm.To, Subject: mm.Subject, Body: body})
+	if err != nil {
+		mm.LastError = err.Error()
+		mm.Status = models.MailPending
+		if mm.Attempts >= mailMaxAttempts {
+			mm.Status = models.MailFailed
+		}
+		return err
+	}
+	mm.Status = models.MailSent
+	mm.SentAt = time.Now().UTC()
+	return nil
+}
+
+// MailQueue records an email in the outbox for MailDeliverPending to send. Nothing is sent while a request is
+// handled, so how long it takes does not depend on the mailer.
+func (p *MailService) MailQueue(m *models.Mail) (*models.Mail, error) {
+	err := m.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	mm, err := newMailModel(&models.Mail{To: m.To, Subject: m.Subject, Body: m.Body, Link: m.Link, Status: models.MailPending})
+	if err != nil {
+		return nil, err
+	}
+	mm, err = p.handler.InsertOne(mm)
+	if err != nil {
+		return nil, err
+	}
+	return mm.toRoot(), nil
+}
+
+// MailDeliverPending sends the emails in the outbox that have not been sent yet, returning how many were sent
+func (p *MailService) MailDeliverPending() (int, error) {
+	mms, err := p.handler.FindMany(&mailModel{Status: models.MailPending})
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, mm := range mms {
+		if p.deliver(mm) == nil {
+			sent++
+		}
+		if _, err = p.handler.UpdateOne(&mailModel{Id: mm.Id}, mm); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
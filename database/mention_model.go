@@ -0,0 +1,166 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// mentionModel structures a mention BSON document to save in a mentions collection
+type mentionModel struct {
+	Id             primitive.ObjectID `bson:"_id,omitempty"`
+	MessageId      primitive.ObjectID `bson:"message_id,omitempty"`
+	UserId         primitive.ObjectID `bson:"user_id,omitempty"`
+	SenderId       primitive.ObjectID `bson:"sender_id,omitempty"`
+	ConversationId primitive.ObjectID `bson:"conversation_id,omitempty"`
+	GroupId        primitive.ObjectID `bson:"group_id,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at,omitempty"`
+}
+
+// newMentionModel initializes a new pointer to a mentionModel struct from a pointer to a JSON Mention struct
+func newMentionModel(m *models.Mention) (mm *mentionModel, err error) {
+	mm = &mentionModel{CreatedAt: m.CreatedAt}
+	if m.Id != "" && m.Id != "000000000000000000000000" {
+		if mm.Id, err = primitive.ObjectIDFromHex(m.Id); err != nil {
+			return
+		}
+	}
+	if m.MessageId != "" && m.MessageId != "000000000000000000000000" {
+		if mm.MessageId, err = primitive.ObjectIDFromHex(m.MessageId); err != nil {
+			return
+		}
+	}
+	if m.UserId != "" && m.UserId != "000000000000000000000000" {
+		if mm.UserId, err = primitive.ObjectIDFromHex(m.UserId); err != nil {
+			return
+		}
+	}
+	if m.SenderId != "" && m.SenderId != "000000000000000000000000" {
+		if mm.SenderId, err = primitive.ObjectIDFromHex(m.SenderId); err != nil {
+			return
+		}
+	}
+	if m.ConversationId != "" && m.ConversationId != "000000000000000000000000" {
+		if mm.ConversationId, err = primitive.ObjectIDFromHex(m.ConversationId); err != nil {
+			return
+		}
+	}
+	if m.GroupId != "" && m.GroupId != "000000000000000000000000" {
+		mm.GroupId, err = primitive.ObjectIDFromHex(m.GroupId)
+	}
+	return
+}
+
+// update the mentionModel using an overwrite bson.D doc, mentions are never changed once recorded
+func (m *mentionModel) update(doc interface{}) (err error) {
+	return
+}
+
+// bsonLoad loads a bson doc into the mentionModel
+func (m *mentionModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, m)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the mentionModel
+func (m *mentionModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	mm := mentionModel{}
+	err = bson.Unmarshal(data, &mm)
+	if !mm.Id.IsZero() {
+		return m.Id == mm.Id
+	}
+	if !mm.UserId.IsZero() {
+		return m.UserId == mm.UserId
+	}
+	if !mm.MessageId.IsZero() {
+		return m.MessageId == mm.MessageId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the mentionModel
+func (m *mentionModel) getID() (id interface{}) {
+	return m.Id
+}
+
+// addTimeStamps updates a mentionModel struct with a timestamp
+func (m *mentionModel) addTimeStamps(newRecord bool) {
+	if newRecord && m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+}
+
+// addObjectID checks if a mentionModel has a value assigned for Id, if no value a new one is generated and assigned
+func (m *mentionModel) addObjectID() {
+	if m.Id.IsZero() {
+		m.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a mentionModel struct postProcess
+func (m *mentionModel) postProcess() (err error) {
+	if m.UserId.IsZero() {
+		err = errors.New("mention record does not have a user_id")
+	}
+	return
+}
+
+// toDoc converts the bson mentionModel into a bson.D
+func (m *mentionModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(m)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the mentionModel data
+func (m *mentionModel) bsonFilter() (doc bson.D, err error) {
+	if !m.Id.IsZero() {
+		doc = bson.D{{"_id", m.Id}}
+	} else if !m.UserId.IsZero() {
+		doc = bson.D{{"user_id", m.UserId}}
+	} else if !m.MessageId.IsZero() {
+		doc = bson.D{{"message_id", m.MessageId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the mentionModel data
+func (m *mentionModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := m.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a Mention JSON struct from a pointer to a BSON mentionModel
+func (m *mentionModel) toRoot() *models.Mention {
+	mention := &models.Mention{
+		Id:        m.Id.Hex(),
+		MessageId: m.MessageId.Hex(),
+		UserId:    m.UserId.Hex(),
+		SenderId:  m.SenderId.Hex(),
+		CreatedAt: m.CreatedAt,
+	}
+	if !m.ConversationId.IsZero() {
+		mention.ConversationId = m.ConversationId.Hex()
+	}
+	if !m.GroupId.IsZero() {
+		mention.GroupId = m.GroupId.Hex()
+	}
+	return mention
+}
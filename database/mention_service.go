@@ -0,0 +1,60 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"sort"
+)
+
+// MentionService is used by the app to record which users the messages mention
+type MentionService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*mentionModel]
+}
+
+// NewMentionService is an exported function used to initialize a new MentionService struct
+func NewMentionService(db DBClient, handler *DBHandler[*mentionModel]) *MentionService {
+	collection := db.GetCollection("mentions")
+	return &MentionService{collection, db, handler}
+}
+
+// MentionCreate records that a message mentions a user
+func (p *MentionService) MentionCreate(m *models.Mention) (*models.Mention, error) {
+	err := m.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	mm, err := newMentionModel(m)
+	if err != nil {
+		return nil, err
+	}
+	mm, err = p.handler.InsertOne(mm)
+	if err != nil {
+		return nil, err
+	}
+	return mm.toRoot(), nil
+}
+
+// MentionsFind lists the mentions of a user, newest first
+func (p *MentionService) MentionsFind(m *models.Mention) ([]*models.Mention, error) {
+	mentions := []*models.Mention{}
+	if !m.CheckID("user_id") {
+		return mentions, errors.New("missing the following mention fields: user_id")
+	}
+	mm, err := newMentionModel(&models.Mention{UserId: m.UserId})
+	if err != nil {
+		return mentions, err
+	}
+	mms, err := p.handler.FindMany(mm)
+	if err != nil {
+		return mentions, err
+	}
+	for _, mm = range mms {
+		mentions = append(mentions, mm.toRoot())
+	}
+	sort.Slice(mentions, func(i, j int) bool {
+		return mentions[i].CreatedAt.After(mentions[j].CreatedAt)
+	})
+	return mentions, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// messageExpiryModel structures a message expiry BSON document to save in a message_expiries collection
+type messageExpiryModel struct {
+	Id             primitive.ObjectID `bson:"_id,omitempty"`
+	ConversationId primitive.ObjectID `bson:"conversation_id,omitempty"`
+	ExpiresAt      time.Time          `bson:"expires_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at,omitempty"`
+}
+
+// newMessageExpiryModel initializes a new pointer to a messageExpiryModel struct from a pointer to a JSON MessageExpiry struct
+func newMessageExpiryModel(e *models.MessageExpiry) (em *messageExpiryModel, err error) {
+	em = &messageExpiryModel{ExpiresAt: e.ExpiresAt, CreatedAt: e.CreatedAt}
+	if e.Id != "" && e.Id != "000000000000000000000000" {
+		if em.Id, err = primitive.ObjectIDFromHex(e.Id); err != nil {
+			return
+		}
+	}
+	if e.ConversationId != "" && e.ConversationId != "000000000000000000000000" {
+		em.ConversationId, err = primitive.ObjectIDFromHex(e.ConversationId)
+	}
+	return
+}
+
+// update the messageExpiryModel using an overwrite bson.D doc, message expiries are never changed once recorded
+func (e *messageExpiryModel) update(doc interface{}) (err error) {
+	return
+}
+
+// bsonLoad loads a bson doc into the messageExpiryModel
+func (e *messageExpiryModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, e)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the messageExpiryModel
+func (e *messageExpiryModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	em := messageExpiryModel{}
+	err = bson.Unmarshal(data, &em)
+	if !em.Id.IsZero() {
+		return e.Id == em.Id
+	}
+	if !em.ConversationId.IsZero() {
+		return e.ConversationId == em.ConversationId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the messageExpiryModel
+func (e *messageExpiryModel) getID() (id interface{}) {
+	return e.Id
+}
+
+// addTimeStamps updates a messageExpiryModel struct with a timestamp
+func (e *messageExpiryModel) addTimeStamps(newRecord bool) {
+	if newRecord && e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+}
+
+// addObjectID checks if a messageExpiryModel has a value assigned for Id, if no value a new one is generated and assigned
+func (e *messageExpiryModel) addObjectID() {
+	if e.Id.IsZero() {
+		e.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a messageExpiryModel struct postProcess
+func (e *messageExpiryModel) postProcess() (err error) {
+	if e.ExpiresAt.IsZero() {
+		err = errors.New("message expiry record does not have an expires_at")
+	}
+	return
+}
+
+// toDoc converts the bson messageExpiryModel into a bson.D
+func (e *messageExpiryModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(e)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the messageExpiryModel data
+func (e *messageExpiryModel) bsonFilter() (doc bson.D, err error) {
+	if !e.Id.IsZero() {
+		doc = bson.D{{"_id", e.Id}}
+	} else if !e.ConversationId.IsZero() {
+		doc = bson.D{{"conversation_id", e.ConversationId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the messageExpiryModel data
+func (e *messageExpiryModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := e.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a MessageExpiry JSON struct from a pointer to a BSON messageExpiryModel
+func (e *messageExpiryModel) toRoot() *models.MessageExpiry {
+	expiry := &models.MessageExpiry{
+		Id:        e.Id.Hex(),
+		ExpiresAt: e.ExpiresAt,
+		CreatedAt: e.CreatedAt,
+	}
+	if !e.ConversationId.IsZero() {
+		expiry.ConversationId = e.ConversationId.Hex()
+	}
+	return expiry
+}
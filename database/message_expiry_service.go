@@ -0,0 +1,73 @@
+package database
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sort"
+	"time"
+)
+
+// MessageExpiryService is used by the app to track when the messages sent with disappearing messages on expire
+type MessageExpiryService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*messageExpiryModel]
+}
+
+// NewMessageExpiryService is an exported function used to initialize a new MessageExpiryService struct
+func NewMessageExpiryService(db DBClient, handler *DBHandler[*messageExpiryModel]) *MessageExpiryService {
+	collection := db.GetCollection("message_expiries")
+	return &MessageExpiryService{collection, db, handler}
+}
+
+// MessageExpiryCreate records when a message expires
+func (p *MessageExpiryService) MessageExpiryCreate(e *models.MessageExpiry) (*models.MessageExpiry, error) {
+	err := e.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	em, err := newMessageExpiryModel(&models.MessageExpiry{Id: e.Id, ConversationId: e.ConversationId, ExpiresAt: e.ExpiresAt.UTC()})
+	if err != nil {
+		return nil, err
+	}
+	em, err = p.handler.InsertOne(em)
+	if mongo.IsDuplicateKeyError(err) {
+		// the message is created again, such as a scheduled message whose delivery is retried
+		em, err = p.handler.FindOne(&messageExpiryModel{Id: em.Id})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return em.toRoot(), nil
+}
+
+// MessageExpiriesDue lists the message expiries that have passed at t, oldest first
+func (p *MessageExpiryService) MessageExpiriesDue(t time.Time) ([]*models.MessageExpiry, error) {
+	due := []*models.MessageExpiry{}
+	ems, err := p.handler.FindMany(&messageExpiryModel{})
+	if err != nil {
+		return due, err
+	}
+	for _, em := range ems {
+		if e := em.toRoot(); e.Expired(t) {
+			due = append(due, e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].ExpiresAt.Before(due[j].ExpiresAt)
+	})
+	return due, nil
+}
+
+// MessageExpiryDelete removes the expiry of a message once the message is gone
+func (p *MessageExpiryService) MessageExpiryDelete(e *models.MessageExpiry) (*models.MessageExpiry, error) {
+	em, err := newMessageExpiryModel(&models.MessageExpiry{Id: e.Id})
+	if err != nil {
+		return nil, err
+	}
+	em, err = p.handler.DeleteOne(em)
+	if err != nil {
+		return nil, err
+	}
+	return em.toRoot(), nil
+}
@@ -18,6 +18,8 @@ type messageModel struct {
 	ContentType    string             `bson:"content_type,omitempty"`
 	Group          bool               `bson:"group,omitempty"`
 	FileIds        string             `bson:"file_ids"`
+	Mentions       *mentionsModel     `bson:"mentions,omitempty"`
+	ExpiresAt      time.Time          `bson:"expires_at,omitempty"`
 	UpdatedAt      time.Time          `bson:"updated_at,omitempty"`
 	CreatedAt      time.Time          `bson:"created_at,omitempty"`
 	DeletedAt      time.Time          `bson:"deleted_at,omitempty"`
@@ -30,6 +32,8 @@ func newMessageModel(u *models.Message) (um *messageModel, err error) {
 		Content:     u.Content,
 		ContentType: u.ContentType,
 		Group:       u.Group,
+		FileIds:     u.FileIds,
+		ExpiresAt:   u.ExpiresAt,
 		UpdatedAt:   u.UpdatedAt,
 		CreatedAt:   u.CreatedAt,
 		DeletedAt:   u.DeletedAt,
@@ -37,15 +41,51 @@ func newMessageModel(u *models.Message) (um *messageModel, err error) {
 	if u.Id != "" && u.Id != "000000000000000000000000" {
 		um.Id, err = primitive.ObjectIDFromHex(u.Id)
 	}
+	if u.ConversationID != "" && u.ConversationID != "000000000000000000000000" {
+		um.ConversationId, err = primitive.ObjectIDFromHex(u.ConversationID)
+	}
 	if u.SenderID != "" && u.SenderID != "000000000000000000000000" {
 		um.SenderId, err = primitive.ObjectIDFromHex(u.SenderID)
 	}
 	if u.ReceiverID != "" && u.ReceiverID != "000000000000000000000000" {
 		um.ReceiverId, err = primitive.ObjectIDFromHex(u.ReceiverID)
 	}
+	if u.Mentions != nil {
+		um.Mentions, err = newMentionsModel(u.Mentions)
+	}
 	return
 }
 
+// mentionsModel structures the mentions of a message BSON document
+type mentionsModel struct {
+	UserIds   []primitive.ObjectID `bson:"user_ids,omitempty"`
+	Usernames []string             `bson:"usernames,omitempty"`
+	Here      bool                 `bson:"here,omitempty"`
+	All       bool                 `bson:"all,omitempty"`
+}
+
+// newMentionsModel initializes a new pointer to a mentionsModel struct from a pointer to a JSON Mentions struct
+func newMentionsModel(m *models.Mentions) (mm *mentionsModel, err error) {
+	mm = &mentionsModel{Usernames: m.Usernames, Here: m.Here, All: m.All}
+	for _, id := range m.UserIds {
+		oId, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return mm, err
+		}
+		mm.UserIds = append(mm.UserIds, oId)
+	}
+	return
+}
+
+// toRoot creates and return a new pointer to a Mentions JSON struct from a pointer to a BSON mentionsModel
+func (m *mentionsModel) toRoot() *models.Mentions {
+	mentions := &models.Mentions{Usernames: m.Usernames, Here: m.Here, All: m.All}
+	for _, id := range m.UserIds {
+		mentions.UserIds = append(mentions.UserIds, id.Hex())
+	}
+	return mentions
+}
+
 // update the userModel using an overwrite bson.D doc
 func (u *messageModel) update(doc interface{}) (err error) {
 	data, err := bsonMarshall(doc)
@@ -54,6 +94,9 @@ func (u *messageModel) update(doc interface{}) (err error) {
 	}
 	um := messageModel{}
 	err = bson.Unmarshal(data, &um)
+	if len(um.Content) > 0 {
+		u.Content = um.Content
+	}
 	if !um.UpdatedAt.IsZero() {
 		u.UpdatedAt = um.UpdatedAt
 	}
@@ -165,15 +208,24 @@ func (u *messageModel) bsonUpdate() (doc bson.D, err error) {
 
 // toRoot creates and return a new pointer to a User JSON struct from a pointer to a BSON userModel
 func (u *messageModel) toRoot() *models.Message {
-	return &models.Message{
+	m := &models.Message{
 		Id:          u.Id.Hex(),
 		SenderID:    u.SenderId.Hex(),
 		ReceiverID:  u.ReceiverId.Hex(),
 		Content:     u.Content,
 		ContentType: u.ContentType,
 		Group:       u.Group,
+		FileIds:     u.FileIds,
+		ExpiresAt:   u.ExpiresAt,
 		UpdatedAt:   u.UpdatedAt,
 		CreatedAt:   u.CreatedAt,
 		DeletedAt:   u.DeletedAt,
 	}
+	if !u.ConversationId.IsZero() {
+		m.ConversationID = u.ConversationId.Hex()
+	}
+	if u.Mentions != nil {
+		m.Mentions = u.Mentions.toRoot()
+	}
+	return m
 }
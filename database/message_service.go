@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"time"
 )
 
@@ -109,7 +111,9 @@ func (p *MessageService) MessageCreate(g *models.Message) (*models.Message, erro
 		return nil, err
 	}
 	gm, err = p.messageHandler.InsertOne(gm)
-	if err != nil {
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, models.ErrMessageExists
+	} else if err != nil {
 		return nil, err
 	}
 	return gm.toRoot(), err
@@ -145,6 +149,30 @@ func (p *MessageService) MessageFind(g *models.Message) (*models.Message, error)
 	return gm.toRoot(), err
 }
 
+// MessageUpdate is used to edit the content of a message
+func (p *MessageService) MessageUpdate(g *models.Message) (*models.Message, error) {
+	if g.Content == "" {
+		return nil, errors.New("missing the following message fields: content")
+	}
+	cur, err := p.MessageFind(&models.Message{Id: g.Id})
+	if err != nil {
+		return nil, err
+	}
+	gm, err := newMessageModel(cur)
+	if err != nil {
+		return nil, err
+	}
+	cur.Content = g.Content
+	cur.UpdatedAt = time.Now().UTC()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = p.collection.UpdateOne(ctx, bson.D{{"_id", gm.Id}}, bson.D{{"$set", bson.D{{"content", cur.Content}, {"updated_at", cur.UpdatedAt}}}})
+	if err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
 // Message delete is used to delete a Task doc
 func (p *MessageService) MessageDelete(g *models.Message) (*models.Message, error) {
 	gm, err := newMessageModel(g)
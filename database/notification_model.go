@@ -0,0 +1,202 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// notificationModel structures a queued push notification BSON document to save in a notification_outbox collection
+type notificationModel struct {
+	Id            primitive.ObjectID `bson:"_id,omitempty"`
+	UserId        primitive.ObjectID `bson:"user_id,omitempty"`
+	DeviceId      primitive.ObjectID `bson:"device_id,omitempty"`
+	MessageId     primitive.ObjectID `bson:"message_id,omitempty"`
+	Title         string             `bson:"title,omitempty"`
+	Body          string             `bson:"body,omitempty"`
+	Status        string             `bson:"status,omitempty"`
+	Attempts      int                `bson:"attempts,omitempty"`
+	LastError     string             `bson:"last_error,omitempty"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at,omitempty"`
+	SentAt        time.Time          `bson:"sent_at,omitempty"`
+	LastModified  time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty"`
+}
+
+// newNotificationModel initializes a new pointer to a notificationModel struct from a pointer to a JSON Notification struct
+func newNotificationModel(n *models.Notification) (nm *notificationModel, err error) {
+	nm = &notificationModel{
+		Title:         n.Title,
+		Body:          n.Body,
+		Status:        n.Status,
+		Attempts:      n.Attempts,
+		LastError:     n.LastError,
+		NextAttemptAt: n.NextAttemptAt,
+		SentAt:        n.SentAt,
+		LastModified:  n.LastModified,
+		CreatedAt:     n.CreatedAt,
+	}
+	if n.Id != "" && n.Id != "000000000000000000000000" {
+		if nm.Id, err = primitive.ObjectIDFromHex(n.Id); err != nil {
+			return
+		}
+	}
+	if n.UserId != "" && n.UserId != "000000000000000000000000" {
+		if nm.UserId, err = primitive.ObjectIDFromHex(n.UserId); err != nil {
+			return
+		}
+	}
+	if n.DeviceId != "" && n.DeviceId != "000000000000000000000000" {
+		if nm.DeviceId, err = primitive.ObjectIDFromHex(n.DeviceId); err != nil {
+			return
+		}
+	}
+	if n.MessageId != "" && n.MessageId != "000000000000000000000000" {
+		nm.MessageId, err = primitive.ObjectIDFromHex(n.MessageId)
+	}
+	return
+}
+
+// update the notificationModel using an overwrite bson.D doc
+func (n *notificationModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	nm := notificationModel{}
+	err = bson.Unmarshal(data, &nm)
+	if len(nm.Status) > 0 {
+		n.Status = nm.Status
+	}
+	if nm.Attempts > 0 {
+		n.Attempts = nm.Attempts
+	}
+	if len(nm.LastError) > 0 {
+		n.LastError = nm.LastError
+	}
+	if !nm.NextAttemptAt.IsZero() {
+		n.NextAttemptAt = nm.NextAttemptAt
+	}
+	if !nm.SentAt.IsZero() {
+		n.SentAt = nm.SentAt
+	}
+	if !nm.LastModified.IsZero() {
+		n.LastModified = nm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the notificationModel
+func (n *notificationModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, n)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the notificationModel
+func (n *notificationModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	nm := notificationModel{}
+	err = bson.Unmarshal(data, &nm)
+	if !nm.Id.IsZero() {
+		return n.Id == nm.Id
+	}
+	if !nm.UserId.IsZero() {
+		return n.UserId == nm.UserId
+	}
+	if nm.Status != "" {
+		return n.Status == nm.Status
+	}
+	return false
+}
+
+// getID returns the unique identifier of the notificationModel
+func (n *notificationModel) getID() (id interface{}) {
+	return n.Id
+}
+
+// addTimeStamps updates a notificationModel struct with a timestamp
+func (n *notificationModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	n.LastModified = currentTime
+	if newRecord {
+		n.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a notificationModel has a value assigned for Id, if no value a new one is generated and assigned
+func (n *notificationModel) addObjectID() {
+	if n.Id.IsZero() {
+		n.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a notificationModel struct postProcess
+func (n *notificationModel) postProcess() (err error) {
+	if n.DeviceId.IsZero() {
+		err = errors.New("notification record does not have a device")
+	}
+	return
+}
+
+// toDoc converts the bson notificationModel into a bson.D
+func (n *notificationModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(n)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the notificationModel data
+func (n *notificationModel) bsonFilter() (doc bson.D, err error) {
+	if !n.Id.IsZero() {
+		doc = bson.D{{"_id", n.Id}}
+	} else if !n.UserId.IsZero() {
+		doc = bson.D{{"user_id", n.UserId}}
+	} else if n.Status != "" {
+		doc = bson.D{{"status", n.Status}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the notificationModel data
+func (n *notificationModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := n.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a Notification JSON struct from a pointer to a BSON notificationModel
+func (n *notificationModel) toRoot() *models.Notification {
+	notification := &models.Notification{
+		Id:            n.Id.Hex(),
+		UserId:        n.UserId.Hex(),
+		DeviceId:      n.DeviceId.Hex(),
+		Title:         n.Title,
+		Body:          n.Body,
+		Status:        n.Status,
+		Attempts:      n.Attempts,
+		LastError:     n.LastError,
+		NextAttemptAt: n.NextAttemptAt,
+		SentAt:        n.SentAt,
+		LastModified:  n.LastModified,
+		CreatedAt:     n.CreatedAt,
+	}
+	if !n.MessageId.IsZero() {
+		notification.MessageId = n.MessageId.Hex()
+	}
+	return notification
+}
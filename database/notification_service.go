@@ -0,0 +1,238 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/push"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"time"
+)
+
+const (
+	// notificationMaxAttempts is how many times a push notification is tried before it is marked as failed
+	notificationMaxAttempts = 5
+	// defaultNotificationRetryBackoff is the delay before the first retry of a notification, it doubles with every attempt
+	defaultNotificationRetryBackoff = 30 * time.Second
+	// maxNotificationRetryBackoff caps the delay between the retries of a notification
+	maxNotificationRetryBackoff = time.Hour
+	// notificationLease is how long a claimed notification is left to the attempt that claimed it, it can be claimed
+	// again once the lease ran out in case that attempt never finished
+	notificationLease = time.Minute
+)
+
+// notificationRetryBackoff returns the delay before retrying a notification that has been attempted n times,
+// the first delay is configured with NOTIFICATION_RETRY_BACKOFF
+func notificationRetryBackoff(n int) time.Duration {
+	backoff, err := time.ParseDuration(os.Getenv("NOTIFICATION_RETRY_BACKOFF"))
+	if err != nil || backoff <= 0 {
+		backoff = defaultNotificationRetryBackoff
+	}
+	for i := 1; i < n && backoff < maxNotificationRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxNotificationRetryBackoff {
+		backoff = maxNotificationRetryBackoff
+	}
+	return backoff
+}
+
+// NotificationService is used by the app to manage the push notification outbox
+type NotificationService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*notificationModel]
+	dService   *DeviceService
+	provider   push.Provider
+}
+
+// NewNotificationService is an exported function used to initialize a new NotificationService struct
+func NewNotificationService(db DBClient, handler *DBHandler[*notificationModel], dService *DeviceService, p push.Provider) *NotificationService {
+	collection := db.GetCollection("notification_outbox")
+	return &NotificationService{collection: collection, db: db, handler: handler, dService: dService, provider: p}
+}
+
+// NotificationQueue queues a notification for every device of its user in the outbox, returning the notifications
+// that were queued. Notifications are queued before they are attempted so that a restart never loses one.
+func (p *NotificationService) NotificationQueue(n *models.Notification) ([]*models.Notification, error) {
+	var queued []*models.Notification
+	err := n.Validate("create")
+	if err != nil {
+		return queued, err
+	}
+	devices, err := p.dService.DevicesFind(&models.Device{UserId: n.UserId})
+	if err != nil {
+		return queued, err
+	}
+	for _, d := range devices {
+		nm, err := newNotificationModel(&models.Notification{
+			UserId:        n.UserId,
+			DeviceId:      d.Id,
+			MessageId:     n.MessageId,
+			Title:         n.Title,
+			Body:          n.Body,
+			Status:        models.NotificationPending,
+			NextAttemptAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return queued, err
+		}
+		if nm, err = p.handler.InsertOne(nm); err != nil {
+			return queued, err
+		}
+		queued = append(queued, nm.toRoot())
+	}
+	return queued, nil
+}
+
+// NotificationsFind lists the notifications queued for a user
+func (p *NotificationService) NotificationsFind(n *models.Notification) ([]*models.Notification, error) {
+	notifications := []*models.Notification{}
+	if !n.CheckID("user_id") {
+		return notifications, errors.New("missing the following notification fields: user_id")
+	}
+	nm, err := newNotificationModel(&models.Notification{UserId: n.UserId})
+	if err != nil {
+		return notifications, err
+	}
+	nms, err := p.handler.FindMany(nm)
+	if err != nil {
+		return notifications, err
+	}
+	for _, nm = range nms {
+		notifications = append(notifications, nm.toRoot())
+	}
+	return notifications, nil
+}
+
+// deliver hands a queued notification to the push provider and records the outcome of the attempt on the
+// notificationModel, devices whose token the push service no longer accepts are unregistered
+func (p *NotificationService) deliver(nm *notificationModel) error {
+	nm.Attempts++
+	d, err := p.dService.DeviceFind(&models.Device{Id: nm.DeviceId.Hex()})
+	if err != nil {
+		nm.LastError = err.Error()
+		nm.Status = models.NotificationFailed
+		return err
+	}
+	n := &push.Notification{
+		Platform:   d.Platform,
+		Token:      d.Token,
+		PublicKey:  d.PublicKey,
+		AuthSecret: d.AuthSecret,
+		Title:      nm.Title,
+		Body:       nm.Body,
+	}
+	if !nm.MessageId.IsZero() {
+		n.Data = map[string]string{"message_id": nm.MessageId.Hex()}
+	}
+	err = p.provider.Send(n)
+	switch {
+	case err == nil:
+		nm.Status = models.NotificationSent
+		nm.SentAt = time.Now().UTC()
+		return nil
+	case errors.Is(err, push.ErrUnregistered):
+		nm.Status = models.NotificationFailed
+		_, _ = p.dService.DeviceDelete(d)
+	case errors.Is(err, push.ErrUnsupported):
+		nm.Status = models.NotificationFailed
+	default:
+		nm.Status = models.NotificationPending
+		nm.NextAttemptAt = time.Now().UTC().Add(notificationRetryBackoff(nm.Attempts))
+		if nm.Attempts >= notificationMaxAttempts {
+			nm.Status = models.NotificationFailed
+		}
+	}
+	nm.LastError = err.Error()
+	return err
+}
+
+// claim marks a due notification as sending until its lease runs out, returning nil when it is not due or another
+// attempt claimed it first. Notifications whose lease ran out are due again.
+func (p *NotificationService) claim(id primitive.ObjectID) (*notificationModel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	filter := bson.D{
+		{"_id", id},
+		{"status", bson.D{{"$in", bson.A{models.NotificationPending, models.NotificationSending}}}},
+		{"next_attempt_at", bson.D{{"$lte", now}}},
+	}
+	update := bson.D{{"$set", bson.D{
+		{"status", models.NotificationSending},
+		{"next_attempt_at", now.Add(notificationLease)},
+		{"last_modified", now},
+	}}}
+	var nm notificationModel
+	err := p.handler.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&nm)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &nm, nil
+}
+
+// attempt claims a notification and hands it to the push provider, returning whether it was sent
+func (p *NotificationService) attempt(id primitive.ObjectID) (bool, error) {
+	nm, err := p.claim(id)
+	if err != nil || nm == nil {
+		return false, err
+	}
+	sent := p.deliver(nm) == nil
+	_, err = p.handler.UpdateOne(&notificationModel{Id: nm.Id}, nm)
+	return sent, err
+}
+
+// NotificationDeliver attempts a queued notification right away, unless it is not due or another attempt claimed it
+// first
+func (p *NotificationService) NotificationDeliver(n *models.Notification) error {
+	nm, err := newNotificationModel(&models.Notification{Id: n.Id})
+	if err != nil {
+		return err
+	}
+	_, err = p.attempt(nm.Id)
+	return err
+}
+
+// NotificationDeliverPending sends the notifications in the outbox that are due, returning how many were sent. Every
+// notification is claimed before it is attempted, so instances of the app running it at the same time never send the
+// same notification twice.
+func (p *NotificationService) NotificationDeliverPending() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cur, err := p.handler.collection.Find(ctx, bson.D{
+		{"status", bson.D{{"$in", bson.A{models.NotificationPending, models.NotificationSending}}}},
+		{"next_attempt_at", bson.D{{"$lte", time.Now().UTC()}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	cursor := checkCursorENV(cur)
+	defer cursor.Close(ctx)
+	var due []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var nm notificationModel
+		if err = cursor.Decode(&nm); err != nil {
+			return 0, err
+		}
+		due = append(due, nm.Id)
+	}
+	sent := 0
+	for _, id := range due {
+		ok, err := p.attempt(id)
+		if err != nil {
+			return sent, err
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
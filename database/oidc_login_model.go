@@ -0,0 +1,155 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// oidcLoginModel structures a pending external sign in BSON document to save in an oidc_logins collection
+type oidcLoginModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	Provider     string             `bson:"provider,omitempty"`
+	State        string             `bson:"state,omitempty"`
+	Nonce        string             `bson:"nonce,omitempty"`
+	Verifier     string             `bson:"verifier,omitempty"`
+	ExpiresAt    time.Time          `bson:"expires_at,omitempty"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newOIDCLoginModel initializes a new pointer to an oidcLoginModel struct from a pointer to a JSON OIDCLogin struct
+func newOIDCLoginModel(l *models.OIDCLogin) (lm *oidcLoginModel, err error) {
+	lm = &oidcLoginModel{
+		Provider:     l.Provider,
+		State:        l.State,
+		Nonce:        l.Nonce,
+		Verifier:     l.Verifier,
+		ExpiresAt:    l.ExpiresAt,
+		LastModified: l.LastModified,
+		CreatedAt:    l.CreatedAt,
+	}
+	if l.Id != "" && l.Id != "000000000000000000000000" {
+		lm.Id, err = primitive.ObjectIDFromHex(l.Id)
+	}
+	return
+}
+
+// update the oidcLoginModel using an overwrite bson.D doc
+func (l *oidcLoginModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	lm := oidcLoginModel{}
+	err = bson.Unmarshal(data, &lm)
+	if !lm.ExpiresAt.IsZero() {
+		l.ExpiresAt = lm.ExpiresAt
+	}
+	if !lm.LastModified.IsZero() {
+		l.LastModified = lm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the oidcLoginModel
+func (l *oidcLoginModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, l)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the oidcLoginModel
+func (l *oidcLoginModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	lm := oidcLoginModel{}
+	err = bson.Unmarshal(data, &lm)
+	if !lm.Id.IsZero() {
+		return l.Id == lm.Id
+	}
+	if lm.State != "" {
+		return l.State == lm.State
+	}
+	return false
+}
+
+// getID returns the unique identifier of the oidcLoginModel
+func (l *oidcLoginModel) getID() (id interface{}) {
+	return l.Id
+}
+
+// addTimeStamps updates an oidcLoginModel struct with a timestamp
+func (l *oidcLoginModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	l.LastModified = currentTime
+	if newRecord {
+		l.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if an oidcLoginModel has a value assigned for Id, if no value a new one is generated and assigned
+func (l *oidcLoginModel) addObjectID() {
+	if l.Id.IsZero() {
+		l.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates an oidcLoginModel struct postProcess
+func (l *oidcLoginModel) postProcess() (err error) {
+	if l.State == "" {
+		err = errors.New("oidc login record does not have a state")
+	}
+	return
+}
+
+// toDoc converts the bson oidcLoginModel into a bson.D
+func (l *oidcLoginModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(l)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the oidcLoginModel data
+func (l *oidcLoginModel) bsonFilter() (doc bson.D, err error) {
+	if !l.Id.IsZero() {
+		doc = bson.D{{"_id", l.Id}}
+	} else if l.State != "" {
+		doc = bson.D{{"state", l.State}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the oidcLoginModel data
+func (l *oidcLoginModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := l.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to an OIDCLogin JSON struct from a pointer to a BSON oidcLoginModel
+func (l *oidcLoginModel) toRoot() *models.OIDCLogin {
+	return &models.OIDCLogin{
+		Id:           l.Id.Hex(),
+		Provider:     l.Provider,
+		State:        l.State,
+		Nonce:        l.Nonce,
+		Verifier:     l.Verifier,
+		ExpiresAt:    l.ExpiresAt,
+		LastModified: l.LastModified,
+		CreatedAt:    l.CreatedAt,
+	}
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+// oidcLoginTTL is how long a user has to sign in at an external provider and come back
+const oidcLoginTTL = 10 * time.Minute
+
+// OIDCService is used by the app to manage pending external sign ins and the external identities linked to users
+type OIDCService struct {
+	collection      DBCollection
+	db              DBClient
+	loginHandler    *DBHandler[*oidcLoginModel]
+	identityHandler *DBHandler[*identityModel]
+}
+
+// NewOIDCService is an exported function used to initialize a new OIDCService struct
+func NewOIDCService(db DBClient, lHandler *DBHandler[*oidcLoginModel], iHandler *DBHandler[*identityModel]) *OIDCService {
+	collection := db.GetCollection("identities")
+	return &OIDCService{collection, db, lHandler, iHandler}
+}
+
+// OIDCLoginCreate records the state of a sign in that was sent to an external provider
+func (p *OIDCService) OIDCLoginCreate(l *models.OIDCLogin) (*models.OIDCLogin, error) {
+	err := l.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	lm, err := newOIDCLoginModel(&models.OIDCLogin{
+		Provider:  l.Provider,
+		State:     l.State,
+		Nonce:     l.Nonce,
+		Verifier:  l.Verifier,
+		ExpiresAt: time.Now().UTC().Add(oidcLoginTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	lm, err = p.loginHandler.InsertOne(lm)
+	if err != nil {
+		return nil, err
+	}
+	return lm.toRoot(), nil
+}
+
+// OIDCLoginConsume looks up a pending sign in by its state and removes it, so each state can only be used once
+func (p *OIDCService) OIDCLoginConsume(state string) (*models.OIDCLogin, error) {
+	if state == "" {
+		return nil, models.ErrOIDCLoginInvalid
+	}
+	lms, err := p.loginHandler.FindMany(&oidcLoginModel{State: state})
+	if err != nil {
+		return nil, err
+	}
+	if len(lms) == 0 {
+		return nil, models.ErrOIDCLoginInvalid
+	}
+	lm := lms[0]
+	if _, err = p.loginHandler.DeleteOne(&oidcLoginModel{Id: lm.Id}); err != nil {
+		return nil, err
+	}
+	if time.Now().UTC().After(lm.ExpiresAt) {
+		return nil, models.ErrOIDCLoginInvalid
+	}
+	return lm.toRoot(), nil
+}
+
+// IdentityFind looks up the external identity of a provider account, returning nil without an error when it is not linked
+func (p *OIDCService) IdentityFind(i *models.Identity) (*models.Identity, error) {
+	err := i.Validate("find")
+	if err != nil {
+		return nil, err
+	}
+	ims, err := p.identityHandler.FindMany(&identityModel{Provider: i.Provider, Subject: i.Subject})
+	if err != nil {
+		return nil, err
+	}
+	if len(ims) == 0 {
+		return nil, nil
+	}
+	return ims[0].toRoot(), nil
+}
+
+// IdentityCreate links a provider account to a user
+func (p *OIDCService) IdentityCreate(i *models.Identity) (*models.Identity, error) {
+	err := i.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	im, err := newIdentityModel(&models.Identity{
+		UserId:   i.UserId,
+		Provider: i.Provider,
+		Subject:  i.Subject,
+		Email:    i.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	im, err = p.identityHandler.InsertOne(im)
+	if err != nil {
+		return nil, err
+	}
+	return im.toRoot(), nil
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// refreshTokenModel structures a refresh token BSON document to save in a refresh_tokens collection
+type refreshTokenModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	UserId       primitive.ObjectID `bson:"user_id,omitempty"`
+	FamilyId     primitive.ObjectID `bson:"family_id,omitempty"`
+	TokenHash    string             `bson:"token_hash,omitempty"`
+	ExpiresAt    time.Time          `bson:"expires_at,omitempty"`
+	UsedAt       time.Time          `bson:"used_at,omitempty"`
+	RevokedAt    time.Time          `bson:"revoked_at,omitempty"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newRefreshTokenModel initializes a new pointer to a refreshTokenModel struct from a pointer to a JSON RefreshToken struct
+func newRefreshTokenModel(r *models.RefreshToken) (rm *refreshTokenModel, err error) {
+	rm = &refreshTokenModel{
+		TokenHash:    r.TokenHash,
+		ExpiresAt:    r.ExpiresAt,
+		UsedAt:       r.UsedAt,
+		RevokedAt:    r.RevokedAt,
+		LastModified: r.LastModified,
+		CreatedAt:    r.CreatedAt,
+	}
+	if r.CheckID("id") {
+		rm.Id, err = primitive.ObjectIDFromHex(r.Id)
+		if err != nil {
+			return
+		}
+	}
+	if r.CheckID("user_id") {
+		rm.UserId, err = primitive.ObjectIDFromHex(r.UserId)
+		if err != nil {
+			return
+		}
+	}
+	if r.CheckID("family_id") {
+		rm.FamilyId, err = primitive.ObjectIDFromHex(r.FamilyId)
+	}
+	return
+}
+
+// update the refreshTokenModel using an overwrite bson.D doc
+func (r *refreshTokenModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	rm := refreshTokenModel{}
+	err = bson.Unmarshal(data, &rm)
+	if !rm.UsedAt.IsZero() {
+		r.UsedAt = rm.UsedAt
+	}
+	if !rm.RevokedAt.IsZero() {
+		r.RevokedAt = rm.RevokedAt
+	}
+	if !rm.LastModified.IsZero() {
+		r.LastModified = rm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the refreshTokenModel
+func (r *refreshTokenModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, r)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the refreshTokenModel
+func (r *refreshTokenModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	rm := refreshTokenModel{}
+	err = bson.Unmarshal(data, &rm)
+	if !rm.Id.IsZero() {
+		return r.Id == rm.Id
+	}
+	if rm.TokenHash != "" {
+		return r.TokenHash == rm.TokenHash
+	}
+	if !rm.FamilyId.IsZero() {
+		return r.FamilyId == rm.FamilyId
+	}
+	if !rm.UserId.IsZero() {
+		return r.UserId == rm.UserId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the refreshTokenModel
+func (r *refreshTokenModel) getID() (id interface{}) {
+	return r.Id
+}
+
+// addTimeStamps updates a refreshTokenModel struct with a timestamp
+func (r *refreshTokenModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	r.LastModified = currentTime
+	if newRecord {
+		r.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a refreshTokenModel has a value assigned for Id, if no value a new one is generated and assigned
+func (r *refreshTokenModel) addObjectID() {
+	if r.Id.IsZero() {
+		r.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a refreshTokenModel struct postProcess
+func (r *refreshTokenModel) postProcess() (err error) {
+	if r.TokenHash == "" {
+		err = errors.New("refresh token record does not have a token_hash")
+	}
+	return
+}
+
+// toDoc converts the bson refreshTokenModel into a bson.D
+func (r *refreshTokenModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(r)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the refreshTokenModel data
+func (r *refreshTokenModel) bsonFilter() (doc bson.D, err error) {
+	if !r.Id.IsZero() {
+		doc = bson.D{{"_id", r.Id}}
+	} else if r.TokenHash != "" {
+		doc = bson.D{{"token_hash", r.TokenHash}}
+	} else if !r.FamilyId.IsZero() {
+		doc = bson.D{{"family_id", r.FamilyId}}
+	} else if !r.UserId.IsZero() {
+		doc = bson.D{{"user_id", r.UserId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the refreshTokenModel data
+func (r *refreshTokenModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := r.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a RefreshToken JSON struct from a pointer to a BSON refreshTokenModel
+func (r *refreshTokenModel) toRoot() *models.RefreshToken {
+	return &models.RefreshToken{
+		Id:           r.Id.Hex(),
+		UserId:       r.UserId.Hex(),
+		FamilyId:     r.FamilyId.Hex(),
+		TokenHash:    r.TokenHash,
+		ExpiresAt:    r.ExpiresAt,
+		UsedAt:       r.UsedAt,
+		RevokedAt:    r.RevokedAt,
+		LastModified: r.LastModified,
+		CreatedAt:    r.CreatedAt,
+	}
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"os"
+	"time"
+)
+
+const defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// RefreshTokenService is used by the app to manage all refresh token related controllers and functionality
+type RefreshTokenService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*refreshTokenModel]
+}
+
+// NewRefreshTokenService is an exported function used to initialize a new RefreshTokenService struct
+func NewRefreshTokenService(db DBClient, handler *DBHandler[*refreshTokenModel]) *RefreshTokenService {
+	collection := db.GetCollection("refresh_tokens")
+	return &RefreshTokenService{collection, db, handler}
+}
+
+// refreshTokenTTL returns how long a refresh token can be exchanged for, configured with REFRESH_TOKEN_TTL
+func refreshTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultRefreshTokenTTL
+	}
+	return ttl
+}
+
+// hashRefreshToken returns the hex encoded sha256 sum of a refresh token, which is what gets stored
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshTokenCreate issues a new refresh token for a user. An empty familyId starts a new token family.
+func (p *RefreshTokenService) RefreshTokenCreate(userId string, familyId string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	rm, err := newRefreshTokenModel(&models.RefreshToken{
+		UserId:    userId,
+		FamilyId:  familyId,
+		TokenHash: hashRefreshToken(token),
+		ExpiresAt: time.Now().UTC().Add(refreshTokenTTL()),
+	})
+	if err != nil {
+		return "", err
+	}
+	if rm.UserId.IsZero() {
+		return "", models.ErrRefreshTokenInvalid
+	}
+	if rm.FamilyId.IsZero() {
+		rm.FamilyId = primitive.NewObjectID()
+	}
+	if _, err = p.handler.InsertOne(rm); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RefreshTokenRotate exchanges a refresh token for a new one in the same family. Presenting a token that
+// was already rotated revokes the whole family, since either the client or an attacker holds a stolen copy.
+func (p *RefreshTokenService) RefreshTokenRotate(token string) (*models.RefreshToken, string, error) {
+	if token == "" {
+		return nil, "", models.ErrRefreshTokenInvalid
+	}
+	rm, err := p.handler.FindOne(&refreshTokenModel{TokenHash: hashRefreshToken(token)})
+	if err != nil {
+		return nil, "", models.ErrRefreshTokenInvalid
+	}
+	rt := rm.toRoot()
+	if !rt.Spent() && rt.Expired() {
+		return nil, "", models.ErrRefreshTokenInvalid
+	}
+	claimed, err := p.claim(rm.Id)
+	if err != nil {
+		return nil, "", err
+	}
+	if !claimed {
+		if err = p.revokeFamily(rm.FamilyId); err != nil {
+			return nil, "", err
+		}
+		return nil, "", models.ErrRefreshTokenReused
+	}
+	newToken, err := p.RefreshTokenCreate(rt.UserId, rt.FamilyId)
+	if err != nil {
+		return nil, "", err
+	}
+	return rt, newToken, nil
+}
+
+// claim atomically marks a refresh token as used, returning false when it was already used or revoked. Two requests
+// presenting the same token can not both claim it, so the loser is treated as reuse.
+func (p *RefreshTokenService) claim(id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	filter := bson.D{{"_id", id}, {"used_at", bson.D{{"$exists", false}}}, {"revoked_at", bson.D{{"$exists", false}}}}
+	update := bson.D{{"$set", bson.D{{"used_at", now}, {"last_modified", now}}}}
+	err := p.handler.collection.FindOneAndUpdate(ctx, filter, update).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RefreshTokenFamilyRevoke revokes every refresh token of a family, used when its session is signed out
+func (p *RefreshTokenService) RefreshTokenFamilyRevoke(familyId string) error {
+	rm, err := newRefreshTokenModel(&models.RefreshToken{FamilyId: familyId})
+	if err != nil {
+		return err
+	}
+	if rm.FamilyId.IsZero() {
+		return models.ErrRefreshTokenInvalid
+	}
+	return p.revokeFamily(rm.FamilyId)
+}
+
+// revokeFamily marks every unrevoked refresh token of a family as revoked
+func (p *RefreshTokenService) revokeFamily(familyId primitive.ObjectID) error {
+	rms, err := p.handler.FindMany(&refreshTokenModel{FamilyId: familyId})
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, rm := range rms {
+		if !rm.RevokedAt.IsZero() {
+			continue
+		}
+		rm.RevokedAt = now
+		if _, err = p.handler.UpdateOne(&refreshTokenModel{Id: rm.Id}, rm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// scheduledMessageModel structures a scheduled message BSON document to save in a scheduled_messages collection
+type scheduledMessageModel struct {
+	Id             primitive.ObjectID `bson:"_id,omitempty"`
+	ConversationId primitive.ObjectID `bson:"conversation_id,omitempty"`
+	SenderId       primitive.ObjectID `bson:"sender_id,omitempty"`
+	ReceiverId     primitive.ObjectID `bson:"receiver_id,omitempty"`
+	Content        string             `bson:"content,omitempty"`
+	ContentType    string             `bson:"content_type,omitempty"`
+	Group          bool               `bson:"group,omitempty"`
+	SendAt         time.Time          `bson:"send_at,omitempty"`
+	Status         string             `bson:"status,omitempty"`
+	Attempts       int                `bson:"attempts,omitempty"`
+	LastError      string             `bson:"last_error,omitempty"`
+	SentAt         time.Time          `bson:"sent_at,omitempty"`
+	LastModified   time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at,omitempty"`
+}
+
+// newScheduledMessageModel initializes a new pointer to a scheduledMessageModel struct from a pointer to a JSON ScheduledMessage struct
+func newScheduledMessageModel(s *models.ScheduledMessage) (sm *scheduledMessageModel, err error) {
+	sm = &scheduledMessageModel{
+		Content:      s.Content,
+		ContentType:  s.ContentType,
+		Group:        s.Group,
+		SendAt:       s.SendAt,
+		Status:       s.Status,
+		Attempts:     s.Attempts,
+		LastError:    s.LastError,
+		SentAt:       s.SentAt,
+		LastModified: s.LastModified,
+		CreatedAt:    s.CreatedAt,
+	}
+	if s.Id != "" && s.Id != "000000000000000000000000" {
+		if sm.Id, err = primitive.ObjectIDFromHex(s.Id); err != nil {
+			return
+		}
+	}
+	if s.ConversationID != "" && s.ConversationID != "000000000000000000000000" {
+		if sm.ConversationId, err = primitive.ObjectIDFromHex(s.ConversationID); err != nil {
+			return
+		}
+	}
+	if s.SenderID != "" && s.SenderID != "000000000000000000000000" {
+		if sm.SenderId, err = primitive.ObjectIDFromHex(s.SenderID); err != nil {
+			return
+		}
+	}
+	if s.ReceiverID != "" && s.ReceiverID != "000000000000000000000000" {
+		sm.ReceiverId, err = primitive.ObjectIDFromHex(s.ReceiverID)
+	}
+	return
+}
+
+// update the scheduledMessageModel using an overwrite bson.D doc
+func (s *scheduledMessageModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	sm := scheduledMessageModel{}
+	err = bson.Unmarshal(data, &sm)
+	if len(sm.Content) > 0 {
+		s.Content = sm.Content
+	}
+	if len(sm.ContentType) > 0 {
+		s.ContentType = sm.ContentType
+	}
+	if !sm.SendAt.IsZero() {
+		s.SendAt = sm.SendAt
+	}
+	if len(sm.Status) > 0 {
+		s.Status = sm.Status
+	}
+	if sm.Attempts > 0 {
+		s.Attempts = sm.Attempts
+	}
+	if len(sm.LastError) > 0 {
+		s.LastError = sm.LastError
+	}
+	if !sm.SentAt.IsZero() {
+		s.SentAt = sm.SentAt
+	}
+	if !sm.LastModified.IsZero() {
+		s.LastModified = sm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the scheduledMessageModel
+func (s *scheduledMessageModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, s)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the scheduledMessageModel
+func (s *scheduledMessageModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	sm := scheduledMessageModel{}
+	err = bson.Unmarshal(data, &sm)
+	if !sm.Id.IsZero() {
+		return s.Id == sm.Id
+	}
+	if !sm.SenderId.IsZero() {
+		return s.SenderId == sm.SenderId
+	}
+	if sm.Status != "" {
+		return s.Status == sm.Status
+	}
+	return false
+}
+
+// getID returns the unique identifier of the scheduledMessageModel
+func (s *scheduledMessageModel) getID() (id interface{}) {
+	return s.Id
+}
+
+// addTimeStamps updates a scheduledMessageModel struct with a timestamp
+func (s *scheduledMessageModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	s.LastModified = currentTime
+	if newRecord {
+		s.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a scheduledMessageModel has a value assigned for Id, if no value a new one is generated and assigned
+func (s *scheduledMessageModel) addObjectID() {
+	if s.Id.IsZero() {
+		s.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a scheduledMessageModel struct postProcess
+func (s *scheduledMessageModel) postProcess() (err error) {
+	if s.SenderId.IsZero() {
+		err = errors.New("scheduled message record does not have a sender_id")
+	}
+	return
+}
+
+// toDoc converts the bson scheduledMessageModel into a bson.D
+func (s *scheduledMessageModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(s)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the scheduledMessageModel data
+func (s *scheduledMessageModel) bsonFilter() (doc bson.D, err error) {
+	if !s.Id.IsZero() {
+		doc = bson.D{{"_id", s.Id}}
+	} else if !s.SenderId.IsZero() {
+		doc = bson.D{{"sender_id", s.SenderId}}
+	} else if s.Status != "" {
+		doc = bson.D{{"status", s.Status}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the scheduledMessageModel data
+func (s *scheduledMessageModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := s.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a ScheduledMessage JSON struct from a pointer to a BSON scheduledMessageModel
+func (s *scheduledMessageModel) toRoot() *models.ScheduledMessage {
+	m := &models.ScheduledMessage{
+		Id:           s.Id.Hex(),
+		SenderID:     s.SenderId.Hex(),
+		ReceiverID:   s.ReceiverId.Hex(),
+		Content:      s.Content,
+		ContentType:  s.ContentType,
+		Group:        s.Group,
+		SendAt:       s.SendAt,
+		Status:       s.Status,
+		Attempts:     s.Attempts,
+		LastError:    s.LastError,
+		SentAt:       s.SentAt,
+		LastModified: s.LastModified,
+		CreatedAt:    s.CreatedAt,
+	}
+	if !s.ConversationId.IsZero() {
+		m.ConversationID = s.ConversationId.Hex()
+	}
+	return m
+}
@@ -0,0 +1,235 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"time"
+)
+
+// scheduledMessageLease is how long a claimed scheduled message is left to the attempt that claimed it, it can be
+// claimed again once the lease ran out in case that attempt never finished
+const scheduledMessageLease = time.Minute
+
+// ScheduledMessageService is used by the app to manage the messages users scheduled to be sent later
+type ScheduledMessageService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*scheduledMessageModel]
+}
+
+// NewScheduledMessageService is an exported function used to initialize a new ScheduledMessageService struct
+func NewScheduledMessageService(db DBClient, handler *DBHandler[*scheduledMessageModel]) *ScheduledMessageService {
+	collection := db.GetCollection("scheduled_messages")
+	return &ScheduledMessageService{collection, db, handler}
+}
+
+// ScheduledMessageCreate schedules a message to be sent at its SendAt
+func (p *ScheduledMessageService) ScheduledMessageCreate(s *models.ScheduledMessage) (*models.ScheduledMessage, error) {
+	err := s.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	sm, err := newScheduledMessageModel(&models.ScheduledMessage{
+		ConversationID: s.ConversationID,
+		SenderID:       s.SenderID,
+		ReceiverID:     s.ReceiverID,
+		Content:        s.Content,
+		ContentType:    s.ContentType,
+		Group:          s.Group,
+		SendAt:         s.SendAt.UTC(),
+		Status:         models.ScheduledPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sm, err = p.handler.InsertOne(sm)
+	if err != nil {
+		return nil, err
+	}
+	return sm.toRoot(), nil
+}
+
+// ScheduledMessagesFind lists the scheduled messages of a sender that have not been sent, soonest first
+func (p *ScheduledMessageService) ScheduledMessagesFind(s *models.ScheduledMessage) ([]*models.ScheduledMessage, error) {
+	scheduled := []*models.ScheduledMessage{}
+	if !s.CheckID("sender_id") {
+		return scheduled, models.ErrScheduledMessageNotFound
+	}
+	sm, err := newScheduledMessageModel(&models.ScheduledMessage{SenderID: s.SenderID})
+	if err != nil {
+		return scheduled, err
+	}
+	sms, err := p.handler.FindMany(sm)
+	if err != nil {
+		return scheduled, err
+	}
+	for _, sm = range sms {
+		if sm.Status != models.ScheduledSent {
+			scheduled = append(scheduled, sm.toRoot())
+		}
+	}
+	sort.Slice(scheduled, func(i, j int) bool {
+		return scheduled[i].SendAt.Before(scheduled[j].SendAt)
+	})
+	return scheduled, nil
+}
+
+// ScheduledMessageFind finds a scheduled message by its id, a scheduled message of another sender than the one set
+// is not found
+func (p *ScheduledMessageService) ScheduledMessageFind(s *models.ScheduledMessage) (*models.ScheduledMessage, error) {
+	if !s.CheckID("id") {
+		return nil, models.ErrScheduledMessageNotFound
+	}
+	sm, err := newScheduledMessageModel(&models.ScheduledMessage{Id: s.Id})
+	if err != nil {
+		return nil, err
+	}
+	sms, err := p.handler.FindMany(sm)
+	if err != nil {
+		return nil, err
+	}
+	if len(sms) == 0 || (s.SenderID != "" && sms[0].SenderId.Hex() != s.SenderID) {
+		return nil, models.ErrScheduledMessageNotFound
+	}
+	return sms[0].toRoot(), nil
+}
+
+// ScheduledMessageUpdate edits the content or reschedules a pending scheduled message of its sender. The edit only
+// applies while the message is pending, so a message that is being delivered is never changed underneath it.
+func (p *ScheduledMessageService) ScheduledMessageUpdate(s *models.ScheduledMessage) (*models.ScheduledMessage, error) {
+	err := s.Validate("update")
+	if err != nil {
+		return nil, err
+	}
+	if !s.CheckID("sender_id") {
+		return nil, models.ErrScheduledMessageNotFound
+	}
+	sm, err := newScheduledMessageModel(&models.ScheduledMessage{Id: s.Id, SenderID: s.SenderID})
+	if err != nil {
+		return nil, err
+	}
+	set := bson.D{{"last_modified", time.Now().UTC()}}
+	if s.Content != "" {
+		set = append(set, bson.E{Key: "content", Value: s.Content})
+	}
+	if s.ContentType != "" {
+		set = append(set, bson.E{Key: "content_type", Value: s.ContentType})
+	}
+	if !s.SendAt.IsZero() {
+		set = append(set, bson.E{Key: "send_at", Value: s.SendAt.UTC()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	filter := bson.D{{"_id", sm.Id}, {"sender_id", sm.SenderId}, {"status", models.ScheduledPending}}
+	err = p.handler.collection.FindOneAndUpdate(ctx, filter, bson.D{{"$set", set}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(sm)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, err = p.ScheduledMessageFind(s); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrScheduledMessageNotPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sm.toRoot(), nil
+}
+
+// ScheduledMessageDelete cancels a scheduled message of its sender that has not been sent
+func (p *ScheduledMessageService) ScheduledMessageDelete(s *models.ScheduledMessage) (*models.ScheduledMessage, error) {
+	cur, err := p.ScheduledMessageFind(s)
+	if err != nil {
+		return nil, err
+	}
+	if cur.Status == models.ScheduledSent || cur.Status == models.ScheduledSending {
+		return nil, models.ErrScheduledMessageNotPending
+	}
+	sm, err := newScheduledMessageModel(&models.ScheduledMessage{Id: cur.Id})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.handler.DeleteOne(sm); err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// ScheduledMessagesDue lists the pending scheduled messages whose SendAt has passed at t, oldest first, along with the
+// ones whose delivery was claimed by an attempt that did not finish within scheduledMessageLease
+func (p *ScheduledMessageService) ScheduledMessagesDue(t time.Time) ([]*models.ScheduledMessage, error) {
+	due := []*models.ScheduledMessage{}
+	sms, err := p.handler.FindMany(&scheduledMessageModel{Status: models.ScheduledPending})
+	if err != nil {
+		return due, err
+	}
+	for _, sm := range sms {
+		if s := sm.toRoot(); s.Due(t) {
+			due = append(due, s)
+		}
+	}
+	sms, err = p.handler.FindMany(&scheduledMessageModel{Status: models.ScheduledSending})
+	if err != nil {
+		return due, err
+	}
+	for _, sm := range sms {
+		if sm.LastModified.Add(scheduledMessageLease).Before(t) {
+			due = append(due, sm.toRoot())
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].SendAt.Before(due[j].SendAt)
+	})
+	return due, nil
+}
+
+// ScheduledMessageClaim marks a due scheduled message as sending, returning it as it was claimed or nil when it was
+// edited, canceled or claimed by another attempt since it was listed by ScheduledMessagesDue
+func (p *ScheduledMessageService) ScheduledMessageClaim(s *models.ScheduledMessage) (*models.ScheduledMessage, error) {
+	sm, err := newScheduledMessageModel(&models.ScheduledMessage{Id: s.Id})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	filter := bson.D{{"_id", sm.Id}, {"status", s.Status}, {"last_modified", s.LastModified}}
+	update := bson.D{{"$set", bson.D{{"status", models.ScheduledSending}, {"last_modified", time.Now().UTC()}}}}
+	err = p.handler.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(sm)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sm.toRoot(), nil
+}
+
+// ScheduledMessageDeliveryUpdate records the outcome of an attempt to deliver a scheduled message it claimed. Only the
+// delivery fields are set and only while the message is still claimed, so an outcome is never recorded over a message
+// another attempt claimed after the lease ran out.
+func (p *ScheduledMessageService) ScheduledMessageDeliveryUpdate(s *models.ScheduledMessage) error {
+	sm, err := newScheduledMessageModel(&models.ScheduledMessage{Id: s.Id})
+	if err != nil {
+		return err
+	}
+	set := bson.D{{"status", s.Status}, {"attempts", s.Attempts}, {"last_modified", time.Now().UTC()}}
+	if s.LastError != "" {
+		set = append(set, bson.E{Key: "last_error", Value: s.LastError})
+	}
+	if !s.SentAt.IsZero() {
+		set = append(set, bson.E{Key: "sent_at", Value: s.SentAt.UTC()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	filter := bson.D{{"_id", sm.Id}, {"status", models.ScheduledSending}}
+	err = p.handler.collection.FindOneAndUpdate(ctx, filter, bson.D{{"$set", set}}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	return err
+}
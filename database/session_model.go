@@ -0,0 +1,167 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// sessionModel structures a device session BSON document to save in a sessions collection
+type sessionModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	UserId       primitive.ObjectID `bson:"user_id,omitempty"`
+	DeviceName   string             `bson:"device_name,omitempty"`
+	UserAgent    string             `bson:"user_agent,omitempty"`
+	IPAddress    string             `bson:"ip_address,omitempty"`
+	LastUsedAt   time.Time          `bson:"last_used_at,omitempty"`
+	RevokedAt    time.Time          `bson:"revoked_at,omitempty"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newSessionModel initializes a new pointer to a sessionModel struct from a pointer to a JSON Session struct
+func newSessionModel(s *models.Session) (sm *sessionModel, err error) {
+	sm = &sessionModel{
+		DeviceName:   s.DeviceName,
+		UserAgent:    s.UserAgent,
+		IPAddress:    s.IPAddress,
+		LastUsedAt:   s.LastUsedAt,
+		RevokedAt:    s.RevokedAt,
+		LastModified: s.LastModified,
+		CreatedAt:    s.CreatedAt,
+	}
+	if s.CheckID("id") {
+		sm.Id, err = primitive.ObjectIDFromHex(s.Id)
+		if err != nil {
+			return
+		}
+	}
+	if s.CheckID("user_id") {
+		sm.UserId, err = primitive.ObjectIDFromHex(s.UserId)
+	}
+	return
+}
+
+// update the sessionModel using an overwrite bson.D doc
+func (s *sessionModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	sm := sessionModel{}
+	err = bson.Unmarshal(data, &sm)
+	if !sm.LastUsedAt.IsZero() {
+		s.LastUsedAt = sm.LastUsedAt
+	}
+	if !sm.RevokedAt.IsZero() {
+		s.RevokedAt = sm.RevokedAt
+	}
+	if !sm.LastModified.IsZero() {
+		s.LastModified = sm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the sessionModel
+func (s *sessionModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, s)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the sessionModel
+func (s *sessionModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	sm := sessionModel{}
+	err = bson.Unmarshal(data, &sm)
+	if !sm.Id.IsZero() {
+		return s.Id == sm.Id
+	}
+	if !sm.UserId.IsZero() {
+		return s.UserId == sm.UserId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the sessionModel
+func (s *sessionModel) getID() (id interface{}) {
+	return s.Id
+}
+
+// addTimeStamps updates a sessionModel struct with a timestamp
+func (s *sessionModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	s.LastModified = currentTime
+	if newRecord {
+		s.CreatedAt = currentTime
+		s.LastUsedAt = currentTime
+	}
+}
+
+// addObjectID checks if a sessionModel has a value assigned for Id, if no value a new one is generated and assigned
+func (s *sessionModel) addObjectID() {
+	if s.Id.IsZero() {
+		s.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a sessionModel struct postProcess
+func (s *sessionModel) postProcess() (err error) {
+	if s.UserId.IsZero() {
+		err = errors.New("session record does not have a user_id")
+	}
+	return
+}
+
+// toDoc converts the bson sessionModel into a bson.D
+func (s *sessionModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(s)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the sessionModel data
+func (s *sessionModel) bsonFilter() (doc bson.D, err error) {
+	if !s.Id.IsZero() {
+		doc = bson.D{{"_id", s.Id}}
+	} else if !s.UserId.IsZero() {
+		doc = bson.D{{"user_id", s.UserId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the sessionModel data
+func (s *sessionModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := s.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a Session JSON struct from a pointer to a BSON sessionModel
+func (s *sessionModel) toRoot() *models.Session {
+	return &models.Session{
+		Id:           s.Id.Hex(),
+		UserId:       s.UserId.Hex(),
+		DeviceName:   s.DeviceName,
+		UserAgent:    s.UserAgent,
+		IPAddress:    s.IPAddress,
+		LastUsedAt:   s.LastUsedAt,
+		RevokedAt:    s.RevokedAt,
+		LastModified: s.LastModified,
+		CreatedAt:    s.CreatedAt,
+	}
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+// sessionTouchInterval limits how often a session's last used time is written while it is in use
+const sessionTouchInterval = time.Minute
+
+// sessionCacheTTL is how long a session is trusted by SessionFindCached, which bounds how long a session signed out
+// on another instance of the app stays usable here
+const sessionCacheTTL = 30 * time.Second
+
+// SessionService is used by the app to manage all device session related controllers and functionality
+type SessionService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*sessionModel]
+	cache      *ttlCache[models.Session]
+}
+
+// NewSessionService is an exported function used to initialize a new SessionService struct
+func NewSessionService(db DBClient, handler *DBHandler[*sessionModel]) *SessionService {
+	collection := db.GetCollection("sessions")
+	return &SessionService{collection, db, handler, newTTLCache[models.Session](sessionCacheTTL)}
+}
+
+// SessionCreate is used to record a new session when a user signs in
+func (p *SessionService) SessionCreate(s *models.Session) (*models.Session, error) {
+	err := s.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	sm, err := newSessionModel(&models.Session{
+		UserId:     s.UserId,
+		DeviceName: s.DeviceName,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sm, err = p.handler.InsertOne(sm)
+	if err != nil {
+		return nil, err
+	}
+	return sm.toRoot(), nil
+}
+
+// SessionFind is used to find a specific session, scoped to its user when a user_id is given
+func (p *SessionService) SessionFind(s *models.Session) (*models.Session, error) {
+	sm, err := newSessionModel(&models.Session{Id: s.Id, UserId: s.UserId})
+	if err != nil {
+		return nil, err
+	}
+	if sm.Id.IsZero() {
+		return nil, errors.New("missing the following session fields: id")
+	}
+	found, err := p.handler.FindOne(&sessionModel{Id: sm.Id})
+	if err != nil {
+		return nil, err
+	}
+	if !sm.UserId.IsZero() && found.UserId != sm.UserId {
+		return nil, errors.New("session not found")
+	}
+	return found.toRoot(), nil
+}
+
+// SessionFindCached is SessionFind for authenticating requests, a session is only read from the database once every
+// sessionCacheTTL. Sessions revoked by this instance of the app are dropped from the cache right away.
+func (p *SessionService) SessionFindCached(s *models.Session) (*models.Session, error) {
+	session, ok := p.cache.get(s.Id)
+	if !ok {
+		found, err := p.SessionFind(&models.Session{Id: s.Id})
+		if err != nil {
+			return nil, err
+		}
+		session = *found
+		p.cache.put(session.Id, session)
+	}
+	if s.UserId != "" && session.UserId != s.UserId {
+		return nil, errors.New("session not found")
+	}
+	return &session, nil
+}
+
+// SessionsFind is used to list the active sessions of a user
+func (p *SessionService) SessionsFind(s *models.Session) ([]*models.Session, error) {
+	var sessions []*models.Session
+	sm, err := newSessionModel(&models.Session{UserId: s.UserId})
+	if err != nil {
+		return sessions, err
+	}
+	if sm.UserId.IsZero() {
+		return sessions, errors.New("missing the following session fields: user_id")
+	}
+	sms, err := p.handler.FindMany(sm)
+	if err != nil {
+		return sessions, err
+	}
+	staleBefore := time.Now().UTC().Add(-refreshTokenTTL())
+	for _, m := range sms {
+		if !m.RevokedAt.IsZero() || m.LastUsedAt.Before(staleBefore) {
+			continue
+		}
+		sessions = append(sessions, m.toRoot())
+	}
+	return sessions, nil
+}
+
+// SessionTouch records that a session was just used, at most once per sessionTouchInterval
+func (p *SessionService) SessionTouch(s *models.Session) error {
+	if time.Since(s.LastUsedAt) < sessionTouchInterval {
+		return nil
+	}
+	sm, err := newSessionModel(s)
+	if err != nil {
+		return err
+	}
+	sm.LastUsedAt = time.Now().UTC()
+	_, err = p.handler.UpdateOne(&sessionModel{Id: sm.Id}, sm)
+	p.cache.remove(s.Id)
+	return err
+}
+
+// SessionRevoke is used to sign out a session belonging to a user
+func (p *SessionService) SessionRevoke(s *models.Session) (*models.Session, error) {
+	err := s.Validate("revoke")
+	if err != nil {
+		return nil, err
+	}
+	session, err := p.SessionFind(s)
+	if err != nil {
+		return nil, err
+	}
+	if !session.Active() {
+		return session, nil
+	}
+	sm, err := newSessionModel(session)
+	if err != nil {
+		return nil, err
+	}
+	sm.RevokedAt = time.Now().UTC()
+	sm, err = p.handler.UpdateOne(&sessionModel{Id: sm.Id}, sm)
+	p.cache.remove(session.Id)
+	if err != nil {
+		return nil, err
+	}
+	return sm.toRoot(), nil
+}
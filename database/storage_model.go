@@ -0,0 +1,152 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// storageModel structures a storage quota BSON document to save in a storage collection
+type storageModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	OwnerId      primitive.ObjectID `bson:"owner_id,omitempty"`
+	OwnerType    string             `bson:"owner_type,omitempty"`
+	Quota        int64              `bson:"quota"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newStorageModel initializes a new pointer to a storageModel struct from a pointer to a JSON Storage struct
+func newStorageModel(s *models.Storage) (sm *storageModel, err error) {
+	sm = &storageModel{
+		OwnerType:    s.OwnerType,
+		Quota:        s.Quota,
+		LastModified: s.LastModified,
+		CreatedAt:    s.CreatedAt,
+	}
+	if s.CheckID("id") {
+		sm.Id, err = primitive.ObjectIDFromHex(s.Id)
+		if err != nil {
+			return
+		}
+	}
+	if s.CheckID("owner_id") {
+		sm.OwnerId, err = primitive.ObjectIDFromHex(s.OwnerId)
+	}
+	return
+}
+
+// update the storageModel using an overwrite bson.D doc
+func (s *storageModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	sm := storageModel{}
+	err = bson.Unmarshal(data, &sm)
+	s.Quota = sm.Quota
+	if !sm.LastModified.IsZero() {
+		s.LastModified = sm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the storageModel
+func (s *storageModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, s)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the storageModel
+func (s *storageModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	sm := storageModel{}
+	err = bson.Unmarshal(data, &sm)
+	if !sm.Id.IsZero() {
+		return s.Id == sm.Id
+	}
+	if !sm.OwnerId.IsZero() {
+		return s.OwnerId == sm.OwnerId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the storageModel
+func (s *storageModel) getID() (id interface{}) {
+	return s.Id
+}
+
+// addTimeStamps updates a storageModel struct with a timestamp
+func (s *storageModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	s.LastModified = currentTime
+	if newRecord {
+		s.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a storageModel has a value assigned for Id, if no value a new one is generated and assigned
+func (s *storageModel) addObjectID() {
+	if s.Id.IsZero() {
+		s.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a storageModel struct postProcess
+func (s *storageModel) postProcess() (err error) {
+	if s.OwnerId.IsZero() {
+		err = errors.New("storage record does not have an owner_id")
+	}
+	return
+}
+
+// toDoc converts the bson storageModel into a bson.D
+func (s *storageModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(s)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the storageModel data
+func (s *storageModel) bsonFilter() (doc bson.D, err error) {
+	if !s.Id.IsZero() {
+		doc = bson.D{{"_id", s.Id}}
+	} else if !s.OwnerId.IsZero() {
+		doc = bson.D{{"owner_id", s.OwnerId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the storageModel data
+func (s *storageModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := s.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a Storage JSON struct from a pointer to a BSON storageModel
+func (s *storageModel) toRoot() *models.Storage {
+	return &models.Storage{
+		Id:           s.Id.Hex(),
+		OwnerId:      s.OwnerId.Hex(),
+		OwnerType:    s.OwnerType,
+		Quota:        s.Quota,
+		LastModified: s.LastModified,
+		CreatedAt:    s.CreatedAt,
+	}
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// storageUsageModel structures a storage usage BSON document to save in a storage_usage collection. It has the id of
+// its file owner and counts the bytes they store, which are reserved with a conditional $inc so that concurrent
+// uploads can not take the owner over their quota.
+type storageUsageModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	Used         int64              `bson:"used"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newStorageUsageModel initializes a new pointer to a storageUsageModel struct for the file owner of a Storage
+func newStorageUsageModel(s *models.Storage) (um *storageUsageModel, err error) {
+	um = &storageUsageModel{Used: s.Used}
+	if s.CheckID("owner_id") {
+		um.Id, err = primitive.ObjectIDFromHex(s.OwnerId)
+	}
+	return
+}
+
+// update the storageUsageModel using an overwrite bson.D doc
+func (s *storageUsageModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	um := storageUsageModel{}
+	err = bson.Unmarshal(data, &um)
+	s.Used = um.Used
+	if !um.LastModified.IsZero() {
+		s.LastModified = um.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the storageUsageModel
+func (s *storageUsageModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, s)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the storageUsageModel
+func (s *storageUsageModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	um := storageUsageModel{}
+	err = bson.Unmarshal(data, &um)
+	if !um.Id.IsZero() {
+		return s.Id == um.Id
+	}
+	return false
+}
+
+// getID returns the unique identifier of the storageUsageModel
+func (s *storageUsageModel) getID() (id interface{}) {
+	return s.Id
+}
+
+// addTimeStamps updates a storageUsageModel struct with a timestamp
+func (s *storageUsageModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	s.LastModified = currentTime
+	if newRecord {
+		s.CreatedAt = currentTime
+	}
+}
+
+// addObjectID is a no-op, a storageUsageModel has the id of its file owner
+func (s *storageUsageModel) addObjectID() {}
+
+// postProcess updates a storageUsageModel struct postProcess
+func (s *storageUsageModel) postProcess() (err error) {
+	return
+}
+
+// toDoc converts the bson storageUsageModel into a bson.D
+func (s *storageUsageModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(s)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the storageUsageModel data
+func (s *storageUsageModel) bsonFilter() (doc bson.D, err error) {
+	if !s.Id.IsZero() {
+		doc = bson.D{{"_id", s.Id}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the storageUsageModel data
+func (s *storageUsageModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := s.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// twoFactorModel structures a two factor BSON document to save in a two_factors collection.
+// Used recovery codes are blanked rather than removed so an update never drops the field. Challenge is bound to the
+// sign in challenges of the user and FailedCodes counts the wrong codes entered since one was last accepted.
+type twoFactorModel struct {
+	Id            primitive.ObjectID `bson:"_id,omitempty"`
+	UserId        primitive.ObjectID `bson:"user_id,omitempty"`
+	Secret        string             `bson:"secret,omitempty"`
+	Enabled       bool               `bson:"enabled,omitempty"`
+	RecoveryCodes []string           `bson:"recovery_codes,omitempty"`
+	LastUsedStep  int64              `bson:"last_used_step,omitempty"`
+	Challenge     string             `bson:"challenge,omitempty"`
+	FailedCodes   int                `bson:"failed_codes,omitempty"`
+	ConfirmedAt   time.Time          `bson:"confirmed_at,omitempty"`
+	LastModified  time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty"`
+}
+
+// newTwoFactorModel initializes a new pointer to a twoFactorModel struct from a pointer to a JSON TwoFactor struct
+func newTwoFactorModel(t *models.TwoFactor) (tm *twoFactorModel, err error) {
+	tm = &twoFactorModel{
+		Secret:       t.Secret,
+		Enabled:      t.Enabled,
+		ConfirmedAt:  t.ConfirmedAt,
+		LastModified: t.LastModified,
+		CreatedAt:    t.CreatedAt,
+	}
+	if t.CheckID("id") {
+		tm.Id, err = primitive.ObjectIDFromHex(t.Id)
+		if err != nil {
+			return
+		}
+	}
+	if t.CheckID("user_id") {
+		tm.UserId, err = primitive.ObjectIDFromHex(t.UserId)
+	}
+	return
+}
+
+// update the twoFactorModel using an overwrite bson.D doc
+func (t *twoFactorModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	tm := twoFactorModel{}
+	err = bson.Unmarshal(data, &tm)
+	if len(tm.Secret) > 0 {
+		t.Secret = tm.Secret
+	}
+	if tm.Enabled {
+		t.Enabled = tm.Enabled
+	}
+	if len(tm.RecoveryCodes) > 0 {
+		t.RecoveryCodes = tm.RecoveryCodes
+	}
+	if tm.LastUsedStep > t.LastUsedStep {
+		t.LastUsedStep = tm.LastUsedStep
+	}
+	if !tm.ConfirmedAt.IsZero() {
+		t.ConfirmedAt = tm.ConfirmedAt
+	}
+	if !tm.LastModified.IsZero() {
+		t.LastModified = tm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the twoFactorModel
+func (t *twoFactorModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, t)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the twoFactorModel
+func (t *twoFactorModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	tm := twoFactorModel{}
+	err = bson.Unmarshal(data, &tm)
+	if !tm.Id.IsZero() {
+		return t.Id == tm.Id
+	}
+	if !tm.UserId.IsZero() {
+		return t.UserId == tm.UserId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the twoFactorModel
+func (t *twoFactorModel) getID() (id interface{}) {
+	return t.Id
+}
+
+// addTimeStamps updates a twoFactorModel struct with a timestamp
+func (t *twoFactorModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	t.LastModified = currentTime
+	if newRecord {
+		t.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a twoFactorModel has a value assigned for Id, if no value a new one is generated and assigned
+func (t *twoFactorModel) addObjectID() {
+	if t.Id.IsZero() {
+		t.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a twoFactorModel struct postProcess
+func (t *twoFactorModel) postProcess() (err error) {
+	if t.Secret == "" {
+		err = errors.New("two factor record does not have a secret")
+	}
+	return
+}
+
+// toDoc converts the bson twoFactorModel into a bson.D
+func (t *twoFactorModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(t)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the twoFactorModel data
+func (t *twoFactorModel) bsonFilter() (doc bson.D, err error) {
+	if !t.Id.IsZero() {
+		doc = bson.D{{"_id", t.Id}}
+	} else if !t.UserId.IsZero() {
+		doc = bson.D{{"user_id", t.UserId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the twoFactorModel data
+func (t *twoFactorModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := t.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a TwoFactor JSON struct from a pointer to a BSON twoFactorModel,
+// leaving out the secret and recovery code hashes
+func (t *twoFactorModel) toRoot() *models.TwoFactor {
+	left := 0
+	for _, code := range t.RecoveryCodes {
+		if code != "" {
+			left++
+		}
+	}
+	return &models.TwoFactor{
+		Id:                t.Id.Hex(),
+		UserId:            t.UserId.Hex(),
+		Enabled:           t.Enabled,
+		RecoveryCodesLeft: left,
+		ConfirmedAt:       t.ConfirmedAt,
+		LastModified:      t.LastModified,
+		CreatedAt:         t.CreatedAt,
+	}
+}
@@ -0,0 +1,322 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTOTPIssuer  = "Messenger"
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	maxFailedCodes     = 5
+)
+
+// TwoFactorService is used by the app to manage all two-factor authentication related controllers and functionality
+type TwoFactorService struct {
+	collection DBCollection
+	db         DBClient
+	handler    *DBHandler[*twoFactorModel]
+}
+
+// NewTwoFactorService is an exported function used to initialize a new TwoFactorService struct
+func NewTwoFactorService(db DBClient, handler *DBHandler[*twoFactorModel]) *TwoFactorService {
+	collection := db.GetCollection("two_factors")
+	return &TwoFactorService{collection, db, handler}
+}
+
+// totpIssuer returns the issuer shown by authenticator apps, configured with TOTP_ISSUER
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultTOTPIssuer
+}
+
+// normalizeRecoveryCode strips the formatting a user may have typed a recovery code with
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+// hashRecoveryCode returns the hex encoded sha256 sum of a recovery code, which is what gets stored
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes returns a new set of one-time recovery codes formatted as xxxxx-xxxxx
+func generateRecoveryCodes() ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(buf))[:recoveryCodeLength]
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+	}
+	return codes, nil
+}
+
+// find looks up the two factor record of a user, returning nil without an error when the user never enrolled
+func (p *TwoFactorService) find(userId string) (*twoFactorModel, error) {
+	t := &models.TwoFactor{UserId: userId}
+	err := t.Validate("find")
+	if err != nil {
+		return nil, err
+	}
+	tm, err := newTwoFactorModel(t)
+	if err != nil {
+		return nil, err
+	}
+	tms, err := p.handler.FindMany(tm)
+	if err != nil {
+		return nil, err
+	}
+	if len(tms) == 0 {
+		return nil, nil
+	}
+	return tms[0], nil
+}
+
+// TwoFactorFind is used to find the two-factor status of a user
+func (p *TwoFactorService) TwoFactorFind(userId string) (*models.TwoFactor, error) {
+	tm, err := p.find(userId)
+	if err != nil {
+		return nil, err
+	}
+	if tm == nil {
+		return &models.TwoFactor{UserId: userId}, nil
+	}
+	return tm.toRoot(), nil
+}
+
+// TwoFactorEnroll starts a TOTP enrollment for a user, replacing any enrollment that was never confirmed.
+// The returned TwoFactor is the only one that carries the secret and its otpauth URI.
+func (p *TwoFactorService) TwoFactorEnroll(userId string, account string) (*models.TwoFactor, error) {
+	tm, err := p.find(userId)
+	if err != nil {
+		return nil, err
+	}
+	if tm != nil && tm.Enabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if tm == nil {
+		tm, err = newTwoFactorModel(&models.TwoFactor{UserId: userId, Secret: secret})
+		if err != nil {
+			return nil, err
+		}
+		tm, err = p.handler.InsertOne(tm)
+	} else {
+		tm.Secret = secret
+		tm, err = p.handler.UpdateOne(&twoFactorModel{Id: tm.Id}, tm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	enrollment := tm.toRoot()
+	enrollment.Secret = secret
+	enrollment.URI = auth.TOTPURI(totpIssuer(), account, secret)
+	return enrollment, nil
+}
+
+// TwoFactorConfirm enables two-factor authentication once a user proves their authenticator app holds the secret.
+// The returned TwoFactor is the only one that carries the plain recovery codes.
+func (p *TwoFactorService) TwoFactorConfirm(userId string, code string) (*models.TwoFactor, error) {
+	tm, err := p.find(userId)
+	if err != nil {
+		return nil, err
+	}
+	if tm == nil {
+		return nil, errors.New("two-factor enrollment has not been started")
+	}
+	if tm.Enabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	step, ok := auth.ValidateTOTP(tm.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, models.ErrTwoFactorInvalid
+	}
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	tm.RecoveryCodes = make([]string, len(codes))
+	for i, c := range codes {
+		tm.RecoveryCodes[i] = hashRecoveryCode(c)
+	}
+	tm.Enabled = true
+	tm.LastUsedStep = step
+	tm.ConfirmedAt = time.Now().UTC()
+	tm, err = p.handler.UpdateOne(&twoFactorModel{Id: tm.Id}, tm)
+	if err != nil {
+		return nil, err
+	}
+	confirmed := tm.toRoot()
+	confirmed.RecoveryCodes = codes
+	return confirmed, nil
+}
+
+// TwoFactorVerify checks a TOTP code or a recovery code for a user with two-factor authentication enabled.
+// A TOTP code is only accepted once and a recovery code is used up when it is accepted.
+func (p *TwoFactorService) TwoFactorVerify(userId string, code string) error {
+	tm, err := p.find(userId)
+	if err != nil {
+		return err
+	}
+	if tm == nil || !tm.Enabled {
+		return models.ErrTwoFactorNotEnabled
+	}
+	return p.verify(tm, code, "")
+}
+
+// verify accepts a TOTP code or a recovery code by claiming it with a conditional update, so the same code can not be
+// accepted twice by concurrent requests. When a sign in challenge is given the claim also requires and uses it up.
+// A code that can not be claimed is counted as a wrong one.
+func (p *TwoFactorService) verify(tm *twoFactorModel, code string, challenge string) error {
+	filter := func(e bson.E) bson.D {
+		f := bson.D{{"_id", tm.Id}}
+		if challenge != "" {
+			f = append(f, bson.E{Key: "challenge", Value: challenge})
+		}
+		return append(f, e)
+	}
+	unset := bson.D{{"failed_codes", ""}}
+	if challenge != "" {
+		unset = append(unset, bson.E{Key: "challenge", Value: ""})
+	}
+	code = strings.TrimSpace(code)
+	if step, ok := auth.ValidateTOTP(tm.Secret, code, time.Now()); ok {
+		claimed, err := p.claim(filter(bson.E{Key: "last_used_step", Value: bson.D{{"$lt", step}}}),
+			bson.D{{"$set", bson.D{{"last_used_step", step}}}, {"$unset", unset}})
+		if err != nil || claimed {
+			return err
+		}
+	}
+	claimed, err := p.claim(filter(bson.E{Key: "recovery_codes", Value: hashRecoveryCode(code)}),
+		bson.D{{"$set", bson.D{{"recovery_codes.$", ""}}}, {"$unset", unset}})
+	if err != nil || claimed {
+		return err
+	}
+	if err = p.recordFailedCode(tm.Id); err != nil {
+		return err
+	}
+	return models.ErrTwoFactorInvalid
+}
+
+// claim applies an update to the two factor record matching filter, returning false when no record matched
+func (p *TwoFactorService) claim(filter bson.D, update bson.D) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := p.handler.collection.FindOneAndUpdate(ctx, filter, update).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// recordFailedCode counts a wrong code against a user. Once maxFailedCodes have been entered their sign in
+// challenges are invalidated, so guessing codes requires signing in with the password again.
+func (p *TwoFactorService) recordFailedCode(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	after := options.After
+	var tm twoFactorModel
+	err := p.handler.collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}}, bson.D{{"$inc", bson.D{{"failed_codes", 1}}}},
+		&options.FindOneAndUpdateOptions{ReturnDocument: &after}).Decode(&tm)
+	if err != nil || tm.FailedCodes < maxFailedCodes {
+		return err
+	}
+	return p.unset(id, "challenge", "failed_codes")
+}
+
+// unset removes fields from the two factor record of a user, used to clear the wrong codes counted against them and
+// to invalidate their sign in challenges
+func (p *TwoFactorService) unset(id primitive.ObjectID, fields ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	unset := bson.D{}
+	for _, field := range fields {
+		unset = append(unset, bson.E{Key: field, Value: ""})
+	}
+	return p.handler.collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}}, bson.D{{"$unset", unset}}).Err()
+}
+
+// TwoFactorChallenge returns the value the sign in challenges of a user are bound to, generating one when their
+// previous challenges were invalidated
+func (p *TwoFactorService) TwoFactorChallenge(userId string) (string, error) {
+	tm, err := p.find(userId)
+	if err != nil {
+		return "", err
+	}
+	if tm == nil || !tm.Enabled {
+		return "", models.ErrTwoFactorNotEnabled
+	}
+	if tm.Challenge != "" {
+		return tm.Challenge, nil
+	}
+	buf := make([]byte, 16)
+	if _, err = rand.Read(buf); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	filter := bson.D{{"_id", tm.Id}, {"challenge", bson.D{{"$exists", false}}}}
+	update := bson.D{{"$set", bson.D{{"challenge", hex.EncodeToString(buf)}}}}
+	err = p.handler.collection.FindOneAndUpdate(ctx, filter, update).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return p.TwoFactorChallenge(userId) // another sign in generated it first
+	}
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// TwoFactorVerifyChallenge checks the second factor of a sign in challenge, rejecting challenges that were
+// invalidated by too many wrong codes. The challenge is used up along with the code, so it only starts one session.
+func (p *TwoFactorService) TwoFactorVerifyChallenge(userId string, challenge string, code string) error {
+	tm, err := p.find(userId)
+	if err != nil {
+		return err
+	}
+	if tm == nil || tm.Challenge == "" || subtle.ConstantTimeCompare([]byte(tm.Challenge), []byte(challenge)) != 1 {
+		return auth.ErrActionTokenInvalid
+	}
+	if !tm.Enabled {
+		return models.ErrTwoFactorNotEnabled
+	}
+	return p.verify(tm, code, challenge)
+}
+
+// TwoFactorDisable turns off two-factor authentication for a user after checking one of their codes
+func (p *TwoFactorService) TwoFactorDisable(userId string, code string) error {
+	if err := p.TwoFactorVerify(userId, code); err != nil {
+		return err
+	}
+	tm, err := p.find(userId)
+	if err != nil {
+		return err
+	}
+	_, err = p.handler.DeleteOne(&twoFactorModel{Id: tm.Id})
+	return err
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// unreadModel structures an unread thread BSON document to save in an unreads collection
+type unreadModel struct {
+	Id            primitive.ObjectID `bson:"_id,omitempty"`
+	UserId        primitive.ObjectID `bson:"user_id,omitempty"`
+	ThreadId      primitive.ObjectID `bson:"thread_id,omitempty"`
+	Kind          string             `bson:"kind,omitempty"`
+	Count         int                `bson:"count,omitempty"`
+	LastMessageId primitive.ObjectID `bson:"last_message_id,omitempty"`
+	LastSenderId  primitive.ObjectID `bson:"last_sender_id,omitempty"`
+	LastMessageAt time.Time          `bson:"last_message_at,omitempty"`
+	LastModified  time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at,omitempty"`
+}
+
+// newUnreadModel initializes a new pointer to an unreadModel struct from a pointer to a JSON Unread struct
+func newUnreadModel(u *models.Unread) (um *unreadModel, err error) {
+	um = &unreadModel{
+		Kind:          u.Kind,
+		Count:         u.Count,
+		LastMessageAt: u.LastMessageAt,
+		LastModified:  u.LastModified,
+		CreatedAt:     u.CreatedAt,
+	}
+	if u.Id != "" && u.Id != "000000000000000000000000" {
+		if um.Id, err = primitive.ObjectIDFromHex(u.Id); err != nil {
+			return
+		}
+	}
+	if u.UserId != "" && u.UserId != "000000000000000000000000" {
+		if um.UserId, err = primitive.ObjectIDFromHex(u.UserId); err != nil {
+			return
+		}
+	}
+	if u.ThreadId != "" && u.ThreadId != "000000000000000000000000" {
+		if um.ThreadId, err = primitive.ObjectIDFromHex(u.ThreadId); err != nil {
+			return
+		}
+	}
+	if u.LastMessageId != "" && u.LastMessageId != "000000000000000000000000" {
+		if um.LastMessageId, err = primitive.ObjectIDFromHex(u.LastMessageId); err != nil {
+			return
+		}
+	}
+	if u.LastSenderId != "" && u.LastSenderId != "000000000000000000000000" {
+		um.LastSenderId, err = primitive.ObjectIDFromHex(u.LastSenderId)
+	}
+	return
+}
+
+// update the unreadModel using an overwrite bson.D doc
+func (u *unreadModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	um := unreadModel{}
+	err = bson.Unmarshal(data, &um)
+	if um.Count > 0 {
+		u.Count = um.Count
+	}
+	if !um.LastMessageId.IsZero() {
+		u.LastMessageId = um.LastMessageId
+	}
+	if !um.LastSenderId.IsZero() {
+		u.LastSenderId = um.LastSenderId
+	}
+	if !um.LastMessageAt.IsZero() {
+		u.LastMessageAt = um.LastMessageAt
+	}
+	if !um.LastModified.IsZero() {
+		u.LastModified = um.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the unreadModel
+func (u *unreadModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, u)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the unreadModel
+func (u *unreadModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	um := unreadModel{}
+	err = bson.Unmarshal(data, &um)
+	if !um.Id.IsZero() {
+		return u.Id == um.Id
+	}
+	if !um.UserId.IsZero() && !um.ThreadId.IsZero() {
+		return u.UserId == um.UserId && u.ThreadId == um.ThreadId
+	}
+	if !um.UserId.IsZero() {
+		return u.UserId == um.UserId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the unreadModel
+func (u *unreadModel) getID() (id interface{}) {
+	return u.Id
+}
+
+// addTimeStamps updates an unreadModel struct with a timestamp
+func (u *unreadModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	u.LastModified = currentTime
+	if newRecord {
+		u.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if an unreadModel has a value assigned for Id, if no value a new one is generated and assigned
+func (u *unreadModel) addObjectID() {
+	if u.Id.IsZero() {
+		u.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates an unreadModel struct postProcess
+func (u *unreadModel) postProcess() (err error) {
+	if u.UserId.IsZero() {
+		err = errors.New("unread record does not have a user_id")
+	}
+	return
+}
+
+// toDoc converts the bson unreadModel into a bson.D
+func (u *unreadModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(u)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the unreadModel data
+func (u *unreadModel) bsonFilter() (doc bson.D, err error) {
+	if !u.Id.IsZero() {
+		doc = bson.D{{"_id", u.Id}}
+	} else if !u.UserId.IsZero() && !u.ThreadId.IsZero() {
+		doc = bson.D{{"user_id", u.UserId}, {"thread_id", u.ThreadId}}
+	} else if !u.UserId.IsZero() {
+		doc = bson.D{{"user_id", u.UserId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the unreadModel data
+func (u *unreadModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := u.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to an Unread JSON struct from a pointer to a BSON unreadModel
+func (u *unreadModel) toRoot() *models.Unread {
+	return &models.Unread{
+		Id:            u.Id.Hex(),
+		UserId:        u.UserId.Hex(),
+		ThreadId:      u.ThreadId.Hex(),
+		Kind:          u.Kind,
+		Count:         u.Count,
+		LastMessageId: u.LastMessageId.Hex(),
+		LastSenderId:  u.LastSenderId.Hex(),
+		LastMessageAt: u.LastMessageAt,
+		LastModified:  u.LastModified,
+		CreatedAt:     u.CreatedAt,
+	}
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"time"
+)
+
+// uploadChunkModel structures a received chunk inside an upload BSON document
+type uploadChunkModel struct {
+	Number   int                `bson:"number"`
+	Size     int64              `bson:"size"`
+	Checksum string             `bson:"checksum,omitempty"`
+	GridFSId primitive.ObjectID `bson:"gridfs_id,omitempty"`
+}
+
+// uploadModel structures an upload session BSON document to save in an uploads collection
+type uploadModel struct {
+	Id           primitive.ObjectID  `bson:"_id,omitempty"`
+	OwnerId      primitive.ObjectID  `bson:"owner_id,omitempty"`
+	OwnerType    string              `bson:"owner_type,omitempty"`
+	Name         string              `bson:"name,omitempty"`
+	FileType     string              `bson:"file_type,omitempty"`
+	Size         int64               `bson:"size,omitempty"`
+	ChunkSize    int64               `bson:"chunk_size,omitempty"`
+	Checksum     string              `bson:"checksum,omitempty"`
+	Chunks       []*uploadChunkModel `bson:"chunks,omitempty"`
+	FileId       primitive.ObjectID  `bson:"file_id,omitempty"`
+	ExpiresAt    time.Time           `bson:"expires_at,omitempty"`
+	LastModified time.Time           `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at,omitempty"`
+}
+
+// newUploadModel initializes a new pointer to an uploadModel struct from a pointer to a JSON Upload struct
+func newUploadModel(u *models.Upload) (um *uploadModel, err error) {
+	um = &uploadModel{
+		OwnerType:    u.OwnerType,
+		Name:         u.Name,
+		FileType:     u.FileType,
+		Size:         u.Size,
+		ChunkSize:    u.ChunkSize,
+		Checksum:     u.Checksum,
+		ExpiresAt:    u.ExpiresAt,
+		LastModified: u.LastModified,
+		CreatedAt:    u.CreatedAt,
+	}
+	if u.CheckID("id") {
+		um.Id, err = primitive.ObjectIDFromHex(u.Id)
+		if err != nil {
+			return
+		}
+	}
+	if u.CheckID("owner_id") {
+		um.OwnerId, err = primitive.ObjectIDFromHex(u.OwnerId)
+		if err != nil {
+			return
+		}
+	}
+	if u.FileId != "" {
+		um.FileId, err = primitive.ObjectIDFromHex(u.FileId)
+		if err != nil {
+			return
+		}
+	}
+	for _, c := range u.Chunks {
+		cm := &uploadChunkModel{Number: c.Number, Size: c.Size, Checksum: c.Checksum}
+		if c.GridFSId != "" {
+			cm.GridFSId, err = primitive.ObjectIDFromHex(c.GridFSId)
+			if err != nil {
+				return
+			}
+		}
+		um.Chunks = append(um.Chunks, cm)
+	}
+	return
+}
+
+// update the uploadModel using an overwrite bson.D doc
+func (u *uploadModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	um := uploadModel{}
+	err = bson.Unmarshal(data, &um)
+	if len(um.Chunks) > 0 {
+		u.Chunks = um.Chunks
+	}
+	if !um.FileId.IsZero() {
+		u.FileId = um.FileId
+	}
+	if !um.ExpiresAt.IsZero() {
+		u.ExpiresAt = um.ExpiresAt
+	}
+	if !um.LastModified.IsZero() {
+		u.LastModified = um.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the uploadModel
+func (u *uploadModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, u)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the uploadModel
+func (u *uploadModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	um := uploadModel{}
+	err = bson.Unmarshal(data, &um)
+	if !um.Id.IsZero() {
+		return u.Id == um.Id
+	}
+	if !um.OwnerId.IsZero() {
+		return u.OwnerId == um.OwnerId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the uploadModel
+func (u *uploadModel) getID() (id interface{}) {
+	return u.Id
+}
+
+// addTimeStamps updates an uploadModel struct with a timestamp
+func (u *uploadModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	u.LastModified = currentTime
+	if newRecord {
+		u.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if an uploadModel has a value assigned for Id, if no value a new one is generated and assigned
+func (u *uploadModel) addObjectID() {
+	if u.Id.IsZero() {
+		u.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates an uploadModel struct postProcess
+func (u *uploadModel) postProcess() (err error) {
+	if u.OwnerId.IsZero() {
+		err = errors.New("upload record does not have an owner_id")
+	}
+	return
+}
+
+// toDoc converts the bson uploadModel into a bson.D
+func (u *uploadModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(u)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the uploadModel data
+func (u *uploadModel) bsonFilter() (doc bson.D, err error) {
+	if !u.Id.IsZero() {
+		doc = bson.D{{"_id", u.Id}}
+	} else if !u.OwnerId.IsZero() {
+		doc = bson.D{{"owner_id", u.OwnerId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the uploadModel data
+func (u *uploadModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := u.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to an Upload JSON struct from a pointer to a BSON uploadModel
+func (u *uploadModel) toRoot() *models.Upload {
+	upload := &models.Upload{
+		Id:           u.Id.Hex(),
+		OwnerId:      u.OwnerId.Hex(),
+		OwnerType:    u.OwnerType,
+		Name:         u.Name,
+		FileType:     u.FileType,
+		Size:         u.Size,
+		ChunkSize:    u.ChunkSize,
+		Checksum:     u.Checksum,
+		ExpiresAt:    u.ExpiresAt,
+		LastModified: u.LastModified,
+		CreatedAt:    u.CreatedAt,
+	}
+	if !u.FileId.IsZero() {
+		upload.FileId = u.FileId.Hex()
+	}
+	for _, c := range u.Chunks {
+		upload.Chunks = append(upload.Chunks, &models.UploadChunk{
+			Number:   c.Number,
+			Size:     c.Size,
+			Checksum: c.Checksum,
+			GridFSId: c.GridFSId.Hex(),
+		})
+	}
+	sort.Slice(upload.Chunks, func(i, j int) bool { return upload.Chunks[i].Number < upload.Chunks[j].Number })
+	upload.ComputeOffset()
+	return upload
+}
@@ -10,29 +10,36 @@ import (
 
 // userModel structures a group BSON document to save in a users collection
 type userModel struct {
-	Id         primitive.ObjectID `bson:"_id,omitempty"`
-	Username   string             `bson:"username,omitempty"`
-	Password   string             `bson:"password,omitempty"`
-	Email      string             `bson:"email,omitempty"`
-	Phone      string             `bson:"phone,omitempty"`
-	ImageId    string             `bson:"image_id,omitempty"`
-	RootAdmin  bool               `bson:"root_admin,omitempty"`
-	LastActive time.Time          `bson:"last_active,omitempty"`
-	CreatedAt  time.Time          `bson:"created_at,omitempty"`
-	DeletedAt  time.Time          `bson:"deleted_at,omitempty"`
+	Id              primitive.ObjectID `bson:"_id,omitempty"`
+	Username        string             `bson:"username,omitempty"`
+	Password        string             `bson:"password,omitempty"`
+	PasswordHistory []string           `bson:"password_history,omitempty"`
+	Email           string             `bson:"email,omitempty"`
+	EmailVerifiedAt time.Time          `bson:"email_verified_at,omitempty"`
+	Phone           string             `bson:"phone,omitempty"`
+	ImageId         string             `bson:"image_id,omitempty"`
+	Role            string             `bson:"role,omitempty"`
+	RootAdmin       bool               `bson:"root_admin,omitempty"`
+	LastActive      time.Time          `bson:"last_active,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at,omitempty"`
+	DeletedAt       time.Time          `bson:"deleted_at,omitempty"`
 }
 
 // newUserModel initializes a new pointer to a userModel struct from a pointer to a JSON User struct
 func newUserModel(u *models.User) (um *userModel, err error) {
 	um = &userModel{
-		Username:   u.Username,
-		Password:   u.Password,
-		Email:      u.Email,
-		Phone:      u.Phone,
-		RootAdmin:  u.RootAdmin,
-		LastActive: u.LastActive,
-		CreatedAt:  u.CreatedAt,
-		DeletedAt:  u.DeletedAt,
+		Username:        u.Username,
+		Password:        u.Password,
+		PasswordHistory: u.PasswordHistory,
+		Email:           u.Email,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		Phone:           u.Phone,
+		ImageId:         u.ImageId,
+		Role:            u.Role,
+		RootAdmin:       u.RootAdmin,
+		LastActive:      u.LastActive,
+		CreatedAt:       u.CreatedAt,
+		DeletedAt:       u.DeletedAt,
 	}
 	if u.Id != "" && u.Id != "000000000000000000000000" {
 		um.Id, err = primitive.ObjectIDFromHex(u.Id)
@@ -59,12 +66,25 @@ func (u *userModel) update(doc interface{}) (err error) {
 	if len(um.Password) > 0 {
 		u.Password = um.Password
 	}
+	if len(um.PasswordHistory) > 0 {
+		u.PasswordHistory = um.PasswordHistory
+	}
 	if len(um.Email) > 0 {
 		u.Email = um.Email
 	}
+	if !um.EmailVerifiedAt.IsZero() {
+		u.EmailVerifiedAt = um.EmailVerifiedAt
+	}
 	if len(um.Phone) > 0 {
 		u.Phone = um.Phone
 	}
+	if len(um.ImageId) > 0 {
+		u.ImageId = um.ImageId
+	}
+	if len(um.Role) > 0 {
+		u.Role = um.Role
+		u.RootAdmin = um.RootAdmin
+	}
 	if len(um.Id.Hex()) > 0 && um.Id.Hex() != "000000000000000000000000" {
 		u.Id = um.Id
 	}
@@ -105,6 +125,9 @@ func (u *userModel) match(doc interface{}) bool {
 		}
 		return false
 	}
+	if um.Username != "" {
+		return u.Username == um.Username
+	}
 
 	return false
 }
@@ -156,6 +179,8 @@ func (u *userModel) bsonFilter() (doc bson.D, err error) {
 		doc = bson.D{{"_id", u.Id}}
 	} else if u.Email != "" {
 		doc = bson.D{{"email", u.Email}}
+	} else if u.Username != "" {
+		doc = bson.D{{"username", u.Username}}
 	}
 	return
 }
@@ -172,16 +197,27 @@ func (u *userModel) bsonUpdate() (doc bson.D, err error) {
 
 // toRoot creates and return a new pointer to a User JSON struct from a pointer to a BSON userModel
 func (u *userModel) toRoot() *models.User {
-	return &models.User{
-		Id:         u.Id.Hex(),
-		Username:   u.Username,
-		Password:   u.Password,
-		Email:      u.Email,
-		Phone:      u.Phone,
-		ImageId:    u.ImageId,
-		RootAdmin:  u.RootAdmin,
-		LastActive: u.LastActive,
-		CreatedAt:  u.CreatedAt,
-		DeletedAt:  u.DeletedAt,
+	user := &models.User{
+		Id:              u.Id.Hex(),
+		Username:        u.Username,
+		Password:        u.Password,
+		PasswordHistory: u.PasswordHistory,
+		Email:           u.Email,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		Phone:           u.Phone,
+		ImageId:         u.ImageId,
+		LastActive:      u.LastActive,
+		CreatedAt:       u.CreatedAt,
+		DeletedAt:       u.DeletedAt,
+	}
+	role := u.Role
+	if role == "" { // users stored before roles existed only had the root admin flag
+		role = models.RoleMember
+		if u.RootAdmin {
+			role = models.RoleRootAdmin
+		}
 	}
+	user.SetRole(role)
+	user.BuildImageURL()
+	return user
 }
@@ -4,25 +4,36 @@ import (
 	"context"
 	"errors"
 	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/password"
 	"github.com/ablancas22/messenger-backend/utilities"
 	"go.mongodb.org/mongo-driver/bson"
 	"golang.org/x/crypto/bcrypt"
+	"log"
 	"sync"
 	"time"
 )
 
+// userTouchInterval limits how often a user's last active time is written while they use the app
+const userTouchInterval = time.Minute
+
+// userCacheTTL is how long a user is trusted by UserFindCached, which bounds how long a role change or deletion made
+// on another instance of the app takes to apply here
+const userCacheTTL = 15 * time.Second
+
 // UserService is used by the app to manage all user related controllers and functionality
 type UserService struct {
 	collection   DBCollection
 	db           DBClient
 	userHandler  *DBHandler[*userModel]
 	groupHandler *DBHandler[*groupModel]
+	policy       *password.Policy
+	cache        *ttlCache[models.User]
 }
 
 // NewUserService is an exported function used to initialize a new UserService struct
-func NewUserService(db DBClient, uHandler *DBHandler[*userModel], gHandler *DBHandler[*groupModel]) *UserService {
+func NewUserService(db DBClient, uHandler *DBHandler[*userModel], gHandler *DBHandler[*groupModel], policy *password.Policy) *UserService {
 	collection := db.GetCollection("users")
-	return &UserService{collection, db, uHandler, gHandler}
+	return &UserService{collection, db, uHandler, gHandler, policy, newTTLCache[models.User](userCacheTTL)}
 }
 
 // checkLinkedRecords ensures the email is unique and groupId valid for a User
@@ -54,7 +65,56 @@ func (p *UserService) checkLinkedRecords(g *groupModel, u *userModel, curUser *u
 	return nil
 }
 
-// AuthenticateUser is used to authenticate users that are signing in
+var (
+	dummyPasswordOnce sync.Once
+	dummyPassword     string
+)
+
+// compareDummyPassword spends as long as checking a real password, so sign ins with an unknown email
+// take the same time as sign ins with a wrong password
+func compareDummyPassword(pw string) {
+	dummyPasswordOnce.Do(func() {
+		dummyPassword, _ = password.Hash("dummy-password")
+	})
+	_ = password.Compare(dummyPassword, pw)
+}
+
+// newPasswordHash checks a new password against the password policy and the user's recent passwords, returning
+// its hash along with the password history to store with it
+func (p *UserService) newPasswordHash(um *userModel, newPassword string) (string, []string, error) {
+	err := p.policy.Check(newPassword)
+	if err != nil {
+		return "", nil, err
+	}
+	recent := append([]string{um.Password}, um.PasswordHistory...)
+	if err = p.policy.CheckReuse(newPassword, recent); err != nil {
+		return "", nil, err
+	}
+	hashedPassword, err := password.Hash(newPassword)
+	if err != nil {
+		return "", nil, err
+	}
+	return hashedPassword, p.policy.Remember(um.Password, um.PasswordHistory), nil
+}
+
+// setPassword stores a new password hash and password history on a user doc
+func (p *UserService) setPassword(um *userModel, hashedPassword string, history []string) error {
+	set := bson.D{
+		{"password", hashedPassword},
+		{"last_modified", time.Now().UTC()},
+	}
+	update := bson.D{{"$set", set}}
+	if len(history) > 0 {
+		update = bson.D{{"$set", append(set, bson.E{Key: "password_history", Value: history})}}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := p.collection.UpdateOne(ctx, bson.D{{"_id", um.Id}}, update)
+	return err
+}
+
+// AuthenticateUser is used to authenticate users that are signing in, an unknown email and a wrong password
+// fail with the same error so sign ins can not be used to find out which emails have accounts
 func (p *UserService) AuthenticateUser(u *models.User) (*models.User, error) {
 	um, err := newUserModel(u)
 	if err != nil {
@@ -62,14 +122,30 @@ func (p *UserService) AuthenticateUser(u *models.User) (*models.User, error) {
 	}
 	checkUser, err := p.userHandler.FindOne(um)
 	if err != nil {
-		return nil, errors.New("invalid email")
+		compareDummyPassword(u.Password)
+		return nil, models.ErrInvalidCredentials
 	}
 	rootUser := checkUser.toRoot()
+	if rootUser.HasRole(models.RoleBot) { // bots only sign in with their bot token
+		compareDummyPassword(u.Password)
+		return nil, models.ErrInvalidCredentials
+	}
 	err = rootUser.Authenticate(u.Password)
-	if err == nil {
-		return rootUser, nil
+	if err != nil {
+		return nil, models.ErrInvalidCredentials
 	}
-	return nil, errors.New("invalid password")
+	if password.NeedsRehash(checkUser.Password) { // upgrade old bcrypt hashes while the password is at hand
+		hashedPassword, err := password.Hash(u.Password)
+		if err == nil {
+			err = p.setPassword(checkUser, hashedPassword, checkUser.PasswordHistory)
+		}
+		if err != nil {
+			log.Println("Password rehash failed:", err)
+		} else {
+			rootUser.Password = hashedPassword
+		}
+	}
+	return rootUser, nil
 }
 
 // UserCreate is used to create a new user
@@ -87,13 +163,18 @@ func (p *UserService) UserCreate(u *models.User) (*models.User, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = p.policy.Check(u.Password); err != nil {
+		return nil, err
+	}
 	err = u.HashPassword()
 	if err != nil {
 		return nil, err
 	}
-	u.RootAdmin = false
-	if docCount == 0 {
-		u.RootAdmin = true
+	u.SetRole(models.RoleMember)
+	u.EmailVerifiedAt = time.Time{}
+	if docCount == 0 { // the first user is the root admin configured by the operator
+		u.SetRole(models.RoleRootAdmin)
+		u.EmailVerifiedAt = time.Now().UTC()
 	}
 	um, err = newUserModel(u)
 	if err != nil {
@@ -116,6 +197,7 @@ func (p *UserService) UserDelete(u *models.User) (*models.User, error) {
 	if err != nil {
 		return nil, err
 	}
+	p.cache.remove(um.Id.Hex())
 	return um.toRoot(), err
 }
 
@@ -149,6 +231,21 @@ func (p *UserService) UserFind(u *models.User) (*models.User, error) {
 	return um.toRoot(), err
 }
 
+// UserFindCached is UserFind by id for authenticating requests, a user is only read from the database once every
+// userCacheTTL. Users changed by this instance of the app are dropped from the cache right away.
+func (p *UserService) UserFindCached(u *models.User) (*models.User, error) {
+	user, ok := p.cache.get(u.Id)
+	if !ok {
+		found, err := p.UserFind(&models.User{Id: u.Id})
+		if err != nil {
+			return nil, err
+		}
+		user = *found
+		p.cache.put(user.Id, user)
+	}
+	return &user, nil
+}
+
 // UserUpdate is used to update an existing user doc
 func (p *UserService) UserUpdate(u *models.User) (*models.User, error) {
 	filter, err := u.BuildFilter()
@@ -172,23 +269,151 @@ func (p *UserService) UserUpdate(u *models.User) (*models.User, error) {
 	if err != nil {
 		return u, err
 	}
+	newPassword := u.Password
 	u.BuildUpdate(curUser.toRoot())
+	u.ImageId = "" // avatars are only changed through UserImageSet
+	u.EmailVerifiedAt = time.Time{}
+	u.PasswordHistory = nil
+	emailChanged := u.Email != curUser.Email
+	if newPassword != "" {
+		u.Password, u.PasswordHistory, err = p.newPasswordHash(curUser, newPassword)
+		if err != nil {
+			return nil, err
+		}
+	}
 	um, err := newUserModel(u)
 	if err != nil {
 		return nil, err
 	}
-	err = u.HashPassword()
-	if u.Password != "" {
+	um, err = p.userHandler.UpdateOne(f, um)
+	if err != nil {
+		return nil, err
+	}
+	p.cache.remove(um.Id.Hex())
+	if emailChanged && !curUser.EmailVerifiedAt.IsZero() { // a new email has to be verified again
+		_, err = p.collection.UpdateOne(ctx, bson.D{{"_id", um.Id}}, bson.D{{"$unset", bson.D{{"email_verified_at", ""}}}})
 		if err != nil {
 			return nil, err
 		}
-		um.Password = u.Password
+		um.EmailVerifiedAt = time.Time{}
 	}
-	um, err = p.userHandler.UpdateOne(f, um)
+	return um.toRoot(), err
+}
+
+// UserVerifyEmail marks the email of a user as verified, as long as it has not changed since it was sent a verification
+func (p *UserService) UserVerifyEmail(u *models.User) (*models.User, error) {
+	um, err := newUserModel(&models.User{Id: u.Id})
 	if err != nil {
 		return nil, err
 	}
-	return um.toRoot(), err
+	um, err = p.userHandler.FindOne(um)
+	if err != nil {
+		return nil, err
+	}
+	if um.Email != u.Email {
+		return nil, errors.New("email address has changed since the verification email was sent")
+	}
+	if !um.EmailVerifiedAt.IsZero() {
+		return um.toRoot(), nil
+	}
+	um.EmailVerifiedAt = time.Now().UTC()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = p.collection.UpdateOne(ctx, bson.D{{"_id", um.Id}}, bson.D{{"$set", bson.D{{"email_verified_at", um.EmailVerifiedAt}}}})
+	if err != nil {
+		return nil, err
+	}
+	return um.toRoot(), nil
+}
+
+// UserPasswordReset is used to set a new password for a user who proved they own their email
+func (p *UserService) UserPasswordReset(u *models.User, newPassword string) (*models.User, error) {
+	if newPassword == "" {
+		return nil, errors.New("missing new password")
+	}
+	um, err := newUserModel(&models.User{Id: u.Id})
+	if err != nil {
+		return nil, err
+	}
+	um, err = p.userHandler.FindOne(um)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, history, err := p.newPasswordHash(um, newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.setPassword(um, hashedPassword, history); err != nil {
+		return nil, err
+	}
+	um.Password = ""
+	um.PasswordHistory = nil
+	return um.toRoot(), nil
+}
+
+// UserRoleSet changes the role of a user
+func (p *UserService) UserRoleSet(u *models.User) (*models.User, error) {
+	um, err := newUserModel(&models.User{Id: u.Id})
+	if err != nil {
+		return nil, err
+	}
+	um, err = p.userHandler.FindOne(um)
+	if err != nil {
+		return nil, err
+	}
+	cur := um.toRoot()
+	cur.SetRole(u.Role)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = p.collection.UpdateOne(ctx, bson.D{{"_id", um.Id}}, bson.D{{"$set", bson.D{{"role", cur.Role}, {"root_admin", cur.RootAdmin}}}})
+	p.cache.remove(cur.Id)
+	if err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// UserImageSet associates an image file with a user, an empty ImageId removes the user's current image
+func (p *UserService) UserImageSet(u *models.User) (*models.User, error) {
+	um, err := newUserModel(&models.User{Id: u.Id})
+	if err != nil {
+		return nil, err
+	}
+	f, err := um.bsonFilter()
+	if err != nil {
+		return nil, err
+	}
+	update := bson.D{{"$set", bson.D{{"image_id", u.ImageId}}}}
+	if u.ImageId == "" {
+		update = bson.D{{"$unset", bson.D{{"image_id", ""}}}}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = p.collection.UpdateOne(ctx, f, update)
+	if err != nil {
+		return nil, err
+	}
+	um, err = p.userHandler.FindOne(um)
+	if err != nil {
+		return nil, err
+	}
+	return um.toRoot(), nil
+}
+
+// UserTouch records that a user was just active, at most once per userTouchInterval
+func (p *UserService) UserTouch(u *models.User) error {
+	if time.Since(u.LastActive) < userTouchInterval {
+		return nil
+	}
+	um, err := newUserModel(&models.User{Id: u.Id})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = p.collection.UpdateOne(ctx, bson.D{{"_id", um.Id}}, bson.D{{"$set", bson.D{{"last_active", time.Now().UTC()}}}})
+	p.cache.remove(u.Id)
+	return err
 }
 
 // UpdatePassword is used to update the currently logged-in user's password
@@ -204,25 +429,15 @@ func (p *UserService) UpdatePassword(u *models.User, currentPassword string, new
 	rootUser := user.toRoot()
 	err = rootUser.Authenticate(currentPassword)
 	if err == nil { // 3. Update doc with new password
-		currentTime := time.Now().UTC()
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+		hashedPassword, history, err := p.newPasswordHash(user, newPassword)
 		if err != nil {
 			return nil, err
 		}
-		filter := bson.D{{"_id", user.Id}}
-		update := bson.D{{"$set",
-			bson.D{
-				{"password", string(hashedPassword)},
-				{"last_modified", currentTime},
-			},
-		}}
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		_, err = p.collection.UpdateOne(ctx, filter, update)
-		if err != nil {
+		if err = p.setPassword(user, hashedPassword, history); err != nil {
 			return nil, err
 		}
 		user.Password = ""
+		user.PasswordHistory = nil
 		return user.toRoot(), nil
 	}
 	return nil, errors.New("invalid password")
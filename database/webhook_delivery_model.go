@@ -0,0 +1,193 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// deliveryAttemptModel structures the outcome of one delivery attempt in a webhookDeliveryModel
+type deliveryAttemptModel struct {
+	At    time.Time `bson:"at"`
+	Error string    `bson:"error,omitempty"`
+}
+
+// webhookDeliveryModel structures a queued webhook delivery BSON document to save in a webhook_deliveries collection
+type webhookDeliveryModel struct {
+	Id            primitive.ObjectID     `bson:"_id,omitempty"`
+	WebhookId     primitive.ObjectID     `bson:"webhook_id,omitempty"`
+	EventType     string                 `bson:"event_type,omitempty"`
+	Payload       string                 `bson:"payload,omitempty"`
+	Status        string                 `bson:"status,omitempty"`
+	Attempts      []deliveryAttemptModel `bson:"attempts,omitempty"`
+	NextAttemptAt time.Time              `bson:"next_attempt_at,omitempty"`
+	DeliveredAt   time.Time              `bson:"delivered_at,omitempty"`
+	LastModified  time.Time              `bson:"last_modified,omitempty"`
+	CreatedAt     time.Time              `bson:"created_at,omitempty"`
+}
+
+// newWebhookDeliveryModel initializes a new pointer to a webhookDeliveryModel struct from a pointer to a JSON WebhookDelivery struct
+func newWebhookDeliveryModel(d *models.WebhookDelivery) (dm *webhookDeliveryModel, err error) {
+	dm = &webhookDeliveryModel{
+		EventType:     d.EventType,
+		Payload:       d.Payload,
+		Status:        d.Status,
+		NextAttemptAt: d.NextAttemptAt,
+		DeliveredAt:   d.DeliveredAt,
+		LastModified:  d.LastModified,
+		CreatedAt:     d.CreatedAt,
+	}
+	for _, a := range d.Attempts {
+		dm.Attempts = append(dm.Attempts, deliveryAttemptModel{At: a.At, Error: a.Error})
+	}
+	if d.Id != "" && d.Id != "000000000000000000000000" {
+		if dm.Id, err = primitive.ObjectIDFromHex(d.Id); err != nil {
+			return
+		}
+	}
+	if d.WebhookId != "" && d.WebhookId != "000000000000000000000000" {
+		dm.WebhookId, err = primitive.ObjectIDFromHex(d.WebhookId)
+	}
+	return
+}
+
+// update the webhookDeliveryModel using an overwrite bson.D doc
+func (d *webhookDeliveryModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	dm := webhookDeliveryModel{}
+	err = bson.Unmarshal(data, &dm)
+	if len(dm.Status) > 0 {
+		d.Status = dm.Status
+	}
+	if len(dm.Attempts) > 0 {
+		d.Attempts = dm.Attempts
+	}
+	if !dm.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = dm.NextAttemptAt
+	}
+	if !dm.DeliveredAt.IsZero() {
+		d.DeliveredAt = dm.DeliveredAt
+	}
+	if !dm.LastModified.IsZero() {
+		d.LastModified = dm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the webhookDeliveryModel
+func (d *webhookDeliveryModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, d)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the webhookDeliveryModel
+func (d *webhookDeliveryModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	dm := webhookDeliveryModel{}
+	err = bson.Unmarshal(data, &dm)
+	if !dm.Id.IsZero() {
+		return d.Id == dm.Id
+	}
+	if dm.WebhookId.IsZero() && dm.Status == "" {
+		return false
+	}
+	if !dm.WebhookId.IsZero() && d.WebhookId != dm.WebhookId {
+		return false
+	}
+	return dm.Status == "" || d.Status == dm.Status
+}
+
+// getID returns the unique identifier of the webhookDeliveryModel
+func (d *webhookDeliveryModel) getID() (id interface{}) {
+	return d.Id
+}
+
+// addTimeStamps updates a webhookDeliveryModel struct with a timestamp
+func (d *webhookDeliveryModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	d.LastModified = currentTime
+	if newRecord {
+		d.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a webhookDeliveryModel has a value assigned for Id, if no value a new one is generated and assigned
+func (d *webhookDeliveryModel) addObjectID() {
+	if d.Id.IsZero() {
+		d.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a webhookDeliveryModel struct postProcess
+func (d *webhookDeliveryModel) postProcess() (err error) {
+	if d.WebhookId.IsZero() {
+		err = errors.New("webhook delivery record does not have a webhook_id")
+	}
+	return
+}
+
+// toDoc converts the bson webhookDeliveryModel into a bson.D
+func (d *webhookDeliveryModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(d)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the webhookDeliveryModel data
+func (d *webhookDeliveryModel) bsonFilter() (doc bson.D, err error) {
+	if !d.Id.IsZero() {
+		doc = bson.D{{"_id", d.Id}}
+		return
+	}
+	if !d.WebhookId.IsZero() {
+		doc = append(doc, bson.E{Key: "webhook_id", Value: d.WebhookId})
+	}
+	if d.Status != "" {
+		doc = append(doc, bson.E{Key: "status", Value: d.Status})
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the webhookDeliveryModel data
+func (d *webhookDeliveryModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := d.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a WebhookDelivery JSON struct from a pointer to a BSON webhookDeliveryModel
+func (d *webhookDeliveryModel) toRoot() *models.WebhookDelivery {
+	delivery := &models.WebhookDelivery{
+		Id:            d.Id.Hex(),
+		WebhookId:     d.WebhookId.Hex(),
+		EventType:     d.EventType,
+		Payload:       d.Payload,
+		Status:        d.Status,
+		NextAttemptAt: d.NextAttemptAt,
+		DeliveredAt:   d.DeliveredAt,
+		LastModified:  d.LastModified,
+		CreatedAt:     d.CreatedAt,
+	}
+	for _, a := range d.Attempts {
+		delivery.Attempts = append(delivery.Attempts, models.DeliveryAttempt{At: a.At, Error: a.Error})
+	}
+	return delivery
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// webhookModel structures a webhook BSON document to save in a webhooks collection
+type webhookModel struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty"`
+	GroupId      primitive.ObjectID `bson:"group_id,omitempty"`
+	URL          string             `bson:"url,omitempty"`
+	Secret       string             `bson:"secret,omitempty"`
+	Events       []string           `bson:"events,omitempty"`
+	Disabled     bool               `bson:"disabled"`
+	Failures     int                `bson:"failures"`
+	DisabledAt   time.Time          `bson:"disabled_at,omitempty"`
+	CreatedBy    primitive.ObjectID `bson:"created_by,omitempty"`
+	LastModified time.Time          `bson:"last_modified,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// newWebhookModel initializes a new pointer to a webhookModel struct from a pointer to a JSON Webhook struct
+func newWebhookModel(w *models.Webhook) (wm *webhookModel, err error) {
+	wm = &webhookModel{
+		URL:          w.URL,
+		Secret:       w.Secret,
+		Events:       w.Events,
+		Disabled:     w.Disabled,
+		Failures:     w.Failures,
+		DisabledAt:   w.DisabledAt,
+		LastModified: w.LastModified,
+		CreatedAt:    w.CreatedAt,
+	}
+	if w.Id != "" && w.Id != "000000000000000000000000" {
+		if wm.Id, err = primitive.ObjectIDFromHex(w.Id); err != nil {
+			return
+		}
+	}
+	if w.GroupId != "" && w.GroupId != "000000000000000000000000" {
+		if wm.GroupId, err = primitive.ObjectIDFromHex(w.GroupId); err != nil {
+			return
+		}
+	}
+	if w.CreatedBy != "" && w.CreatedBy != "000000000000000000000000" {
+		wm.CreatedBy, err = primitive.ObjectIDFromHex(w.CreatedBy)
+	}
+	return
+}
+
+// update the webhookModel using an overwrite bson.D doc, disabled and failures are copied whenever they are set
+// so that a webhook can be enabled again
+func (w *webhookModel) update(doc interface{}) (err error) {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return
+	}
+	wm := webhookModel{}
+	set := bson.M{}
+	if err = bson.Unmarshal(data, &wm); err != nil {
+		return
+	}
+	if err = bson.Unmarshal(data, &set); err != nil {
+		return
+	}
+	if len(wm.URL) > 0 {
+		w.URL = wm.URL
+	}
+	if len(wm.Secret) > 0 {
+		w.Secret = wm.Secret
+	}
+	if _, ok := set["events"]; ok {
+		w.Events = wm.Events
+	}
+	if _, ok := set["disabled"]; ok {
+		w.Disabled = wm.Disabled
+		w.DisabledAt = wm.DisabledAt
+	}
+	if _, ok := set["failures"]; ok {
+		w.Failures = wm.Failures
+	}
+	if !wm.LastModified.IsZero() {
+		w.LastModified = wm.LastModified
+	}
+	return
+}
+
+// bsonLoad loads a bson doc into the webhookModel
+func (w *webhookModel) bsonLoad(doc bson.D) (err error) {
+	bData, err := bsonMarshall(doc)
+	if err != nil {
+		return err
+	}
+	err = bson.Unmarshal(bData, w)
+	return err
+}
+
+// match compares an input bson doc and returns whether there's a match with the webhookModel
+func (w *webhookModel) match(doc interface{}) bool {
+	data, err := bsonMarshall(doc)
+	if err != nil {
+		return false
+	}
+	wm := webhookModel{}
+	err = bson.Unmarshal(data, &wm)
+	if !wm.Id.IsZero() {
+		return w.Id == wm.Id
+	}
+	if !wm.GroupId.IsZero() {
+		return w.GroupId == wm.GroupId
+	}
+	return false
+}
+
+// getID returns the unique identifier of the webhookModel
+func (w *webhookModel) getID() (id interface{}) {
+	return w.Id
+}
+
+// addTimeStamps updates a webhookModel struct with a timestamp
+func (w *webhookModel) addTimeStamps(newRecord bool) {
+	currentTime := time.Now().UTC()
+	w.LastModified = currentTime
+	if newRecord {
+		w.CreatedAt = currentTime
+	}
+}
+
+// addObjectID checks if a webhookModel has a value assigned for Id, if no value a new one is generated and assigned
+func (w *webhookModel) addObjectID() {
+	if w.Id.IsZero() {
+		w.Id = primitive.NewObjectID()
+	}
+}
+
+// postProcess updates a webhookModel struct postProcess
+func (w *webhookModel) postProcess() (err error) {
+	if w.GroupId.IsZero() {
+		err = errors.New("webhook record does not have a group_id")
+	}
+	return
+}
+
+// toDoc converts the bson webhookModel into a bson.D
+func (w *webhookModel) toDoc() (doc bson.D, err error) {
+	data, err := bson.Marshal(w)
+	if err != nil {
+		return
+	}
+	err = bson.Unmarshal(data, &doc)
+	return
+}
+
+// bsonFilter generates a bson filter for MongoDB queries from the webhookModel data
+func (w *webhookModel) bsonFilter() (doc bson.D, err error) {
+	if !w.Id.IsZero() {
+		doc = bson.D{{"_id", w.Id}}
+	} else if !w.GroupId.IsZero() {
+		doc = bson.D{{"group_id", w.GroupId}}
+	}
+	return
+}
+
+// bsonUpdate generates a bson update for MongoDB queries from the webhookModel data
+func (w *webhookModel) bsonUpdate() (doc bson.D, err error) {
+	inner, err := w.toDoc()
+	if err != nil {
+		return
+	}
+	doc = bson.D{{"$set", inner}}
+	return
+}
+
+// toRoot creates and return a new pointer to a Webhook JSON struct from a pointer to a BSON webhookModel
+func (w *webhookModel) toRoot() *models.Webhook {
+	return &models.Webhook{
+		Id:           w.Id.Hex(),
+		GroupId:      w.GroupId.Hex(),
+		URL:          w.URL,
+		Secret:       w.Secret,
+		Events:       w.Events,
+		Disabled:     w.Disabled,
+		Failures:     w.Failures,
+		DisabledAt:   w.DisabledAt,
+		CreatedBy:    w.CreatedBy.Hex(),
+		LastModified: w.LastModified,
+		CreatedAt:    w.CreatedAt,
+	}
+}
@@ -0,0 +1,382 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/webhook"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// webhookMaxAttempts is how many times a delivery is tried before it is marked as failed
+	webhookMaxAttempts = 6
+	// webhookDisableAfter is how many attempts in a row may fail before a webhook is disabled
+	webhookDisableAfter = 10
+	// defaultWebhookRetryBackoff is the delay before the first retry of a delivery, it doubles with every attempt
+	defaultWebhookRetryBackoff = 30 * time.Second
+	// maxWebhookRetryBackoff caps the delay between the retries of a delivery
+	maxWebhookRetryBackoff = time.Hour
+	// webhookDeliveryTimeout bounds how long an endpoint has to accept a delivery
+	webhookDeliveryTimeout = 10 * time.Second
+	// webhookDeliveryLease is how long a claimed delivery is left to the attempt that claimed it, it can be claimed
+	// again once the lease ran out in case that attempt never finished
+	webhookDeliveryLease = time.Minute
+)
+
+// webhookRetryBackoff returns the delay before retrying a delivery that has been attempted n times,
+// the first delay is configured with WEBHOOK_RETRY_BACKOFF
+func webhookRetryBackoff(n int) time.Duration {
+	backoff, err := time.ParseDuration(os.Getenv("WEBHOOK_RETRY_BACKOFF"))
+	if err != nil || backoff <= 0 {
+		backoff = defaultWebhookRetryBackoff
+	}
+	for i := 1; i < n && backoff < maxWebhookRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxWebhookRetryBackoff {
+		backoff = maxWebhookRetryBackoff
+	}
+	return backoff
+}
+
+// WebhookService is used by the app to manage the webhooks of groups and the queue of their deliveries
+type WebhookService struct {
+	collection      DBCollection
+	db              DBClient
+	handler         *DBHandler[*webhookModel]
+	deliveryHandler *DBHandler[*webhookDeliveryModel]
+	client          *http.Client
+}
+
+// NewWebhookService is an exported function used to initialize a new WebhookService struct
+func NewWebhookService(db DBClient, handler *DBHandler[*webhookModel], dHandler *DBHandler[*webhookDeliveryModel], client *http.Client) *WebhookService {
+	collection := db.GetCollection("webhooks")
+	return &WebhookService{collection: collection, db: db, handler: handler, deliveryHandler: dHandler, client: client}
+}
+
+// WebhookCreate registers a new webhook for a group with a generated signing secret
+func (p *WebhookService) WebhookCreate(w *models.Webhook) (*models.Webhook, error) {
+	err := w.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	wm, err := newWebhookModel(&models.Webhook{
+		GroupId:   w.GroupId,
+		URL:       w.URL,
+		Secret:    secret,
+		Events:    w.Events,
+		CreatedBy: w.CreatedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	wm, err = p.handler.InsertOne(wm)
+	if err != nil {
+		return nil, err
+	}
+	return wm.toRoot(), nil
+}
+
+// WebhooksFind lists the webhooks of a group
+func (p *WebhookService) WebhooksFind(w *models.Webhook) ([]*models.Webhook, error) {
+	hooks := []*models.Webhook{}
+	if !w.CheckID("group_id") {
+		return hooks, errors.New("missing the following webhook fields: group_id")
+	}
+	wm, err := newWebhookModel(&models.Webhook{GroupId: w.GroupId})
+	if err != nil {
+		return hooks, err
+	}
+	wms, err := p.handler.FindMany(wm)
+	if err != nil {
+		return hooks, err
+	}
+	for _, wm = range wms {
+		hooks = append(hooks, wm.toRoot())
+	}
+	return hooks, nil
+}
+
+// WebhookFind looks up a webhook by its id
+func (p *WebhookService) WebhookFind(w *models.Webhook) (*models.Webhook, error) {
+	if !w.CheckID("id") {
+		return nil, models.ErrWebhookNotFound
+	}
+	wm, err := newWebhookModel(&models.Webhook{Id: w.Id})
+	if err != nil {
+		return nil, err
+	}
+	wms, err := p.handler.FindMany(wm)
+	if err != nil {
+		return nil, err
+	}
+	if len(wms) == 0 {
+		return nil, models.ErrWebhookNotFound
+	}
+	return wms[0].toRoot(), nil
+}
+
+// WebhookUpdate saves the url, events and disabled state of a webhook, enabling a webhook clears its failures
+func (p *WebhookService) WebhookUpdate(w *models.Webhook) (*models.Webhook, error) {
+	err := w.Validate("update")
+	if err != nil {
+		return nil, err
+	}
+	cur, err := p.WebhookFind(w)
+	if err != nil {
+		return nil, err
+	}
+	if w.URL != "" {
+		cur.URL = w.URL
+	}
+	cur.Events = w.Events
+	if !w.Disabled {
+		cur.Failures = 0
+		cur.DisabledAt = time.Time{}
+	} else if !cur.Disabled {
+		cur.DisabledAt = time.Now().UTC()
+	}
+	cur.Disabled = w.Disabled
+	return p.save(cur)
+}
+
+// save overwrites a webhook doc
+func (p *WebhookService) save(w *models.Webhook) (*models.Webhook, error) {
+	wm, err := newWebhookModel(w)
+	if err != nil {
+		return nil, err
+	}
+	wm, err = p.handler.UpdateOne(&webhookModel{Id: wm.Id}, wm)
+	if err != nil {
+		return nil, err
+	}
+	return wm.toRoot(), nil
+}
+
+// WebhookDelete removes a webhook, its pending deliveries fail when they come up
+func (p *WebhookService) WebhookDelete(w *models.Webhook) (*models.Webhook, error) {
+	cur, err := p.WebhookFind(w)
+	if err != nil {
+		return nil, err
+	}
+	wm, err := newWebhookModel(&models.Webhook{Id: cur.Id})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.handler.DeleteOne(wm); err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// WebhookDeliveriesFind lists the deliveries queued for a webhook along with their attempts
+func (p *WebhookService) WebhookDeliveriesFind(d *models.WebhookDelivery) ([]*models.WebhookDelivery, error) {
+	deliveries := []*models.WebhookDelivery{}
+	dm, err := newWebhookDeliveryModel(&models.WebhookDelivery{WebhookId: d.WebhookId, Status: d.Status})
+	if err != nil {
+		return deliveries, err
+	}
+	if dm.WebhookId.IsZero() {
+		return deliveries, errors.New("missing the following webhook delivery fields: webhook_id")
+	}
+	dms, err := p.deliveryHandler.FindMany(dm)
+	if err != nil {
+		return deliveries, err
+	}
+	for _, dm = range dms {
+		deliveries = append(deliveries, dm.toRoot())
+	}
+	return deliveries, nil
+}
+
+// WebhookEnqueue queues a delivery of an event to every enabled webhook of its group subscribed to it,
+// returning the deliveries that were queued
+func (p *WebhookService) WebhookEnqueue(e *models.Event) ([]*models.WebhookDelivery, error) {
+	var queued []*models.WebhookDelivery
+	hooks, err := p.WebhooksFind(&models.Webhook{GroupId: e.GroupId})
+	if err != nil {
+		return queued, err
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return queued, err
+	}
+	for _, w := range hooks {
+		if w.Disabled || !w.Subscribed(e.Type) {
+			continue
+		}
+		dm, err := newWebhookDeliveryModel(&models.WebhookDelivery{
+			WebhookId:     w.Id,
+			EventType:     e.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return queued, err
+		}
+		if dm, err = p.deliveryHandler.InsertOne(dm); err != nil {
+			return queued, err
+		}
+		queued = append(queued, dm.toRoot())
+	}
+	return queued, nil
+}
+
+// deliver attempts a queued delivery and records the outcome on it and on its webhook, disabling webhooks that
+// keep failing
+func (p *WebhookService) deliver(dm *webhookDeliveryModel) error {
+	w, err := p.WebhookFind(&models.Webhook{Id: dm.WebhookId.Hex()})
+	if err != nil || w.Disabled {
+		dm.Status = models.DeliveryFailed
+		return errors.New("webhook is not enabled")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookDeliveryTimeout)
+	defer cancel()
+	now := time.Now().UTC()
+	err = webhook.Post(ctx, p.client, w.URL, w.Secret, dm.EventType, []byte(dm.Payload))
+	attempt := deliveryAttemptModel{At: now}
+	if err == nil {
+		dm.Attempts = append(dm.Attempts, attempt)
+		dm.Status = models.DeliveryDelivered
+		dm.DeliveredAt = now
+		if w.Failures > 0 {
+			return p.updateWebhook(bson.D{{"_id", dm.WebhookId}}, bson.D{{"$set", bson.D{{"failures", 0}}}})
+		}
+		return nil
+	}
+	attempt.Error = err.Error()
+	dm.Attempts = append(dm.Attempts, attempt)
+	dm.Status = models.DeliveryPending
+	dm.NextAttemptAt = now.Add(webhookRetryBackoff(len(dm.Attempts)))
+	if len(dm.Attempts) >= webhookMaxAttempts {
+		dm.Status = models.DeliveryFailed
+	}
+	if saveErr := p.recordFailure(dm.WebhookId, now); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// recordFailure counts a failed attempt against a webhook and disables it once webhookDisableAfter attempts in a row
+// failed. The count is incremented in place so attempts failing at the same time are all counted.
+func (p *WebhookService) recordFailure(id primitive.ObjectID, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var wm webhookModel
+	err := p.handler.collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}}, bson.D{{"$inc", bson.D{{"failures", 1}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&wm)
+	if err != nil || wm.Failures < webhookDisableAfter {
+		return err
+	}
+	return p.updateWebhook(bson.D{{"_id", id}, {"disabled", false}},
+		bson.D{{"$set", bson.D{{"disabled", true}, {"disabled_at", now}}}})
+}
+
+// updateWebhook applies an update to the webhook matching filter, a webhook that was deleted or no longer matches is
+// left alone
+func (p *WebhookService) updateWebhook(filter bson.D, update bson.D) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := p.handler.collection.FindOneAndUpdate(ctx, filter, update).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	return err
+}
+
+// claim marks a due delivery as delivering until its lease runs out, returning nil when it is not due or another
+// attempt claimed it first. Deliveries whose lease ran out are due again.
+func (p *WebhookService) claim(id primitive.ObjectID) (*webhookDeliveryModel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	filter := bson.D{
+		{"_id", id},
+		{"status", bson.D{{"$in", bson.A{models.DeliveryPending, models.DeliveryDelivering}}}},
+		{"next_attempt_at", bson.D{{"$lte", now}}},
+	}
+	update := bson.D{{"$set", bson.D{
+		{"status", models.DeliveryDelivering},
+		{"next_attempt_at", now.Add(webhookDeliveryLease)},
+		{"last_modified", now},
+	}}}
+	var dm webhookDeliveryModel
+	err := p.deliveryHandler.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&dm)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &dm, nil
+}
+
+// attempt claims a delivery and attempts it, returning whether it was delivered
+func (p *WebhookService) attempt(id primitive.ObjectID) (bool, error) {
+	dm, err := p.claim(id)
+	if err != nil || dm == nil {
+		return false, err
+	}
+	delivered := p.deliver(dm) == nil
+	_, err = p.deliveryHandler.UpdateOne(&webhookDeliveryModel{Id: dm.Id}, dm)
+	return delivered, err
+}
+
+// WebhookDeliver attempts a queued delivery right away, unless it is not due or another attempt claimed it first
+func (p *WebhookService) WebhookDeliver(d *models.WebhookDelivery) error {
+	dm, err := newWebhookDeliveryModel(&models.WebhookDelivery{Id: d.Id})
+	if err != nil {
+		return err
+	}
+	_, err = p.attempt(dm.Id)
+	return err
+}
+
+// WebhookDeliverPending attempts the queued deliveries that are due, returning how many were delivered. Every
+// delivery is claimed before it is attempted, so instances of the app running it at the same time never attempt the
+// same delivery twice.
+func (p *WebhookService) WebhookDeliverPending() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cur, err := p.deliveryHandler.collection.Find(ctx, bson.D{
+		{"status", bson.D{{"$in", bson.A{models.DeliveryPending, models.DeliveryDelivering}}}},
+		{"next_attempt_at", bson.D{{"$lte", time.Now().UTC()}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	cursor := checkCursorENV(cur)
+	defer cursor.Close(ctx)
+	var due []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var dm webhookDeliveryModel
+		if err = cursor.Decode(&dm); err != nil {
+			return 0, err
+		}
+		due = append(due, dm.Id)
+	}
+	delivered := 0
+	for _, id := range due {
+		ok, err := p.attempt(id)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work, it returns how many items it processed
+type Job func() (int, error)
+
+// entry is a Job scheduled on a Runner
+type entry struct {
+	name     string
+	interval time.Duration
+	job      Job
+}
+
+// Runner runs Jobs in the background, each on its own interval. A Job never overlaps with itself, a run that takes
+// longer than its interval delays the next one.
+type Runner struct {
+	mu      sync.Mutex
+	entries []entry
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewRunner returns a Runner without Jobs
+func NewRunner() *Runner {
+	return &Runner{stop: make(chan struct{})}
+}
+
+// Every schedules job to run every interval once the Runner is started, name identifies it in the logs
+func (r *Runner) Every(name string, interval time.Duration, job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry{name, interval, job})
+}
+
+// Start runs every scheduled Job in the background until Stop is called
+func (r *Runner) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		r.wg.Add(1)
+		go r.loop(e)
+	}
+}
+
+// Stop stops the Runner and waits for the Jobs that are running to finish
+func (r *Runner) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+}
+
+// loop runs a Job every time its ticker fires until the Runner is stopped
+func (r *Runner) loop(e entry) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			run(e)
+		}
+	}
+}
+
+// run runs a Job once and logs its outcome
+func run(e entry) {
+	processed, err := e.job()
+	if err != nil {
+		log.Println("Job "+e.name+" failed:", err)
+		return
+	}
+	if processed > 0 {
+		log.Println("Job "+e.name+" processed:", processed)
+	}
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner(t *testing.T) {
+	var fast, failing, slow int32
+	r := NewRunner()
+	r.Every("fast", time.Millisecond, func() (int, error) {
+		atomic.AddInt32(&fast, 1)
+		return 1, nil
+	})
+	r.Every("failing", time.Millisecond, func() (int, error) {
+		atomic.AddInt32(&failing, 1)
+		return 0, errors.New("unavailable")
+	})
+	r.Every("slow", time.Hour, func() (int, error) {
+		atomic.AddInt32(&slow, 1)
+		return 0, nil
+	})
+	r.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for (atomic.LoadInt32(&fast) < 3 || atomic.LoadInt32(&failing) < 3) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	r.Stop()
+	if ran, failed := atomic.LoadInt32(&fast), atomic.LoadInt32(&failing); ran < 3 || failed < 3 {
+		t.Errorf("Runner ran fast %d and failing %d times, want at least 3 each", ran, failed)
+	}
+	if atomic.LoadInt32(&slow) != 0 {
+		t.Errorf("Runner ran slow before its interval")
+	}
+	stopped := atomic.LoadInt32(&fast)
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadInt32(&fast) != stopped {
+		t.Errorf("Runner kept running jobs after Stop")
+	}
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"sync"
+)
+
+// FakeMailer is an in-memory Mailer for tests that keeps every email it was asked to send
+type FakeMailer struct {
+	// Err, when set, is returned by Send to simulate an unavailable relay
+	Err  error
+	mu   sync.Mutex
+	sent []*Message
+}
+
+// Send records m
+func (f *FakeMailer) Send(m *Message) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	if err := m.validate(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+// Sent returns the emails sent to a recipient, oldest first
+func (f *FakeMailer) Sent(to string) []*Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var sent []*Message
+	for _, m := range f.sent {
+		if m.To == to {
+			sent = append(sent, m)
+		}
+	}
+	return sent
+}
@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// FileMailer writes every email to its own .eml file in a directory, it is intended for local development
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer returns a FileMailer writing to dir
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{Dir: dir, From: from}
+}
+
+// Send writes m to a new file in the mail directory
+func (f *FileMailer) Send(m *Message) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.Dir, 0o700); err != nil {
+		return err
+	}
+	name := strconv.FormatInt(time.Now().UnixNano(), 10) + ".eml"
+	return os.WriteFile(filepath.Join(f.Dir, name), (&SMTPMailer{From: f.From}).format(m), 0o600)
+}
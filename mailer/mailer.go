@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"errors"
+	"log"
+	"strings"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is implemented by the transports the mail outbox hands queued emails to
+type Mailer interface {
+	Send(m *Message) error
+}
+
+// New returns the Mailer configured by kind, one of smtp, file, log or fake.
+// For smtp the address is [user:password@]host:port and for file it is the directory emails are written to.
+func New(kind string, address string, from string) (Mailer, error) {
+	switch kind {
+	case "smtp":
+		if address == "" || from == "" {
+			return nil, errors.New("smtp mailer requires an address and a from address")
+		}
+		return NewSMTPMailer(address, from), nil
+	case "file":
+		if address == "" {
+			return nil, errors.New("file mailer requires a directory")
+		}
+		return NewFileMailer(address, from), nil
+	case "fake":
+		return &FakeMailer{}, nil
+	case "log", "":
+		return &LogMailer{}, nil
+	}
+	return nil, errors.New("unrecognized mailer: " + kind)
+}
+
+// LogMailer writes emails to the application log instead of sending them, it is intended for local development only
+type LogMailer struct{}
+
+// Send logs the recipient, subject and body of m
+func (l *LogMailer) Send(m *Message) error {
+	log.Printf("Mail to %s: %s\n%s", m.To, m.Subject, m.Body)
+	return nil
+}
+
+// validate rejects messages that are missing a recipient or would let a header be injected
+func (m *Message) validate() error {
+	if m.To == "" {
+		return errors.New("message is missing a recipient")
+	}
+	if strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return errors.New("message headers can not contain line breaks")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveSMTP accepts a single SMTP session on l and sends the received message data to got
+func serveSMTP(t *testing.T, l net.Listener, got chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM"), strings.HasPrefix(cmd, "RCPT TO"):
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err = r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			got <- data.String()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			t.Errorf("serveSMTP() unexpected command %q", cmd)
+			reply("500 unrecognized command")
+		}
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can not listen on localhost: %v", err)
+	}
+	defer l.Close()
+	got := make(chan string, 1)
+	go serveSMTP(t, l, got)
+	m, err := New("smtp", l.Addr().String(), "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Send(&Message{To: "user@example.com", Subject: "Hello", Body: "line one\nline two"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	data := <-got
+	for _, want := range []string{"From: noreply@example.com\r\n", "To: user@example.com\r\n", "Subject: Hello\r\n", "line one\r\nline two"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Send() message is missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestMailer_Send(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string   // The name of the test
+		kind    string   // The kind of mailer
+		message *Message // The message being sent
+		wantErr bool     // whether we want an error.
+	}{
+		{"file", "file", &Message{To: "user@example.com", Subject: "Hello", Body: "hi"}, false},
+		{"fake", "fake", &Message{To: "user@example.com", Subject: "Hello", Body: "hi"}, false},
+		{"log", "log", &Message{To: "user@example.com", Subject: "Hello", Body: "hi"}, false},
+		{"missing recipient", "fake", &Message{Subject: "Hello"}, true},
+		{"header injection", "file", &Message{To: "user@example.com", Subject: "Hello\r\nBcc: someone@example.com"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.kind, dir, "noreply@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = m.Send(tt.message); (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fake, ok := m.(*FakeMailer); ok && !tt.wantErr && len(fake.Sent(tt.message.To)) != 1 {
+				t.Errorf("Send() did not record the message")
+			}
+		})
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Send() wrote %d files, want 1", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil || !strings.Contains(string(data), "To: user@example.com") {
+		t.Errorf("Send() wrote an unexpected file %q", data)
+	}
+	if _, err = New("carrier-pigeon", "", ""); err == nil {
+		t.Errorf("New() accepted an unrecognized mailer")
+	}
+}
@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"bytes"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends emails through an SMTP relay, upgrading to TLS when the relay offers STARTTLS
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+// NewSMTPMailer returns an SMTPMailer for an address of the form [user:password@]host:port
+func NewSMTPMailer(address string, from string) *SMTPMailer {
+	s := &SMTPMailer{Addr: address, From: from}
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		s.Addr = address[at+1:]
+		user, password, _ := strings.Cut(address[:at], ":")
+		host, _, _ := strings.Cut(s.Addr, ":")
+		s.Auth = smtp.PlainAuth("", user, password, host)
+	}
+	return s
+}
+
+// format renders m as an RFC 5322 message
+func (s *SMTPMailer) format(m *Message) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + s.From + "\r\n")
+	buf.WriteString("To: " + m.To + "\r\n")
+	buf.WriteString("Subject: " + m.Subject + "\r\n")
+	buf.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// Send delivers m to the relay
+func (s *SMTPMailer) Send(m *Message) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{m.To}, s.format(m))
+}
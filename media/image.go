@@ -0,0 +1,170 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // registers the gif decoder
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// ThumbnailSizes are the longest-edge sizes, in pixels, generated for uploaded images
+var ThumbnailSizes = []int{64, 256, 1024}
+
+// maxImagePixels guards against decompression bombs by limiting the decoded image area
+const maxImagePixels = 50000000
+
+// ErrUnsupportedImage is returned when an image can not be safely decoded
+var ErrUnsupportedImage = errors.New("unsupported or invalid image")
+
+// Thumbnail is a resized copy of an uploaded image
+type Thumbnail struct {
+	Size     int
+	Width    int
+	Height   int
+	FileType string
+	Data     []byte
+}
+
+// ProcessedImage is an uploaded image with its metadata removed and thumbnails generated
+type ProcessedImage struct {
+	Data       []byte
+	FileType   string
+	Width      int
+	Height     int
+	Thumbnails []*Thumbnail
+}
+
+// IsImage determines whether a mime type is an image format that can be processed
+func IsImage(fileType string) bool {
+	switch strings.ToLower(fileType) {
+	case "image/jpeg", "image/jpg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// ProcessImage strips privacy sensitive metadata from an image, records its dimensions and generates thumbnails
+func ProcessImage(data []byte, fileType string) (*ProcessedImage, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrUnsupportedImage
+	}
+	p := &ProcessedImage{Data: data, FileType: "image/" + format}
+	switch format {
+	case "jpeg":
+		orientation := jpegOrientation(data)
+		p.Data, err = stripJPEG(data)
+		if err != nil {
+			return nil, err
+		}
+		if orientation > 1 {
+			img, err := jpeg.Decode(bytes.NewReader(p.Data))
+			if err != nil {
+				return nil, ErrUnsupportedImage
+			}
+			var buf bytes.Buffer
+			if err = jpeg.Encode(&buf, orient(img, orientation), &jpeg.Options{Quality: 95}); err != nil {
+				return nil, err
+			}
+			p.Data = buf.Bytes()
+		}
+	case "png":
+		p.Data, err = stripPNG(data)
+		if err != nil {
+			return nil, err
+		}
+	case "gif":
+	default:
+		return nil, ErrUnsupportedImage
+	}
+	img, _, err := image.Decode(bytes.NewReader(p.Data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	p.Width = img.Bounds().Dx()
+	p.Height = img.Bounds().Dy()
+	for _, size := range ThumbnailSizes {
+		if p.Width <= size && p.Height <= size {
+			break
+		}
+		thumb, err := resize(img, size, format)
+		if err != nil {
+			return nil, err
+		}
+		p.Thumbnails = append(p.Thumbnails, thumb)
+	}
+	return p, nil
+}
+
+// resize scales img so that its longest edge is size pixels and encodes it
+func resize(img image.Image, size int, format string) (*Thumbnail, error) {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	tw, th := size, size
+	if w > h {
+		th = h * size / w
+	} else {
+		tw = w * size / h
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	thumb := &Thumbnail{Size: size, Width: tw, Height: th}
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		thumb.FileType = "image/jpeg"
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	} else {
+		thumb.FileType = "image/png"
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+	thumb.Data = buf.Bytes()
+	return thumb, nil
+}
+
+// orient applies an EXIF orientation transform so the image displays upright without metadata
+func orient(img image.Image, orientation int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			sx, sy := dx, dy
+			switch orientation {
+			case 2:
+				sx = w - 1 - dx
+			case 3:
+				sx, sy = w-1-dx, h-1-dy
+			case 4:
+				sy = h - 1 - dy
+			case 5:
+				sx, sy = dy, dx
+			case 6:
+				sx, sy = dy, h-1-dx
+			case 7:
+				sx, sy = w-1-dy, h-1-dx
+			case 8:
+				sx, sy = w-1-dy, dx
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
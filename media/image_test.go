@@ -0,0 +1,136 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage returns a w x h image with a red top left pixel
+func testImage(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{B: 255, A: 255})
+		}
+	}
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	return img
+}
+
+// testJPEG encodes a test image as a JPEG carrying an EXIF block with the given orientation
+func testJPEG(t *testing.T, w, h, orientation int) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(w, h), &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0, 0, 0, 0, 0}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, []byte("GPSLatitude")...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(payload)+2))
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	out = append(out, payload...)
+	out = append(out, []byte{0xFF, 0xFE, 0, 9, 'c', 'o', 'm', 'e', 'r', 'a', '!'}...)
+	return append(out, data[2:]...)
+}
+
+// testPNG encodes a test image as a PNG carrying a tEXt chunk
+func testPNG(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(w, h)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	text := []byte("Comment\x00GPSLatitude")
+	chunk := make([]byte, 8, 12+len(text))
+	binary.BigEndian.PutUint32(chunk, uint32(len(text)))
+	copy(chunk[4:], "tEXt")
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	ihdrEnd := len(pngSignature) + 25
+	out := append([]byte{}, data[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+func TestProcessImage(t *testing.T) {
+	tests := []struct {
+		name       string // The name of the test
+		data       func(t *testing.T) []byte
+		fileType   string // The declared mime type of the upload
+		wantErr    bool   // whether we want an error.
+		width      int    // The expected width after orientation is applied
+		height     int    // The expected height after orientation is applied
+		thumbnails int    // The expected number of thumbnails
+	}{
+		{"jpeg", func(t *testing.T) []byte { return testJPEG(t, 300, 200, 1) }, "image/jpeg", false, 300, 200, 2},
+		{"rotated jpeg", func(t *testing.T) []byte { return testJPEG(t, 300, 200, 6) }, "image/jpeg", false, 200, 300, 2},
+		{"png", func(t *testing.T) []byte { return testPNG(t, 100, 40) }, "image/png", false, 100, 40, 1},
+		{"small png", func(t *testing.T) []byte { return testPNG(t, 32, 32) }, "image/png", false, 32, 32, 0},
+		{"not an image", func(t *testing.T) []byte { return []byte("hello world") }, "image/png", true, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessImage(tt.data(t), tt.fileType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Width != tt.width || got.Height != tt.height {
+				t.Errorf("ProcessImage() dimensions = %dx%d, want %dx%d", got.Width, got.Height, tt.width, tt.height)
+			}
+			for _, leak := range []string{"Exif", "GPSLatitude", "camera!"} {
+				if bytes.Contains(got.Data, []byte(leak)) {
+					t.Errorf("ProcessImage() data still contains %q", leak)
+				}
+			}
+			if _, _, err = image.Decode(bytes.NewReader(got.Data)); err != nil {
+				t.Errorf("ProcessImage() data does not decode: %v", err)
+			}
+			if len(got.Thumbnails) != tt.thumbnails {
+				t.Fatalf("ProcessImage() thumbnails = %d, want %d", len(got.Thumbnails), tt.thumbnails)
+			}
+			for _, thumb := range got.Thumbnails {
+				if thumb.Width > thumb.Size || thumb.Height > thumb.Size || (thumb.Width != thumb.Size && thumb.Height != thumb.Size) {
+					t.Errorf("ProcessImage() thumbnail %d is %dx%d", thumb.Size, thumb.Width, thumb.Height)
+				}
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := testImage(3, 2)
+	tests := []struct {
+		name        string // The name of the test
+		orientation int    // The EXIF orientation to apply
+		x, y        int    // Where the red top left pixel should end up
+	}{
+		{"normal", 1, 0, 0},
+		{"mirrored", 2, 2, 0},
+		{"rotated 180", 3, 2, 1},
+		{"flipped", 4, 0, 1},
+		{"transposed", 5, 0, 0},
+		{"rotated 90 cw", 6, 1, 0},
+		{"transversed", 7, 1, 2},
+		{"rotated 90 ccw", 8, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := orient(img, tt.orientation)
+			if r, _, _, _ := got.At(tt.x, tt.y).RGBA(); r == 0 {
+				t.Errorf("orient() red pixel not at (%d, %d)", tt.x, tt.y)
+			}
+		})
+	}
+}
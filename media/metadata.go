@@ -0,0 +1,148 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks are ancillary PNG chunks that can carry camera, location or authoring details
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// jpegSegments walks the marker segments of a JPEG header, calling fn with each marker and its payload
+// until the start of scan is reached. It returns the offset of the start of scan segment.
+func jpegSegments(data []byte, fn func(marker byte, start, end int, payload []byte)) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, ErrUnsupportedImage
+	}
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xFF {
+			return 0, ErrUnsupportedImage
+		}
+		for i < len(data) && data[i] == 0xFF { // skip fill bytes
+			i++
+		}
+		if i >= len(data) {
+			break
+		}
+		marker := data[i]
+		start := i - 1
+		i++
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			fn(marker, start, i, nil)
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			return start, nil
+		}
+		if i+2 > len(data) {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[i : i+2]))
+		if length < 2 || i+length > len(data) {
+			break
+		}
+		fn(marker, start, i+length, data[i+2:i+length])
+		i += length
+	}
+	return 0, ErrUnsupportedImage
+}
+
+// stripJPEG losslessly removes EXIF, XMP, IPTC and comment segments from a JPEG
+func stripJPEG(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	out.Write(data[:2])
+	sos, err := jpegSegments(data, func(marker byte, start, end int, payload []byte) {
+		switch {
+		case marker == 0xE0, marker == 0xEE: // JFIF and Adobe colour transform
+		case marker == 0xE2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")):
+		case marker >= 0xE0 && marker <= 0xEF, marker == 0xFE:
+			return
+		}
+		out.Write(data[start:end])
+	})
+	if err != nil {
+		return nil, err
+	}
+	out.Write(data[sos:])
+	return out.Bytes(), nil
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or 1 when it is not set
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	_, _ = jpegSegments(data, func(marker byte, start, end int, payload []byte) {
+		if marker != 0xE1 || !bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			return
+		}
+		if o := exifOrientation(payload[6:]); o >= 1 && o <= 8 {
+			orientation = o
+		}
+	})
+	return orientation
+}
+
+// exifOrientation reads the orientation tag from the first IFD of a TIFF encoded EXIF block
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8 : entry+10]))
+		}
+	}
+	return 0
+}
+
+// stripPNG removes textual, timestamp and EXIF chunks from a PNG
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrUnsupportedImage
+	}
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	i := len(pngSignature)
+	for i+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrUnsupportedImage
+		}
+		chunkType := string(data[i+4 : i+8])
+		if !pngMetadataChunks[chunkType] {
+			out.Write(data[i:end])
+		}
+		i = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+// API key scopes limit what an API key is allowed to do on behalf of its user
+const (
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+	ScopeGroupsAdmin   = "groups:admin"
+)
+
+// APIKeyScopes lists every scope an API key can be granted
+var APIKeyScopes = []string{ScopeMessagesRead, ScopeMessagesWrite, ScopeGroupsAdmin}
+
+// ErrAPIKeyInvalid is returned when an API key is unknown or has been revoked
+var ErrAPIKeyInvalid = errors.New("invalid api key")
+
+// APIKey is a root struct that is used to store the json encoded data for/from a mongodb api key doc.
+// Only a hash of the key is stored, the key itself is returned once when it is created.
+type APIKey struct {
+	Id           string    `json:"id,omitempty"`
+	UserId       string    `json:"user_id,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	Scopes       []string  `json:"scopes,omitempty"`
+	Prefix       string    `json:"prefix,omitempty"`
+	Key          string    `json:"key,omitempty"`
+	KeyHash      string    `json:"-"`
+	LastUsedAt   time.Time `json:"last_used_at,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *APIKey) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		if !utilities.CheckObjectID(g.Id) {
+			return false
+		}
+	case "user_id":
+		if !utilities.CheckObjectID(g.UserId) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate an APIKey for different scenarios such as create, list or delete
+func (g *APIKey) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+		if g.Name == "" {
+			missingFields = append(missingFields, "name")
+		}
+		if len(g.Scopes) == 0 {
+			missingFields = append(missingFields, "scopes")
+		}
+		for _, scope := range g.Scopes {
+			if !utilities.IfStrInSlice(scope, APIKeyScopes) {
+				return errors.New("unrecognized api key scope: " + scope)
+			}
+		}
+	case "find":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+	case "delete":
+		if !g.CheckID("id") {
+			missingFields = append(missingFields, "id")
+		}
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following api key fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
+
+// HasScopes determines whether the APIKey was granted every inputted scope
+func (g *APIKey) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !utilities.IfStrInSlice(scope, g.Scopes) {
+			return false
+		}
+	}
+	return true
+}
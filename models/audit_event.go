@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Audit event actions
+const (
+	AuditRoleChange = "role.change"
+)
+
+// AuditEvent is a root struct that is used to store the json encoded data for/from a mongodb audit event doc.
+// An AuditEvent records a privileged change, who made it and what it changed From and To.
+type AuditEvent struct {
+	Id        string    `json:"id,omitempty"`
+	Action    string    `json:"action,omitempty"`
+	ActorId   string    `json:"actor_id,omitempty"`
+	TargetId  string    `json:"target_id,omitempty"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// Validate an AuditEvent for different scenarios such as recording it
+func (g *AuditEvent) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if g.Action == "" {
+			missingFields = append(missingFields, "action")
+		}
+		if g.ActorId == "" {
+			missingFields = append(missingFields, "actor_id")
+		}
+		if g.TargetId == "" {
+			missingFields = append(missingFields, "target_id")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following audit event fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
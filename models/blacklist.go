@@ -6,7 +6,8 @@ import "time"
 type Blacklist struct {
 	Id        string    `json:"id,omitempty"`
 	UserID    string    `json:"user_id,omitempty"`
-	AuthToken string    `json:"auth_token,omitempty"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
 	CreatedAt time.Time `json:"created_at,omitempty"`
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BotScopes are the scopes of a bot token, bots read and post messages in the conversations they are added to
+var BotScopes = []string{ScopeMessagesRead, ScopeMessagesWrite}
+
+// Errors returned when managing bots
+var (
+	ErrBotNotFound  = errors.New("bot not found")
+	ErrBotForbidden = errors.New("only the owner of a bot can manage it")
+)
+
+// ErrBotWebhookURL is returned for bot webhook URLs that are not absolute http or https URLs
+var ErrBotWebhookURL = errors.New("webhook_url must be an absolute http or https url")
+
+// Bot is a root struct that is used to store the json encoded data for/from a mongodb bot doc.
+// A Bot holds the settings of a bot User, which is owned by the human User who created it.
+type Bot struct {
+	Id            string    `json:"id,omitempty"`
+	UserId        string    `json:"user_id,omitempty"`
+	OwnerId       string    `json:"owner_id,omitempty"`
+	WebhookURL    string    `json:"webhook_url,omitempty"`
+	WebhookSecret string    `json:"webhook_secret,omitempty"`
+	LastModified  time.Time `json:"last_modified,omitempty"`
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Bot) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "user_id":
+		return utilities.CheckObjectID(g.UserId)
+	case "owner_id":
+		return utilities.CheckObjectID(g.OwnerId)
+	}
+	return true
+}
+
+// checkWebhookURL determines whether the webhook URL of a Bot can be delivered to, an empty URL turns delivery off
+func (g *Bot) checkWebhookURL() error {
+	if g.WebhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(g.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrBotWebhookURL
+	}
+	return nil
+}
+
+// Validate a Bot for different scenarios such as creating or updating it
+func (g *Bot) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("owner_id") {
+			missingFields = append(missingFields, "owner_id")
+		}
+		fallthrough
+	case "update":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following bot fields: " + strings.Join(missingFields, ", "))
+	}
+	return g.checkWebhookURL()
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CommandEventType is the event header of the requests custom commands are forwarded with
+const CommandEventType = "command.invoked"
+
+// BuiltinCommands are the names of the commands handled by the server, custom commands can not reuse them
+var BuiltinCommands = []string{"mute", "invite", "poll"}
+
+// Errors returned when registering and running commands
+var (
+	ErrCommandName      = errors.New("command names are 1 to 32 lowercase letters, digits, dashes or underscores")
+	ErrCommandBuiltin   = errors.New("command name is taken by a built-in command")
+	ErrCommandExists    = errors.New("command name exists")
+	ErrCommandTarget    = errors.New("a command forwards to either a bot_id or a url")
+	ErrCommandURL       = errors.New("url must be an absolute http or https url")
+	ErrCommandNotFound  = errors.New("unknown command")
+	ErrCommandForbidden = errors.New("you are not allowed to run this command")
+	ErrCommandNoGroup   = errors.New("commands can only be run in group conversations")
+	ErrCommandFailed    = errors.New("command did not respond")
+	ErrCommandBot       = errors.New("commands can only forward to bots you own or that are members of the group")
+)
+
+// commandNamePattern matches the names commands may be registered with
+var commandNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// Command is a root struct that is used to store the json encoded data for/from a mongodb command doc.
+// Group admins register custom commands that forward the text after /Name to a bot or to a URL signed with Secret.
+type Command struct {
+	Id           string    `json:"id,omitempty"`
+	GroupId      string    `json:"group_id,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	BotId        string    `json:"bot_id,omitempty"`
+	URL          string    `json:"url,omitempty"`
+	Secret       string    `json:"secret,omitempty"`
+	CreatedBy    string    `json:"created_by,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Command) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "group_id":
+		return utilities.CheckObjectID(g.GroupId)
+	case "bot_id":
+		return utilities.CheckObjectID(g.BotId)
+	}
+	return true
+}
+
+// Validate a Command for different scenarios such as registering it
+func (g *Command) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("group_id") {
+			missingFields = append(missingFields, "group_id")
+		}
+		if g.Name == "" {
+			missingFields = append(missingFields, "name")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following command fields: " + strings.Join(missingFields, ", "))
+	}
+	if !commandNamePattern.MatchString(g.Name) {
+		return ErrCommandName
+	}
+	for _, name := range BuiltinCommands {
+		if g.Name == name {
+			return ErrCommandBuiltin
+		}
+	}
+	if (g.BotId == "") == (g.URL == "") {
+		return ErrCommandTarget
+	}
+	if g.BotId != "" && !g.CheckID("bot_id") {
+		return ErrCommandTarget
+	}
+	if g.URL != "" {
+		u, err := url.Parse(g.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrCommandURL
+		}
+	}
+	return nil
+}
+
+// CommandInvocation is the signed JSON body a custom command is forwarded with
+type CommandInvocation struct {
+	Id             string    `json:"id"`
+	Command        string    `json:"command"`
+	Text           string    `json:"text"`
+	UserId         string    `json:"user_id"`
+	GroupId        string    `json:"group_id"`
+	ConversationId string    `json:"conversation_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CommandResponse is the reply to a command, it is only returned to the User who ran the command and never stored.
+// Commands that post a message, such as /poll, return it in Message.
+type CommandResponse struct {
+	Command   string   `json:"command"`
+	Text      string   `json:"text"`
+	Ephemeral bool     `json:"ephemeral"`
+	Message   *Message `json:"message,omitempty"`
+}
@@ -7,13 +7,27 @@ import (
 	"time"
 )
 
+// The message lifetimes disappearing messages can be set to in a Conversation, in seconds
+const (
+	MessageTTLOff   int64 = 0
+	MessageTTLHour  int64 = 60 * 60
+	MessageTTLDay   int64 = 24 * MessageTTLHour
+	MessageTTLWeek  int64 = 7 * MessageTTLDay
+	MessageTTLMonth int64 = 30 * MessageTTLDay
+)
+
+// ErrMessageTTLInvalid is returned when a Conversation is set to a message lifetime that is not supported
+var ErrMessageTTLInvalid = errors.New("message_ttl must be 0 (off), 3600 (1 hour), 86400 (1 day), 604800 (1 week) or 2592000 (30 days)")
+
 type Conversation struct {
-	Id              string    `json:"id,omitempty"`
-	ParticipantsIds []string  `json:"participants_ids,omitempty"`
-	Group           bool      `json:"group,omitempty"` //if group, only ParticipantID is group id
-	DeletedAt       time.Time `json:"deleted_at,omitempty"`
-	UpdatedAt       time.Time `json:"updated_at,omitempty"`
-	CreatedAt       time.Time `json:"created_at,omitempty"`
+	Id              string            `json:"id,omitempty"`
+	ParticipantsIds []string          `json:"participants_ids,omitempty"`
+	Group           bool              `json:"group,omitempty"`       //if group, only ParticipantID is group id
+	ImageURLs       map[string]string `json:"image_urls,omitempty"`  // participant id -> avatar or group picture URL
+	MessageTTL      int64             `json:"message_ttl,omitempty"` // seconds until new messages disappear, 0 when off
+	DeletedAt       time.Time         `json:"deleted_at,omitempty"`
+	UpdatedAt       time.Time         `json:"updated_at,omitempty"`
+	CreatedAt       time.Time         `json:"created_at,omitempty"`
 }
 
 func (g *Conversation) checkID(chkId string) bool {
@@ -51,9 +65,19 @@ func (g *Conversation) Validate(valCase string) (err error) {
 	if len(missingFields) > 0 {
 		return errors.New("missing the following group fields: " + strings.Join(missingFields, ", "))
 	}
+	switch g.MessageTTL {
+	case MessageTTLOff, MessageTTLHour, MessageTTLDay, MessageTTLWeek, MessageTTLMonth:
+	default:
+		return ErrMessageTTLInvalid
+	}
 	return
 }
 
+// MessageLifetime returns how long the messages sent in the Conversation last before they disappear, 0 when they do not
+func (g *Conversation) MessageLifetime() time.Duration {
+	return time.Duration(g.MessageTTL) * time.Second
+}
+
 func (g *Conversation) CheckParticipants(id string) bool {
 	return utilities.IfStrInSlice(id, g.ParticipantsIds)
 }
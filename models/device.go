@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Push platforms a Device can be registered on
+const (
+	DeviceAPNs    = "apns"
+	DeviceFCM     = "fcm"
+	DeviceWebPush = "webpush"
+)
+
+// DevicePlatforms lists every platform a Device can be registered on
+var DevicePlatforms = []string{DeviceAPNs, DeviceFCM, DeviceWebPush}
+
+var (
+	// ErrDevicePlatform is returned when a Device is registered on an unrecognized platform
+	ErrDevicePlatform = errors.New("platform must be one of apns, fcm or webpush")
+	// ErrDeviceToken is returned when an APNs Device is registered with a token that is not hex encoded
+	ErrDeviceToken = errors.New("apns devices require a hex encoded device token")
+	// ErrDeviceSubscription is returned when a Web Push Device is registered without a valid subscription
+	ErrDeviceSubscription = errors.New("webpush devices require an https endpoint token, a public_key and an auth_secret")
+	// ErrDeviceNotFound is returned when a Device does not exist or belongs to another User
+	ErrDeviceNotFound = errors.New("device not found")
+)
+
+// Device is a root struct that is used to store the json encoded data for/from a mongodb device doc.
+// A Device is an app install or browser a User receives push notifications on. Its Token is the APNs device token,
+// the FCM registration token or the Web Push subscription endpoint, Web Push subscriptions also carry the keys
+// their notifications are encrypted with.
+type Device struct {
+	Id           string    `json:"id,omitempty"`
+	UserId       string    `json:"user_id,omitempty"`
+	Platform     string    `json:"platform,omitempty"`
+	Token        string    `json:"token,omitempty"`
+	PublicKey    string    `json:"-"`
+	AuthSecret   string    `json:"-"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Device) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "user_id":
+		return utilities.CheckObjectID(g.UserId)
+	}
+	return true
+}
+
+// Validate a Device for different scenarios such as registering it
+func (g *Device) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+		if g.Platform == "" {
+			missingFields = append(missingFields, "platform")
+		}
+		if g.Token == "" {
+			missingFields = append(missingFields, "token")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following device fields: " + strings.Join(missingFields, ", "))
+	}
+	if !utilities.IfStrInSlice(g.Platform, DevicePlatforms) {
+		return ErrDevicePlatform
+	}
+	if g.Platform == DeviceAPNs {
+		if _, err := hex.DecodeString(g.Token); err != nil {
+			return ErrDeviceToken
+		}
+	}
+	if g.Platform == DeviceWebPush {
+		u, err := url.Parse(g.Token)
+		if err != nil || u.Scheme != "https" || u.Host == "" || g.PublicKey == "" || g.AuthSecret == "" {
+			return ErrDeviceSubscription
+		}
+	}
+	return
+}
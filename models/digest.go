@@ -0,0 +1,136 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+// Email digest frequencies
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+	DigestOff    = "off"
+)
+
+// DigestFrequencies lists every frequency a User can receive email digests at
+var DigestFrequencies = []string{DigestDaily, DigestWeekly, DigestOff}
+
+// ErrDigestFrequency is returned when digest settings are saved with an unrecognized frequency
+var ErrDigestFrequency = errors.New("frequency must be one of daily, weekly or off")
+
+// DigestSettings is a root struct that is used to store the json encoded data for/from a mongodb digest settings doc.
+// Users without DigestSettings receive daily digests.
+type DigestSettings struct {
+	Id           string    `json:"id,omitempty"`
+	UserId       string    `json:"user_id,omitempty"`
+	Frequency    string    `json:"frequency,omitempty"`
+	LastSentAt   time.Time `json:"last_sent_at,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *DigestSettings) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "user_id":
+		return utilities.CheckObjectID(g.UserId)
+	}
+	return true
+}
+
+// Validate DigestSettings for different scenarios such as saving them
+func (g *DigestSettings) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "update":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+		if g.Frequency == "" {
+			missingFields = append(missingFields, "frequency")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following digest settings fields: " + strings.Join(missingFields, ", "))
+	}
+	if !utilities.IfStrInSlice(g.Frequency, DigestFrequencies) {
+		return ErrDigestFrequency
+	}
+	return
+}
+
+// Due determines whether a digest is due at t, digests are sent at most once per period of their frequency
+func (g *DigestSettings) Due(t time.Time) bool {
+	switch g.Frequency {
+	case DigestDaily:
+		return !t.Before(g.LastSentAt.Add(24 * time.Hour))
+	case DigestWeekly:
+		return !t.Before(g.LastSentAt.Add(7 * 24 * time.Hour))
+	}
+	return false
+}
+
+// Unread thread kinds
+const (
+	UnreadGroup        = "group"
+	UnreadConversation = "conversation"
+	UnreadDirect       = "direct"
+)
+
+// Unread is a root struct that is used to store the json encoded data for/from a mongodb unread doc.
+// An Unread counts the messages a User received in a thread since they were last active, a thread is a group, a
+// conversation or the direct messages from another User. Unreads are summarized in email digests.
+type Unread struct {
+	Id            string    `json:"id,omitempty"`
+	UserId        string    `json:"user_id,omitempty"`
+	ThreadId      string    `json:"thread_id,omitempty"`
+	Kind          string    `json:"kind,omitempty"`
+	Count         int       `json:"count,omitempty"`
+	LastMessageId string    `json:"last_message_id,omitempty"`
+	LastSenderId  string    `json:"last_sender_id,omitempty"`
+	LastMessageAt time.Time `json:"last_message_at,omitempty"`
+	LastModified  time.Time `json:"last_modified,omitempty"`
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Unread) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "user_id":
+		return utilities.CheckObjectID(g.UserId)
+	case "thread_id":
+		return utilities.CheckObjectID(g.ThreadId)
+	}
+	return true
+}
+
+// Validate an Unread for different scenarios such as recording a message in it
+func (g *Unread) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+		if !g.CheckID("thread_id") {
+			missingFields = append(missingFields, "thread_id")
+		}
+		if g.Kind == "" {
+			missingFields = append(missingFields, "kind")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following unread fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
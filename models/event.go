@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Event types, published whenever the matching change is made
+const (
+	EventMessageCreated = "message.created"
+	EventMessageEdited  = "message.edited"
+	EventMessageDeleted = "message.deleted"
+	EventMemberJoined   = "member.joined"
+	EventMemberLeft     = "member.left"
+)
+
+// EventTypes lists every event type integrations can subscribe to
+var EventTypes = []string{EventMessageCreated, EventMessageEdited, EventMessageDeleted, EventMemberJoined, EventMemberLeft}
+
+// Event is a change in a conversation that integrations such as bots are told about
+type Event struct {
+	Id        string           `json:"id"`
+	Type      string           `json:"type"`
+	GroupId   string           `json:"group_id,omitempty"`
+	Message   *Message         `json:"message,omitempty"`
+	Member    *GroupMembership `json:"member,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
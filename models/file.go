@@ -1,4 +1,4 @@
-package database
+package models
 
 import (
 	"errors"
@@ -7,20 +7,69 @@ import (
 	"time"
 )
 
+// Scan statuses of a File, only clean files can be downloaded by users other than their owner
+const (
+	ScanPending  = "pending"
+	ScanClean    = "clean"
+	ScanInfected = "infected"
+)
+
+// ErrFileQuarantined is returned when a file that has not passed scanning is downloaded
+var ErrFileQuarantined = errors.New("file has not passed malware scanning")
+
 // File is a root struct that is used to store the json encoded data for/from a mongodb file doc.
 type File struct {
-	Id           string    `json:"id,omitempty"`
-	OwnerId      string    `json:"owner_id,omitempty"`
-	OwnerType    string    `json:"owner_type,omitempty"`
-	GridFSId     string    `json:"gridfs_id,omitempty"`
-	BucketName   string    `json:"bucket_name,omitempty"`
-	BucketType   string    `json:"bucket_type,omitempty"`
-	Name         string    `json:"name,omitempty"`
-	FileType     string    `json:"file_type,omitempty"`
-	Size         int       `json:"size,omitempty"`
-	LastModified time.Time `json:"last_modified,omitempty"`
-	CreatedAt    time.Time `json:"created_at,omitempty"`
-	DeletedAt    time.Time `json:"deleted_at,omitempty"`
+	Id           string           `json:"id,omitempty"`
+	OwnerId      string           `json:"owner_id,omitempty"`
+	OwnerType    string           `json:"owner_type,omitempty"`
+	GridFSId     string           `json:"gridfs_id,omitempty"`
+	BucketName   string           `json:"bucket_name,omitempty"`
+	BucketType   string           `json:"bucket_type,omitempty"`
+	Name         string           `json:"name,omitempty"`
+	FileType     string           `json:"file_type,omitempty"`
+	Size         int64            `json:"size,omitempty"`
+	Checksum     string           `json:"checksum,omitempty"`
+	Width        int              `json:"width,omitempty"`
+	Height       int              `json:"height,omitempty"`
+	Thumbnails   []*FileThumbnail `json:"thumbnails,omitempty"`
+	ScanStatus   string           `json:"scan_status,omitempty"`
+	Signature    string           `json:"signature,omitempty"`
+	MessageIds   []string         `json:"-"` // the messages the file is attached to, whose participants can read it
+	LastModified time.Time        `json:"last_modified,omitempty"`
+	CreatedAt    time.Time        `json:"created_at,omitempty"`
+	DeletedAt    time.Time        `json:"deleted_at,omitempty"`
+}
+
+// FileThumbnail describes a resized copy of an image File
+type FileThumbnail struct {
+	Size     int    `json:"size"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	FileType string `json:"file_type"`
+	GridFSId string `json:"-"`
+}
+
+// Thumbnail returns the smallest thumbnail that is at least size pixels, or nil when the original should be used
+func (g *File) Thumbnail(size int) *FileThumbnail {
+	var match *FileThumbnail
+	for _, t := range g.Thumbnails {
+		if t.Size >= size && (match == nil || t.Size < match.Size) {
+			match = t
+		}
+	}
+	return match
+}
+
+// Downloadable determines whether a user may download the File given its scan status,
+// owners can still retrieve their own files while a scan is pending
+func (g *File) Downloadable(userId string) bool {
+	switch g.ScanStatus {
+	case ScanClean:
+		return true
+	case ScanInfected:
+		return false
+	}
+	return g.OwnerType == "user" && g.OwnerId == userId
 }
 
 // BuildBucketName returns a current name for the bucket of a GridFS File
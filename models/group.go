@@ -11,6 +11,8 @@ type Group struct {
 	Id           string    `json:"id,omitempty"`
 	Name         string    `json:"name,omitempty"`
 	Visibility   bool      `json:"visibility,omitempty"` //invite only, visible for Member requests
+	ImageId      string    `json:"image_id,omitempty"`
+	ImageURL     string    `json:"image_url,omitempty"`
 	LastModified time.Time `json:"last_modified,omitempty"`
 	CreatedAt    time.Time `json:"created_at,omitempty"`
 	DeletedAt    time.Time `json:"deleted_at,omitempty"`
@@ -23,10 +25,22 @@ func (g *Group) checkID(chkId string) bool {
 		if g.Id == "" || g.Id == "000000000000000000000000" {
 			return false
 		}
+	case "image_id":
+		if g.ImageId == "" || g.ImageId == "000000000000000000000000" {
+			return false
+		}
 	}
 	return true
 }
 
+// BuildImageURL sets the URL a Group's picture is served from when an image is associated with the Group
+func (g *Group) BuildImageURL() {
+	g.ImageURL = ""
+	if g.checkID("id") && g.checkID("image_id") {
+		g.ImageURL = "/groups/" + g.Id + "/image"
+	}
+}
+
 // Validate a Group for different scenarios such as loading TokenData, creating new Group, or updating a Group
 func (g *Group) Validate(valCase string) (err error) {
 	var missingFields []string
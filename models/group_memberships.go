@@ -7,13 +7,19 @@ import (
 )
 
 type GroupMembership struct {
-	Id        string    `json:"id,omitempty"`
-	UserId    string    `json:"user_id,omitempty"`
-	GroupId   string    `json:"group_id,omitempty"`
-	Admin     bool      `json:"admin,omitempty"`
-	UpdatedAt time.Time `json:"updated_at,omitempty"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
-	DeletedAt time.Time `json:"deleted_at,omitempty"`
+	Id         string    `json:"id,omitempty"`
+	UserId     string    `json:"user_id,omitempty"`
+	GroupId    string    `json:"group_id,omitempty"`
+	Admin      bool      `json:"admin,omitempty"`
+	MutedUntil time.Time `json:"muted_until,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	DeletedAt  time.Time `json:"deleted_at,omitempty"`
+}
+
+// Muted determines whether the member has muted the group at t
+func (g *GroupMembership) Muted(t time.Time) bool {
+	return g.MutedUntil.After(t)
 }
 
 func (g *GroupMembership) checkID(chkId string) bool {
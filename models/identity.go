@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrIdentityEmailUnverified is returned when an external identity can not be linked because an email is unverified,
+	// either by the provider or by the User who has an account with it
+	ErrIdentityEmailUnverified = errors.New("email address has not been verified, it can not be used to link this sign in to an account")
+	// ErrIdentityRegistrationClosed is returned when an external identity has no account and registration is off
+	ErrIdentityRegistrationClosed = errors.New("no account is linked to this sign in and registration is closed")
+)
+
+// Identity is a root struct that is used to store the json encoded data for/from a mongodb identity doc.
+// An Identity links a User to their account at an external OpenID Connect provider.
+type Identity struct {
+	Id           string    `json:"id,omitempty"`
+	UserId       string    `json:"user_id,omitempty"`
+	Provider     string    `json:"provider,omitempty"`
+	Subject      string    `json:"subject,omitempty"`
+	Email        string    `json:"email,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Identity) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		if g.Id == "" || g.Id == "000000000000000000000000" {
+			return false
+		}
+	case "user_id":
+		if g.UserId == "" || g.UserId == "000000000000000000000000" {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate an Identity for different scenarios such as linking it to a User or finding it
+func (g *Identity) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+		fallthrough
+	case "find":
+		if g.Provider == "" {
+			missingFields = append(missingFields, "provider")
+		}
+		if g.Subject == "" {
+			missingFields = append(missingFields, "subject")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following identity fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
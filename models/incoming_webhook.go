@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrIncomingWebhookInvalid is returned when an incoming webhook token is unknown or has been revoked
+	ErrIncomingWebhookInvalid = errors.New("invalid incoming webhook")
+	// ErrIncomingWebhookRateLimited is returned when an incoming webhook posts more messages than its rate limit allows
+	ErrIncomingWebhookRateLimited = errors.New("incoming webhook rate limit exceeded")
+	// ErrIncomingWebhookConversation is returned when an incoming webhook is added to a conversation that is not a group's
+	ErrIncomingWebhookConversation = errors.New("incoming webhooks can only be added to group conversations")
+)
+
+// IncomingWebhook is a root struct that is used to store the json encoded data for/from a mongodb incoming webhook doc.
+// An IncomingWebhook lets an integration post into a group conversation with a secret token instead of user
+// credentials, its messages are sent by the integration User it is attributed to. Only a hash of the token is
+// stored, the token itself is returned once when the IncomingWebhook is created.
+type IncomingWebhook struct {
+	Id             string    `json:"id,omitempty"`
+	ConversationId string    `json:"conversation_id,omitempty"`
+	GroupId        string    `json:"group_id,omitempty"`
+	UserId         string    `json:"user_id,omitempty"`
+	Name           string    `json:"name,omitempty"`
+	Prefix         string    `json:"prefix,omitempty"`
+	Token          string    `json:"token,omitempty"`
+	TokenHash      string    `json:"-"`
+	CreatedBy      string    `json:"created_by,omitempty"`
+	LastUsedAt     time.Time `json:"last_used_at,omitempty"`
+	LastModified   time.Time `json:"last_modified,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *IncomingWebhook) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "conversation_id":
+		return utilities.CheckObjectID(g.ConversationId)
+	case "group_id":
+		return utilities.CheckObjectID(g.GroupId)
+	case "user_id":
+		return utilities.CheckObjectID(g.UserId)
+	}
+	return true
+}
+
+// Validate an IncomingWebhook for different scenarios such as creating it
+func (g *IncomingWebhook) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("conversation_id") {
+			missingFields = append(missingFields, "conversation_id")
+		}
+		if !g.CheckID("group_id") {
+			missingFields = append(missingFields, "group_id")
+		}
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+		if g.Name == "" {
+			missingFields = append(missingFields, "name")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following incoming webhook fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Login attempt kinds, failed sign ins are tracked against both the account and the address they came from
+const (
+	LoginAttemptAccount = "account"
+	LoginAttemptIP      = "ip"
+)
+
+var (
+	// ErrInvalidCredentials is returned for every failed sign in so responses do not reveal which emails have accounts
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrSignInLocked is returned while an account or address is locked out after too many failed sign ins
+	ErrSignInLocked = errors.New("too many failed sign in attempts, try again later")
+)
+
+// LoginAttempt is a root struct that is used to store the json encoded data for/from a mongodb login attempt doc.
+// Each doc counts the recent failed sign ins of one account or address.
+type LoginAttempt struct {
+	Id            string    `json:"id,omitempty"`
+	Kind          string    `json:"kind,omitempty"`
+	Key           string    `json:"key,omitempty"`
+	Failures      int       `json:"failures,omitempty"`
+	LockedUntil   time.Time `json:"locked_until,omitempty"`
+	LastFailureAt time.Time `json:"last_failure_at,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at,omitempty"`
+	LastModified  time.Time `json:"last_modified,omitempty"`
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+}
+
+// NewLoginAttempt initializes the LoginAttempt tracking a kind of key, normalizing account emails
+func NewLoginAttempt(kind string, key string) *LoginAttempt {
+	if kind == LoginAttemptAccount {
+		key = strings.ToLower(strings.TrimSpace(key))
+	}
+	return &LoginAttempt{Kind: kind, Key: key}
+}
+
+// Locked determines whether sign ins are currently refused for the LoginAttempt's account or address
+func (g *LoginAttempt) Locked() bool {
+	return time.Now().Before(g.LockedUntil)
+}
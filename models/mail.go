@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Mail outbox statuses
+const (
+	MailPending = "pending"
+	MailSent    = "sent"
+	MailFailed  = "failed"
+)
+
+// MailLinkPlaceholder marks where the action link of an email goes in its body
+const MailLinkPlaceholder = "{{link}}"
+
+// MailLink is the action link of an email. Its token is only created when the email is sent, so the outbox never
+// stores a live token.
+type MailLink struct {
+	Path      string    `json:"path,omitempty"`
+	UserId    string    `json:"user_id,omitempty"`
+	Action    string    `json:"action,omitempty"`
+	Check     string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Mail is a root struct that is used to store the json encoded data for/from a mongodb mail outbox doc.
+// Emails are queued in the outbox and retried until they are sent or run out of attempts.
+type Mail struct {
+	Id           string    `json:"id,omitempty"`
+	To           string    `json:"to,omitempty"`
+	Subject      string    `json:"subject,omitempty"`
+	Body         string    `json:"body,omitempty"`
+	Link         *MailLink `json:"link,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	Attempts     int       `json:"attempts,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	SentAt       time.Time `json:"sent_at,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// Validate a Mail for different scenarios such as queueing it in the outbox
+func (g *Mail) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if g.To == "" {
+			missingFields = append(missingFields, "to")
+		}
+		if g.Subject == "" {
+			missingFields = append(missingFields, "subject")
+		}
+		if g.Body == "" {
+			missingFields = append(missingFields, "body")
+		}
+		if g.Link != nil && (g.Link.Path == "" || g.Link.UserId == "" || g.Link.Action == "" || g.Link.ExpiresAt.IsZero()) {
+			missingFields = append(missingFields, "link")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following mail fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+// Group roles that can be allowed to mention @all
+const (
+	MentionAllAdmins  = "admin"
+	MentionAllMembers = "member"
+)
+
+// ErrMentionAllForbidden is returned when a group member who is not allowed to mention @all does so
+var ErrMentionAllForbidden = errors.New("only group admins can mention @all in this group")
+
+// Mentions lists who a Message mentions. UserIds holds every participant notified by the Message, whether mentioned
+// by @username or reached through @here or @all.
+type Mentions struct {
+	UserIds   []string `json:"user_ids,omitempty"`
+	Usernames []string `json:"usernames,omitempty"`
+	Here      bool     `json:"here,omitempty"`
+	All       bool     `json:"all,omitempty"`
+}
+
+// Includes determines whether userId is one of the Users the Mentions notify
+func (g *Mentions) Includes(userId string) bool {
+	if g == nil {
+		return false
+	}
+	for _, id := range g.UserIds {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// Mention is a root struct that is used to store the json encoded data for/from a mongodb mention doc.
+// A Mention is recorded for every User a Message notifies so that the messages mentioning a User can be listed.
+type Mention struct {
+	Id             string    `json:"id,omitempty"`
+	MessageId      string    `json:"message_id,omitempty"`
+	UserId         string    `json:"user_id,omitempty"`
+	SenderId       string    `json:"sender_id,omitempty"`
+	ConversationId string    `json:"conversation_id,omitempty"`
+	GroupId        string    `json:"group_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Mention) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "message_id":
+		return utilities.CheckObjectID(g.MessageId)
+	case "user_id":
+		return utilities.CheckObjectID(g.UserId)
+	}
+	return true
+}
+
+// Validate a Mention for different scenarios such as recording it
+func (g *Mention) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("message_id") {
+			missingFields = append(missingFields, "message_id")
+		}
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following mention fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
@@ -6,6 +6,13 @@ import (
 	"time"
 )
 
+// ErrMessageExists is returned when a message is created with the id of a message that is already stored
+var ErrMessageExists = errors.New("message already exists")
+
+// ContentTypeSystem is the content type of the messages the app posts in a conversation, such as when its
+// disappearing messages setting changes
+const ContentTypeSystem = "system"
+
 // Message is a root struct that is used to store the json encoded data for/from a mongodb group doc.
 type Message struct {
 	Id             string    `json:"id,omitempty"`
@@ -16,6 +23,8 @@ type Message struct {
 	ContentType    string    `json:"contentType,omitempty"`
 	Group          bool      `json:"group,omitempty"`
 	FileIds        string    `json:"file_ids"`
+	Mentions       *Mentions `json:"mentions,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at,omitempty"` // set when the message was sent with disappearing messages on
 	UpdatedAt      time.Time `json:"updated_at,omitempty"`
 	CreatedAt      time.Time `json:"created_at,omitempty"`
 	DeletedAt      time.Time `json:"deleted_at,omitempty"`
@@ -62,3 +71,14 @@ func (g *Message) Validate(valCase string) (err error) {
 	}
 	return
 }
+
+// Attachments returns the ids of the files attached to a message
+func (g *Message) Attachments() []string {
+	var ids []string
+	for _, id := range strings.Split(g.FileIds, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+// MessageExpiry is a root struct that is used to store the json encoded data for/from a mongodb message expiry doc.
+// A MessageExpiry is recorded for every Message sent with disappearing messages on, it has the id of its Message so
+// that the Message and its attachments can be removed once it expires.
+type MessageExpiry struct {
+	Id             string    `json:"id,omitempty"`
+	ConversationId string    `json:"conversation_id,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *MessageExpiry) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "conversation_id":
+		return utilities.CheckObjectID(g.ConversationId)
+	}
+	return true
+}
+
+// Validate a MessageExpiry for different scenarios such as recording it
+func (g *MessageExpiry) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("id") {
+			missingFields = append(missingFields, "id")
+		}
+		if g.ExpiresAt.IsZero() {
+			missingFields = append(missingFields, "expires_at")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following message expiry fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
+
+// Expired determines whether the Message of a MessageExpiry has expired at t
+func (g *MessageExpiry) Expired(t time.Time) bool {
+	return !g.ExpiresAt.After(t)
+}
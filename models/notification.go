@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+// Notification outbox statuses, a notification is sending while an instance of the app has claimed it for an attempt
+const (
+	NotificationPending = "pending"
+	NotificationSending = "sending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// Notification is a root struct that is used to store the json encoded data for/from a mongodb notification outbox doc.
+// A Notification is queued for every Device of a User a push notification is sent to, and retried until it is sent
+// or runs out of attempts, so notifications survive restarts and push service outages.
+type Notification struct {
+	Id            string    `json:"id,omitempty"`
+	UserId        string    `json:"user_id,omitempty"`
+	DeviceId      string    `json:"device_id,omitempty"`
+	MessageId     string    `json:"message_id,omitempty"`
+	Title         string    `json:"title,omitempty"`
+	Body          string    `json:"body,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	Attempts      int       `json:"attempts,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+	SentAt        time.Time `json:"sent_at,omitempty"`
+	LastModified  time.Time `json:"last_modified,omitempty"`
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Notification) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "user_id":
+		return utilities.CheckObjectID(g.UserId)
+	case "message_id":
+		return utilities.CheckObjectID(g.MessageId)
+	}
+	return true
+}
+
+// Validate a Notification for different scenarios such as queueing it in the outbox
+func (g *Notification) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+		if g.Title == "" {
+			missingFields = append(missingFields, "title")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following notification fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
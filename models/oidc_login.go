@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrOIDCLoginInvalid is returned when an OpenID Connect sign in is completed with an unknown or expired state
+var ErrOIDCLoginInvalid = errors.New("invalid or expired sign in state")
+
+// OIDCLogin is a root struct that is used to store the json encoded data for/from a mongodb oidc login doc.
+// An OIDCLogin holds the PKCE verifier and nonce of a sign in with an external provider until the user returns.
+type OIDCLogin struct {
+	Id           string    `json:"id,omitempty"`
+	Provider     string    `json:"provider,omitempty"`
+	State        string    `json:"state,omitempty"`
+	Nonce        string    `json:"-"`
+	Verifier     string    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// Validate an OIDCLogin for different scenarios such as starting a sign in
+func (g *OIDCLogin) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if g.Provider == "" {
+			missingFields = append(missingFields, "provider")
+		}
+		if g.State == "" {
+			missingFields = append(missingFields, "state")
+		}
+		if g.Nonce == "" {
+			missingFields = append(missingFields, "nonce")
+		}
+		if g.Verifier == "" {
+			missingFields = append(missingFields, "verifier")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following oidc login fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenInvalid is returned when a refresh token is unknown, expired or malformed
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+)
+
+// RefreshToken is a root struct that is used to store the json encoded data for/from a mongodb refresh token doc.
+// Only a hash of the token is ever stored, the token itself is handed to the client once.
+type RefreshToken struct {
+	Id           string    `json:"id,omitempty"`
+	UserId       string    `json:"user_id,omitempty"`
+	FamilyId     string    `json:"family_id,omitempty"`
+	TokenHash    string    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	UsedAt       time.Time `json:"used_at,omitempty"`
+	RevokedAt    time.Time `json:"revoked_at,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *RefreshToken) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		if !utilities.CheckObjectID(g.Id) {
+			return false
+		}
+	case "user_id":
+		if !utilities.CheckObjectID(g.UserId) {
+			return false
+		}
+	case "family_id":
+		if !utilities.CheckObjectID(g.FamilyId) {
+			return false
+		}
+	}
+	return true
+}
+
+// Expired determines whether the refresh token can no longer be exchanged
+func (g *RefreshToken) Expired() bool {
+	return !g.ExpiresAt.IsZero() && time.Now().After(g.ExpiresAt)
+}
+
+// Spent determines whether the refresh token has already been rotated or revoked
+func (g *RefreshToken) Spent() bool {
+	return !g.UsedAt.IsZero() || !g.RevokedAt.IsZero()
+}
@@ -0,0 +1,44 @@
+package models
+
+import "errors"
+
+// User roles, from the most to the least privileged. Root admins manage the whole service, support staff can
+// help users with their accounts, members use the messenger and bots are accounts driven by an integration.
+const (
+	RoleRootAdmin = "root_admin"
+	RoleSupport   = "support"
+	RoleMember    = "member"
+	RoleBot       = "bot"
+)
+
+var (
+	// ErrInvalidRole is returned for roles that do not exist or can not be assigned
+	ErrInvalidRole = errors.New("invalid role, choose one of root_admin, support or member")
+	// ErrRoleChangeForbidden is returned when the role of a User can not be changed, such as a user's own role or a bot's
+	ErrRoleChangeForbidden = errors.New("the role of this user can not be changed")
+)
+
+// AssignableRole determines whether users can be promoted or demoted to a role, bot accounts are created as bots
+func AssignableRole(role string) bool {
+	switch role {
+	case RoleRootAdmin, RoleSupport, RoleMember:
+		return true
+	}
+	return false
+}
+
+// SetRole sets the role of a User, keeping RootAdmin in step for clients that predate roles
+func (g *User) SetRole(role string) {
+	g.Role = role
+	g.RootAdmin = role == RoleRootAdmin
+}
+
+// HasRole determines whether a User has one of the roles
+func (g *User) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if g.Role == role {
+			return true
+		}
+	}
+	return false
+}
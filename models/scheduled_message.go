@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+// Scheduled message statuses, a scheduled message is sending while an instance of the app has claimed it for an
+// attempt to deliver it
+const (
+	ScheduledPending = "pending"
+	ScheduledSending = "sending"
+	ScheduledSent    = "sent"
+	ScheduledFailed  = "failed"
+)
+
+var (
+	// ErrScheduledMessageNotFound is returned when a scheduled message does not exist or belongs to another User
+	ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+	// ErrScheduledMessageNotPending is returned when a scheduled message that was already sent, or failed to be, is changed
+	ErrScheduledMessageNotPending = errors.New("only pending scheduled messages can be changed")
+	// ErrSendAtPast is returned when a message is scheduled to be sent at a time that has already passed
+	ErrSendAtPast = errors.New("send_at must be in the future")
+	// ErrScheduledCommand is returned when a slash command is scheduled
+	ErrScheduledCommand = errors.New("slash commands can not be scheduled")
+)
+
+// ScheduledMessage is a root struct that is used to store the json encoded data for/from a mongodb scheduled message doc.
+// A ScheduledMessage is delivered as the Message with the same id once its SendAt passes, so it is only created once
+// however many times delivery is attempted.
+type ScheduledMessage struct {
+	Id             string    `json:"id,omitempty"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	SenderID       string    `json:"sender_id,omitempty"`
+	ReceiverID     string    `json:"receiver_id,omitempty"`
+	Content        string    `json:"content,omitempty"`
+	ContentType    string    `json:"contentType,omitempty"`
+	Group          bool      `json:"group,omitempty"`
+	SendAt         time.Time `json:"send_at,omitempty"`
+	Status         string    `json:"status,omitempty"`
+	Attempts       int       `json:"attempts,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	SentAt         time.Time `json:"sent_at,omitempty"`
+	LastModified   time.Time `json:"last_modified,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *ScheduledMessage) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "sender_id":
+		return utilities.CheckObjectID(g.SenderID)
+	case "receiver_id":
+		return utilities.CheckObjectID(g.ReceiverID)
+	}
+	return true
+}
+
+// Validate a ScheduledMessage for different scenarios such as scheduling or editing it
+func (g *ScheduledMessage) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("sender_id") {
+			missingFields = append(missingFields, "sender_id")
+		}
+		if !g.CheckID("receiver_id") {
+			missingFields = append(missingFields, "receiver_id")
+		}
+		if g.Content == "" {
+			missingFields = append(missingFields, "content")
+		}
+		if g.SendAt.IsZero() {
+			missingFields = append(missingFields, "send_at")
+		}
+	case "update":
+		if !g.CheckID("id") {
+			missingFields = append(missingFields, "id")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following scheduled message fields: " + strings.Join(missingFields, ", "))
+	}
+	if !g.SendAt.IsZero() && !g.SendAt.After(time.Now()) {
+		return ErrSendAtPast
+	}
+	return
+}
+
+// Due determines whether a ScheduledMessage is waiting to be delivered at t
+func (g *ScheduledMessage) Due(t time.Time) bool {
+	return g.Status == ScheduledPending && !g.SendAt.After(t)
+}
+
+// Message returns the Message a ScheduledMessage is delivered as
+func (g *ScheduledMessage) Message() *Message {
+	return &Message{
+		Id:             g.Id,
+		ConversationID: g.ConversationID,
+		SenderID:       g.SenderID,
+		ReceiverID:     g.ReceiverID,
+		Content:        g.Content,
+		ContentType:    g.ContentType,
+		Group:          g.Group,
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+// ErrSessionRevoked is returned when a token belongs to a session that has been signed out
+var ErrSessionRevoked = errors.New("session has been signed out")
+
+// ErrTokenNoSession is returned when a token was not issued for a session, so it could not be revoked by signing out
+var ErrTokenNoSession = errors.New("token is not bound to a session")
+
+// Session is a root struct that is used to store the json encoded data for/from a mongodb session doc.
+// A session is created on every sign-in and lives as long as its refresh token family.
+type Session struct {
+	Id           string    `json:"id,omitempty"`
+	UserId       string    `json:"user_id,omitempty"`
+	DeviceName   string    `json:"device_name,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	IPAddress    string    `json:"ip_address,omitempty"`
+	Current      bool      `json:"current"`
+	LastUsedAt   time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    time.Time `json:"revoked_at,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Session) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		if !utilities.CheckObjectID(g.Id) {
+			return false
+		}
+	case "user_id":
+		if !utilities.CheckObjectID(g.UserId) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate a Session for different scenarios such as creating a session on sign-in, or revoking one
+func (g *Session) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+	case "revoke":
+		if !g.CheckID("id") {
+			missingFields = append(missingFields, "id")
+		}
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following session fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
+
+// Active determines whether the session has not been signed out
+func (g *Session) Active() bool {
+	return g.RevokedAt.IsZero()
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when storing a file would take its owner over their storage quota
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Storage is a root struct that is used to store the json encoded storage usage and quota of a file owner.
+type Storage struct {
+	Id           string    `json:"id,omitempty"`
+	OwnerId      string    `json:"owner_id,omitempty"`
+	OwnerType    string    `json:"owner_type,omitempty"`
+	Quota        int64     `json:"quota"`
+	Used         int64     `json:"used"`
+	Available    int64     `json:"available"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Storage) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		if !utilities.CheckObjectID(g.Id) {
+			return false
+		}
+	case "owner_id":
+		if !utilities.CheckObjectID(g.OwnerId) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate a Storage for different scenarios such as retrieving usage, or updating a quota
+func (g *Storage) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "retrieve", "update":
+		if !g.CheckID("owner_id") {
+			missingFields = append(missingFields, "owner_id")
+		}
+		if g.OwnerType != "user" && g.OwnerType != "group" {
+			missingFields = append(missingFields, "owner_type")
+		}
+		if valCase == "update" && g.Quota < 0 {
+			return errors.New("quota can not be negative")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following storage fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
+
+// CalculateAvailable sets the number of bytes the owner can still store
+func (g *Storage) CalculateAvailable() {
+	g.Available = g.Quota - g.Used
+	if g.Available < 0 {
+		g.Available = 0
+	}
+}
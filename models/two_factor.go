@@ -0,0 +1,64 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTwoFactorInvalid is returned when a TOTP or recovery code does not match, or was already used
+	ErrTwoFactorInvalid = errors.New("invalid two-factor code")
+	// ErrTwoFactorNotEnabled is returned when a user has not confirmed a two-factor enrollment
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorRequired is returned when the admin policy requires a user to enable two-factor authentication
+	ErrTwoFactorRequired = errors.New("two-factor authentication is required for admin accounts")
+)
+
+// TwoFactor is a root struct that is used to store the json encoded data for/from a mongodb two factor doc.
+// The secret is only returned when enrolling and the recovery codes only when the enrollment is confirmed.
+type TwoFactor struct {
+	Id                string    `json:"id,omitempty"`
+	UserId            string    `json:"user_id,omitempty"`
+	Enabled           bool      `json:"enabled"`
+	Secret            string    `json:"secret,omitempty"`
+	URI               string    `json:"otpauth_uri,omitempty"`
+	RecoveryCodes     []string  `json:"recovery_codes,omitempty"`
+	RecoveryCodesLeft int       `json:"recovery_codes_left"`
+	ConfirmedAt       time.Time `json:"confirmed_at,omitempty"`
+	LastModified      time.Time `json:"last_modified,omitempty"`
+	CreatedAt         time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *TwoFactor) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		if !utilities.CheckObjectID(g.Id) {
+			return false
+		}
+	case "user_id":
+		if !utilities.CheckObjectID(g.UserId) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate a TwoFactor for different scenarios such as enrolling or verifying a code
+func (g *TwoFactor) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "find", "enroll", "verify":
+		if !g.CheckID("user_id") {
+			missingFields = append(missingFields, "user_id")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following two factor fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrFileTooLarge is returned when a file exceeds the configured MAX_FILE_SIZE
+	ErrFileTooLarge = errors.New("file exceeds the maximum allowed size")
+	// ErrChecksumMismatch is returned when uploaded bytes do not match the checksum supplied by the client
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrUploadExpired is returned when an upload session is used after it has expired
+	ErrUploadExpired = errors.New("upload session has expired")
+)
+
+// Upload is a root struct that is used to store the json encoded data for/from a mongodb upload session doc.
+type Upload struct {
+	Id           string         `json:"id,omitempty"`
+	OwnerId      string         `json:"owner_id,omitempty"`
+	OwnerType    string         `json:"owner_type,omitempty"`
+	Name         string         `json:"name,omitempty"`
+	FileType     string         `json:"file_type,omitempty"`
+	Size         int64          `json:"size,omitempty"`
+	ChunkSize    int64          `json:"chunk_size,omitempty"`
+	Checksum     string         `json:"checksum,omitempty"`
+	Chunks       []*UploadChunk `json:"chunks,omitempty"`
+	Offset       int64          `json:"offset"`
+	FileId       string         `json:"file_id,omitempty"`
+	ExpiresAt    time.Time      `json:"expires_at,omitempty"`
+	LastModified time.Time      `json:"last_modified,omitempty"`
+	CreatedAt    time.Time      `json:"created_at,omitempty"`
+}
+
+// UploadChunk is a numbered piece of an Upload that has been received by the server
+type UploadChunk struct {
+	Number   int    `json:"number"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum,omitempty"`
+	GridFSId string `json:"-"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Upload) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		if !utilities.CheckObjectID(g.Id) {
+			return false
+		}
+	case "owner_id":
+		if !utilities.CheckObjectID(g.OwnerId) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate an Upload for different scenarios such as creating a new Upload session
+func (g *Upload) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("owner_id") {
+			missingFields = append(missingFields, "owner_id")
+		}
+		if g.OwnerType == "" {
+			missingFields = append(missingFields, "owner_type")
+		}
+		if g.Name == "" {
+			missingFields = append(missingFields, "name")
+		}
+		if g.FileType == "" {
+			missingFields = append(missingFields, "file_type")
+		}
+		if g.Size <= 0 {
+			missingFields = append(missingFields, "size")
+		}
+		if g.Checksum == "" {
+			missingFields = append(missingFields, "checksum")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following upload fields: " + strings.Join(missingFields, ", "))
+	}
+	return
+}
+
+// ChunkCount returns the number of chunks required to complete the Upload
+func (g *Upload) ChunkCount() int {
+	if g.ChunkSize <= 0 {
+		return 0
+	}
+	return int((g.Size + g.ChunkSize - 1) / g.ChunkSize)
+}
+
+// ExpectedChunkSize returns the number of bytes a given chunk number must contain
+func (g *Upload) ExpectedChunkSize(number int) int64 {
+	count := g.ChunkCount()
+	if number < 0 || number >= count {
+		return 0
+	}
+	if number == count-1 {
+		return g.Size - int64(count-1)*g.ChunkSize
+	}
+	return g.ChunkSize
+}
+
+// FindChunk returns the received chunk with the given number, or nil if it has not been received
+func (g *Upload) FindChunk(number int) *UploadChunk {
+	for _, c := range g.Chunks {
+		if c.Number == number {
+			return c
+		}
+	}
+	return nil
+}
+
+// ComputeOffset sets Offset to the number of contiguous bytes received from the start of the file
+func (g *Upload) ComputeOffset() int64 {
+	g.Offset = 0
+	for i := 0; i < g.ChunkCount(); i++ {
+		c := g.FindChunk(i)
+		if c == nil {
+			break
+		}
+		g.Offset += c.Size
+	}
+	return g.Offset
+}
+
+// Complete determines whether every chunk of the Upload has been received
+func (g *Upload) Complete() bool {
+	return g.ComputeOffset() == g.Size
+}
+
+// ToFile creates a new File from a completed Upload
+func (g *Upload) ToFile() *File {
+	return &File{
+		OwnerId:   g.OwnerId,
+		OwnerType: g.OwnerType,
+		Name:      g.Name,
+		FileType:  g.FileType,
+		Size:      g.Size,
+		Checksum:  g.Checksum,
+	}
+}
@@ -2,25 +2,32 @@ package models
 
 import (
 	"errors"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/ablancas22/messenger-backend/password"
 	"strings"
 	"time"
 )
 
 // User is a root struct that is used to store the json encoded data for/from a mongodb user doc.
 type User struct {
-	Id         string    `json:"_id,omitempty"`
-	Username   string    `json:"username,omitempty"`
-	Password   string    `json:"password,omitempty"`
-	Email      string    `json:"email,omitempty"`
-	Phone      string    `json:"phone,omitempty"`
-	ImageId    string    `json:"image_id,omitempty"`
-	RootAdmin  bool      `json:"root_admin,omitempty"`
-	LastActive time.Time `json:"last_active,omitempty"`
-	CreatedAt  time.Time `json:"created_at,omitempty"`
-	DeletedAt  time.Time `json:"deleted_at,omitempty"`
+	Id              string    `json:"_id,omitempty"`
+	Username        string    `json:"username,omitempty"`
+	Password        string    `json:"password,omitempty"`
+	PasswordHistory []string  `json:"-"`
+	Email           string    `json:"email,omitempty"`
+	EmailVerifiedAt time.Time `json:"email_verified_at,omitempty"`
+	Phone           string    `json:"phone,omitempty"`
+	ImageId         string    `json:"image_id,omitempty"`
+	ImageURL        string    `json:"image_url,omitempty"`
+	Role            string    `json:"role,omitempty"`
+	RootAdmin       bool      `json:"root_admin,omitempty"`
+	LastActive      time.Time `json:"last_active,omitempty"`
+	CreatedAt       time.Time `json:"created_at,omitempty"`
+	DeletedAt       time.Time `json:"deleted_at,omitempty"`
 }
 
+// ErrEmailNotVerified is returned when a user has to verify their email before using a route
+var ErrEmailNotVerified = errors.New("email address has not been verified")
+
 // checkID determines whether a specified ID is set or not
 func (g *User) checkID(chkId string) bool {
 	switch chkId {
@@ -36,12 +43,23 @@ func (g *User) checkID(chkId string) bool {
 	return true
 }
 
+// BuildImageURL sets the URL a User's avatar is served from when an image is associated with the User
+func (g *User) BuildImageURL() {
+	g.ImageURL = ""
+	if g.checkID("id") && g.checkID("image_id") {
+		g.ImageURL = "/users/" + g.Id + "/image"
+	}
+}
+
+// EmailVerified determines whether the User confirmed they own their email address
+func (g *User) EmailVerified() bool {
+	return !g.EmailVerifiedAt.IsZero()
+}
+
 // Authenticate compares an input password with the hashed password stored in the User model
 func (g *User) Authenticate(checkPassword string) error {
 	if len(g.Password) != 0 {
-		password := []byte(g.Password)
-		cPassword := []byte(checkPassword)
-		return bcrypt.CompareHashAndPassword(password, cPassword)
+		return password.Compare(g.Password, checkPassword)
 	}
 	return errors.New("no password set to hash in user model")
 }
@@ -49,12 +67,11 @@ func (g *User) Authenticate(checkPassword string) error {
 // HashPassword hashes a user password and associates it with the user struct
 func (g *User) HashPassword() error {
 	if len(g.Password) != 0 {
-		password := []byte(g.Password)
-		hashedPassword, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+		hashedPassword, err := password.Hash(g.Password)
 		if err != nil {
 			return err
 		}
-		g.Password = string(hashedPassword)
+		g.Password = hashedPassword
 		return nil
 	}
 	return errors.New("no password set to hash in user model")
@@ -129,7 +146,6 @@ func (g *User) BuildUpdate(curUser *User) {
 	if len(g.Id) == 0 {
 		g.Id = curUser.Id
 	}
-	if g.RootAdmin {
-		g.RootAdmin = curUser.RootAdmin
-	}
+	g.Role = curUser.Role // roles are only changed by root admins through their own route
+	g.RootAdmin = curUser.RootAdmin
 }
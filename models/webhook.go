@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Webhook delivery statuses, a delivery is delivering while an instance of the app has claimed it for an attempt
+const (
+	DeliveryPending    = "pending"
+	DeliveryDelivering = "delivering"
+	DeliveryDelivered  = "delivered"
+	DeliveryFailed     = "failed"
+)
+
+// Errors returned when registering webhooks
+var (
+	ErrWebhookURL      = errors.New("url must be an absolute http or https url")
+	ErrWebhookEvent    = errors.New("unrecognized webhook event")
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// Webhook is a root struct that is used to store the json encoded data for/from a mongodb webhook doc.
+// Group admins register webhooks to have the events of their group delivered to an external URL, signed with Secret.
+type Webhook struct {
+	Id           string    `json:"id,omitempty"`
+	GroupId      string    `json:"group_id,omitempty"`
+	URL          string    `json:"url,omitempty"`
+	Secret       string    `json:"secret,omitempty"`
+	Events       []string  `json:"events,omitempty"`
+	Disabled     bool      `json:"disabled"`
+	Failures     int       `json:"failures"`
+	DisabledAt   time.Time `json:"disabled_at,omitempty"`
+	CreatedBy    string    `json:"created_by,omitempty"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+// CheckID determines whether a specified ID is set or not
+func (g *Webhook) CheckID(chkId string) bool {
+	switch chkId {
+	case "id":
+		return utilities.CheckObjectID(g.Id)
+	case "group_id":
+		return utilities.CheckObjectID(g.GroupId)
+	}
+	return true
+}
+
+// Subscribed determines whether the Webhook receives events of eventType, a Webhook without events receives every event
+func (g *Webhook) Subscribed(eventType string) bool {
+	if len(g.Events) == 0 {
+		return true
+	}
+	for _, e := range g.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEvents determines whether every event a Webhook subscribes to exists
+func (g *Webhook) checkEvents() error {
+	for _, e := range g.Events {
+		known := false
+		for _, t := range EventTypes {
+			if e == t {
+				known = true
+			}
+		}
+		if !known {
+			return ErrWebhookEvent
+		}
+	}
+	return nil
+}
+
+// Validate a Webhook for different scenarios such as registering or updating it
+func (g *Webhook) Validate(valCase string) (err error) {
+	var missingFields []string
+	switch valCase {
+	case "create":
+		if !g.CheckID("group_id") {
+			missingFields = append(missingFields, "group_id")
+		}
+		if g.URL == "" {
+			missingFields = append(missingFields, "url")
+		}
+	case "update":
+		if !g.CheckID("id") {
+			missingFields = append(missingFields, "id")
+		}
+	default:
+		return errors.New("unrecognized validation case")
+	}
+	if len(missingFields) > 0 {
+		return errors.New("missing the following webhook fields: " + strings.Join(missingFields, ", "))
+	}
+	if g.URL != "" {
+		u, err := url.Parse(g.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrWebhookURL
+		}
+	}
+	return g.checkEvents()
+}
+
+// DeliveryAttempt records the outcome of one attempt at delivering a WebhookDelivery
+type DeliveryAttempt struct {
+	At    time.Time `json:"at"`
+	Error string    `json:"error,omitempty"`
+}
+
+// WebhookDelivery is a root struct that is used to store the json encoded data for/from a mongodb webhook delivery doc.
+// Deliveries are queued for every event a Webhook receives and retried with backoff until they are delivered or run
+// out of attempts.
+type WebhookDelivery struct {
+	Id            string            `json:"id,omitempty"`
+	WebhookId     string            `json:"webhook_id,omitempty"`
+	EventType     string            `json:"event_type,omitempty"`
+	Payload       string            `json:"payload,omitempty"`
+	Status        string            `json:"status,omitempty"`
+	Attempts      []DeliveryAttempt `json:"attempts,omitempty"`
+	NextAttemptAt time.Time         `json:"next_attempt_at,omitempty"`
+	DeliveredAt   time.Time         `json:"delivered_at,omitempty"`
+	LastModified  time.Time         `json:"last_modified,omitempty"`
+	CreatedAt     time.Time         `json:"created_at,omitempty"`
+}
@@ -0,0 +1,203 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// MockUser is the account a MockProvider signs users in as
+type MockUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// mockCode is an authorization code issued by a MockProvider
+type mockCode struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	user        MockUser
+}
+
+// MockProvider is a local OpenID Connect provider for tests. It approves every authorization request as User
+// and checks the client credentials, redirect URI and PKCE verifier when codes are redeemed.
+type MockProvider struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	server       *httptest.Server
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  MockUser
+	codes map[string]*mockCode
+}
+
+// NewMockProvider starts a MockProvider, which has to be closed once the test is done
+func NewMockProvider() (*MockProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	m := &MockProvider{ClientID: "mock-client", ClientSecret: "mock-secret", key: key, codes: make(map[string]*mockCode)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.serveDiscovery)
+	mux.HandleFunc("/authorize", m.serveAuthorize)
+	mux.HandleFunc("/token", m.serveToken)
+	mux.HandleFunc("/jwks", m.serveJWKS)
+	m.server = httptest.NewServer(mux)
+	m.URL = m.server.URL
+	return m, nil
+}
+
+// Close shuts the MockProvider down
+func (m *MockProvider) Close() {
+	m.server.Close()
+}
+
+// SetUser sets the account the MockProvider signs the next users in as
+func (m *MockProvider) SetUser(u MockUser) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.user = u
+}
+
+// Config returns the relying party Config for the MockProvider
+func (m *MockProvider) Config(name string, redirectURL string) Config {
+	return Config{Name: name, Issuer: m.URL, ClientID: m.ClientID, ClientSecret: m.ClientSecret, RedirectURL: redirectURL}
+}
+
+// Authorize follows an authorization URL the way a browser would and returns the code and state the
+// MockProvider redirects back with
+func (m *MockProvider) Authorize(authURL string) (string, string, error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	res, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		return "", "", errors.New("mock provider refused the authorization request: " + res.Status)
+	}
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		return "", "", err
+	}
+	return location.Query().Get("code"), location.Query().Get("state"), nil
+}
+
+// IDToken signs an ID token for the current user as the MockProvider would, so tests can tamper with its claims
+func (m *MockProvider) IDToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "mock"
+	return token.SignedString(m.key)
+}
+
+func (m *MockProvider) serveDiscovery(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 m.URL,
+		"authorization_endpoint": m.URL + "/authorize",
+		"token_endpoint":         m.URL + "/token",
+		"jwks_uri":               m.URL + "/jwks",
+	})
+}
+
+func (m *MockProvider) serveAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != m.ClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" ||
+		q.Get("code_challenge") == "" || q.Get("redirect_uri") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	code, err := RandomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m.mu.Lock()
+	m.codes[code] = &mockCode{redirectURI: q.Get("redirect_uri"), challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), user: m.user}
+	m.mu.Unlock()
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *MockProvider) serveToken(w http.ResponseWriter, r *http.Request) {
+	tokenError := func(code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != m.ClientID || clientSecret != m.ClientSecret {
+		tokenError("invalid_client")
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError("invalid_request")
+		return
+	}
+	m.mu.Lock()
+	c, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code")) // codes are single use
+	m.mu.Unlock()
+	if !ok || c.redirectURI != r.PostForm.Get("redirect_uri") || Challenge(r.PostForm.Get("code_verifier")) != c.challenge {
+		tokenError("invalid_grant")
+		return
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            m.URL,
+		"sub":            c.user.Subject,
+		"aud":            m.ClientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          c.nonce,
+		"email":          c.user.Email,
+		"email_verified": c.user.EmailVerified,
+		"name":           c.user.Name,
+	}
+	idToken, err := m.IDToken(claims)
+	if err != nil {
+		tokenError("server_error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (m *MockProvider) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
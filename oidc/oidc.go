@@ -0,0 +1,96 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownProvider is returned for provider names that are not configured
+var ErrUnknownProvider = errors.New("unknown sign in provider")
+
+// Registry holds the configured OpenID Connect providers by name
+type Registry struct {
+	providers map[string]*Provider
+}
+
+var (
+	registryMu   sync.Mutex
+	registryFile string
+	registry     *Registry
+)
+
+// LoadProviders builds a Registry from the JSON list of provider configs in the OIDC_PROVIDERS_FILE file,
+// an empty Registry is returned when no file is configured
+func LoadProviders() (*Registry, error) {
+	r := &Registry{providers: make(map[string]*Provider)}
+	path := os.Getenv("OIDC_PROVIDERS_FILE")
+	if path == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []Config
+	if err = json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("invalid oidc providers file: %w", err)
+	}
+	for _, c := range configs {
+		p, err := NewProvider(c)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := r.providers[p.Name]; ok {
+			return nil, fmt.Errorf("duplicate oidc provider %q", p.Name)
+		}
+		r.providers[p.Name] = p
+	}
+	return r, nil
+}
+
+// currentRegistry returns the Registry for the current environment, reloading it whenever OIDC_PROVIDERS_FILE changes
+func currentRegistry() (*Registry, error) {
+	path := os.Getenv("OIDC_PROVIDERS_FILE")
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if registry != nil && registryFile == path {
+		return registry, nil
+	}
+	r, err := LoadProviders()
+	if err != nil {
+		return nil, err
+	}
+	registry, registryFile = r, path
+	return r, nil
+}
+
+// Lookup returns the configured provider with a name
+func Lookup(name string) (*Provider, error) {
+	r, err := currentRegistry()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Names returns the names of the configured providers in order
+func Names() ([]string, error) {
+	r, err := currentRegistry()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
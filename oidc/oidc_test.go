@@ -0,0 +1,137 @@
+package oidc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newTestProvider(t *testing.T) (*MockProvider, *Provider) {
+	mock, err := NewMockProvider()
+	if err != nil {
+		t.Fatalf("NewMockProvider() error = %v", err)
+	}
+	t.Cleanup(mock.Close)
+	mock.SetUser(MockUser{Subject: "user-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"})
+	p, err := NewProvider(mock.Config("mock", "http://localhost:3000/sso/callback"))
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	return mock, p
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	mock, p := newTestProvider(t)
+	ctx := context.Background()
+	verifier, _ := RandomString()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	code, state, err := mock.Authorize(authURL)
+	if err != nil || state != "state-1" {
+		t.Fatalf("Authorize() state = %q, error = %v", state, err)
+	}
+	if _, err = p.Exchange(ctx, code, "wrong-verifier", "nonce-1"); err == nil {
+		t.Error("Exchange() accepted a wrong PKCE verifier")
+	}
+	code, _, _ = mock.Authorize(authURL)
+	claims, err := p.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "user@example.com" || !claims.EmailVerified {
+		t.Errorf("Exchange() claims = %+v", claims)
+	}
+	if _, err = p.Exchange(ctx, code, verifier, "nonce-1"); err == nil {
+		t.Error("Exchange() redeemed an authorization code twice")
+	}
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	mock, p := newTestProvider(t)
+	claims := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":   mock.URL,
+			"sub":   "user-1",
+			"aud":   mock.ClientID,
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": "nonce-1",
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		wantErr bool
+	}{
+		{"valid", claims(nil), false},
+		{"audience list", claims(func(c jwt.MapClaims) { c["aud"] = []string{"other", mock.ClientID} }), false},
+		{"wrong nonce", claims(func(c jwt.MapClaims) { c["nonce"] = "nonce-2" }), true},
+		{"wrong audience", claims(func(c jwt.MapClaims) { c["aud"] = "other" }), true},
+		{"wrong authorized party", claims(func(c jwt.MapClaims) { c["azp"] = "other" }), true},
+		{"wrong issuer", claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }), true},
+		{"expired", claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), true},
+		{"missing expiry", claims(func(c jwt.MapClaims) { delete(c, "exp") }), true},
+		{"missing subject", claims(func(c jwt.MapClaims) { delete(c, "sub") }), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idToken, err := mock.IDToken(tt.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = p.VerifyIDToken(context.Background(), idToken, "nonce-1"); (err != nil) != tt.wantErr {
+				t.Errorf("VerifyIDToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(nil)).SignedString([]byte(mock.ClientSecret))
+	if _, err := p.VerifyIDToken(context.Background(), hmacToken, "nonce-1"); err == nil {
+		t.Error("VerifyIDToken() accepted an HS256 token")
+	}
+}
+
+func TestLoadProviders(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	provider := `{"name":"corp","issuer":"https://sso.example.com","client_id":"messenger","redirect_url":"https://app.example.com/sso"}`
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{"not configured", "", []string{}, false},
+		{"configured", write("ok.json", "["+provider+"]"), []string{"corp"}, false},
+		{"duplicate", write("dup.json", "["+provider+","+provider+"]"), nil, true},
+		{"missing fields", write("missing.json", `[{"name":"corp"}]`), nil, true},
+		{"invalid json", write("invalid.json", `{`), nil, true},
+		{"missing file", filepath.Join(dir, "missing.json"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OIDC_PROVIDERS_FILE", tt.path)
+			names, err := Names()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Names() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(names) != len(tt.want) || (len(names) > 0 && names[0] != tt.want[0]) {
+				t.Errorf("Names() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
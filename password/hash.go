@@ -0,0 +1,106 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// argon2id parameters, following the second recommended option of RFC 9106
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// ErrMismatch is returned when a password does not match a hash
+var ErrMismatch = errors.New("password does not match")
+
+var argonEncoding = base64.RawStdEncoding
+
+// Hash returns the argon2id hash of a password encoded in the PHC string format
+func Hash(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("no password set to hash")
+	}
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+		argonEncoding.EncodeToString(salt), argonEncoding.EncodeToString(key)), nil
+}
+
+// argonHash is a decoded argon2id PHC string
+type argonHash struct {
+	version int
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// decodeArgonHash parses an argon2id PHC string
+func decodeArgonHash(hash string) (*argonHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errors.New("invalid argon2id hash")
+	}
+	h := &argonHash{}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &h.version); err != nil {
+		return nil, errors.New("invalid argon2id hash")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil {
+		return nil, errors.New("invalid argon2id hash")
+	}
+	var err error
+	if h.salt, err = argonEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errors.New("invalid argon2id hash")
+	}
+	if h.key, err = argonEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, errors.New("invalid argon2id hash")
+	}
+	return h, nil
+}
+
+// Compare checks a password against an argon2id or bcrypt hash
+func Compare(hash string, password string) error {
+	if hash == "" {
+		return errors.New("no password hash to compare against")
+	}
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			return ErrMismatch
+		}
+		return nil
+	}
+	h, err := decodeArgonHash(hash)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	if subtle.ConstantTimeCompare(key, h.key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// NeedsRehash determines whether a hash was made with an older algorithm or weaker parameters than Hash uses,
+// so it should be replaced the next time the password is known
+func NeedsRehash(hash string) bool {
+	h, err := decodeArgonHash(hash)
+	if err != nil {
+		return true
+	}
+	return h.version != argon2.Version || h.memory < argonMemory || h.time < argonTime || h.threads != argonThreads ||
+		len(h.key) < argonKeyLen
+}
@@ -0,0 +1,146 @@
+package password
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	argonHash, err := Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Fatalf("Hash() = %q, want an argon2id PHC string", argonHash)
+	}
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		wantErr  error
+	}{
+		{"argon2id match", argonHash, "correct horse", nil},
+		{"argon2id mismatch", argonHash, "wrong horse", ErrMismatch},
+		{"bcrypt match", string(bcryptHash), "correct horse", nil},
+		{"bcrypt mismatch", string(bcryptHash), "wrong horse", ErrMismatch},
+		{"malformed argon2id", "$argon2id$v=19$m=65536$salt$key", "correct horse", errors.New("invalid argon2id hash")},
+		{"empty hash", "", "correct horse", errors.New("no password hash to compare against")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Compare(tt.hash, tt.password)
+			if (err == nil) != (tt.wantErr == nil) || (err != nil && err.Error() != tt.wantErr.Error()) {
+				t.Errorf("Compare() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argonHash, _ := Hash("correct horse")
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"current argon2id", argonHash, false},
+		{"bcrypt", string(bcryptHash), true},
+		{"weaker argon2id", strings.Replace(argonHash, "t=3", "t=1", 1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	banned := filepath.Join(t.TempDir(), "banned.txt")
+	if err := os.WriteFile(banned, []byte("# common passwords\nPassword1\n\nletmein123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewPolicy("8", "", banned)
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{"long enough", "a long passphrase", nil},
+		{"too short", "short", ErrTooShort},
+		{"multibyte characters count once", "ééééééé", ErrTooShort},
+		{"too long", strings.Repeat("a", maxLength+1), ErrTooLong},
+		{"banned", "letmein123", ErrBanned},
+		{"banned ignoring case", "password1", ErrBanned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := policy.Check(tt.password); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		minLength  string
+		history    string
+		bannedFile string
+		wantErr    bool
+	}{
+		{"defaults", "", "", "", false},
+		{"configured", "12", "0", "", false},
+		{"invalid min length", "twelve", "", "", true},
+		{"zero min length", "0", "", "", true},
+		{"negative history", "", "-1", "", true},
+		{"missing banned file", "", "", filepath.Join(t.TempDir(), "missing.txt"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPolicy(tt.minLength, tt.history, tt.bannedFile); (err != nil) != tt.wantErr {
+				t.Errorf("NewPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_Reuse(t *testing.T) {
+	policy, _ := NewPolicy("", "3", "")
+	var current string
+	var previous []string
+	for _, pw := range []string{"first password", "second password", "third password"} {
+		hash, _ := Hash(pw)
+		if current != "" {
+			previous = policy.Remember(current, previous)
+		}
+		current = hash
+	}
+	if len(previous) != 2 {
+		t.Fatalf("Remember() kept %d hashes, want 2", len(previous))
+	}
+	recent := append([]string{current}, previous...)
+	for _, pw := range []string{"first password", "second password", "third password"} {
+		if err := policy.CheckReuse(pw, recent); !errors.Is(err, ErrReused) {
+			t.Errorf("CheckReuse(%q) error = %v, want %v", pw, err, ErrReused)
+		}
+	}
+	if err := policy.CheckReuse("fourth password", recent); err != nil {
+		t.Errorf("CheckReuse() error = %v, want nil", err)
+	}
+	previous = policy.Remember(current, previous)
+	if err := policy.CheckReuse("first password", previous); err != nil {
+		t.Errorf("CheckReuse() of a forgotten password error = %v, want nil", err)
+	}
+}
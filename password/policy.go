@@ -0,0 +1,128 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Policy defaults, used when a setting is not configured
+const (
+	defaultMinLength = 8
+	defaultHistory   = 5
+	// maxLength bounds the work of hashing a password
+	maxLength = 128
+)
+
+var (
+	// ErrTooShort is returned for passwords under the minimum length of a Policy
+	ErrTooShort = errors.New("password is too short")
+	// ErrTooLong is returned for passwords over the maximum length
+	ErrTooLong = errors.New("password is too long")
+	// ErrBanned is returned for passwords on the banned password list
+	ErrBanned = errors.New("password is too common, choose a different one")
+	// ErrReused is returned for passwords that match one of the user's recent passwords
+	ErrReused = errors.New("password was used recently, choose a different one")
+)
+
+// Policy holds the rules new passwords have to follow. History is how many of a user's most recent passwords,
+// counting the current one, can not be chosen again.
+type Policy struct {
+	MinLength int
+	History   int
+	banned    map[string]struct{}
+}
+
+// NewPolicy returns the Policy configured by a minimum length, the number of previous passwords that can not be
+// reused and the path of a banned password file with one password per line. Empty settings use the defaults and
+// an empty path bans nothing.
+func NewPolicy(minLength string, history string, bannedFile string) (*Policy, error) {
+	p := &Policy{MinLength: defaultMinLength, History: defaultHistory, banned: map[string]struct{}{}}
+	var err error
+	if minLength != "" {
+		if p.MinLength, err = strconv.Atoi(minLength); err != nil || p.MinLength < 1 || p.MinLength > maxLength {
+			return nil, errors.New("invalid password minimum length: " + minLength)
+		}
+	}
+	if history != "" {
+		if p.History, err = strconv.Atoi(history); err != nil || p.History < 0 {
+			return nil, errors.New("invalid password history: " + history)
+		}
+	}
+	if bannedFile != "" {
+		if err = p.loadBanned(bannedFile); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// loadBanned reads the banned password list, skipping blank lines and # comments
+func (p *Policy) loadBanned(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.banned[strings.ToLower(line)] = struct{}{}
+	}
+	return s.Err()
+}
+
+// IsPolicyViolation determines whether an error was returned because a new password does not follow a Policy
+func IsPolicyViolation(err error) bool {
+	return errors.Is(err, ErrTooShort) || errors.Is(err, ErrTooLong) || errors.Is(err, ErrBanned) || errors.Is(err, ErrReused)
+}
+
+// Check determines whether a new password follows the Policy
+func (p *Policy) Check(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("%w, it needs at least %d characters", ErrTooShort, p.MinLength)
+	}
+	if length > maxLength {
+		return ErrTooLong
+	}
+	if _, ok := p.banned[strings.ToLower(password)]; ok {
+		return ErrBanned
+	}
+	return nil
+}
+
+// CheckReuse determines whether a new password matches one of the hashes of a user's most recent passwords,
+// ordered newest first starting with the current one
+func (p *Policy) CheckReuse(password string, hashes []string) error {
+	for i, hash := range hashes {
+		if i >= p.History {
+			break
+		}
+		if Compare(hash, password) == nil {
+			return ErrReused
+		}
+	}
+	return nil
+}
+
+// Remember returns the previous password hashes to keep after a password change, the replaced hash is added to
+// the front and only as many are kept as CheckReuse needs alongside the new current password
+func (p *Policy) Remember(replaced string, previous []string) []string {
+	keep := p.History - 1
+	if keep <= 0 {
+		return nil
+	}
+	history := append([]string{replaced}, previous...)
+	if len(history) > keep {
+		history = history[:keep]
+	}
+	return history
+}
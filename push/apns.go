@@ -0,0 +1,114 @@
+package push
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// apnsTokenLifetime is how long an APNs provider token is reused, APNs rejects tokens older than an hour
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNsProvider sends notifications to Apple devices with token based authentication
+type APNsProvider struct {
+	// Endpoint is the APNs server, api.sandbox.push.apple.com for development builds of an app
+	Endpoint string
+	KeyID    string
+	TeamID   string
+	Topic    string
+	key      *ecdsa.PrivateKey
+	client   *http.Client
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsProvider returns an APNsProvider signing its provider tokens with the .p8 key in keyFile
+func NewAPNsProvider(keyFile string, keyID string, teamID string, topic string, client *http.Client) (*APNsProvider, error) {
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, errors.New("apns provider requires a key id, a team id and a topic")
+	}
+	pem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, err
+	}
+	return &APNsProvider{Endpoint: "https://api.push.apple.com", KeyID: keyID, TeamID: teamID, Topic: topic, key: key, client: client}, nil
+}
+
+// providerToken returns the signed JWT authenticating requests to APNs, reusing it until it is apnsTokenLifetime old
+func (p *APNsProvider) providerToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Since(p.issuedAt) < apnsTokenLifetime {
+		return p.token, nil
+	}
+	now := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"iss": p.TeamID, "iat": now.Unix()})
+	t.Header["kid"] = p.KeyID
+	signed, err := t.SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+	p.token, p.issuedAt = signed, now
+	return signed, nil
+}
+
+// Send posts n as an alert to the device token of n, tokens that are not hex encoded are never sent to APNs
+func (p *APNsProvider) Send(n *Notification) error {
+	if _, err := hex.DecodeString(n.Token); err != nil || n.Token == "" {
+		return ErrUnregistered
+	}
+	token, err := p.providerToken()
+	if err != nil {
+		return err
+	}
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": n.Title, "body": n.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", p.Endpoint+"/3/device/"+n.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("Apns-Topic", p.Topic)
+	req.Header.Set("Apns-Push-Type", "alert")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var reply struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&reply)
+	if resp.StatusCode == http.StatusGone || reply.Reason == "BadDeviceToken" || reply.Reason == "Unregistered" {
+		return ErrUnregistered
+	}
+	return errors.New("apns responded " + resp.Status + " " + reply.Reason)
+}
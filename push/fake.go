@@ -0,0 +1,51 @@
+package push
+
+import (
+	"sync"
+)
+
+// FakeProvider is an in-memory Provider for tests that keeps every notification it was asked to send
+type FakeProvider struct {
+	// Err, when set, is returned by Send to simulate an unavailable push service
+	Err          error
+	mu           sync.Mutex
+	sent         []*Notification
+	unregistered map[string]bool
+}
+
+// Unregister makes Send reject the notifications for token with ErrUnregistered, as if the app was uninstalled
+func (f *FakeProvider) Unregister(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.unregistered == nil {
+		f.unregistered = make(map[string]bool)
+	}
+	f.unregistered[token] = true
+}
+
+// Send records n
+func (f *FakeProvider) Send(n *Notification) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.unregistered[n.Token] {
+		return ErrUnregistered
+	}
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+// Sent returns the notifications sent to a device token, oldest first
+func (f *FakeProvider) Sent(token string) []*Notification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var sent []*Notification
+	for _, n := range f.sent {
+		if n.Token == token {
+			sent = append(sent, n)
+		}
+	}
+	return sent
+}
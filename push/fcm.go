@@ -0,0 +1,145 @@
+package push
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fcmScope is the OAuth scope the access tokens of an FCMProvider are requested for
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMProvider sends notifications to Android devices through the FCM HTTP v1 API with a service account
+type FCMProvider struct {
+	// Endpoint is the FCM server
+	Endpoint    string
+	ProjectID   string
+	ClientEmail string
+	TokenURI    string
+	key         *rsa.PrivateKey
+	client      *http.Client
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMProvider returns an FCMProvider authenticating with the service account key in credentialsFile
+func NewFCMProvider(credentialsFile string, client *http.Client) (*FCMProvider, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	var credentials struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err = json.Unmarshal(data, &credentials); err != nil {
+		return nil, err
+	}
+	if credentials.ProjectID == "" || credentials.ClientEmail == "" || credentials.TokenURI == "" {
+		return nil, errors.New("fcm credentials require a project_id, a client_email and a token_uri")
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(credentials.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	return &FCMProvider{
+		Endpoint:    "https://fcm.googleapis.com",
+		ProjectID:   credentials.ProjectID,
+		ClientEmail: credentials.ClientEmail,
+		TokenURI:    credentials.TokenURI,
+		key:         key,
+		client:      client,
+	}, nil
+}
+
+// token returns an OAuth access token for the service account, exchanging a signed JWT for a new one shortly before
+// the current one expires
+func (p *FCMProvider) token() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.accessToken != "" && time.Now().Add(time.Minute).Before(p.expiresAt) {
+		return p.accessToken, nil
+	}
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   p.ClientEmail,
+		"scope": fcmScope,
+		"aud":   p.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	resp, err := p.client.PostForm(p.TokenURI, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("fcm token request responded " + resp.Status)
+	}
+	var reply struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 65536)).Decode(&reply); err != nil {
+		return "", err
+	}
+	if reply.AccessToken == "" {
+		return "", errors.New("fcm token request returned no access token")
+	}
+	p.accessToken, p.expiresAt = reply.AccessToken, now.Add(time.Duration(reply.ExpiresIn)*time.Second)
+	return p.accessToken, nil
+}
+
+// Send posts n as a notification message to the registration token of n
+func (p *FCMProvider) Send(n *Notification) error {
+	token, err := p.token()
+	if err != nil {
+		return err
+	}
+	message := map[string]interface{}{
+		"token":        n.Token,
+		"notification": map[string]string{"title": n.Title, "body": n.Body},
+	}
+	if len(n.Data) > 0 {
+		message["data"] = n.Data
+	}
+	body, err := json.Marshal(map[string]interface{}{"message": message})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", p.Endpoint+"/v1/projects/"+p.ProjectID+"/messages:send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusNotFound || strings.Contains(string(reply), "UNREGISTERED") {
+		return ErrUnregistered
+	}
+	return errors.New("fcm responded " + resp.Status)
+}
@@ -0,0 +1,111 @@
+package push
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/webhook"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Platforms a device can register push tokens for
+const (
+	APNs    = "apns"
+	FCM     = "fcm"
+	WebPush = "webpush"
+)
+
+var (
+	// ErrUnregistered is returned by a Provider when a push service no longer accepts a device token
+	ErrUnregistered = errors.New("device token is no longer registered")
+	// ErrUnsupported is returned when no Provider is configured for the platform of a notification
+	ErrUnsupported = errors.New("push platform is not configured")
+)
+
+// Notification is a push notification addressed to a single device
+type Notification struct {
+	Platform string
+	// Token is the APNs device token, the FCM registration token or the Web Push subscription endpoint
+	Token string
+	// PublicKey and AuthSecret are the base64url encoded p256dh and auth keys of a Web Push subscription
+	PublicKey  string
+	AuthSecret string
+	Title      string
+	Body       string
+	Data       map[string]string
+}
+
+// Provider is implemented by the push services the notification outbox hands queued notifications to
+type Provider interface {
+	Send(n *Notification) error
+}
+
+// Config holds the credentials of the push services, a platform without credentials is left unconfigured
+type Config struct {
+	APNsKeyFile        string
+	APNsKeyID          string
+	APNsTeamID         string
+	APNsTopic          string
+	FCMCredentialsFile string
+	VAPIDPrivateKey    string
+	VAPIDSubject       string
+}
+
+// New returns the Provider configured by kind, one of live, log or fake.
+// A live Provider sends through APNs, FCM and Web Push for the platforms that have credentials in c. Web Push
+// endpoints are supplied by users, so they are sent to with a client that refuses to connect to internal addresses.
+func New(kind string, c Config) (Provider, error) {
+	switch kind {
+	case "live":
+		client := &http.Client{Timeout: 10 * time.Second}
+		providers := Providers{}
+		if c.APNsKeyFile != "" {
+			p, err := NewAPNsProvider(c.APNsKeyFile, c.APNsKeyID, c.APNsTeamID, c.APNsTopic, client)
+			if err != nil {
+				return nil, err
+			}
+			providers[APNs] = p
+		}
+		if c.FCMCredentialsFile != "" {
+			p, err := NewFCMProvider(c.FCMCredentialsFile, client)
+			if err != nil {
+				return nil, err
+			}
+			providers[FCM] = p
+		}
+		if c.VAPIDPrivateKey != "" {
+			p, err := NewWebPushProvider(c.VAPIDPrivateKey, c.VAPIDSubject, webhook.NewClient(10*time.Second))
+			if err != nil {
+				return nil, err
+			}
+			providers[WebPush] = p
+		}
+		return providers, nil
+	case "fake":
+		return &FakeProvider{}, nil
+	case "log", "":
+		return &LogProvider{}, nil
+	}
+	return nil, errors.New("unrecognized push provider: " + kind)
+}
+
+// Providers is a Provider that hands every notification to the Provider of its platform
+type Providers map[string]Provider
+
+// Send sends n through the Provider of its platform
+func (p Providers) Send(n *Notification) error {
+	provider, ok := p[n.Platform]
+	if !ok {
+		return ErrUnsupported
+	}
+	return provider.Send(n)
+}
+
+// LogProvider writes notifications to the application log instead of sending them, it is intended for local development only
+type LogProvider struct{}
+
+// Send logs the platform, title and body of n
+func (l *LogProvider) Send(n *Notification) error {
+	log.Printf("Push to %s device: %s\n%s", n.Platform, n.Title, n.Body)
+	return nil
+}
@@ -0,0 +1,267 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeKey writes key as a PKCS8 PEM file in dir and returns its contents and path
+func writeKey(t *testing.T, dir string, key interface{}) (string, string) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	path := filepath.Join(dir, "key.pem")
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return string(data), path
+}
+
+func TestAPNsProvider_Send(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, keyFile := writeKey(t, t.TempDir(), key)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := jwt.Parse(strings.TrimPrefix(r.Header.Get("Authorization"), "bearer "), func(t *jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		if err != nil || token.Header["kid"] != "KEY123" || token.Claims.(jwt.MapClaims)["iss"] != "TEAM123" || r.Header.Get("Apns-Topic") != "com.example.messenger" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var payload struct {
+			Aps struct {
+				Alert struct {
+					Title string `json:"title"`
+				} `json:"alert"`
+			} `json:"aps"`
+			MessageId string `json:"message_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		switch {
+		case r.URL.Path == "/3/device/dead":
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write([]byte(`{"reason":"Unregistered"}`))
+		case r.URL.Path != "/3/device/abcd" || payload.Aps.Alert.Title != "Hello" || payload.MessageId != "1":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"reason":"BadRequest"}`))
+		}
+	}))
+	defer server.Close()
+	if _, err := NewAPNsProvider(keyFile, "", "TEAM123", "com.example.messenger", server.Client()); err == nil {
+		t.Errorf("NewAPNsProvider() accepted a missing key id")
+	}
+	p, err := NewAPNsProvider(keyFile, "KEY123", "TEAM123", "com.example.messenger", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Endpoint = server.URL
+	if err = p.Send(&Notification{Platform: APNs, Token: "abcd", Title: "Hello", Body: "hi", Data: map[string]string{"message_id": "1"}}); err != nil {
+		t.Errorf("Send() error = %v", err)
+	}
+	if err = p.Send(&Notification{Platform: APNs, Token: "dead", Title: "Hello"}); !errors.Is(err, ErrUnregistered) {
+		t.Errorf("Send() error = %v, want ErrUnregistered", err)
+	}
+	if err = p.Send(&Notification{Platform: APNs, Token: "abcd/../../x", Title: "Hello"}); !errors.Is(err, ErrUnregistered) {
+		t.Errorf("Send() error = %v, want ErrUnregistered for a token that is not hex", err)
+	}
+	if err = p.Send(&Notification{Platform: APNs, Token: "abcd", Title: "Bye"}); err == nil || errors.Is(err, ErrUnregistered) {
+		t.Errorf("Send() error = %v, want a rejected notification", err)
+	}
+}
+
+func TestFCMProvider_Send(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	dir := t.TempDir()
+	keyPEM, _ := writeKey(t, dir, key)
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	tokenRequests := 0
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		_, err := jwt.Parse(r.FormValue("assertion"), func(t *jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		if err != nil || r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"secret-token","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v1/projects/messenger/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Message struct {
+				Token string `json:"token"`
+			} `json:"message"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Header.Get("Authorization") != "Bearer secret-token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case body.Message.Token == "stale":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+		}
+	})
+	credentials, _ := json.Marshal(map[string]string{"project_id": "messenger", "client_email": "push@example.com", "private_key": keyPEM, "token_uri": server.URL + "/token"})
+	credentialsFile := filepath.Join(dir, "credentials.json")
+	if err := os.WriteFile(credentialsFile, credentials, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewFCMProvider(credentialsFile, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Endpoint = server.URL
+	for i := 0; i < 2; i++ {
+		if err = p.Send(&Notification{Platform: FCM, Token: "abc", Title: "Hello", Body: "hi"}); err != nil {
+			t.Errorf("Send() error = %v", err)
+		}
+	}
+	if tokenRequests != 1 {
+		t.Errorf("Send() requested %d access tokens, want 1", tokenRequests)
+	}
+	if err = p.Send(&Notification{Platform: FCM, Token: "stale", Title: "Hello"}); !errors.Is(err, ErrUnregistered) {
+		t.Errorf("Send() error = %v, want ErrUnregistered", err)
+	}
+}
+
+// decrypt reverses the aes128gcm encryption of a Web Push payload with the private key of the subscription
+func decrypt(t *testing.T, body []byte, uaKey *ecdsa.PrivateKey, auth []byte) []byte {
+	salt, idLen := body[:16], int(body[20])
+	asPublic, ciphertext := body[21:21+idLen], body[21+idLen:]
+	curve := elliptic.P256()
+	asX, asY := elliptic.Unmarshal(curve, asPublic)
+	sharedX, _ := curve.ScalarMult(asX, asY, uaKey.D.Bytes())
+	sharedSecret := make([]byte, 32)
+	sharedX.FillBytes(sharedSecret)
+	uaPublic := elliptic.Marshal(curve, uaKey.X, uaKey.Y)
+	ikm := make([]byte, 32)
+	_, _ = io.ReadFull(hkdf.New(sha256.New, sharedSecret, auth, append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)), ikm)
+	cek, nonce := make([]byte, 16), make([]byte, 12)
+	_, _ = io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: aes128gcm\x00")), cek)
+	_, _ = io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: nonce\x00")), nonce)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil || len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatalf("decrypt() error = %v", err)
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestWebPushProvider_Send(t *testing.T) {
+	vapidKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	uaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	auth := make([]byte, 16)
+	_, _ = rand.Read(auth)
+	got := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/expired" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		vapid := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "vapid t="), ", k=")
+		token, err := jwt.Parse(vapid[0], func(t *jwt.Token) (interface{}, error) {
+			return &vapidKey.PublicKey, nil
+		})
+		if err != nil || token.Claims.(jwt.MapClaims)["aud"] != "http://"+r.Host || r.Header.Get("Content-Encoding") != "aes128gcm" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		got <- string(decrypt(t, body, uaKey, auth))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	p, err := NewWebPushProvider(base64.RawURLEncoding.EncodeToString(vapidKey.D.FillBytes(make([]byte, 32))), "mailto:admin@example.com", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := base64.RawURLEncoding.EncodeToString(elliptic.Marshal(elliptic.P256(), vapidKey.X, vapidKey.Y)); p.PublicKey() != want {
+		t.Errorf("PublicKey() = %s, want %s", p.PublicKey(), want)
+	}
+	n := &Notification{
+		Platform:   WebPush,
+		Token:      server.URL + "/subscription",
+		PublicKey:  base64.RawURLEncoding.EncodeToString(elliptic.Marshal(elliptic.P256(), uaKey.X, uaKey.Y)),
+		AuthSecret: base64.RawURLEncoding.EncodeToString(auth),
+		Title:      "Hello",
+		Body:       "hi",
+	}
+	if err = p.Send(n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if payload := <-got; !strings.Contains(payload, `"title":"Hello"`) {
+		t.Errorf("Send() delivered an unexpected payload %s", payload)
+	}
+	n.Token = server.URL + "/expired"
+	if err = p.Send(n); !errors.Is(err, ErrUnregistered) {
+		t.Errorf("Send() error = %v, want ErrUnregistered", err)
+	}
+	n.Body = strings.Repeat("a", webPushRecordSize)
+	if err = p.Send(n); err == nil {
+		t.Errorf("Send() accepted a payload larger than a record")
+	}
+	n.Body, n.PublicKey = "hi", "invalid"
+	if err = p.Send(n); err == nil {
+		t.Errorf("Send() accepted invalid subscription keys")
+	}
+}
+
+func TestProvider_Send(t *testing.T) {
+	tests := []struct {
+		name    string // The name of the test
+		kind    string // The kind of provider
+		wantErr bool   // whether we want an error.
+	}{
+		{"fake", "fake", false},
+		{"log", "log", false},
+		{"live without credentials", "live", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.kind, Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := &Notification{Platform: FCM, Token: "abc", Title: "Hello"}
+			if err = p.Send(n); (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fake, ok := p.(*FakeProvider); ok {
+				if len(fake.Sent("abc")) != 1 {
+					t.Errorf("Send() did not record the notification")
+				}
+				fake.Unregister("abc")
+				if err = p.Send(n); !errors.Is(err, ErrUnregistered) {
+					t.Errorf("Send() error = %v, want ErrUnregistered", err)
+				}
+			}
+		})
+	}
+	if _, err := New("carrier-pigeon", Config{}); err == nil {
+		t.Errorf("New() accepted an unrecognized provider")
+	}
+}
@@ -0,0 +1,173 @@
+package push
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// webPushRecordSize is the size of the single aes128gcm record a Web Push payload is encrypted into
+const webPushRecordSize = 4096
+
+// WebPushProvider sends notifications to browser push subscriptions, identifying itself to push services with VAPID
+type WebPushProvider struct {
+	// Subject is the mailto: or https: contact of the application sending the notifications
+	Subject string
+	key     *ecdsa.PrivateKey
+	client  *http.Client
+}
+
+// decodeBase64URL decodes the unpadded base64url encoding used for Web Push keys, tolerating padding
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// NewWebPushProvider returns a WebPushProvider signing with the base64url encoded P-256 VAPID private key
+func NewWebPushProvider(privateKey string, subject string, client *http.Client) (*WebPushProvider, error) {
+	if subject == "" {
+		return nil, errors.New("web push provider requires a subject")
+	}
+	d, err := decodeBase64URL(privateKey)
+	if err != nil || len(d) != 32 {
+		return nil, errors.New("vapid private key must be a base64url encoded P-256 private key")
+	}
+	key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	key.Curve = elliptic.P256()
+	key.X, key.Y = key.Curve.ScalarBaseMult(d)
+	return &WebPushProvider{Subject: subject, key: key, client: client}, nil
+}
+
+// PublicKey returns the base64url encoded VAPID public key browsers subscribe with as their applicationServerKey
+func (p *WebPushProvider) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(elliptic.Marshal(p.key.Curve, p.key.X, p.key.Y))
+}
+
+// encrypt encrypts plaintext for a subscription with the aes128gcm content encoding of RFC 8291
+func encrypt(plaintext []byte, publicKey string, authSecret string) ([]byte, error) {
+	uaPublic, err := decodeBase64URL(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := decodeBase64URL(authSecret)
+	if err != nil {
+		return nil, err
+	}
+	curve := elliptic.P256()
+	uaX, uaY := elliptic.Unmarshal(curve, uaPublic)
+	if uaX == nil || len(auth) == 0 {
+		return nil, errors.New("invalid web push subscription keys")
+	}
+	if len(plaintext) > webPushRecordSize-16-1-86 {
+		return nil, errors.New("notification payload is too large")
+	}
+	asKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := elliptic.Marshal(curve, asKey.X, asKey.Y)
+	sharedX, _ := curve.ScalarMult(uaX, uaY, asKey.D.Bytes())
+	sharedSecret := make([]byte, 32)
+	sharedX.FillBytes(sharedSecret)
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm := make([]byte, 32)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, sharedSecret, auth, keyInfo), ikm); err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek := make([]byte, 16)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 12)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// the header carries the salt, the record size and the ephemeral public key the receiver derives the keys with
+	header := make([]byte, 16+4+1)
+	copy(header, salt)
+	binary.BigEndian.PutUint32(header[16:], webPushRecordSize)
+	header[20] = byte(len(asPublic))
+	header = append(header, asPublic...)
+	// 0x02 delimits the last, and only, record
+	return gcm.Seal(header, nonce, append(plaintext, 0x02), nil), nil
+}
+
+// vapid returns the Authorization header identifying the application to the push service of endpoint
+func (p *WebPushProvider) vapid(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", errors.New("invalid web push endpoint")
+	}
+	t, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": p.Subject,
+	}).SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + t + ", k=" + p.PublicKey(), nil
+}
+
+// Send posts n encrypted to the subscription endpoint in the token of n
+func (p *WebPushProvider) Send(n *Notification) error {
+	payload, err := json.Marshal(map[string]interface{}{"title": n.Title, "body": n.Body, "data": n.Data})
+	if err != nil {
+		return err
+	}
+	body, err := encrypt(payload, n.PublicKey, n.AuthSecret)
+	if err != nil {
+		return err
+	}
+	authorization, err := p.vapid(n.Token)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", n.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", "86400")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrUnregistered
+	}
+	return errors.New("web push service responded " + resp.Status)
+}
@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the INSTREAM chunks sent to clamd
+const clamdChunkSize = 64 << 10
+
+// ClamdScanner scans file contents with a clamd compatible daemon using the INSTREAM command
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner initializes a ClamdScanner for an address such as unix:/run/clamav/clamd.ctl or tcp:localhost:3310
+func NewClamdScanner(address string) *ClamdScanner {
+	network := "tcp"
+	if i := strings.Index(address, ":"); i > 0 && (address[:i] == "unix" || address[:i] == "tcp") {
+		network, address = address[:i], address[i+1:]
+	}
+	return &ClamdScanner{network: network, address: address, timeout: 2 * time.Minute}
+}
+
+// Scan streams r to clamd and parses its verdict
+func (s *ClamdScanner) Scan(r io.Reader) (*Result, error) {
+	conn, err := net.DialTimeout(s.network, s.address, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, rErr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err = conn.Write(buf[:4+n]); err != nil {
+				return nil, err
+			}
+		}
+		if rErr == io.EOF {
+			break
+		}
+		if rErr != nil {
+			return nil, rErr
+		}
+	}
+	if _, err = conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, err
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply converts a clamd reply such as "stream: Eicar-Signature FOUND" into a Result
+func parseClamdReply(reply string) (*Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	}
+	return nil, errors.New("clamd: " + reply)
+}
@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// serveClamd accepts a single INSTREAM request on l and replies with a verdict for the received stream
+func serveClamd(t *testing.T, l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	cmd := make([]byte, len("zINSTREAM\x00"))
+	if _, err = io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+		t.Errorf("serveClamd() unexpected command %q", cmd)
+		return
+	}
+	var data bytes.Buffer
+	for {
+		var size uint32
+		if err = binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if _, err = io.CopyN(&data, conn, int64(size)); err != nil {
+			return
+		}
+	}
+	reply := "stream: OK\x00"
+	if strings.Contains(data.String(), EICAR) {
+		reply = "stream: Eicar-Signature FOUND\x00"
+	}
+	_, _ = conn.Write([]byte(reply))
+}
+
+func TestClamdScanner_Scan(t *testing.T) {
+	tests := []struct {
+		name    string  // The name of the test
+		content string  // The scanned contents
+		want    *Result // What we want the scanner to return
+	}{
+		{"clean", "hello world!", &Result{}},
+		{"infected", "prefix " + EICAR, &Result{Infected: true, Signature: "Eicar-Signature"}},
+		{"large clean", strings.Repeat("a", 3*clamdChunkSize+17), &Result{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Skipf("can not listen on localhost: %v", err)
+			}
+			defer l.Close()
+			go serveClamd(t, l)
+			got, err := NewClamdScanner("tcp:" + l.Addr().String()).Scan(strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseClamdReply(t *testing.T) {
+	tests := []struct {
+		name    string  // The name of the test
+		reply   string  // The raw clamd reply
+		want    *Result // What we want the parser to return
+		wantErr bool    // whether we want an error.
+	}{
+		{"ok", "stream: OK\x00", &Result{}, false},
+		{"found", "stream: Win.Test.EICAR_HDB-1 FOUND\x00", &Result{Infected: true, Signature: "Win.Test.EICAR_HDB-1"}, false},
+		{"size limit", "INSTREAM size limit exceeded. ERROR\x00", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClamdReply(tt.reply)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClamdReply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseClamdReply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+)
+
+// EICAR is the industry standard anti-malware test string, the FakeScanner reports it as infected
+const EICAR = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// FakeScanner is an in-memory Scanner for tests that flags contents containing the EICAR test string
+type FakeScanner struct {
+	// Err, when set, is returned by Scan to simulate an unavailable scanner
+	Err error
+}
+
+// Scan reads r and reports it as infected when it contains the EICAR test string
+func (s *FakeScanner) Scan(r io.Reader) (*Result, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(data, []byte(EICAR)) {
+		return &Result{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return &Result{}, nil
+}
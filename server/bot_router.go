@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/services"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+)
+
+type botRouter struct {
+	aService *services.TokenService
+}
+
+// NewBotRouter is a function that initializes a new botRouter struct
+func NewBotRouter(router *mux.Router, a *services.TokenService) *mux.Router {
+	bRouter := botRouter{a}
+	router.HandleFunc("/bots", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/bots", a.MemberTokenVerifyMiddleWare(bRouter.BotsShow)).Methods("GET")
+	router.HandleFunc("/bots", a.MemberTokenVerifyMiddleWare(bRouter.CreateBot)).Methods("POST")
+	router.HandleFunc("/bots/{botId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/bots/{botId}", a.MemberTokenVerifyMiddleWare(bRouter.ModifyBot)).Methods("PATCH")
+	router.HandleFunc("/bots/{botId}", a.MemberTokenVerifyMiddleWare(bRouter.DeleteBot)).Methods("DELETE")
+	router.HandleFunc("/bots/{botId}/token", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/bots/{botId}/token", a.MemberTokenVerifyMiddleWare(bRouter.RotateBotToken)).Methods("POST")
+	return router
+}
+
+// respondWithBotError maps the errors of managing a bot onto response codes
+func respondWithBotError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrBotForbidden):
+		utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: err.Error()})
+	case errors.Is(err, models.ErrBotNotFound):
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+	default:
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+	}
+}
+
+// readBotDTO reads the botDTO of a request body
+func readBotDTO(w http.ResponseWriter, r *http.Request) (*botDTO, bool) {
+	var dto botDTO
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	if err = json.Unmarshal(body, &dto); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	return &dto, true
+}
+
+// BotsShow is the handler function that lists the bots of the requesting user
+func (br *botRouter) BotsShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	bots, err := br.aService.Bots(tokenData.UserId)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(botsDTO{Bots: bots}); err != nil {
+		return
+	}
+}
+
+// CreateBot is the handler function that creates a bot owned by the requesting user and returns its bot token once
+func (br *botRouter) CreateBot(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	dto, ok := readBotDTO(w, r)
+	if !ok {
+		return
+	}
+	if dto.Username == "" {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing username"})
+		return
+	}
+	b, k, err := br.aService.CreateBot(tokenData.UserId, dto.Username, dto.WebhookURL)
+	if err != nil {
+		respondWithBotError(w, err)
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(botDTO{Bot: b, Token: k}); err != nil {
+		return
+	}
+}
+
+// ModifyBot is the handler function that changes the webhook URL of a bot
+func (br *botRouter) ModifyBot(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	botId := mux.Vars(r)["botId"]
+	if !utilities.CheckObjectID(botId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing botId"})
+		return
+	}
+	dto, ok := readBotDTO(w, r)
+	if !ok {
+		return
+	}
+	b, err := br.aService.UpdateBot(tokenData.UserId, botId, dto.WebhookURL)
+	if err != nil {
+		respondWithBotError(w, err)
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusAccepted)
+	if err = json.NewEncoder(w).Encode(b); err != nil {
+		return
+	}
+}
+
+// DeleteBot is the handler function that removes a bot along with its user and tokens
+func (br *botRouter) DeleteBot(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	botId := mux.Vars(r)["botId"]
+	if !utilities.CheckObjectID(botId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing botId"})
+		return
+	}
+	b, err := br.aService.DeleteBot(tokenData.UserId, botId)
+	if err != nil {
+		respondWithBotError(w, err)
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(b); err != nil {
+		return
+	}
+}
+
+// RotateBotToken is the handler function that revokes the token of a bot and returns a new one
+func (br *botRouter) RotateBotToken(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	botId := mux.Vars(r)["botId"]
+	if !utilities.CheckObjectID(botId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing botId"})
+		return
+	}
+	k, err := br.aService.RotateBotToken(tokenData.UserId, botId)
+	if err != nil {
+		respondWithBotError(w, err)
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(botDTO{Token: k}); err != nil {
+		return
+	}
+}
@@ -32,8 +32,7 @@ func NewContactRouter(router *mux.Router, a *services.TokenService, t services.M
 
 // ContactsShow returns all contacts to client
 func (cr *contactRouter) ContactsShow(w http.ResponseWriter, r *http.Request) {
-	authToken := r.Header.Get("Auth-Token")
-	tokenData, err := auth.DecodeJWT(authToken)
+	tokenData, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
@@ -16,24 +16,55 @@ type conversationRouter struct {
 	tService services.MessageService
 	cService services.ConversationService
 	uService services.UserService
+	gService  services.GroupService
+	gmService services.GroupMembershipService
 }
 
 //NewConversationRouter is a function that initializes a new groupRouter struct
-func NewConversationRouter(router *mux.Router, a *services.TokenService, t services.MessageService, c services.ConversationService, u services.UserService) *mux.Router {
-	gRouter := conversationRouter{a, t, c, u}
+func NewConversationRouter(router *mux.Router, a *services.TokenService, t services.MessageService, c services.ConversationService, u services.UserService, g services.GroupService, gm services.GroupMembershipService) *mux.Router {
+	gRouter := conversationRouter{a, t, c, u, g, gm}
 	router.HandleFunc("/conversations", utilities.HandleOptionsRequest).Methods("OPTIONS")
-	router.HandleFunc("/conversations", a.MemberTokenVerifyMiddleWare(gRouter.ConversationsShow)).Methods("GET")
-	router.HandleFunc("/conversations", a.MemberTokenVerifyMiddleWare(gRouter.CreateConversation)).Methods("POST")
+	router.HandleFunc("/conversations", a.MemberTokenVerifyMiddleWare(gRouter.ConversationsShow, models.ScopeMessagesRead)).Methods("GET")
+	router.HandleFunc("/conversations", a.MemberTokenVerifyMiddleWare(a.VerifiedEmailMiddleWare(gRouter.CreateConversation), models.ScopeMessagesWrite)).Methods("POST")
 	router.HandleFunc("/conversations/{conversationId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
-	router.HandleFunc("/conversations/{conversationsId}", a.MemberTokenVerifyMiddleWare(gRouter.ConversationShow)).Methods("GET")
-	router.HandleFunc("/conversations/{conversationsId}", a.MemberTokenVerifyMiddleWare(gRouter.DeleteConversation)).Methods("DELETE")
+	router.HandleFunc("/conversations/{conversationsId}", a.MemberTokenVerifyMiddleWare(gRouter.ConversationShow, models.ScopeMessagesRead)).Methods("GET")
+	router.HandleFunc("/conversations/{conversationsId}", a.MemberTokenVerifyMiddleWare(gRouter.DeleteConversation, models.ScopeMessagesWrite)).Methods("DELETE")
+	router.HandleFunc("/conversations/{conversationId}/disappearing", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/conversations/{conversationId}/disappearing", a.MemberTokenVerifyMiddleWare(gRouter.UpdateDisappearingMessages, models.ScopeMessagesWrite)).Methods("PUT")
+	router.HandleFunc("/conversations/{conversationId}/incoming-webhooks", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/conversations/{conversationId}/incoming-webhooks", a.MemberTokenVerifyMiddleWare(gRouter.IncomingWebhooksShow)).Methods("GET")
+	router.HandleFunc("/conversations/{conversationId}/incoming-webhooks", a.MemberTokenVerifyMiddleWare(gRouter.CreateIncomingWebhook)).Methods("POST")
+	router.HandleFunc("/conversations/{conversationId}/incoming-webhooks/{hookId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/conversations/{conversationId}/incoming-webhooks/{hookId}", a.MemberTokenVerifyMiddleWare(gRouter.DeleteIncomingWebhook)).Methods("DELETE")
+	router.HandleFunc("/hooks/{token}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/hooks/{token}", gRouter.PostIncomingWebhook).Methods("POST")
 	return router
 }
 
+// addImageURLs populates a conversation with the avatar or group picture URL of each participant that has one
+func (gr *conversationRouter) addImageURLs(c *models.Conversation) {
+	for _, id := range c.ParticipantsIds {
+		var url string
+		if c.Group {
+			if g, err := gr.gService.GroupFind(&models.Group{Id: id}); err == nil {
+				url = g.ImageURL
+			}
+		} else if u, err := gr.uService.UserFind(&models.User{Id: id}); err == nil {
+			url = u.ImageURL
+		}
+		if url == "" {
+			continue
+		}
+		if c.ImageURLs == nil {
+			c.ImageURLs = make(map[string]string)
+		}
+		c.ImageURLs[id] = url
+	}
+}
+
 // ConversationsShow returns all conversations to client
 func (gr *conversationRouter) ConversationsShow(w http.ResponseWriter, r *http.Request) {
-	authToken := r.Header.Get("Auth-Token")
-	tokenData, err := auth.DecodeJWT(authToken)
+	tokenData, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
@@ -48,6 +79,9 @@ func (gr *conversationRouter) ConversationsShow(w http.ResponseWriter, r *http.R
 		utilities.RespondWithError(w, http.StatusServiceUnavailable, utilities.JWTError{Message: err.Error()})
 		return
 	}
+	for _, c := range conversations {
+		gr.addImageURLs(c)
+	}
 	if err = json.NewEncoder(w).Encode(conversationsDTO{Conversations: conversations}); err != nil {
 		return
 	}
@@ -75,6 +109,7 @@ func (gr *conversationRouter) CreateConversation(w http.ResponseWriter, r *http.
 		utilities.RespondWithError(w, http.StatusServiceUnavailable, utilities.JWTError{Message: err.Error()})
 		return
 	} else {
+		gr.addImageURLs(g)
 		w = utilities.SetResponseHeaders(w, "", "")
 		w.WriteHeader(http.StatusCreated)
 		if err = json.NewEncoder(w).Encode(g); err != nil {
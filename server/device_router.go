@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/services"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+)
+
+type deviceRouter struct {
+	aService *services.TokenService
+	dService services.DeviceService
+}
+
+// NewDeviceRouter is a function that initializes a new deviceRouter struct
+func NewDeviceRouter(router *mux.Router, a *services.TokenService, d services.DeviceService) *mux.Router {
+	dRouter := deviceRouter{a, d}
+	router.HandleFunc("/devices", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/devices", a.MemberTokenVerifyMiddleWare(dRouter.DevicesShow)).Methods("GET")
+	router.HandleFunc("/devices", a.MemberTokenVerifyMiddleWare(dRouter.RegisterDevice)).Methods("POST")
+	router.HandleFunc("/devices/{deviceId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/devices/{deviceId}", a.MemberTokenVerifyMiddleWare(dRouter.DeleteDevice)).Methods("DELETE")
+	return router
+}
+
+// DevicesShow is the handler function that lists the devices the requesting user receives push notifications on
+func (dr *deviceRouter) DevicesShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	devices, err := dr.dService.DevicesFind(&models.Device{UserId: tokenData.UserId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusServiceUnavailable, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(devicesDTO{Devices: devices}); err != nil {
+		return
+	}
+}
+
+// RegisterDevice is the handler function that registers a device token of the requesting user for push notifications
+func (dr *deviceRouter) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	var dto deviceDTO
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &dto); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	d, err := dr.dService.DeviceRegister(&models.Device{
+		UserId:     tokenData.UserId,
+		Platform:   dto.Platform,
+		Token:      dto.Token,
+		PublicKey:  dto.PublicKey,
+		AuthSecret: dto.AuthSecret,
+	})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(d); err != nil {
+		return
+	}
+}
+
+// DeleteDevice is the handler function that stops push notifications to a device of the requesting user
+func (dr *deviceRouter) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	deviceId := mux.Vars(r)["deviceId"]
+	if !utilities.CheckObjectID(deviceId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing deviceId"})
+		return
+	}
+	d, err := dr.dService.DeviceDelete(&models.Device{Id: deviceId, UserId: tokenData.UserId})
+	if errors.Is(err, models.ErrDeviceNotFound) {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	} else if err != nil {
+		utilities.RespondWithError(w, http.StatusServiceUnavailable, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(d); err != nil {
+		return
+	}
+}
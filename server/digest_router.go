@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/services"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+)
+
+type digestRouter struct {
+	aService  *services.TokenService
+	dgService services.DigestService
+	digester  *services.Digester
+}
+
+// NewDigestRouter is a function that initializes a new digestRouter struct
+func NewDigestRouter(router *mux.Router, a *services.TokenService, dg services.DigestService, digester *services.Digester) *mux.Router {
+	dgRouter := digestRouter{a, dg, digester}
+	router.HandleFunc("/digest", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/digest", a.MemberTokenVerifyMiddleWare(dgRouter.DigestSettingsShow)).Methods("GET")
+	router.HandleFunc("/digest", a.MemberTokenVerifyMiddleWare(dgRouter.ModifyDigestSettings)).Methods("PUT")
+	router.HandleFunc("/digest/unsubscribe", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/digest/unsubscribe", dgRouter.Unsubscribe).Methods("POST")
+	return router
+}
+
+// DigestSettingsShow is the handler function that returns how often the requesting user receives email digests
+func (dr *digestRouter) DigestSettingsShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	settings, err := dr.dgService.DigestSettingsFind(&models.DigestSettings{UserId: tokenData.UserId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusServiceUnavailable, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(settings); err != nil {
+		return
+	}
+}
+
+// ModifyDigestSettings is the handler function that changes how often the requesting user receives email digests
+func (dr *digestRouter) ModifyDigestSettings(w http.ResponseWriter, r *http.Request) {
+	var dto digestSettingsDTO
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &dto); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	settings, err := dr.dgService.DigestSettingsUpdate(&models.DigestSettings{UserId: tokenData.UserId, Frequency: dto.Frequency})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(settings); err != nil {
+		return
+	}
+}
+
+// Unsubscribe is the handler function that turns off the email digests of a user with the unsubscribe link of one
+func (dr *digestRouter) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	var dto unsubscribeDTO
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &dto); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = dr.digester.Unsubscribe(dto.Token); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	return
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/services"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/gorilla/mux"
+	"io"
+	"log"
+	"net/http"
+)
+
+// findParticipantConversation loads a conversation and ensures the requesting user takes part in it, either as one of
+// its participants or as a member of its group, or is a root admin
+func (gr *conversationRouter) findParticipantConversation(w http.ResponseWriter, r *http.Request) (*models.Conversation, string, bool) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return nil, "", false
+	}
+	conversationId := mux.Vars(r)["conversationId"]
+	if !utilities.CheckObjectID(conversationId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing conversationId"})
+		return nil, "", false
+	}
+	c, err := gr.cService.ConversationFind(&models.Conversation{Id: conversationId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return nil, "", false
+	}
+	if !tokenData.RootAdmin {
+		member := c.CheckParticipants(tokenData.UserId)
+		if c.Group && len(c.ParticipantsIds) > 0 {
+			_, err = gr.gmService.GroupMembershipFind(&models.GroupMembership{UserId: tokenData.UserId, GroupId: c.ParticipantsIds[0]})
+			member = err == nil
+		}
+		if !member {
+			utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: "unauthorized"})
+			return nil, "", false
+		}
+	}
+	return c, tokenData.UserId, true
+}
+
+// UpdateDisappearingMessages sets how long the messages sent in a conversation from now on last, posting a system
+// message in the conversation when the setting changes
+func (gr *conversationRouter) UpdateDisappearingMessages(w http.ResponseWriter, r *http.Request) {
+	c, actorId, ok := gr.findParticipantConversation(w, r)
+	if !ok {
+		return
+	}
+	var dto disappearingMessagesDTO
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &dto); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if dto.MessageTTL != c.MessageTTL {
+		c.MessageTTL = dto.MessageTTL
+		if c, err = gr.cService.ConversationUpdate(c); err != nil {
+			utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+			return
+		}
+		actor, err := gr.uService.UserFind(&models.User{Id: actorId})
+		if err == nil {
+			_, err = gr.tService.MessageCreate(services.MessageLifetimeNotice(c, actor))
+		}
+		if err != nil {
+			// the setting is already changed, a notice that fails to post only leaves it out of the conversation
+			log.Println("Disappearing messages notice of conversation "+c.Id+" failed:", err)
+		}
+	}
+	gr.addImageURLs(c)
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(c); err != nil {
+		return
+	}
+}
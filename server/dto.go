@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ablancas22/messenger-backend/models"
+	"time"
 )
 
 /*
@@ -36,6 +37,53 @@ func (u *userSignIn) toUser() (*models.User, error) {
 	}, nil
 }
 
+// twoFactorChallengeDTO is returned by sign in instead of the user while a second factor is still needed
+type twoFactorChallengeDTO struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// oidcLoginDTO is returned when a sign in with an external provider is started
+type oidcLoginDTO struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	State            string    `json:"state"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// oidcCallback is used when completing a sign in with the code and state an external provider redirected back with
+type oidcCallback struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// roleChange is used when a root admin promotes or demotes a user
+type roleChange struct {
+	Role string `json:"role"`
+}
+
+// twoFactorCode is used when submitting a TOTP or recovery code, along with the challenge token when signing in
+type twoFactorCode struct {
+	ChallengeToken string `json:"challenge_token,omitempty"`
+	Code           string `json:"code"`
+}
+
+// forgotPassword is used when asking for a password reset email
+type forgotPassword struct {
+	Email string `json:"email"`
+}
+
+// resetPassword is used when redeeming a password reset token
+type resetPassword struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// verifyEmail is used when redeeming an email verification token
+type verifyEmail struct {
+	Token string `json:"token"`
+}
+
 // usersDTO is used when returning a slice of User
 type usersDTO struct {
 	Users []*models.User `json:"users"`
@@ -106,6 +154,11 @@ type conversationsDTO struct {
 	Conversations []*models.Conversation `json:"conversations"`
 }
 
+// disappearingMessagesDTO is used when setting how long the messages of a conversation last, in seconds
+type disappearingMessagesDTO struct {
+	MessageTTL int64 `json:"message_ttl"`
+}
+
 /*
 ================ Contacts DTOs ==================
 */
@@ -123,3 +176,117 @@ type contactsDTO struct {
 type groupMembershipsDTO struct {
 	GroupMemberships []*models.GroupMembership `json:"groupMemberships"`
 }
+
+/*
+================ Bots DTOs ==================
+*/
+
+// botDTO is used when creating or updating a bot, and returns a bot along with its token when one was just issued
+type botDTO struct {
+	Username   string         `json:"username,omitempty"`
+	WebhookURL string         `json:"webhook_url"`
+	Bot        *models.Bot    `json:"bot,omitempty"`
+	Token      *models.APIKey `json:"token,omitempty"`
+}
+
+// botsDTO is used when returning a slice of Bot
+type botsDTO struct {
+	Bots []*models.Bot `json:"bots"`
+}
+
+/*
+================ Webhooks DTOs ==================
+*/
+
+// webhookDTO is used when registering or updating a group webhook, an omitted disabled flag keeps the current state
+type webhookDTO struct {
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	Disabled *bool    `json:"disabled"`
+}
+
+// webhooksDTO is used when returning a slice of Webhook
+type webhooksDTO struct {
+	Webhooks []*models.Webhook `json:"webhooks"`
+}
+
+// webhookDeliveriesDTO is used when returning a slice of WebhookDelivery
+type webhookDeliveriesDTO struct {
+	Deliveries []*models.WebhookDelivery `json:"deliveries"`
+}
+
+/*
+================ Incoming Webhooks DTOs ==================
+*/
+
+// incomingWebhookDTO is used when adding an incoming webhook to a conversation
+type incomingWebhookDTO struct {
+	Name string `json:"name"`
+}
+
+// incomingWebhooksDTO is used when returning a slice of IncomingWebhook
+type incomingWebhooksDTO struct {
+	IncomingWebhooks []*models.IncomingWebhook `json:"incoming_webhooks"`
+}
+
+// incomingWebhookPayload is the JSON payload integrations post to an incoming webhook
+type incomingWebhookPayload struct {
+	Text string `json:"text"`
+}
+
+/*
+================ Commands DTOs ==================
+*/
+
+// commandDTO is used when registering a custom command for a group
+type commandDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	BotId       string `json:"bot_id"`
+	URL         string `json:"url"`
+}
+
+// commandsDTO is used when returning a slice of Command
+type commandsDTO struct {
+	Commands []*models.Command `json:"commands"`
+}
+
+/*
+================ Devices DTOs ==================
+*/
+
+// deviceDTO is used when registering a device for push notifications
+type deviceDTO struct {
+	Platform   string `json:"platform"`
+	Token      string `json:"token"`
+	PublicKey  string `json:"public_key"`
+	AuthSecret string `json:"auth_secret"`
+}
+
+// devicesDTO is used when returning a slice of Device
+type devicesDTO struct {
+	Devices []*models.Device `json:"devices"`
+}
+
+/*
+================ Digests DTOs ==================
+*/
+
+// digestSettingsDTO is used when changing how often the requesting user receives email digests
+type digestSettingsDTO struct {
+	Frequency string `json:"frequency"`
+}
+
+// unsubscribeDTO is used when redeeming the unsubscribe link of an email digest
+type unsubscribeDTO struct {
+	Token string `json:"token"`
+}
+
+/*
+================ Scheduled Messages DTOs ==================
+*/
+
+// scheduledMessagesDTO is used when returning a slice of ScheduledMessage
+type scheduledMessagesDTO struct {
+	ScheduledMessages []*models.ScheduledMessage `json:"scheduled_messages"`
+}
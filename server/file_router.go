@@ -0,0 +1,420 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/media"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/services"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/gorilla/mux"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+type fileRouter struct {
+	aService *services.TokenService
+	fService services.FileService
+	access   *services.FileAccess
+}
+
+// NewFileRouter is a function that initializes a new fileRouter struct
+func NewFileRouter(router *mux.Router, a *services.TokenService, f services.FileService, fa *services.FileAccess) *mux.Router {
+	fRouter := fileRouter{a, f, fa}
+	router.HandleFunc("/uploads", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/uploads", a.MemberTokenVerifyMiddleWare(fRouter.CreateUpload)).Methods("POST")
+	router.HandleFunc("/uploads/{uploadId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/uploads/{uploadId}", a.MemberTokenVerifyMiddleWare(fRouter.UploadShow)).Methods("GET")
+	router.HandleFunc("/uploads/{uploadId}", a.MemberTokenVerifyMiddleWare(fRouter.DeleteUpload)).Methods("DELETE")
+	router.HandleFunc("/uploads/{uploadId}/chunks/{chunkNumber}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/uploads/{uploadId}/chunks/{chunkNumber}", a.MemberTokenVerifyMiddleWare(fRouter.UploadChunk)).Methods("POST")
+	router.HandleFunc("/uploads/{uploadId}/complete", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/uploads/{uploadId}/complete", a.MemberTokenVerifyMiddleWare(fRouter.CompleteUpload)).Methods("POST")
+	router.HandleFunc("/files/{fileId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/files/{fileId}", a.MemberTokenVerifyMiddleWare(fRouter.FileShow)).Methods("GET")
+	router.HandleFunc("/files/{fileId}", a.MemberTokenVerifyMiddleWare(fRouter.DeleteFile)).Methods("DELETE")
+	router.HandleFunc("/files/{fileId}/download", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/files/{fileId}/download", a.MemberTokenVerifyMiddleWare(fRouter.DownloadFile)).Methods("GET")
+	router.HandleFunc("/users/me/storage", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/users/me/storage", a.MemberTokenVerifyMiddleWare(fRouter.MyStorageShow)).Methods("GET")
+	router.HandleFunc("/users/{userId}/storage", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/users/{userId}/storage", a.AdminTokenVerifyMiddleWare(fRouter.StorageShow)).Methods("GET")
+	router.HandleFunc("/users/{userId}/storage", a.AdminTokenVerifyMiddleWare(fRouter.UpdateStorage)).Methods("PUT")
+	router.HandleFunc("/groups/{groupId}/storage", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/groups/{groupId}/storage", a.AdminTokenVerifyMiddleWare(fRouter.StorageShow)).Methods("GET")
+	router.HandleFunc("/groups/{groupId}/storage", a.AdminTokenVerifyMiddleWare(fRouter.UpdateStorage)).Methods("PUT")
+	return router
+}
+
+// uploadErrorStatus maps an upload error to the HTTP status code returned to the client
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrFileTooLarge), errors.Is(err, models.ErrQuotaExceeded):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, models.ErrUploadExpired):
+		return http.StatusGone
+	}
+	return http.StatusBadRequest
+}
+
+// findOwnUpload loads an upload session and ensures it belongs to the requesting user
+func (fr *fileRouter) findOwnUpload(w http.ResponseWriter, r *http.Request) (*models.Upload, bool) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	uploadId := mux.Vars(r)["uploadId"]
+	if !utilities.CheckObjectID(uploadId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing uploadId"})
+		return nil, false
+	}
+	upload, err := fr.fService.UploadFind(&models.Upload{Id: uploadId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	if upload.OwnerType != "user" || upload.OwnerId != tokenData.UserId {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: "upload not found"})
+		return nil, false
+	}
+	return upload, true
+}
+
+// CreateUpload starts a new resumable upload session from a REST Request post body
+func (fr *fileRouter) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	var upload models.Upload
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &upload); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	upload.Id = utilities.GenerateObjectID()
+	upload.OwnerId = tokenData.UserId
+	upload.OwnerType = "user"
+	u, err := fr.fService.UploadCreate(&upload)
+	if err != nil {
+		utilities.RespondWithError(w, uploadErrorStatus(err), utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(u); err != nil {
+		return
+	}
+}
+
+// UploadShow returns an upload session along with the offset received so far
+func (fr *fileRouter) UploadShow(w http.ResponseWriter, r *http.Request) {
+	upload, ok := fr.findOwnUpload(w, r)
+	if !ok {
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(upload); err != nil {
+		return
+	}
+}
+
+// UploadChunk stores a numbered chunk of an upload session from the raw request body
+func (fr *fileRouter) UploadChunk(w http.ResponseWriter, r *http.Request) {
+	upload, ok := fr.findOwnUpload(w, r)
+	if !ok {
+		return
+	}
+	chunkNumber, err := strconv.Atoi(mux.Vars(r)["chunkNumber"])
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "invalid chunkNumber"})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, upload.ChunkSize+1))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if int64(len(body)) > upload.ChunkSize {
+		utilities.RespondWithError(w, http.StatusRequestEntityTooLarge, utilities.JWTError{Message: "chunk exceeds the upload chunk_size"})
+		return
+	}
+	chunk := models.UploadChunk{Number: chunkNumber, Checksum: r.Header.Get("Upload-Checksum")}
+	u, err := fr.fService.UploadChunkWrite(upload, &chunk, body)
+	if err != nil {
+		utilities.RespondWithError(w, uploadErrorStatus(err), utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.WriteHeader(http.StatusAccepted)
+	if err = json.NewEncoder(w).Encode(u); err != nil {
+		return
+	}
+}
+
+// CompleteUpload verifies and assembles a fully received upload session into a File
+func (fr *fileRouter) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	upload, ok := fr.findOwnUpload(w, r)
+	if !ok {
+		return
+	}
+	f, err := fr.fService.UploadComplete(upload)
+	if err != nil {
+		utilities.RespondWithError(w, uploadErrorStatus(err), utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(f); err != nil {
+		return
+	}
+}
+
+// DeleteUpload aborts an upload session
+func (fr *fileRouter) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	upload, ok := fr.findOwnUpload(w, r)
+	if !ok {
+		return
+	}
+	u, err := fr.fService.UploadDelete(upload)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(u); err != nil {
+		return
+	}
+}
+
+// findReadableFile loads the file named in the request path, responding with not found unless the requesting user
+// owns it, is a root admin or is a participant of a conversation it is attached to
+func (fr *fileRouter) findReadableFile(w http.ResponseWriter, r *http.Request) (*models.File, *auth.TokenData, bool) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return nil, nil, false
+	}
+	fileId := mux.Vars(r)["fileId"]
+	if !utilities.CheckObjectID(fileId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing fileId"})
+		return nil, nil, false
+	}
+	file, err := fr.fService.FileFind(&models.File{Id: fileId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return nil, nil, false
+	}
+	if !tokenData.RootAdmin && !fr.access.CanRead(file, tokenData.UserId) {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: "file not found"})
+		return nil, nil, false
+	}
+	return file, tokenData, true
+}
+
+// FileShow shows a specific file's metadata
+func (fr *fileRouter) FileShow(w http.ResponseWriter, r *http.Request) {
+	file, _, ok := fr.findReadableFile(w, r)
+	if !ok {
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(file); err != nil {
+		return
+	}
+}
+
+// DownloadFile streams a file's contents, or one of its image thumbnails, to the client
+func (fr *fileRouter) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	file, tokenData, ok := fr.findReadableFile(w, r)
+	if !ok {
+		return
+	}
+	serveFile(w, fr.fService, file, tokenData.UserId, r.URL.Query().Get("thumbnail"), "attachment")
+}
+
+// serveFile streams a file to the client, or the thumbnail best matching size when one is requested.
+// Files that have not passed scanning are only served to their owner, and infected files to no one.
+func serveFile(w http.ResponseWriter, fs services.FileService, file *models.File, userId string, size string, disposition string) {
+	if !file.Downloadable(userId) {
+		utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: models.ErrFileQuarantined.Error()})
+		return
+	}
+	var thumbnail *models.FileThumbnail
+	if size != "" {
+		s, err := strconv.Atoi(size)
+		if err != nil || s <= 0 {
+			utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "invalid thumbnail size"})
+			return
+		}
+		thumbnail = file.Thumbnail(s)
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	if thumbnail != nil {
+		w.Header().Set("Content-Type", thumbnail.FileType)
+		w.Header().Set("Content-Disposition", "inline; filename="+strconv.Quote(file.Name))
+		w.WriteHeader(http.StatusOK)
+		_ = fs.ThumbnailDownload(file, thumbnail, w)
+		return
+	}
+	w.Header().Set("Content-Type", file.FileType)
+	w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	w.Header().Set("Content-Disposition", disposition+"; filename="+strconv.Quote(file.Name))
+	w.WriteHeader(http.StatusOK)
+	_, _ = fs.FileDownload(file, w)
+}
+
+// readImage reads an image from a raw request body, responding with an error when it is not an acceptable image. The
+// type of the image is detected from its contents, the declared Content-Type only has to be an image type.
+func readImage(w http.ResponseWriter, r *http.Request, fs services.FileService) ([]byte, string, bool) {
+	fileType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !media.IsImage(fileType) {
+		utilities.RespondWithError(w, http.StatusUnsupportedMediaType, utilities.JWTError{Message: "image must be a jpeg, png or gif"})
+		return nil, "", false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, fs.MaxFileSize()+1))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return nil, "", false
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return nil, "", false
+	}
+	if int64(len(body)) > fs.MaxFileSize() {
+		utilities.RespondWithError(w, http.StatusRequestEntityTooLarge, utilities.JWTError{Message: models.ErrFileTooLarge.Error()})
+		return nil, "", false
+	}
+	if fileType = http.DetectContentType(body); !media.IsImage(fileType) {
+		utilities.RespondWithError(w, http.StatusUnsupportedMediaType, utilities.JWTError{Message: "image must be a jpeg, png or gif"})
+		return nil, "", false
+	}
+	return body, fileType, true
+}
+
+// DeleteFile deletes a file owned by the requesting user
+func (fr *fileRouter) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	fileId := mux.Vars(r)["fileId"]
+	if !utilities.CheckObjectID(fileId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing fileId"})
+		return
+	}
+	file, err := fr.fService.FileFind(&models.File{Id: fileId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if file.OwnerId != tokenData.UserId && !tokenData.RootAdmin {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: "unauthorized"})
+		return
+	}
+	file, err = fr.fService.FileDelete(file)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(file); err != nil {
+		return
+	}
+}
+
+// storageOwner builds a Storage filter for the user or group named in the request path
+func storageOwner(r *http.Request) *models.Storage {
+	vars := mux.Vars(r)
+	if groupId, ok := vars["groupId"]; ok {
+		return &models.Storage{OwnerId: groupId, OwnerType: "group"}
+	}
+	return &models.Storage{OwnerId: vars["userId"], OwnerType: "user"}
+}
+
+// MyStorageShow returns the storage used by the requesting user along with their quota
+func (fr *fileRouter) MyStorageShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	storage, err := fr.fService.StorageFind(&models.Storage{OwnerId: tokenData.UserId, OwnerType: "user"})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(storage); err != nil {
+		return
+	}
+}
+
+// StorageShow returns the storage used by a user or group along with its quota
+func (fr *fileRouter) StorageShow(w http.ResponseWriter, r *http.Request) {
+	storage, err := fr.fService.StorageFind(storageOwner(r))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(storage); err != nil {
+		return
+	}
+}
+
+// UpdateStorage adjusts the storage quota of a user or group from a REST Request put body
+func (fr *fileRouter) UpdateStorage(w http.ResponseWriter, r *http.Request) {
+	var storage models.Storage
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &storage); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	owner := storageOwner(r)
+	owner.Quota = storage.Quota
+	s, err := fr.fService.StorageUpdate(owner)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(s); err != nil {
+		return
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+)
+
+// CommandsShow lists the custom commands of a group, without their secrets
+func (gr *groupRouter) CommandsShow(w http.ResponseWriter, r *http.Request) {
+	group, ok := gr.findAdminGroup(w, r)
+	if !ok {
+		return
+	}
+	commands, err := gr.cmdDispatcher.Commands(group.Id)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	for _, cmd := range commands {
+		cmd.Secret = ""
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(commandsDTO{Commands: commands}); err != nil {
+		return
+	}
+}
+
+// CreateCommand registers a custom command for a group, the signing secret of a command forwarded to a URL is only
+// returned here
+func (gr *groupRouter) CreateCommand(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	group, ok := gr.findAdminGroup(w, r)
+	if !ok {
+		return
+	}
+	var dto commandDTO
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &dto); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	cmd, err := gr.cmdDispatcher.CreateCommand(&models.Command{
+		GroupId:     group.Id,
+		Name:        dto.Name,
+		Description: dto.Description,
+		BotId:       dto.BotId,
+		URL:         dto.URL,
+		CreatedBy:   tokenData.UserId,
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, models.ErrCommandExists):
+			status = http.StatusConflict
+		case errors.Is(err, models.ErrCommandBot):
+			status = http.StatusForbidden
+		}
+		utilities.RespondWithError(w, status, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(cmd); err != nil {
+		return
+	}
+}
+
+// DeleteCommand removes a custom command from a group
+func (gr *groupRouter) DeleteCommand(w http.ResponseWriter, r *http.Request) {
+	group, ok := gr.findAdminGroup(w, r)
+	if !ok {
+		return
+	}
+	cmd, err := gr.cmdDispatcher.DeleteCommand(group.Id, mux.Vars(r)["commandId"])
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	cmd.Secret = ""
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(cmd); err != nil {
+		return
+	}
+}
@@ -13,33 +13,52 @@ import (
 )
 
 type groupRouter struct {
-	aService  *services.TokenService
-	gService  services.GroupService
-	uService  services.UserService
-	gmService services.GroupMembershipService
+	aService      *services.TokenService
+	gService      services.GroupService
+	uService      services.UserService
+	gmService     services.GroupMembershipService
+	fService      services.FileService
+	wService      services.WebhookService
+	cmdDispatcher *services.CommandDispatcher
 }
 
 // NewGroupRouter is a function that initializes a new groupRouter struct
-func NewGroupRouter(router *mux.Router, a *services.TokenService, g services.GroupService, u services.UserService, gm services.GroupMembershipService) *mux.Router {
-	gRouter := groupRouter{a, g, u, gm}
+func NewGroupRouter(router *mux.Router, a *services.TokenService, g services.GroupService, u services.UserService, gm services.GroupMembershipService, f services.FileService, wh services.WebhookService, cd *services.CommandDispatcher) *mux.Router {
+	gRouter := groupRouter{a, g, u, gm, f, wh, cd}
 	router.HandleFunc("/groups", utilities.HandleOptionsRequest).Methods("OPTIONS")
-	router.HandleFunc("/groups", a.AdminTokenVerifyMiddleWare(gRouter.GroupsShow)).Methods("GET")
-	router.HandleFunc("/groups", a.AdminTokenVerifyMiddleWare(gRouter.CreateGroup)).Methods("POST")
+	router.HandleFunc("/groups", a.AdminTokenVerifyMiddleWare(gRouter.GroupsShow, models.ScopeGroupsAdmin)).Methods("GET")
+	router.HandleFunc("/groups", a.AdminTokenVerifyMiddleWare(gRouter.CreateGroup, models.ScopeGroupsAdmin)).Methods("POST")
 	router.HandleFunc("/groups/{groupId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
-	router.HandleFunc("/groups/{groupId}", a.AdminTokenVerifyMiddleWare(gRouter.GroupShow)).Methods("GET")
-	router.HandleFunc("/groups", a.AdminTokenVerifyMiddleWare(gRouter.CreateGroup)).Methods("POST")
-	router.HandleFunc("/groups/{groupId}", a.AdminTokenVerifyMiddleWare(gRouter.DeleteGroup)).Methods("DELETE")
-	router.HandleFunc("/groups/{groupId}", a.AdminTokenVerifyMiddleWare(gRouter.ModifyGroup)).Methods("PATCH")
-	router.HandleFunc("/groups/{groupId}/users", a.MemberTokenVerifyMiddleWare(gRouter.GetGroupUsers)).Methods("GET")
-	router.HandleFunc("/groups/{groupId}/users/{userId}", a.MemberTokenVerifyMiddleWare(gRouter.DeleteGroupUser)).Methods("DELETE")
-	router.HandleFunc("/groups/{groupId}/users/{userId}", a.MemberTokenVerifyMiddleWare(gRouter.AddGroupUser)).Methods("POST")
+	router.HandleFunc("/groups/{groupId}", a.AdminTokenVerifyMiddleWare(gRouter.GroupShow, models.ScopeGroupsAdmin)).Methods("GET")
+	router.HandleFunc("/groups", a.AdminTokenVerifyMiddleWare(gRouter.CreateGroup, models.ScopeGroupsAdmin)).Methods("POST")
+	router.HandleFunc("/groups/{groupId}", a.AdminTokenVerifyMiddleWare(gRouter.DeleteGroup, models.ScopeGroupsAdmin)).Methods("DELETE")
+	router.HandleFunc("/groups/{groupId}", a.AdminTokenVerifyMiddleWare(gRouter.ModifyGroup, models.ScopeGroupsAdmin)).Methods("PATCH")
+	router.HandleFunc("/groups/{groupId}/users", a.MemberTokenVerifyMiddleWare(gRouter.GetGroupUsers, models.ScopeGroupsAdmin)).Methods("GET")
+	router.HandleFunc("/groups/{groupId}/users/{userId}", a.MemberTokenVerifyMiddleWare(gRouter.DeleteGroupUser, models.ScopeGroupsAdmin)).Methods("DELETE")
+	router.HandleFunc("/groups/{groupId}/users/{userId}", a.MemberTokenVerifyMiddleWare(gRouter.AddGroupUser, models.ScopeGroupsAdmin)).Methods("POST")
+	router.HandleFunc("/groups/{groupId}/image", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/groups/{groupId}/image", a.MemberTokenVerifyMiddleWare(gRouter.GroupImageShow)).Methods("GET")
+	router.HandleFunc("/groups/{groupId}/image", a.MemberTokenVerifyMiddleWare(gRouter.UpdateGroupImage)).Methods("PUT")
+	router.HandleFunc("/groups/{groupId}/image", a.MemberTokenVerifyMiddleWare(gRouter.DeleteGroupImage)).Methods("DELETE")
+	router.HandleFunc("/groups/{groupId}/webhooks", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/groups/{groupId}/webhooks", a.MemberTokenVerifyMiddleWare(gRouter.WebhooksShow)).Methods("GET")
+	router.HandleFunc("/groups/{groupId}/webhooks", a.MemberTokenVerifyMiddleWare(gRouter.CreateWebhook)).Methods("POST")
+	router.HandleFunc("/groups/{groupId}/webhooks/{webhookId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/groups/{groupId}/webhooks/{webhookId}", a.MemberTokenVerifyMiddleWare(gRouter.ModifyWebhook)).Methods("PATCH")
+	router.HandleFunc("/groups/{groupId}/webhooks/{webhookId}", a.MemberTokenVerifyMiddleWare(gRouter.DeleteWebhook)).Methods("DELETE")
+	router.HandleFunc("/groups/{groupId}/webhooks/{webhookId}/deliveries", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/groups/{groupId}/webhooks/{webhookId}/deliveries", a.MemberTokenVerifyMiddleWare(gRouter.WebhookDeliveriesShow)).Methods("GET")
+	router.HandleFunc("/groups/{groupId}/commands", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/groups/{groupId}/commands", a.MemberTokenVerifyMiddleWare(gRouter.CommandsShow)).Methods("GET")
+	router.HandleFunc("/groups/{groupId}/commands", a.MemberTokenVerifyMiddleWare(gRouter.CreateCommand)).Methods("POST")
+	router.HandleFunc("/groups/{groupId}/commands/{commandId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/groups/{groupId}/commands/{commandId}", a.MemberTokenVerifyMiddleWare(gRouter.DeleteCommand)).Methods("DELETE")
 	return router
 }
 
 func (gr *groupRouter) AddGroupUser(w http.ResponseWriter, r *http.Request) {
 	var groupMember *models.GroupMembership
-	authToken := r.Header.Get("Auth-Token")
-	tokenData, err := auth.DecodeJWT(authToken)
+	tokenData, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
@@ -87,8 +106,7 @@ func (gr *groupRouter) AddGroupUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (gr *groupRouter) DeleteGroupUser(w http.ResponseWriter, r *http.Request) {
-	authToken := r.Header.Get("Auth-Token")
-	tokenData, err := auth.DecodeJWT(authToken)
+	tokenData, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
@@ -136,8 +154,7 @@ func (gr *groupRouter) GroupsShow(w http.ResponseWriter, r *http.Request) {
 // CreateGroup from a REST Request post body
 func (gr *groupRouter) CreateGroup(w http.ResponseWriter, r *http.Request) {
 	var group models.Group
-	authToken := r.Header.Get("Auth-Token")
-	tokenData, err := auth.DecodeJWT(authToken)
+	tokenData, err := auth.LoadTokenFromRequest(r)
 	fmt.Println("\n\ntoken", tokenData)
 
 	if err != nil {
@@ -315,3 +332,113 @@ func (gr *groupRouter) getGroupUsers(groupId string) (*groupUsersDTO, error) {
 	}
 	return &dto, nil
 }
+
+// findAdminGroup loads a group and ensures the requesting user is one of its admins or a root admin
+func (gr *groupRouter) findAdminGroup(w http.ResponseWriter, r *http.Request) (*models.Group, bool) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	groupId := mux.Vars(r)["groupId"]
+	if !utilities.CheckObjectID(groupId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing groupId"})
+		return nil, false
+	}
+	if !tokenData.RootAdmin {
+		gm, err := gr.gmService.GroupMembershipFind(&models.GroupMembership{UserId: tokenData.UserId, GroupId: groupId})
+		if err != nil || !gm.Admin {
+			utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: "unauthorized"})
+			return nil, false
+		}
+	}
+	group, err := gr.gService.GroupFind(&models.Group{Id: groupId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	return group, true
+}
+
+// GroupImageShow serves a group's picture, resized when a size query parameter is given
+func (gr *groupRouter) GroupImageShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	groupId := mux.Vars(r)["groupId"]
+	if !utilities.CheckObjectID(groupId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing groupId"})
+		return
+	}
+	group, err := gr.gService.GroupFind(&models.Group{Id: groupId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if group.ImageId == "" {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: "group has no image"})
+		return
+	}
+	file, err := gr.fService.FileFind(&models.File{Id: group.ImageId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	serveFile(w, gr.fService, file, tokenData.UserId, r.URL.Query().Get("size"), "inline")
+}
+
+// UpdateGroupImage uploads or replaces a group's picture from a raw image request body
+func (gr *groupRouter) UpdateGroupImage(w http.ResponseWriter, r *http.Request) {
+	group, ok := gr.findAdminGroup(w, r)
+	if !ok {
+		return
+	}
+	data, fileType, ok := readImage(w, r, gr.fService)
+	if !ok {
+		return
+	}
+	file, err := gr.fService.FileCreate(&models.File{OwnerId: group.Id, OwnerType: "group", BucketType: "avatar", Name: "group_image", FileType: fileType}, data)
+	if err != nil {
+		utilities.RespondWithError(w, uploadErrorStatus(err), utilities.JWTError{Message: err.Error()})
+		return
+	}
+	g, err := gr.gService.GroupImageSet(&models.Group{Id: group.Id, ImageId: file.Id})
+	if err != nil {
+		_, _ = gr.fService.FileDelete(file)
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if group.ImageId != "" {
+		_, _ = gr.fService.FileDelete(&models.File{Id: group.ImageId})
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(g); err != nil {
+		return
+	}
+}
+
+// DeleteGroupImage removes a group's picture
+func (gr *groupRouter) DeleteGroupImage(w http.ResponseWriter, r *http.Request) {
+	group, ok := gr.findAdminGroup(w, r)
+	if !ok {
+		return
+	}
+	if group.ImageId == "" {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: "group has no image"})
+		return
+	}
+	g, err := gr.gService.GroupImageSet(&models.Group{Id: group.Id})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	_, _ = gr.fService.FileDelete(&models.File{Id: group.ImageId})
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(g); err != nil {
+		return
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+)
+
+// readWebhookDTO reads the webhookDTO of a request body
+func readWebhookDTO(w http.ResponseWriter, r *http.Request) (*webhookDTO, bool) {
+	var dto webhookDTO
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	if err = json.Unmarshal(body, &dto); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return nil, false
+	}
+	return &dto, true
+}
+
+// findGroupWebhook loads a webhook of a group the requesting user administers
+func (gr *groupRouter) findGroupWebhook(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	group, ok := gr.findAdminGroup(w, r)
+	if !ok {
+		return nil, false
+	}
+	hook, err := gr.wService.WebhookFind(&models.Webhook{Id: mux.Vars(r)["webhookId"]})
+	if err != nil || hook.GroupId != group.Id {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: models.ErrWebhookNotFound.Error()})
+		return nil, false
+	}
+	return hook, true
+}
+
+// WebhooksShow lists the webhooks of a group, without their secrets
+func (gr *groupRouter) WebhooksShow(w http.ResponseWriter, r *http.Request) {
+	group, ok := gr.findAdminGroup(w, r)
+	if !ok {
+		return
+	}
+	hooks, err := gr.wService.WebhooksFind(&models.Webhook{GroupId: group.Id})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(webhooksDTO{Webhooks: hooks}); err != nil {
+		return
+	}
+}
+
+// CreateWebhook registers a webhook for a group, its signing secret is only returned here
+func (gr *groupRouter) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	group, ok := gr.findAdminGroup(w, r)
+	if !ok {
+		return
+	}
+	dto, ok := readWebhookDTO(w, r)
+	if !ok {
+		return
+	}
+	hook, err := gr.wService.WebhookCreate(&models.Webhook{GroupId: group.Id, URL: dto.URL, Events: dto.Events, CreatedBy: tokenData.UserId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(hook); err != nil {
+		return
+	}
+}
+
+// ModifyWebhook changes the url, events or disabled state of a webhook, enabling a webhook clears its failures
+func (gr *groupRouter) ModifyWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := gr.findGroupWebhook(w, r)
+	if !ok {
+		return
+	}
+	dto, ok := readWebhookDTO(w, r)
+	if !ok {
+		return
+	}
+	hook.URL = dto.URL
+	if dto.Events != nil {
+		hook.Events = dto.Events
+	}
+	if dto.Disabled != nil {
+		hook.Disabled = *dto.Disabled
+	}
+	hook, err := gr.wService.WebhookUpdate(hook)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, models.ErrWebhookNotFound) {
+			status = http.StatusNotFound
+		}
+		utilities.RespondWithError(w, status, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	hook.Secret = ""
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusAccepted)
+	if err = json.NewEncoder(w).Encode(hook); err != nil {
+		return
+	}
+}
+
+// DeleteWebhook removes a webhook from a group
+func (gr *groupRouter) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := gr.findGroupWebhook(w, r)
+	if !ok {
+		return
+	}
+	hook, err := gr.wService.WebhookDelete(hook)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	hook.Secret = ""
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(hook); err != nil {
+		return
+	}
+}
+
+// WebhookDeliveriesShow lists the queued deliveries of a webhook along with their attempts
+func (gr *groupRouter) WebhookDeliveriesShow(w http.ResponseWriter, r *http.Request) {
+	hook, ok := gr.findGroupWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveries, err := gr.wService.WebhookDeliveriesFind(&models.WebhookDelivery{WebhookId: hook.Id, Status: r.URL.Query().Get("status")})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(webhookDeliveriesDTO{Deliveries: deliveries}); err != nil {
+		return
+	}
+}
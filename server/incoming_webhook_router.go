@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// findAdminConversation loads a group conversation and ensures the requesting user is one of the group's admins or a root admin
+func (gr *conversationRouter) findAdminConversation(w http.ResponseWriter, r *http.Request) (*models.Conversation, string, bool) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return nil, "", false
+	}
+	conversationId := mux.Vars(r)["conversationId"]
+	if !utilities.CheckObjectID(conversationId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing conversationId"})
+		return nil, "", false
+	}
+	c, err := gr.cService.ConversationFind(&models.Conversation{Id: conversationId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return nil, "", false
+	}
+	if !c.Group || len(c.ParticipantsIds) == 0 {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: models.ErrIncomingWebhookConversation.Error()})
+		return nil, "", false
+	}
+	if !tokenData.RootAdmin {
+		gm, err := gr.gmService.GroupMembershipFind(&models.GroupMembership{UserId: tokenData.UserId, GroupId: c.ParticipantsIds[0]})
+		if err != nil || !gm.Admin {
+			utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: "unauthorized"})
+			return nil, "", false
+		}
+	}
+	return c, tokenData.UserId, true
+}
+
+// IncomingWebhooksShow lists the incoming webhooks of a group conversation
+func (gr *conversationRouter) IncomingWebhooksShow(w http.ResponseWriter, r *http.Request) {
+	c, _, ok := gr.findAdminConversation(w, r)
+	if !ok {
+		return
+	}
+	hooks, err := gr.aService.IncomingWebhooks(c.Id)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(incomingWebhooksDTO{IncomingWebhooks: hooks}); err != nil {
+		return
+	}
+}
+
+// CreateIncomingWebhook adds an incoming webhook to a group conversation, its token is only returned here
+func (gr *conversationRouter) CreateIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	c, actorId, ok := gr.findAdminConversation(w, r)
+	if !ok {
+		return
+	}
+	var dto incomingWebhookDTO
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &dto); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	hook, err := gr.aService.CreateIncomingWebhook(actorId, c, dto.Name)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(hook); err != nil {
+		return
+	}
+}
+
+// DeleteIncomingWebhook revokes an incoming webhook of a group conversation
+func (gr *conversationRouter) DeleteIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	c, _, ok := gr.findAdminConversation(w, r)
+	if !ok {
+		return
+	}
+	hook, err := gr.aService.RevokeIncomingWebhook(c.Id, mux.Vars(r)["hookId"])
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(hook); err != nil {
+		return
+	}
+}
+
+// PostIncomingWebhook posts the text of a payload into the conversation of an incoming webhook, the token in the
+// path authenticates the request
+func (gr *conversationRouter) PostIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, retryAfter, err := gr.aService.VerifyIncomingWebhook(mux.Vars(r)["token"])
+	if errors.Is(err, models.ErrIncomingWebhookRateLimited) {
+		seconds := int((retryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		w.Header().Add("Access-Control-Expose-Headers", "Retry-After")
+		utilities.RespondWithError(w, http.StatusTooManyRequests, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: models.ErrIncomingWebhookInvalid.Error()})
+		return
+	}
+	var payload incomingWebhookPayload
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &payload); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if payload.Text == "" {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing text"})
+		return
+	}
+	message, err := gr.tService.MessageCreate(&models.Message{
+		Id:             utilities.GenerateObjectID(),
+		ConversationID: hook.ConversationId,
+		SenderID:       hook.UserId,
+		ReceiverID:     hook.GroupId,
+		Group:          true,
+		Content:        payload.Text,
+	})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusServiceUnavailable, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(message); err != nil {
+		return
+	}
+}
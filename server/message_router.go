@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/ablancas22/messenger-backend/auth"
 	"github.com/ablancas22/messenger-backend/models"
 	"github.com/ablancas22/messenger-backend/services"
@@ -9,33 +10,41 @@ import (
 	"github.com/gorilla/mux"
 	"io"
 	"net/http"
+	"strings"
 )
 
 type messageRouter struct {
-	aService *services.TokenService
-	tService services.MessageService
+	aService      *services.TokenService
+	tService      services.MessageService
+	cmdDispatcher *services.CommandDispatcher
+	mnService     services.MentionService
 }
 
 // NewMessageRouter is a function that initializes a new groupRouter struct
-func NewMessageRouter(router *mux.Router, a *services.TokenService, t services.MessageService) *mux.Router {
-	gRouter := messageRouter{a, t}
+func NewMessageRouter(router *mux.Router, a *services.TokenService, t services.MessageService, cd *services.CommandDispatcher, mn services.MentionService) *mux.Router {
+	gRouter := messageRouter{a, t, cd, mn}
 	router.HandleFunc("/messages", utilities.HandleOptionsRequest).Methods("OPTIONS")
-	router.HandleFunc("/messages", a.MemberTokenVerifyMiddleWare(gRouter.MessagesShow)).Methods("GET")
-	router.HandleFunc("/messages", a.MemberTokenVerifyMiddleWare(gRouter.CreateMessage)).Methods("POST")
+	router.HandleFunc("/messages", a.MemberTokenVerifyMiddleWare(gRouter.MessagesShow, models.ScopeMessagesRead)).Methods("GET")
+	router.HandleFunc("/messages", a.MemberTokenVerifyMiddleWare(a.VerifiedEmailMiddleWare(gRouter.CreateMessage), models.ScopeMessagesWrite)).Methods("POST")
 	router.HandleFunc("/messages/{messageId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
-	router.HandleFunc("/messages/{messageId}", a.MemberTokenVerifyMiddleWare(gRouter.MessageShow)).Methods("GET")
-	router.HandleFunc("/messages/{messageId}", a.MemberTokenVerifyMiddleWare(gRouter.DeleteMessage)).Methods("DELETE")
+	router.HandleFunc("/messages/{messageId}", a.MemberTokenVerifyMiddleWare(gRouter.MessageShow, models.ScopeMessagesRead)).Methods("GET")
+	router.HandleFunc("/messages/{messageId}", a.MemberTokenVerifyMiddleWare(gRouter.ModifyMessage, models.ScopeMessagesWrite)).Methods("PATCH")
+	router.HandleFunc("/messages/{messageId}", a.MemberTokenVerifyMiddleWare(gRouter.DeleteMessage, models.ScopeMessagesWrite)).Methods("DELETE")
 	return router
 }
 
 // MessagesShow returns all messages to client
 func (gr *messageRouter) MessagesShow(w http.ResponseWriter, r *http.Request) {
-	authToken := r.Header.Get("Auth-Token")
-	tokenData, err := auth.DecodeJWT(authToken)
+	tokenData, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
 	}
+	// ?mentions=me only lists the messages that mention the requesting user
+	if r.URL.Query().Get("mentions") == "me" {
+		gr.mentionsShow(w, tokenData.UserId)
+		return
+	}
 	var filter models.Message
 	filter.SenderID = tokenData.UserId
 	filter.ReceiverID = tokenData.UserId
@@ -51,9 +60,36 @@ func (gr *messageRouter) MessagesShow(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// mentionsShow returns the messages that mention a user to client, newest first
+func (gr *messageRouter) mentionsShow(w http.ResponseWriter, userId string) {
+	mentions, err := gr.mnService.MentionsFind(&models.Mention{UserId: userId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusServiceUnavailable, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	messages := []*models.Message{}
+	for _, mention := range mentions {
+		message, err := gr.tService.MessageFind(&models.Message{Id: mention.MessageId})
+		if err != nil {
+			continue // the message has been deleted since it mentioned the user
+		}
+		messages = append(messages, message)
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(messagesDTO{Messages: messages}); err != nil {
+		return
+	}
+}
+
 // CreateTask from a REST Request post body
 func (gr *messageRouter) CreateMessage(w http.ResponseWriter, r *http.Request) {
 	var message models.Message
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
 	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
@@ -68,8 +104,20 @@ func (gr *messageRouter) CreateMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	message.Id = utilities.GenerateObjectID()
+	message.SenderID = tokenData.UserId
+	if services.IsCommand(message.Content) {
+		gr.runCommand(w, &message)
+		return
+	}
+	// a leading "//" posts a message that starts with a slash
+	if strings.HasPrefix(message.Content, "//") {
+		message.Content = message.Content[1:]
+	}
 	g, err := gr.tService.MessageCreate(&message)
-	if err != nil {
+	if errors.Is(err, models.ErrMentionAllForbidden) {
+		utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: err.Error()})
+		return
+	} else if err != nil {
 		utilities.RespondWithError(w, http.StatusServiceUnavailable, utilities.JWTError{Message: err.Error()})
 		return
 	} else {
@@ -102,6 +150,53 @@ func (gr *messageRouter) MessageShow(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// ModifyMessage edits the content of a message, only its sender can edit a message
+func (gr *messageRouter) ModifyMessage(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	messageId := mux.Vars(r)["messageId"]
+	if !utilities.CheckObjectID(messageId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing messageId"})
+		return
+	}
+	var edit models.Message
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &edit); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	message, err := gr.tService.MessageFind(&models.Message{Id: messageId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if message.SenderID != tokenData.UserId {
+		utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: "only the sender can edit a message"})
+		return
+	}
+	message, err = gr.tService.MessageUpdate(&models.Message{Id: messageId, Content: edit.Content})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusAccepted)
+	if err = json.NewEncoder(w).Encode(message); err != nil {
+		return
+	}
+}
+
 // DeleteMessage deletes a message
 func (gr *messageRouter) DeleteMessage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -122,3 +217,25 @@ func (gr *messageRouter) DeleteMessage(w http.ResponseWriter, r *http.Request) {
 	}
 	return
 }
+
+// runCommand runs the slash command a message starts with instead of posting it, the response is only returned to the
+// User who ran it
+func (gr *messageRouter) runCommand(w http.ResponseWriter, message *models.Message) {
+	response, err := gr.cmdDispatcher.Dispatch(message)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, models.ErrCommandForbidden):
+			status = http.StatusForbidden
+		case errors.Is(err, models.ErrCommandFailed):
+			status = http.StatusBadGateway
+		}
+		utilities.RespondWithError(w, status, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		return
+	}
+}
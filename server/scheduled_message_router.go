@@ -0,0 +1,198 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/services"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type scheduledMessageRouter struct {
+	aService  *services.TokenService
+	smService services.ScheduledMessageService
+}
+
+// NewScheduledMessageRouter is a function that initializes a new scheduledMessageRouter struct
+func NewScheduledMessageRouter(router *mux.Router, a *services.TokenService, sm services.ScheduledMessageService) *mux.Router {
+	smRouter := scheduledMessageRouter{a, sm}
+	router.HandleFunc("/scheduled-messages", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/scheduled-messages", a.MemberTokenVerifyMiddleWare(smRouter.ScheduledMessagesShow, models.ScopeMessagesRead)).Methods("GET")
+	router.HandleFunc("/scheduled-messages", a.MemberTokenVerifyMiddleWare(a.VerifiedEmailMiddleWare(smRouter.CreateScheduledMessage), models.ScopeMessagesWrite)).Methods("POST")
+	router.HandleFunc("/scheduled-messages/{scheduledMessageId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/scheduled-messages/{scheduledMessageId}", a.MemberTokenVerifyMiddleWare(smRouter.ScheduledMessageShow, models.ScopeMessagesRead)).Methods("GET")
+	router.HandleFunc("/scheduled-messages/{scheduledMessageId}", a.MemberTokenVerifyMiddleWare(smRouter.ModifyScheduledMessage, models.ScopeMessagesWrite)).Methods("PATCH")
+	router.HandleFunc("/scheduled-messages/{scheduledMessageId}", a.MemberTokenVerifyMiddleWare(smRouter.DeleteScheduledMessage, models.ScopeMessagesWrite)).Methods("DELETE")
+	return router
+}
+
+// scheduledMessageErrorStatus returns the response status of an error managing a scheduled message
+func scheduledMessageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrScheduledMessageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrScheduledMessageNotPending):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// ScheduledMessagesShow is the handler function that lists the scheduled messages of the requesting user that have
+// not been sent
+func (sr *scheduledMessageRouter) ScheduledMessagesShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	scheduled, err := sr.smService.ScheduledMessagesFind(&models.ScheduledMessage{SenderID: tokenData.UserId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusServiceUnavailable, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(scheduledMessagesDTO{ScheduledMessages: scheduled}); err != nil {
+		return
+	}
+}
+
+// CreateScheduledMessage is the handler function that schedules a message of the requesting user to be sent at its send_at
+func (sr *scheduledMessageRouter) CreateScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	var scheduled models.ScheduledMessage
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &scheduled); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if services.IsCommand(scheduled.Content) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: models.ErrScheduledCommand.Error()})
+		return
+	}
+	// a leading "//" schedules a message that starts with a slash
+	if strings.HasPrefix(scheduled.Content, "//") {
+		scheduled.Content = scheduled.Content[1:]
+	}
+	s, err := sr.smService.ScheduledMessageCreate(&models.ScheduledMessage{
+		ConversationID: scheduled.ConversationID,
+		SenderID:       tokenData.UserId,
+		ReceiverID:     scheduled.ReceiverID,
+		Content:        scheduled.Content,
+		ContentType:    scheduled.ContentType,
+		Group:          scheduled.Group,
+		SendAt:         scheduled.SendAt,
+	})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(s); err != nil {
+		return
+	}
+}
+
+// ScheduledMessageShow is the handler function that returns a scheduled message of the requesting user
+func (sr *scheduledMessageRouter) ScheduledMessageShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	s, err := sr.smService.ScheduledMessageFind(&models.ScheduledMessage{Id: mux.Vars(r)["scheduledMessageId"], SenderID: tokenData.UserId})
+	if err != nil {
+		utilities.RespondWithError(w, scheduledMessageErrorStatus(err), utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(s); err != nil {
+		return
+	}
+}
+
+// ModifyScheduledMessage is the handler function that edits the content or the send_at of a pending scheduled message
+// of the requesting user
+func (sr *scheduledMessageRouter) ModifyScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	var edit models.ScheduledMessage
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &edit); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if services.IsCommand(edit.Content) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: models.ErrScheduledCommand.Error()})
+		return
+	}
+	if strings.HasPrefix(edit.Content, "//") {
+		edit.Content = edit.Content[1:]
+	}
+	s, err := sr.smService.ScheduledMessageUpdate(&models.ScheduledMessage{
+		Id:          mux.Vars(r)["scheduledMessageId"],
+		SenderID:    tokenData.UserId,
+		Content:     edit.Content,
+		ContentType: edit.ContentType,
+		SendAt:      edit.SendAt,
+	})
+	if err != nil {
+		utilities.RespondWithError(w, scheduledMessageErrorStatus(err), utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusAccepted)
+	if err = json.NewEncoder(w).Encode(s); err != nil {
+		return
+	}
+}
+
+// DeleteScheduledMessage is the handler function that cancels a scheduled message of the requesting user that has
+// not been sent
+func (sr *scheduledMessageRouter) DeleteScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	s, err := sr.smService.ScheduledMessageDelete(&models.ScheduledMessage{Id: mux.Vars(r)["scheduledMessageId"], SenderID: tokenData.UserId})
+	if err != nil {
+		utilities.RespondWithError(w, scheduledMessageErrorStatus(err), utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(s); err != nil {
+		return
+	}
+}
@@ -1,15 +1,22 @@
 package server
 
 import (
+	"fmt"
 	"github.com/ablancas22/messenger-backend/services"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 )
 
+// incomingWebhookPrefix starts the paths of incoming webhooks, the rest of the path is the secret token of the hook
+const incomingWebhookPrefix = "/hooks/"
+
 // Server is a struct that stores the API Apps high level attributes such as the router, config, and services
 type Server struct {
 	Router                  *mux.Router
@@ -20,16 +27,31 @@ type Server struct {
 	GroupMembershipsService services.GroupMembershipService
 	ConversationService     services.ConversationService
 	ContactService          services.ContactService
+	FileService             services.FileService
+	MailService             services.MailService
+	WebhookService          services.WebhookService
+	DeviceService           services.DeviceService
+	NotificationService     services.NotificationService
+	DigestService           services.DigestService
+	Digester                *services.Digester
+	ScheduledMessageService services.ScheduledMessageService
+	Scheduler               *services.Scheduler
+	MessageSweeper          *services.MessageSweeper
 }
 
 // NewServer is a function used to initialize a new Server struct
-func NewServer(u services.UserService, g services.GroupService, tt services.MessageService, t *services.TokenService, gm services.GroupMembershipService, c services.ConversationService, co services.ContactService) *Server {
+func NewServer(u services.UserService, g services.GroupService, tt services.MessageService, t *services.TokenService, gm services.GroupMembershipService, c services.ConversationService, co services.ContactService, f services.FileService, m services.MailService, wh services.WebhookService, cd *services.CommandDispatcher, mn services.MentionService, dv services.DeviceService, n services.NotificationService, dg services.DigestService, digester *services.Digester, sm services.ScheduledMessageService, scheduler *services.Scheduler, sweeper *services.MessageSweeper) *Server {
 	router := mux.NewRouter().StrictSlash(true)
-	router = NewGroupRouter(router, t, g, u, gm)
-	router = NewUserRouter(router, t, u, g)
-	router = NewMessageRouter(router, t, tt)
-	router = NewConversationRouter(router, t, tt, c, u)
+	router = NewGroupRouter(router, t, g, u, gm, f, wh, cd)
+	router = NewUserRouter(router, t, u, g, f)
+	router = NewMessageRouter(router, t, tt, cd, mn)
+	router = NewConversationRouter(router, t, tt, c, u, g, gm)
 	router = NewContactRouter(router, t, tt, co, u)
+	router = NewFileRouter(router, t, f, services.NewFileAccess(tt, gm, c))
+	router = NewBotRouter(router, t)
+	router = NewDeviceRouter(router, t, dv)
+	router = NewDigestRouter(router, t, dg, digester)
+	router = NewScheduledMessageRouter(router, t, sm)
 	return &Server{
 		Router:                  router,
 		TokenService:            t,
@@ -39,14 +61,39 @@ func NewServer(u services.UserService, g services.GroupService, tt services.Mess
 		GroupMembershipsService: gm,
 		ConversationService:     c,
 		ContactService:          co,
+		FileService:             f,
+		MailService:             m,
+		WebhookService:          wh,
+		DeviceService:           dv,
+		NotificationService:     n,
+		DigestService:           dg,
+		Digester:                digester,
+		ScheduledMessageService: sm,
+		Scheduler:               scheduler,
+		MessageSweeper:          sweeper,
+	}
+}
+
+// logRequest writes an access log line in Apache Common Log Format like handlers.LoggingHandler, except that the token
+// of an incoming webhook is left out of the path as it is all a caller needs to post to the hook
+func logRequest(w io.Writer, p handlers.LogFormatterParams) {
+	host, _, err := net.SplitHostPort(p.Request.RemoteAddr)
+	if err != nil {
+		host = p.Request.RemoteAddr
+	}
+	uri := p.URL.RequestURI()
+	if strings.HasPrefix(p.URL.Path, incomingWebhookPrefix) {
+		uri = incomingWebhookPrefix + "[redacted]"
 	}
+	fmt.Fprintf(w, "%s - - [%s] %q %d %d\n", host, p.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
+		p.Request.Method+" "+uri+" "+p.Request.Proto, p.StatusCode, p.Size)
 }
 
 // Start starts the initialized Server
 func (s *Server) Start() {
 	log.Println("Listening on port " + os.Getenv("PORT"))
 	go func() {
-		if err := http.ListenAndServe(":"+os.Getenv("PORT"), handlers.LoggingHandler(os.Stdout, s.Router)); err != nil {
+		if err := http.ListenAndServe(":"+os.Getenv("PORT"), handlers.CustomLoggingHandler(os.Stdout, s.Router, logRequest)); err != nil {
 			log.Fatal("http.ListenAndServe: ", err)
 		}
 	}()
@@ -2,35 +2,83 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/ablancas22/messenger-backend/auth"
 	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/oidc"
+	"github.com/ablancas22/messenger-backend/password"
 	"github.com/ablancas22/messenger-backend/services"
 	"github.com/ablancas22/messenger-backend/utilities"
 	"github.com/gorilla/mux"
 	"io"
+	"log"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type userRouter struct {
 	aService *services.TokenService
 	uService services.UserService
 	gService services.GroupService
+	fService services.FileService
 }
 
 // NewUserRouter is a function that initializes a new userRouter struct
-func NewUserRouter(router *mux.Router, a *services.TokenService, u services.UserService, g services.GroupService) *mux.Router {
-	uRouter := userRouter{a, u, g}
+func NewUserRouter(router *mux.Router, a *services.TokenService, u services.UserService, g services.GroupService, f services.FileService) *mux.Router {
+	uRouter := userRouter{a, u, g, f}
 	router.HandleFunc("/auth", utilities.HandleOptionsRequest).Methods("OPTIONS")
 	router.HandleFunc("/auth", uRouter.SignIn).Methods("POST")
 	router.HandleFunc("/auth", a.MemberTokenVerifyMiddleWare(uRouter.RefreshSession)).Methods("GET")
 	router.HandleFunc("/auth", a.MemberTokenVerifyMiddleWare(uRouter.SignOut)).Methods("DELETE")
+	router.HandleFunc("/.well-known/jwks.json", uRouter.JWKS).Methods("GET")
+	router.HandleFunc("/auth/refresh", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/refresh", uRouter.RefreshToken).Methods("POST")
+	router.HandleFunc("/auth/sessions", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/sessions", a.MemberTokenVerifyMiddleWare(uRouter.SessionsShow)).Methods("GET")
+	router.HandleFunc("/auth/sessions", a.MemberTokenVerifyMiddleWare(uRouter.SignOutEverywhere)).Methods("DELETE")
+	router.HandleFunc("/auth/sessions/{sessionId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/sessions/{sessionId}", a.MemberTokenVerifyMiddleWare(uRouter.DeleteSession)).Methods("DELETE")
+	router.HandleFunc("/auth/2fa", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/2fa", uRouter.SignInTwoFactor).Methods("POST")
+	router.HandleFunc("/auth/2fa", a.MemberTokenVerifyMiddleWare(uRouter.TwoFactorShow)).Methods("GET")
+	router.HandleFunc("/auth/2fa", a.MemberTokenVerifyMiddleWare(uRouter.DisableTwoFactor)).Methods("DELETE")
+	router.HandleFunc("/auth/2fa/enroll", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/2fa/enroll", a.MemberTokenVerifyMiddleWare(uRouter.EnrollTwoFactor)).Methods("POST")
+	router.HandleFunc("/auth/2fa/confirm", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/2fa/confirm", a.MemberTokenVerifyMiddleWare(uRouter.ConfirmTwoFactor)).Methods("POST")
+	router.HandleFunc("/auth/oidc", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/oidc", uRouter.OIDCProvidersShow).Methods("GET")
+	router.HandleFunc("/auth/oidc/{provider}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/oidc/{provider}", uRouter.StartOIDCLogin).Methods("POST")
+	router.HandleFunc("/auth/oidc/{provider}/callback", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/oidc/{provider}/callback", uRouter.OIDCCallback).Methods("POST")
+	router.HandleFunc("/auth/lockouts", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/lockouts", a.SupportTokenVerifyMiddleWare(uRouter.LockoutsShow)).Methods("GET")
+	router.HandleFunc("/auth/lockouts/{lockoutId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/lockouts/{lockoutId}", a.SupportTokenVerifyMiddleWare(uRouter.DeleteLockout)).Methods("DELETE")
+	router.HandleFunc("/audit-events", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/audit-events", a.AdminTokenVerifyMiddleWare(uRouter.AuditEventsShow)).Methods("GET")
 	router.HandleFunc("/auth/register", utilities.HandleOptionsRequest).Methods("OPTIONS")
 	router.HandleFunc("/auth/register", uRouter.RegisterUser).Methods("POST")
-	router.HandleFunc("/auth/api-key", utilities.HandleOptionsRequest).Methods("OPTIONS")
-	router.HandleFunc("/auth/api-key", a.MemberTokenVerifyMiddleWare(uRouter.GenerateAPIKey)).Methods("GET")
+	router.HandleFunc("/auth/api-keys", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/api-keys", a.MemberTokenVerifyMiddleWare(uRouter.APIKeysShow)).Methods("GET")
+	router.HandleFunc("/auth/api-keys", a.MemberTokenVerifyMiddleWare(uRouter.CreateAPIKey)).Methods("POST")
+	router.HandleFunc("/auth/api-keys/{keyId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/api-keys/{keyId}", a.MemberTokenVerifyMiddleWare(uRouter.DeleteAPIKey)).Methods("DELETE")
 	router.HandleFunc("/auth/password", utilities.HandleOptionsRequest).Methods("OPTIONS")
 	router.HandleFunc("/auth/password", a.MemberTokenVerifyMiddleWare(uRouter.UpdatePassword)).Methods("POST")
+	router.HandleFunc("/auth/password/forgot", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/password/forgot", uRouter.ForgotPassword).Methods("POST")
+	router.HandleFunc("/auth/password/reset", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/password/reset", uRouter.ResetPassword).Methods("POST")
+	router.HandleFunc("/auth/verify-email", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/verify-email", uRouter.VerifyEmail).Methods("POST")
+	router.HandleFunc("/auth/verify-email/resend", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/auth/verify-email/resend", a.MemberTokenVerifyMiddleWare(uRouter.ResendVerifyEmail)).Methods("POST")
 	router.HandleFunc("/users", utilities.HandleOptionsRequest).Methods("OPTIONS")
 	router.HandleFunc("/users", a.MemberTokenVerifyMiddleWare(uRouter.UsersShow)).Methods("GET")
 	router.HandleFunc("/users/{userId}", utilities.HandleOptionsRequest).Methods("OPTIONS")
@@ -38,6 +86,12 @@ func NewUserRouter(router *mux.Router, a *services.TokenService, u services.User
 	router.HandleFunc("/users", a.AdminTokenVerifyMiddleWare(uRouter.CreateUser)).Methods("POST")
 	router.HandleFunc("/users/{userId}", a.AdminTokenVerifyMiddleWare(uRouter.DeleteUser)).Methods("DELETE")
 	router.HandleFunc("/users/{userId}", a.MemberTokenVerifyMiddleWare(uRouter.ModifyUser)).Methods("PATCH")
+	router.HandleFunc("/users/{userId}/role", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/users/{userId}/role", a.AdminTokenVerifyMiddleWare(uRouter.ChangeUserRole)).Methods("PUT")
+	router.HandleFunc("/users/{userId}/image", utilities.HandleOptionsRequest).Methods("OPTIONS")
+	router.HandleFunc("/users/{userId}/image", a.MemberTokenVerifyMiddleWare(uRouter.UserImageShow)).Methods("GET")
+	router.HandleFunc("/users/{userId}/image", a.MemberTokenVerifyMiddleWare(uRouter.UpdateUserImage)).Methods("PUT")
+	router.HandleFunc("/users/{userId}/image", a.MemberTokenVerifyMiddleWare(uRouter.DeleteUserImage)).Methods("DELETE")
 	return router
 }
 
@@ -52,7 +106,7 @@ func (ur *userRouter) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
 		return
 	}
-	decodedToken, err := auth.DecodeJWT(r.Header.Get("Auth-Token"))
+	decodedToken, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
@@ -70,7 +124,11 @@ func (ur *userRouter) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	inUser := decodedToken.ToUser()
 	u, err := ur.uService.UpdatePassword(inUser, pw.CurrentPassword, pw.NewPassword)
 	if err != nil {
-		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		status := http.StatusUnauthorized
+		if password.IsPolicyViolation(err) {
+			status = http.StatusBadRequest
+		}
+		utilities.RespondWithError(w, status, utilities.JWTError{Message: err.Error()})
 		return
 	} else {
 		w = utilities.SetResponseHeaders(w, "", "")
@@ -83,6 +141,110 @@ func (ur *userRouter) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ForgotPassword is the handler function that emails a password reset link, it always reports success so it can
+// not be used to find out which emails have accounts
+func (ur *userRouter) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var forgot forgotPassword
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &forgot); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = ur.aService.SendPasswordReset(forgot.Email); err != nil {
+		log.Println("Password reset email failed:", err)
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusAccepted)
+	return
+}
+
+// ResetPassword is the handler function that sets a new password with a password reset token
+func (ur *userRouter) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var reset resetPassword
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &reset); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = ur.aService.ResetPassword(reset.Token, reset.NewPassword); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	return
+}
+
+// VerifyEmail is the handler function that marks a users email as verified with an email verification token
+func (ur *userRouter) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var verify verifyEmail
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &verify); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	u, err := ur.aService.VerifyEmail(verify.Token)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	u.Password = ""
+	if err = json.NewEncoder(w).Encode(u); err != nil {
+		return
+	}
+}
+
+// ResendVerifyEmail is the handler function that emails the requesting user a new verification link
+func (ur *userRouter) ResendVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	u, err := ur.uService.UserFind(tokenData.ToUser())
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if u.EmailVerified() {
+		utilities.RespondWithError(w, http.StatusConflict, utilities.JWTError{Message: "email address is already verified"})
+		return
+	}
+	if err = ur.aService.SendVerificationEmail(u); err != nil {
+		utilities.RespondWithError(w, http.StatusInternalServerError, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusAccepted)
+	return
+}
+
 // ModifyUser is the handler function that updates a user
 func (ur *userRouter) ModifyUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -121,7 +283,8 @@ func (ur *userRouter) ModifyUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// SignIn is the handler function that manages the user SignIn process
+// SignIn is the handler function that manages the user SignIn process.
+// Users with two-factor authentication enabled get a challenge token to complete the sign in at /auth/2fa.
 func (ur *userRouter) SignIn(w http.ResponseWriter, r *http.Request) {
 	var user models.User
 	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
@@ -137,30 +300,279 @@ func (ur *userRouter) SignIn(w http.ResponseWriter, r *http.Request) {
 		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
 		return
 	}
+	ip := clientIP(r)
+	if !ur.checkSignInLock(w, user.Email, ip) {
+		return
+	}
 	u, err := ur.uService.AuthenticateUser(&user)
 	if err != nil {
+		if fErr := ur.aService.SignInFailed(user.Email, ip); fErr != nil {
+			log.Println("Failed to record failed sign in:", fErr)
+		}
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
 	} else {
-		sessionToken, err := ur.aService.GenerateToken(u, "session")
-		if err != nil {
-			utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		if !ur.signInUser(w, r, body, u) {
 			return
 		}
-		w = utilities.SetResponseHeaders(w, sessionToken, "")
-		w.WriteHeader(http.StatusOK)
-		u.Password = ""
-		if err = json.NewEncoder(w).Encode(u); err != nil {
+		if err = ur.aService.SignInSucceeded(u.Email); err != nil {
+			log.Println("Failed to clear failed sign ins:", err)
+		}
+		return
+	}
+}
+
+// signInUser responds to a sign in that identified a User with a new session, or with a challenge token when
+// the User has two-factor authentication enabled, returning whether a session was created
+func (ur *userRouter) signInUser(w http.ResponseWriter, r *http.Request, body []byte, u *models.User) bool {
+	twoFactor, err := ur.aService.TwoFactorEnabled(u.Id)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusInternalServerError, utilities.JWTError{Message: err.Error()})
+		return false
+	}
+	if twoFactor {
+		challenge := twoFactorChallengeDTO{TwoFactorRequired: true}
+		challenge.ChallengeToken, challenge.ExpiresAt, err = ur.aService.CreateTwoFactorChallenge(u)
+		if err != nil {
+			utilities.RespondWithError(w, http.StatusInternalServerError, utilities.JWTError{Message: err.Error()})
+			return false
+		}
+		w = utilities.SetResponseHeaders(w, "", "")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(challenge)
+		return false
+	}
+	sessionToken, refreshToken, err := ur.aService.CreateSession(u, newDeviceSession(r, body))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return false
+	}
+	w = utilities.SetResponseHeaders(w, sessionToken, "")
+	w.Header().Set("Refresh-Token", refreshToken)
+	w.WriteHeader(http.StatusOK)
+	u.Password = ""
+	_ = json.NewEncoder(w).Encode(u)
+	return true
+}
+
+// OIDCProvidersShow is the handler function that lists the external providers users can sign in with
+func (ur *userRouter) OIDCProvidersShow(w http.ResponseWriter, r *http.Request) {
+	providers, err := ur.aService.OIDCProviders()
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusInternalServerError, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(providers); err != nil {
+		return
+	}
+}
+
+// StartOIDCLogin is the handler function that begins a sign in with an external provider, returning the URL to send the user to
+func (ur *userRouter) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	authURL, l, err := ur.aService.StartOIDCLogin(vars["provider"])
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
 			return
 		}
+		utilities.RespondWithError(w, http.StatusBadGateway, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(oidcLoginDTO{AuthorizationURL: authURL, State: l.State, ExpiresAt: l.ExpiresAt}); err != nil {
 		return
 	}
 }
 
+// OIDCCallback is the handler function that completes a sign in with the code and state an external provider
+// redirected the user back with. Users with two-factor authentication enabled get a challenge token like SignIn.
+func (ur *userRouter) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	var callback oidcCallback
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &callback); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	ip := clientIP(r)
+	if !ur.checkSignInLock(w, "", ip) {
+		return
+	}
+	vars := mux.Vars(r)
+	u, err := ur.aService.CompleteOIDCLogin(vars["provider"], callback.Code, callback.State)
+	switch {
+	case err == nil:
+		ur.signInUser(w, r, body, u)
+	case errors.Is(err, oidc.ErrUnknownProvider):
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+	case errors.Is(err, models.ErrIdentityEmailUnverified):
+		utilities.RespondWithError(w, http.StatusConflict, utilities.JWTError{Message: err.Error()})
+	case errors.Is(err, models.ErrIdentityRegistrationClosed):
+		utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: err.Error()})
+	default:
+		if fErr := ur.aService.SignInFailed("", ip); fErr != nil {
+			log.Println("Failed to record failed sign in:", fErr)
+		}
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+	}
+}
+
+// SignInTwoFactor is the handler function that completes a SignIn with a TOTP or recovery code
+func (ur *userRouter) SignInTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var code twoFactorCode
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &code); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	ip := clientIP(r)
+	if !ur.checkSignInLock(w, "", ip) {
+		return
+	}
+	u, sessionToken, refreshToken, err := ur.aService.CompleteTwoFactorSignIn(code.ChallengeToken, code.Code, newDeviceSession(r, body))
+	if err != nil {
+		if fErr := ur.aService.SignInFailed("", ip); fErr != nil {
+			log.Println("Failed to record failed sign in:", fErr)
+		}
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = ur.aService.SignInSucceeded(u.Email); err != nil {
+		log.Println("Failed to clear failed sign ins:", err)
+	}
+	w = utilities.SetResponseHeaders(w, sessionToken, "")
+	w.Header().Set("Refresh-Token", refreshToken)
+	w.WriteHeader(http.StatusOK)
+	u.Password = ""
+	if err = json.NewEncoder(w).Encode(u); err != nil {
+		return
+	}
+}
+
+// TwoFactorShow is the handler function that returns whether the requesting user has two-factor authentication enabled
+func (ur *userRouter) TwoFactorShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	t, err := ur.aService.TwoFactor(tokenData.UserId)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(t); err != nil {
+		return
+	}
+}
+
+// EnrollTwoFactor is the handler function that starts a TOTP enrollment, returning the secret and its otpauth URI
+func (ur *userRouter) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	t, err := ur.aService.EnrollTwoFactor(tokenData.UserId)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(t); err != nil {
+		return
+	}
+}
+
+// ConfirmTwoFactor is the handler function that enables two-factor authentication once the user enters a code
+// from their authenticator app, returning their one-time recovery codes
+func (ur *userRouter) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var code twoFactorCode
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &code); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	t, err := ur.aService.ConfirmTwoFactor(tokenData.UserId, code.Code)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(t); err != nil {
+		return
+	}
+}
+
+// DisableTwoFactor is the handler function that turns off two-factor authentication after checking a code
+func (ur *userRouter) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var code twoFactorCode
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &code); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = ur.aService.DisableTwoFactor(tokenData.ToUser(), code.Code); err != nil {
+		utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	return
+}
+
 // RefreshSession is the handler function that refreshes a users JWT token
 func (ur *userRouter) RefreshSession(w http.ResponseWriter, r *http.Request) {
-	authToken := r.Header.Get("Auth-Token")
-	tokenData, err := auth.DecodeJWT(authToken)
+	tokenData, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
@@ -170,7 +582,7 @@ func (ur *userRouter) RefreshSession(w http.ResponseWriter, r *http.Request) {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
 	}
-	newToken, err := ur.aService.GenerateToken(user, "session")
+	newToken, err := ur.aService.GenerateSessionToken(user, tokenData.SessionId)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
 		return
@@ -180,37 +592,277 @@ func (ur *userRouter) RefreshSession(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-// GenerateAPIKey is the handler function that generates 6 month API Key for a given user
-func (ur *userRouter) GenerateAPIKey(w http.ResponseWriter, r *http.Request) {
-	authToken := r.Header.Get("Auth-Token")
-	tokenData, err := auth.DecodeJWT(authToken)
+// JWKS is the handler function that publishes the public keys session tokens can be verified with
+func (ur *userRouter) JWKS(w http.ResponseWriter, r *http.Request) {
+	jwks, err := auth.CurrentJWKS()
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusInternalServerError, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(jwks); err != nil {
+		return
+	}
+}
+
+// RefreshToken is the handler function that exchanges a refresh token for a new session token and refresh token
+func (ur *userRouter) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	authToken, refreshToken, err := ur.aService.RefreshAccessToken(r.Header.Get("Refresh-Token"))
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
 	}
-	user, err := ur.uService.UserFind(tokenData.ToUser())
+	w = utilities.SetResponseHeaders(w, authToken, "")
+	w.Header().Set("Refresh-Token", refreshToken)
+	w.WriteHeader(http.StatusOK)
+	return
+}
+
+// CreateAPIKey is the handler function that creates a named and scoped API key for the requesting user
+func (ur *userRouter) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var key models.APIKey
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &key); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	key.UserId = tokenData.UserId
+	k, err := ur.aService.CreateAPIKey(&key)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(k); err != nil {
+		return
+	}
+}
+
+// APIKeysShow is the handler function that lists the API keys of the requesting user
+func (ur *userRouter) APIKeysShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
 	}
-	apiKey, err := ur.aService.GenerateToken(user, "api")
+	keys, err := ur.aService.APIKeys(tokenData.UserId)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
 		return
 	}
-	w = utilities.SetResponseHeaders(w, "", apiKey)
+	w = utilities.SetResponseHeaders(w, "", "")
 	w.WriteHeader(http.StatusOK)
-	return
+	if err = json.NewEncoder(w).Encode(keys); err != nil {
+		return
+	}
+}
+
+// DeleteAPIKey is the handler function that revokes one of the requesting users API keys
+func (ur *userRouter) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	vars := mux.Vars(r)
+	k, err := ur.aService.RevokeAPIKey(&models.APIKey{Id: vars["keyId"], UserId: tokenData.UserId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(k); err != nil {
+		return
+	}
 }
 
 // SignOut is the handler function that ends a users session
 func (ur *userRouter) SignOut(w http.ResponseWriter, r *http.Request) {
-	authToken := r.Header.Get("Auth-Token")
-	err := ur.aService.BlacklistAuthToken(authToken)
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = ur.aService.RevokeSession(&models.Session{Id: tokenData.SessionId, UserId: tokenData.UserId}); err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	return
+}
+
+// newDeviceSession describes the device a sign-in request was made from
+func newDeviceSession(r *http.Request, body []byte) *models.Session {
+	var device struct {
+		DeviceName string `json:"device_name"`
+	}
+	_ = json.Unmarshal(body, &device)
+	return &models.Session{
+		DeviceName: device.DeviceName,
+		UserAgent:  r.UserAgent(),
+		IPAddress:  clientIP(r),
+	}
+}
+
+// trustedProxy determines whether an address is one of the TRUSTED_PROXIES, a comma separated list of IP addresses
+// and CIDR ranges
+func trustedProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if proxy := net.ParseIP(entry); proxy != nil && proxy.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address a request came from. X-Forwarded-For is only honoured for requests made by a trusted
+// proxy, the client being the last address it lists that is not a trusted proxy itself.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !trustedProxy(ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		ip = hop
+		if !trustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// checkSignInLock responds with 429 and a Retry-After header when sign ins for an email or address are locked out,
+// returning whether the sign in may go ahead
+func (ur *userRouter) checkSignInLock(w http.ResponseWriter, email string, ip string) bool {
+	retryAfter, err := ur.aService.SignInLocked(email, ip)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusInternalServerError, utilities.JWTError{Message: err.Error()})
+		return false
+	}
+	if retryAfter <= 0 {
+		return true
+	}
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Add("Access-Control-Expose-Headers", "Retry-After")
+	utilities.RespondWithError(w, http.StatusTooManyRequests, utilities.JWTError{Message: models.ErrSignInLocked.Error()})
+	return false
+}
+
+// LockoutsShow is the handler function that lists the accounts and addresses locked out of signing in
+func (ur *userRouter) LockoutsShow(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := ur.aService.Lockouts()
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(lockouts); err != nil {
+		return
+	}
+}
+
+// DeleteLockout is the handler function that lets a locked out account or address sign in again
+func (ur *userRouter) DeleteLockout(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	lockout, err := ur.aService.ClearLockout(vars["lockoutId"])
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(lockout); err != nil {
+		return
+	}
+}
+
+// SessionsShow is the handler function that lists the devices a user is signed in on
+func (ur *userRouter) SessionsShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	sessions, err := ur.aService.Sessions(tokenData.UserId)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	for _, session := range sessions {
+		session.Current = session.Id == tokenData.SessionId
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(sessions); err != nil {
+		return
+	}
+}
+
+// DeleteSession is the handler function that signs out one of a users devices
+func (ur *userRouter) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	vars := mux.Vars(r)
+	err = ur.aService.RevokeSession(&models.Session{Id: vars["sessionId"], UserId: tokenData.UserId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	return
+}
+
+// SignOutEverywhere is the handler function that signs a user out of every device
+func (ur *userRouter) SignOutEverywhere(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
 	}
+	if err = ur.aService.RevokeSessions(tokenData.UserId); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
 	w = utilities.SetResponseHeaders(w, "", "")
 	w.WriteHeader(http.StatusOK)
 	return
@@ -236,18 +888,21 @@ func (ur *userRouter) RegisterUser(w http.ResponseWriter, r *http.Request) {
 			utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
 			return
 		}
-		user.RootAdmin = true
 		u, err := ur.uService.UserCreate(&user)
 		if err != nil {
 			utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
 			return
 		} else {
-			newToken, err := ur.aService.GenerateToken(u, "session")
+			if err = ur.aService.SendVerificationEmail(u); err != nil {
+				log.Println("Verification email failed:", err)
+			}
+			newToken, refreshToken, err := ur.aService.CreateSession(u, newDeviceSession(r, body))
 			if err != nil {
 				utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 				return
 			}
 			w = utilities.SetResponseHeaders(w, newToken, "")
+			w.Header().Set("Refresh-Token", refreshToken)
 			w.WriteHeader(http.StatusCreated)
 			u.Password = ""
 			if err = json.NewEncoder(w).Encode(u); err != nil {
@@ -274,7 +929,7 @@ func (ur *userRouter) CreateUser(w http.ResponseWriter, r *http.Request) {
 		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
 		return
 	}
-	_, err = auth.DecodeJWT(r.Header.Get("Auth-Token"))
+	_, err = auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
@@ -296,7 +951,7 @@ func (ur *userRouter) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 // UsersShow is the handler that shows a specific user
 func (ur *userRouter) UsersShow(w http.ResponseWriter, r *http.Request) {
-	_, err := auth.DecodeJWT(r.Header.Get("Auth-Token"))
+	_, err := auth.LoadTokenFromRequest(r)
 	if err != nil {
 		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
 		return
@@ -359,3 +1014,183 @@ func (ur *userRouter) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// ChangeUserRole is the handler function that lets a root admin promote or demote a user
+func (ur *userRouter) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	userId := mux.Vars(r)["userId"]
+	if !utilities.CheckObjectID(userId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing userId"})
+		return
+	}
+	var change roleChange
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = r.Body.Close(); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if err = json.Unmarshal(body, &change); err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	user, err := ur.aService.ChangeRole(tokenData.UserId, userId, change.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidRole):
+			utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		case errors.Is(err, models.ErrRoleChangeForbidden):
+			utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: err.Error()})
+		default:
+			utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		}
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusAccepted)
+	user.Password = ""
+	if err = json.NewEncoder(w).Encode(user); err != nil {
+		return
+	}
+}
+
+// AuditEventsShow is the handler function that lists the audit trail, filtered by the target_id and action query parameters
+func (ur *userRouter) AuditEventsShow(w http.ResponseWriter, r *http.Request) {
+	filter := &models.AuditEvent{TargetId: r.URL.Query().Get("target_id"), Action: r.URL.Query().Get("action")}
+	if filter.TargetId != "" && !utilities.CheckObjectID(filter.TargetId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "invalid target_id"})
+		return
+	}
+	events, err := ur.aService.AuditEvents(filter)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(events); err != nil {
+		return
+	}
+}
+
+// UserImageShow serves a user's avatar, resized when a size query parameter is given
+func (ur *userRouter) UserImageShow(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	userId := mux.Vars(r)["userId"]
+	if !utilities.CheckObjectID(userId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing userId"})
+		return
+	}
+	user, err := ur.uService.UserFind(&models.User{Id: userId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if user.ImageId == "" {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: "user has no image"})
+		return
+	}
+	file, err := ur.fService.FileFind(&models.File{Id: user.ImageId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	serveFile(w, ur.fService, file, tokenData.UserId, r.URL.Query().Get("size"), "inline")
+}
+
+// UpdateUserImage uploads or replaces a user's avatar from a raw image request body
+func (ur *userRouter) UpdateUserImage(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	userId := mux.Vars(r)["userId"]
+	if !utilities.CheckObjectID(userId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing userId"})
+		return
+	}
+	if userId != tokenData.UserId && !tokenData.RootAdmin {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: "unauthorized"})
+		return
+	}
+	user, err := ur.uService.UserFind(&models.User{Id: userId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	data, fileType, ok := readImage(w, r, ur.fService)
+	if !ok {
+		return
+	}
+	file, err := ur.fService.FileCreate(&models.File{OwnerId: userId, OwnerType: "user", BucketType: "avatar", Name: "avatar", FileType: fileType}, data)
+	if err != nil {
+		utilities.RespondWithError(w, uploadErrorStatus(err), utilities.JWTError{Message: err.Error()})
+		return
+	}
+	u, err := ur.uService.UserImageSet(&models.User{Id: userId, ImageId: file.Id})
+	if err != nil {
+		_, _ = ur.fService.FileDelete(file)
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if user.ImageId != "" {
+		_, _ = ur.fService.FileDelete(&models.File{Id: user.ImageId})
+	}
+	u.Password = ""
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(u); err != nil {
+		return
+	}
+}
+
+// DeleteUserImage removes a user's avatar
+func (ur *userRouter) DeleteUserImage(w http.ResponseWriter, r *http.Request) {
+	tokenData, err := auth.LoadTokenFromRequest(r)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	userId := mux.Vars(r)["userId"]
+	if !utilities.CheckObjectID(userId) {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: "missing userId"})
+		return
+	}
+	if userId != tokenData.UserId && !tokenData.RootAdmin {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: "unauthorized"})
+		return
+	}
+	user, err := ur.uService.UserFind(&models.User{Id: userId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if user.ImageId == "" {
+		utilities.RespondWithError(w, http.StatusNotFound, utilities.JWTError{Message: "user has no image"})
+		return
+	}
+	u, err := ur.uService.UserImageSet(&models.User{Id: userId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusBadRequest, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	_, _ = ur.fService.FileDelete(&models.File{Id: user.ImageId})
+	u.Password = ""
+	w = utilities.SetResponseHeaders(w, "", "")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(u); err != nil {
+		return
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	verifyEmailTTL   = 48 * time.Hour
+)
+
+// requireVerifiedEmail determines whether the REQUIRE_VERIFIED_EMAIL policy is turned on
+func requireVerifiedEmail() bool {
+	return os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
+}
+
+// passwordCheck binds a password reset token to the current password hash of a User,
+// so the token stops working once it has been used to change the password
+func passwordCheck(u *models.User) string {
+	sum := sha256.Sum256([]byte(u.Password))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SendVerificationEmail queues an email with a link a User verifies they own their email address with
+func (a *TokenService) SendVerificationEmail(u *models.User) error {
+	_, err := a.mService.MailQueue(&models.Mail{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: "Hi " + u.Username + ",\n\nPlease confirm this is your email address by opening the link below:\n\n" +
+			models.MailLinkPlaceholder + "\n\nThe link expires in 48 hours.\n",
+		Link: &models.MailLink{Path: "/verify-email", UserId: u.Id, Action: auth.ActionVerifyEmail, Check: u.Email, ExpiresAt: time.Now().Add(verifyEmailTTL)},
+	})
+	return err
+}
+
+// VerifyEmail redeems an email verification token
+func (a *TokenService) VerifyEmail(token string) (*models.User, error) {
+	t, err := auth.DecodeActionToken(token, auth.ActionVerifyEmail)
+	if err != nil {
+		return nil, err
+	}
+	return a.uService.UserVerifyEmail(&models.User{Id: t.UserId, Email: t.Check})
+}
+
+// SendPasswordReset queues a password reset link for the User with an email address.
+// Unknown addresses are ignored so the response does not reveal which emails have accounts.
+func (a *TokenService) SendPasswordReset(email string) error {
+	if email == "" {
+		return nil
+	}
+	u, err := a.uService.UserFind(&models.User{Email: email})
+	if err != nil {
+		return nil
+	}
+	_, err = a.mService.MailQueue(&models.Mail{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: "Hi " + u.Username + ",\n\nSomeone asked to reset the password of your account. If it was you, open the link below to choose a new one:\n\n" +
+			models.MailLinkPlaceholder + "\n\nThe link expires in 1 hour and can only be used once. If you did not ask for it you can ignore this email.\n",
+		Link: &models.MailLink{Path: "/reset-password", UserId: u.Id, Action: auth.ActionPasswordReset, Check: passwordCheck(u), ExpiresAt: time.Now().Add(passwordResetTTL)},
+	})
+	return err
+}
+
+// ResetPassword redeems a password reset token, setting a new password and signing the User out of every device
+func (a *TokenService) ResetPassword(token string, newPassword string) error {
+	t, err := auth.DecodeActionToken(token, auth.ActionPasswordReset)
+	if err != nil {
+		return err
+	}
+	u, err := a.uService.UserFind(&models.User{Id: t.UserId})
+	if err != nil {
+		return auth.ErrActionTokenInvalid
+	}
+	if t.Check != passwordCheck(u) {
+		return auth.ErrActionTokenInvalid
+	}
+	if _, err = a.uService.UserPasswordReset(u, newPassword); err != nil {
+		return err
+	}
+	return a.RevokeSessions(u.Id)
+}
+
+// VerifiedEmailMiddleWare keeps users who have not verified their email off a route when REQUIRE_VERIFIED_EMAIL is on,
+// it must be wrapped by one of the token verify middlewares
+func (a *TokenService) VerifiedEmailMiddleWare(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireVerifiedEmail() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tokenData, err := auth.LoadTokenFromRequest(r)
+		if err != nil {
+			utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+			return
+		}
+		u, err := a.uService.UserFind(tokenData.ToUser())
+		if err != nil {
+			utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+			return
+		}
+		if !u.EmailVerified() && !u.HasRole(models.RoleBot) {
+			utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: models.ErrEmailNotVerified.Error()})
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,13 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// APIKeyService is an interface used to manage the relevant api key doc controllers
+type APIKeyService interface {
+	APIKeyCreate(k *models.APIKey) (*models.APIKey, error)
+	APIKeysFind(k *models.APIKey) ([]*models.APIKey, error)
+	APIKeyDelete(k *models.APIKey) (*models.APIKey, error)
+	APIKeyVerify(key string) (*models.APIKey, error)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"log"
+	"strings"
+)
+
+// attachmentMessageService is a MessageService that checks the files attached to the messages created through it
+type attachmentMessageService struct {
+	MessageService
+	fService FileService
+}
+
+// NewAttachmentMessageService wraps a MessageService so that a message can only attach files owned by its sender, the
+// ids of other files are dropped, and the files of a message record it so that its participants can read them
+func NewAttachmentMessageService(inner MessageService, fService FileService) MessageService {
+	return &attachmentMessageService{inner, fService}
+}
+
+// MessageCreate drops the attachments the sender does not own, creates the message and records it on its files
+func (s *attachmentMessageService) MessageCreate(g *models.Message) (*models.Message, error) {
+	var files []*models.File
+	var ids []string
+	for _, id := range g.Attachments() {
+		f, err := s.fService.FileFind(&models.File{Id: id})
+		if err != nil || f.OwnerType != "user" || f.OwnerId != g.SenderID {
+			continue
+		}
+		files = append(files, f)
+		ids = append(ids, f.Id)
+	}
+	g.FileIds = strings.Join(ids, ",")
+	m, err := s.MessageService.MessageCreate(g)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err = s.fService.FileAttach(f, m.Id); err != nil {
+			log.Println("File "+f.Id+" failed to be attached to message "+m.Id+":", err)
+		}
+	}
+	return m, nil
+}
+
+// FileAccess decides who can read a File: its owner, the members of the group that owns it and the participants of the
+// messages it is attached to
+type FileAccess struct {
+	tService  MessageService
+	gmService GroupMembershipService
+	cService  ConversationService
+}
+
+// NewFileAccess is an exported function used to initialize a new FileAccess struct
+func NewFileAccess(tService MessageService, gmService GroupMembershipService, cService ConversationService) *FileAccess {
+	return &FileAccess{tService, gmService, cService}
+}
+
+// CanRead determines whether a User can read a File
+func (a *FileAccess) CanRead(f *models.File, userId string) bool {
+	switch f.OwnerType {
+	case "user":
+		if f.OwnerId == userId {
+			return true
+		}
+	case "group":
+		if _, err := a.gmService.GroupMembershipFind(&models.GroupMembership{UserId: userId, GroupId: f.OwnerId}); err == nil {
+			return true
+		}
+	}
+	for _, messageId := range f.MessageIds {
+		m, err := a.tService.MessageFind(&models.Message{Id: messageId})
+		if err != nil {
+			continue // the message was deleted
+		}
+		readers, _, err := participants(m, a.gmService, a.cService)
+		if err != nil {
+			continue
+		}
+		for _, id := range readers {
+			if id == userId {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// AuditEventService is an interface used to manage the relevant audit event doc controllers
+type AuditEventService interface {
+	AuditEventCreate(a *models.AuditEvent) (*models.AuditEvent, error)
+	AuditEventsFind(a *models.AuditEvent) ([]*models.AuditEvent, error)
+}
@@ -1,7 +1,9 @@
 package services
 
+import "time"
+
 // BlacklistService is an interface used to manage the relevant group doc controllers
 type BlacklistService interface {
-	BlacklistAuthToken(authToken string) error
+	BlacklistAuthToken(authToken string, expiresAt time.Time) error
 	CheckTokenBlacklist(authToken string) bool
 }
@@ -0,0 +1,14 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// BotService is an interface used to manage the relevant bot doc controllers
+type BotService interface {
+	BotCreate(b *models.Bot) (*models.Bot, error)
+	BotFind(b *models.Bot) (*models.Bot, error)
+	BotsFind(b *models.Bot) ([]*models.Bot, error)
+	BotUpdate(b *models.Bot) (*models.Bot, error)
+	BotDelete(b *models.Bot) (*models.Bot, error)
+}
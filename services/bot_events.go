@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/webhook"
+	"log"
+	"net/http"
+	"time"
+)
+
+// botDeliveryTimeout bounds how long a bot webhook has to accept an event
+const botDeliveryTimeout = 10 * time.Second
+
+// BotNotifier delivers the events of conversations bots are in to the webhooks of those bots
+type BotNotifier struct {
+	btService BotService
+	cService  ConversationService
+	gmService GroupMembershipService
+	client    *http.Client
+}
+
+// NewBotNotifier is an exported function used to initialize a new BotNotifier struct
+func NewBotNotifier(btService BotService, cService ConversationService, gmService GroupMembershipService) *BotNotifier {
+	return &BotNotifier{btService, cService, gmService, webhook.NewClient(botDeliveryTimeout)}
+}
+
+// groupMembers returns the ids of the members of a group
+func (n *BotNotifier) groupMembers(groupId string) ([]string, error) {
+	gms, err := n.gmService.GroupMembershipsFind(&models.GroupMembership{GroupId: groupId})
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, gm := range gms {
+		ids = append(ids, gm.UserId)
+	}
+	return ids, nil
+}
+
+// recipients returns the ids of the users a message was sent to, the members of its group, the participants of its
+// conversation, or else its receiver
+func (n *BotNotifier) recipients(m *models.Message) ([]string, error) {
+	if m.Group {
+		return n.groupMembers(m.ReceiverID)
+	}
+	if m.ConversationID != "" {
+		c, err := n.cService.ConversationFind(&models.Conversation{Id: m.ConversationID})
+		if err != nil {
+			return nil, err
+		}
+		if c.Group && len(c.ParticipantsIds) > 0 { // the only participant of a group conversation is the group
+			return n.groupMembers(c.ParticipantsIds[0])
+		}
+		return c.ParticipantsIds, nil
+	}
+	return []string{m.ReceiverID}, nil
+}
+
+// Notify is an EventHandler that delivers message events to the bots in the conversation, other than the sender
+func (n *BotNotifier) Notify(e *models.Event) {
+	if e.Message == nil {
+		return
+	}
+	go func() {
+		bots, err := n.btService.BotsFind(&models.Bot{})
+		if err != nil || len(bots) == 0 {
+			return
+		}
+		ids, err := n.recipients(e.Message)
+		if err != nil {
+			log.Println("Bot event recipients failed:", err)
+			return
+		}
+		in := make(map[string]bool)
+		for _, id := range ids {
+			in[id] = true
+		}
+		body, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		for _, b := range bots {
+			if b.WebhookURL == "" || !in[b.UserId] || b.UserId == e.Message.SenderID {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), botDeliveryTimeout)
+			err = webhook.Post(ctx, n.client, b.WebhookURL, b.WebhookSecret, e.Type, body)
+			cancel()
+			if err != nil {
+				log.Println("Bot event delivery failed:", b.UserId, err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/oidc"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/ablancas22/messenger-backend/webhook"
+)
+
+// botTokenName is the name of the API key a bot authenticates with
+const botTokenName = "bot token"
+
+// createBotUser creates a User with the bot role for an integration, it has a placeholder email and never signs in
+// with a password
+func (a *TokenService) createBotUser(id string, username string) (*models.User, error) {
+	// the random password only exists to satisfy the password policy
+	pw, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	u, err := a.uService.UserCreate(&models.User{
+		Id:       id,
+		Username: username,
+		Email:    "bot-" + id + "@bots.invalid",
+		Password: pw,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.uService.UserRoleSet(&models.User{Id: u.Id, Role: models.RoleBot})
+}
+
+// CreateBot creates a bot User owned by ownerId along with its settings and bot token, the token is only returned here
+// and by RotateBotToken
+func (a *TokenService) CreateBot(ownerId string, username string, webhookURL string) (*models.Bot, *models.APIKey, error) {
+	b := &models.Bot{UserId: utilities.GenerateObjectID(), OwnerId: ownerId, WebhookURL: webhookURL}
+	err := b.Validate("create")
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err = a.createBotUser(b.UserId, username); err != nil {
+		return nil, nil, err
+	}
+	if b.WebhookSecret, err = webhook.NewSecret(); err != nil {
+		return nil, nil, err
+	}
+	if b, err = a.btService.BotCreate(b); err != nil {
+		return nil, nil, err
+	}
+	k, err := a.kService.APIKeyCreate(&models.APIKey{UserId: b.UserId, Name: botTokenName, Scopes: models.BotScopes})
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, k, nil
+}
+
+// Bots outputs the bots owned by a User
+func (a *TokenService) Bots(ownerId string) ([]*models.Bot, error) {
+	if !utilities.CheckObjectID(ownerId) {
+		return []*models.Bot{}, nil
+	}
+	return a.btService.BotsFind(&models.Bot{OwnerId: ownerId})
+}
+
+// ownedBot loads the bot with the user id botId, which only its owner and root admins may manage
+func (a *TokenService) ownedBot(actorId string, botId string) (*models.Bot, error) {
+	b, err := a.btService.BotFind(&models.Bot{UserId: botId})
+	if err != nil {
+		return nil, models.ErrBotNotFound
+	}
+	if b.OwnerId == actorId {
+		return b, nil
+	}
+	actor, err := a.uService.UserFind(&models.User{Id: actorId})
+	if err != nil || !actor.HasRole(models.RoleRootAdmin) {
+		return nil, models.ErrBotForbidden
+	}
+	return b, nil
+}
+
+// UpdateBot changes the webhook URL of a bot, an empty URL stops its event deliveries
+func (a *TokenService) UpdateBot(actorId string, botId string, webhookURL string) (*models.Bot, error) {
+	b, err := a.ownedBot(actorId, botId)
+	if err != nil {
+		return nil, err
+	}
+	return a.btService.BotUpdate(&models.Bot{UserId: b.UserId, WebhookURL: webhookURL})
+}
+
+// revokeBotTokens revokes every API key of a bot User
+func (a *TokenService) revokeBotTokens(botId string) error {
+	keys, err := a.kService.APIKeysFind(&models.APIKey{UserId: botId})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if _, err = a.kService.APIKeyDelete(&models.APIKey{Id: k.Id, UserId: botId}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RotateBotToken revokes the token of a bot and returns a new one
+func (a *TokenService) RotateBotToken(actorId string, botId string) (*models.APIKey, error) {
+	b, err := a.ownedBot(actorId, botId)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.revokeBotTokens(b.UserId); err != nil {
+		return nil, err
+	}
+	return a.kService.APIKeyCreate(&models.APIKey{UserId: b.UserId, Name: botTokenName, Scopes: models.BotScopes})
+}
+
+// DeleteBot removes a bot along with its User and tokens
+func (a *TokenService) DeleteBot(actorId string, botId string) (*models.Bot, error) {
+	b, err := a.ownedBot(actorId, botId)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.revokeBotTokens(b.UserId); err != nil {
+		return nil, err
+	}
+	if _, err = a.btService.BotDelete(b); err != nil {
+		return nil, err
+	}
+	if _, err = a.uService.UserDelete(&models.User{Id: b.UserId}); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,13 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// CommandService is an interface used to manage the relevant custom command doc controllers
+type CommandService interface {
+	CommandCreate(c *models.Command) (*models.Command, error)
+	CommandsFind(c *models.Command) ([]*models.Command, error)
+	CommandFind(c *models.Command) (*models.Command, error)
+	CommandDelete(c *models.Command) (*models.Command, error)
+}
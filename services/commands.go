@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"github.com/ablancas22/messenger-backend/webhook"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// commandTimeout bounds how long a bot or URL has to answer a custom command
+	commandTimeout = 5 * time.Second
+	// maxPollOptions caps how many options a poll can have
+	maxPollOptions = 10
+)
+
+// CommandDispatcher runs the slash commands messages start with, built-in commands map onto group and membership
+// operations while custom commands are forwarded to a bot or URL
+type CommandDispatcher struct {
+	uService  UserService
+	gmService GroupMembershipService
+	cService  ConversationService
+	cmService CommandService
+	btService BotService
+	tService  MessageService
+	client    *http.Client
+}
+
+// NewCommandDispatcher is an exported function used to initialize a new CommandDispatcher struct
+func NewCommandDispatcher(uService UserService, gmService GroupMembershipService, cService ConversationService, cmService CommandService, btService BotService, tService MessageService) *CommandDispatcher {
+	return &CommandDispatcher{uService, gmService, cService, cmService, btService, tService, webhook.NewClient(commandTimeout)}
+}
+
+// IsCommand determines whether the content of a message is a slash command, a leading "//" escapes the slash
+func IsCommand(content string) bool {
+	return len(content) > 1 && content[0] == '/' && content[1] != '/'
+}
+
+// parseCommand splits the content of a message into the name of its command and the text after it
+func parseCommand(content string) (name string, text string) {
+	fields := strings.SplitN(strings.TrimPrefix(content, "/"), " ", 2)
+	name = strings.ToLower(fields[0])
+	if len(fields) > 1 {
+		text = strings.TrimSpace(fields[1])
+	}
+	return
+}
+
+// splitArgs splits the text of a command on spaces, keeping the words of double quoted arguments together
+func splitArgs(text string) []string {
+	var args []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			if !quoted {
+				args = append(args, cur.String())
+				cur.Reset()
+			}
+		case unicode.IsSpace(r) && !quoted:
+			if cur.Len() > 0 {
+				args = append(args, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// groupOf returns the id of the group a message is sent to, directly or through a group conversation
+func (d *CommandDispatcher) groupOf(m *models.Message) (string, error) {
+	if m.Group && utilities.CheckObjectID(m.ReceiverID) {
+		return m.ReceiverID, nil
+	}
+	if m.ConversationID != "" {
+		c, err := d.cService.ConversationFind(&models.Conversation{Id: m.ConversationID})
+		if err != nil {
+			return "", err
+		}
+		if c.Group && len(c.ParticipantsIds) > 0 {
+			return c.ParticipantsIds[0], nil
+		}
+	}
+	return "", models.ErrCommandNoGroup
+}
+
+// Dispatch runs the command a message starts with on behalf of its sender, who has to be a member of the group the
+// message is sent to, and returns the response only they get to see
+func (d *CommandDispatcher) Dispatch(m *models.Message) (*models.CommandResponse, error) {
+	name, text := parseCommand(m.Content)
+	groupId, err := d.groupOf(m)
+	if err != nil {
+		return nil, err
+	}
+	gm, err := d.gmService.GroupMembershipFind(&models.GroupMembership{UserId: m.SenderID, GroupId: groupId})
+	if err != nil {
+		return nil, models.ErrCommandForbidden
+	}
+	r := &models.CommandResponse{Command: name, Ephemeral: true}
+	switch name {
+	case "mute":
+		r.Text, err = d.mute(gm, text)
+	case "invite":
+		r.Text, err = d.invite(gm, text)
+	case "poll":
+		r.Message, err = d.poll(m, text)
+		r.Text = "Posted your poll"
+	default:
+		r.Text, err = d.forward(m, groupId, name, text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// mute silences a group for its member for a duration such as 30m or 8h, "off" unmutes it
+func (d *CommandDispatcher) mute(gm *models.GroupMembership, text string) (string, error) {
+	now := time.Now().UTC()
+	until := now
+	if text != "off" {
+		duration, err := time.ParseDuration(text)
+		if err != nil || duration <= 0 {
+			return "", errors.New("usage: /mute <duration such as 30m or 8h> or /mute off")
+		}
+		until = now.Add(duration)
+	}
+	_, err := d.gmService.GroupMembershipUpdate(&models.GroupMembership{Id: gm.Id, UserId: gm.UserId, GroupId: gm.GroupId, MutedUntil: until})
+	if err != nil {
+		return "", err
+	}
+	if text == "off" {
+		return "Unmuted this group", nil
+	}
+	return "Muted this group until " + until.Format(time.RFC3339), nil
+}
+
+// invite adds the User with a @username to the group, only group admins can invite
+func (d *CommandDispatcher) invite(gm *models.GroupMembership, text string) (string, error) {
+	if !gm.Admin {
+		return "", models.ErrCommandForbidden
+	}
+	username := strings.TrimPrefix(text, "@")
+	if username == "" || strings.ContainsAny(username, " \t") {
+		return "", errors.New("usage: /invite @username")
+	}
+	u, err := d.uService.UserFind(&models.User{Username: username})
+	if err != nil {
+		return "", errors.New("no user is named @" + username)
+	}
+	_, err = d.gmService.GroupMembershipCreate(&models.GroupMembership{Id: utilities.GenerateObjectID(), UserId: u.Id, GroupId: gm.GroupId})
+	if err != nil {
+		return "", errors.New("@" + username + " is already a member of this group")
+	}
+	return "Invited @" + username, nil
+}
+
+// poll posts a message asking a question with numbered options, e.g. /poll "Lunch?" "Pizza" "Sushi"
+func (d *CommandDispatcher) poll(m *models.Message, text string) (*models.Message, error) {
+	args := splitArgs(text)
+	if len(args) < 3 || len(args) > maxPollOptions+1 {
+		return nil, errors.New(`usage: /poll "question" "option" "option", with up to 10 options`)
+	}
+	var content strings.Builder
+	content.WriteString("Poll: " + args[0])
+	for i, option := range args[1:] {
+		content.WriteString("\n" + strconv.Itoa(i+1) + ". " + option)
+	}
+	return d.tService.MessageCreate(&models.Message{
+		Id:             utilities.GenerateObjectID(),
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		ReceiverID:     m.ReceiverID,
+		Group:          m.Group,
+		Content:        content.String(),
+	})
+}
+
+// forward sends a custom command of the group to its bot or URL and returns the text field of its JSON reply
+func (d *CommandDispatcher) forward(m *models.Message, groupId string, name string, text string) (string, error) {
+	cmd, err := d.cmService.CommandFind(&models.Command{GroupId: groupId, Name: name})
+	if err != nil {
+		return "", models.ErrCommandNotFound
+	}
+	url, secret := cmd.URL, cmd.Secret
+	if cmd.BotId != "" {
+		b, err := d.btService.BotFind(&models.Bot{UserId: cmd.BotId})
+		if err != nil || b.WebhookURL == "" {
+			return "", models.ErrCommandFailed
+		}
+		url, secret = b.WebhookURL, b.WebhookSecret
+	}
+	body, err := json.Marshal(&models.CommandInvocation{
+		Id:             utilities.GenerateObjectID(),
+		Command:        name,
+		Text:           text,
+		UserId:         m.SenderID,
+		GroupId:        groupId,
+		ConversationId: m.ConversationID,
+		CreatedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	resp, err := webhook.Call(ctx, d.client, url, secret, models.CommandEventType, body)
+	if err != nil {
+		return "", models.ErrCommandFailed
+	}
+	// only the text of the reply is shown, so an endpoint can not be used to read other responses
+	var reply struct {
+		Text *string `json:"text"`
+	}
+	if json.Unmarshal(resp, &reply) != nil || reply.Text == nil {
+		return "", models.ErrCommandFailed
+	}
+	return *reply.Text, nil
+}
+
+// CreateCommand registers a custom command for a group, commands forwarded to a bot need a bot with a webhook URL
+// that is owned by the User creating the command or is a member of the group
+func (d *CommandDispatcher) CreateCommand(c *models.Command) (*models.Command, error) {
+	if c.BotId != "" {
+		b, err := d.btService.BotFind(&models.Bot{UserId: c.BotId})
+		if err != nil {
+			return nil, err
+		}
+		if b.OwnerId != c.CreatedBy {
+			if _, err = d.gmService.GroupMembershipFind(&models.GroupMembership{UserId: b.UserId, GroupId: c.GroupId}); err != nil {
+				return nil, models.ErrCommandBot
+			}
+		}
+		if b.WebhookURL == "" {
+			return nil, errors.New("the bot does not have a webhook_url")
+		}
+	}
+	return d.cmService.CommandCreate(c)
+}
+
+// Commands outputs the custom commands of a group
+func (d *CommandDispatcher) Commands(groupId string) ([]*models.Command, error) {
+	return d.cmService.CommandsFind(&models.Command{GroupId: groupId})
+}
+
+// DeleteCommand removes a custom command of a group
+func (d *CommandDispatcher) DeleteCommand(groupId string, commandId string) (*models.Command, error) {
+	return d.cmService.CommandDelete(&models.Command{Id: commandId, GroupId: groupId})
+}
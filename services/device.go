@@ -0,0 +1,13 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// DeviceService is an interface used to manage the relevant device doc controllers
+type DeviceService interface {
+	DeviceRegister(d *models.Device) (*models.Device, error)
+	DevicesFind(d *models.Device) ([]*models.Device, error)
+	DeviceFind(d *models.Device) (*models.Device, error)
+	DeviceDelete(d *models.Device) (*models.Device, error)
+}
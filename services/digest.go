@@ -0,0 +1,15 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+// DigestService is an interface used to manage the email digest settings of users and their unread threads
+type DigestService interface {
+	DigestSettingsFind(s *models.DigestSettings) (*models.DigestSettings, error)
+	DigestSettingsUpdate(s *models.DigestSettings) (*models.DigestSettings, error)
+	UnreadRecord(u *models.Unread, since time.Time) (*models.Unread, error)
+	UnreadsFind(u *models.Unread) ([]*models.Unread, error)
+	UnreadsClear(u *models.Unread) error
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/auth"
+	"github.com/ablancas22/messenger-backend/models"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultDigestInactiveAfter is how long a User has to be away before they are sent email digests
+	defaultDigestInactiveAfter = 24 * time.Hour
+	// unsubscribeTTL is how long the unsubscribe link of a digest works
+	unsubscribeTTL = 90 * 24 * time.Hour
+	// digestPreviewLength is how many characters of a message its line in a digest shows
+	digestPreviewLength = 80
+)
+
+// digestInactiveAfter returns how long a User has to be away before they are sent email digests, configured with
+// DIGEST_INACTIVE_AFTER
+func digestInactiveAfter() time.Duration {
+	d, err := time.ParseDuration(os.Getenv("DIGEST_INACTIVE_AFTER"))
+	if err != nil || d <= 0 {
+		return defaultDigestInactiveAfter
+	}
+	return d
+}
+
+// UnreadTracker counts the new messages of every participant who is not the sender in their unread threads, which
+// email digests summarize
+type UnreadTracker struct {
+	dgService DigestService
+	uService  UserService
+	gmService GroupMembershipService
+	cService  ConversationService
+}
+
+// NewUnreadTracker is an exported function used to initialize a new UnreadTracker struct
+func NewUnreadTracker(dgService DigestService, uService UserService, gmService GroupMembershipService, cService ConversationService) *UnreadTracker {
+	return &UnreadTracker{dgService, uService, gmService, cService}
+}
+
+// Track is an EventHandler that records every new message in the unread threads of its participants
+func (u *UnreadTracker) Track(e *models.Event) {
+	if e.Type != models.EventMessageCreated || e.Message == nil {
+		return
+	}
+	m := e.Message
+	readers, groupId, err := participants(m, u.gmService, u.cService)
+	if err != nil {
+		log.Println("Unread tracking failed:", err)
+		return
+	}
+	unread := &models.Unread{LastMessageId: m.Id, LastSenderId: m.SenderID, LastMessageAt: m.CreatedAt}
+	switch {
+	case groupId != "":
+		unread.ThreadId, unread.Kind = groupId, models.UnreadGroup
+	case m.ConversationID != "":
+		unread.ThreadId, unread.Kind = m.ConversationID, models.UnreadConversation
+	default:
+		unread.ThreadId, unread.Kind = m.SenderID, models.UnreadDirect
+	}
+	if unread.LastMessageAt.IsZero() {
+		unread.LastMessageAt = time.Now().UTC()
+	}
+	for _, userId := range readers {
+		if userId == "" || userId == m.SenderID {
+			continue
+		}
+		user, err := u.uService.UserFind(&models.User{Id: userId})
+		if err != nil {
+			continue
+		}
+		unread.UserId = userId
+		if _, err = u.dgService.UnreadRecord(unread, user.LastActive); err != nil {
+			log.Println("Unread tracking failed:", err)
+		}
+	}
+}
+
+// Digester emails users who have been away a summary of their unread threads and mentions
+type Digester struct {
+	uService  UserService
+	gService  GroupService
+	tService  MessageService
+	mnService MentionService
+	dgService DigestService
+	mService  MailService
+}
+
+// NewDigester is an exported function used to initialize a new Digester struct
+func NewDigester(uService UserService, gService GroupService, tService MessageService, mnService MentionService, dgService DigestService, mService MailService) *Digester {
+	return &Digester{uService, gService, tService, mnService, dgService, mService}
+}
+
+// username returns the username of a User for the lines of a digest
+func (d *Digester) username(userId string) string {
+	u, err := d.uService.UserFind(&models.User{Id: userId})
+	if err != nil {
+		return "someone"
+	}
+	return "@" + u.Username
+}
+
+// threadName returns how a digest refers to the thread an unread or a mention is in
+func (d *Digester) threadName(kind string, threadId string, senderId string) string {
+	switch kind {
+	case models.UnreadGroup:
+		if g, err := d.gService.GroupFind(&models.Group{Id: threadId}); err == nil {
+			return g.Name
+		}
+		return "a group"
+	case models.UnreadConversation:
+		return "your conversation with " + d.username(senderId)
+	}
+	return d.username(senderId)
+}
+
+// messagePreview returns the quoted beginning of a message for a line of a digest
+func (d *Digester) messagePreview(messageId string) string {
+	m, err := d.tService.MessageFind(&models.Message{Id: messageId})
+	if err != nil {
+		return ""
+	}
+	if m.Content == "" {
+		return " (attachment)"
+	}
+	content := []rune(m.Content)
+	if len(content) > digestPreviewLength {
+		content = append(content[:digestPreviewLength-1], '…')
+	}
+	return ": \"" + string(content) + "\""
+}
+
+// plural returns the count of noun, adding an s when there are several
+func plural(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(count) + " " + noun + "s"
+}
+
+// compose writes the email digest of a User's unread threads and mentions
+func (d *Digester) compose(u *models.User, unreads []*models.Unread, mentions []*models.Mention) (*models.Mail, error) {
+	total := 0
+	var body strings.Builder
+	body.WriteString("Hi " + u.Username + ",\n\nHere is what you missed while you were away.\n")
+	if len(unreads) > 0 {
+		body.WriteString("\nUnread conversations:\n")
+		for _, unread := range unreads {
+			total += unread.Count
+			body.WriteString("- " + d.threadName(unread.Kind, unread.ThreadId, unread.LastSenderId) + ": " + plural(unread.Count, "new message") +
+				", the latest from " + d.username(unread.LastSenderId) + d.messagePreview(unread.LastMessageId) + "\n")
+		}
+	}
+	if len(mentions) > 0 {
+		body.WriteString("\nMentions:\n")
+		for _, mention := range mentions {
+			kind, threadId := models.UnreadDirect, mention.SenderId
+			if mention.GroupId != "" {
+				kind, threadId = models.UnreadGroup, mention.GroupId
+			} else if mention.ConversationId != "" {
+				kind, threadId = models.UnreadConversation, mention.ConversationId
+			}
+			body.WriteString("- " + d.username(mention.SenderId) + " mentioned you in " + d.threadName(kind, threadId, mention.SenderId) +
+				d.messagePreview(mention.MessageId) + "\n")
+		}
+	}
+	body.WriteString("\nYou receive this digest because you have not been active recently. To stop receiving it, open the link below:\n\n" +
+		models.MailLinkPlaceholder + "\n\nYou can also change how often it is sent in your settings.\n")
+	subject := "You have " + plural(total, "unread message")
+	if total == 0 {
+		subject = "You were mentioned " + plural(len(mentions), "time")
+	}
+	link := &models.MailLink{Path: "/unsubscribe", UserId: u.Id, Action: auth.ActionUnsubscribe, Check: u.Email, ExpiresAt: time.Now().Add(unsubscribeTTL)}
+	return &models.Mail{To: u.Email, Subject: subject, Body: body.String(), Link: link}, nil
+}
+
+// digest sends the email digest of a User when one is due, returning whether it was sent
+func (d *Digester) digest(u *models.User, now time.Time) (bool, error) {
+	settings, err := d.dgService.DigestSettingsFind(&models.DigestSettings{UserId: u.Id})
+	if err != nil || !settings.Due(now) {
+		return false, err
+	}
+	since := u.LastActive
+	if settings.LastSentAt.After(since) {
+		since = settings.LastSentAt
+	}
+	allUnreads, err := d.dgService.UnreadsFind(&models.Unread{UserId: u.Id})
+	if err != nil {
+		return false, err
+	}
+	var unreads []*models.Unread
+	for _, unread := range allUnreads {
+		if unread.LastMessageAt.After(u.LastActive) {
+			unreads = append(unreads, unread)
+		}
+	}
+	allMentions, err := d.mnService.MentionsFind(&models.Mention{UserId: u.Id})
+	if err != nil {
+		return false, err
+	}
+	var mentions []*models.Mention
+	for _, mention := range allMentions {
+		if mention.CreatedAt.After(since) {
+			mentions = append(mentions, mention)
+		}
+	}
+	if len(unreads) == 0 && len(mentions) == 0 {
+		return false, nil
+	}
+	mail, err := d.compose(u, unreads, mentions)
+	if err != nil {
+		return false, err
+	}
+	if _, err = d.mService.MailQueue(mail); err != nil {
+		return false, err
+	}
+	settings.LastSentAt = now
+	if _, err = d.dgService.DigestSettingsUpdate(settings); err != nil {
+		return true, err
+	}
+	return true, d.dgService.UnreadsClear(&models.Unread{UserId: u.Id})
+}
+
+// SendDigests queues an email digest for every User who has been away for DIGEST_INACTIVE_AFTER and has unread
+// messages or mentions, at most once per period of their digest frequency, returning how many were queued
+func (d *Digester) SendDigests() (int, error) {
+	users, err := d.uService.UsersFind(&models.User{})
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC()
+	inactiveAfter := digestInactiveAfter()
+	sent := 0
+	for _, u := range users {
+		if u.Email == "" || u.Role == models.RoleBot || now.Sub(u.LastActive) < inactiveAfter {
+			continue
+		}
+		ok, err := d.digest(u, now)
+		if err != nil {
+			log.Println("Digest of user "+u.Id+" failed:", err)
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// Unsubscribe redeems the unsubscribe link of a digest, turning off the digests of its User
+func (d *Digester) Unsubscribe(token string) error {
+	t, err := auth.DecodeActionToken(token, auth.ActionUnsubscribe)
+	if err != nil {
+		return err
+	}
+	u, err := d.uService.UserFind(&models.User{Id: t.UserId})
+	if err != nil || u.Email != t.Check {
+		return auth.ErrActionTokenInvalid
+	}
+	_, err = d.dgService.DigestSettingsUpdate(&models.DigestSettings{UserId: u.Id, Frequency: models.DigestOff})
+	return err
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"log"
+	"time"
+)
+
+// disappearingMessageService is a MessageService that gives the messages created through it in a conversation with
+// disappearing messages on an expiry
+type disappearingMessageService struct {
+	MessageService
+	cService  ConversationService
+	exService MessageExpiryService
+}
+
+// NewDisappearingMessageService wraps a MessageService so that the messages created in a conversation with
+// disappearing messages on expire once the message lifetime of the conversation has passed
+func NewDisappearingMessageService(inner MessageService, cService ConversationService, exService MessageExpiryService) MessageService {
+	return &disappearingMessageService{inner, cService, exService}
+}
+
+// MessageCreate sets when a message expires and creates it. The expiry is recorded before the message is stored, so
+// a message is never left without one.
+func (s *disappearingMessageService) MessageCreate(g *models.Message) (*models.Message, error) {
+	if g.ConversationID == "" {
+		return s.MessageService.MessageCreate(g)
+	}
+	c, err := s.cService.ConversationFind(&models.Conversation{Id: g.ConversationID})
+	if err != nil {
+		return nil, err
+	}
+	if c.MessageTTL == models.MessageTTLOff {
+		return s.MessageService.MessageCreate(g)
+	}
+	if g.Id == "" {
+		g.Id = utilities.GenerateObjectID()
+	}
+	g.ExpiresAt = time.Now().UTC().Add(c.MessageLifetime())
+	if _, err = s.exService.MessageExpiryCreate(&models.MessageExpiry{Id: g.Id, ConversationId: c.Id, ExpiresAt: g.ExpiresAt}); err != nil {
+		return nil, err
+	}
+	return s.MessageService.MessageCreate(g)
+}
+
+// MessageSweeper removes the messages sent with disappearing messages on, along with their attachments, once they expire
+type MessageSweeper struct {
+	exService MessageExpiryService
+	tService  MessageService
+	fService  FileService
+}
+
+// NewMessageSweeper is an exported function used to initialize a new MessageSweeper struct, expired messages are
+// deleted through tService so that clients are told they are gone
+func NewMessageSweeper(exService MessageExpiryService, tService MessageService, fService FileService) *MessageSweeper {
+	return &MessageSweeper{exService, tService, fService}
+}
+
+// sweep deletes an expired message and the files its sender attached to it. A file owned by anyone else is left in
+// place, and a message that was already deleted only has its expiry removed.
+func (s *MessageSweeper) sweep(e *models.MessageExpiry) error {
+	m, err := s.tService.MessageFind(&models.Message{Id: e.Id})
+	if err == nil {
+		for _, fileId := range m.Attachments() {
+			f, err := s.fService.FileFind(&models.File{Id: fileId})
+			if err != nil {
+				continue // the attachment was already deleted
+			}
+			if f.OwnerType != "user" || f.OwnerId != m.SenderID {
+				continue
+			}
+			if _, err = s.fService.FileDelete(&models.File{Id: f.Id}); err != nil {
+				return err
+			}
+		}
+		if _, err = s.tService.MessageDelete(&models.Message{Id: m.Id}); err != nil {
+			return err
+		}
+	}
+	_, err = s.exService.MessageExpiryDelete(e)
+	return err
+}
+
+// PurgeExpiredMessages deletes the messages whose expiry has passed along with their attachments, including the ones
+// that expired while the app was not running, returning how many were deleted
+func (s *MessageSweeper) PurgeExpiredMessages() (int, error) {
+	due, err := s.exService.MessageExpiriesDue(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, e := range due {
+		if err = s.sweep(e); err != nil {
+			log.Println("Expired message "+e.Id+" failed to be deleted:", err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// lifetimeName describes a message lifetime in the notices posted when it changes
+func lifetimeName(ttl int64) string {
+	switch {
+	case ttl%models.MessageTTLWeek == 0:
+		return plural(int(ttl/models.MessageTTLWeek), "week")
+	case ttl%models.MessageTTLDay == 0:
+		return plural(int(ttl/models.MessageTTLDay), "day")
+	}
+	return plural(int(ttl/models.MessageTTLHour), "hour")
+}
+
+// MessageLifetimeNotice returns the system message that tells the participants of a conversation a User changed its
+// disappearing messages setting
+func MessageLifetimeNotice(c *models.Conversation, u *models.User) *models.Message {
+	content := u.Username + " turned off disappearing messages"
+	if c.MessageTTL != models.MessageTTLOff {
+		content = u.Username + " set messages to disappear after " + lifetimeName(c.MessageTTL)
+	}
+	m := &models.Message{ConversationID: c.Id, SenderID: u.Id, ReceiverID: u.Id, Content: content, ContentType: models.ContentTypeSystem}
+	if c.Group && len(c.ParticipantsIds) > 0 {
+		m.ReceiverID, m.Group = c.ParticipantsIds[0], true // the only participant of a group conversation is the group
+		return m
+	}
+	for _, id := range c.ParticipantsIds {
+		if id != u.Id {
+			m.ReceiverID = id
+			break
+		}
+	}
+	return m
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"sync"
+	"time"
+)
+
+// EventHandler is called with every event published on an EventBus
+type EventHandler func(e *models.Event)
+
+// EventBus fans events out to the handlers subscribed to it
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers []EventHandler
+}
+
+// NewEventBus returns an EventBus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe adds h to the handlers called for every published event
+func (b *EventBus) Subscribe(h EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish stamps e and hands it to every subscribed handler, handlers must not block
+func (b *EventBus) Publish(e *models.Event) {
+	if e.Id == "" {
+		e.Id = utilities.GenerateObjectID()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, h := range b.handlers {
+		h(e)
+	}
+}
+
+// eventMessageService is a MessageService that publishes an event for every message created or deleted
+type eventMessageService struct {
+	MessageService
+	bus *EventBus
+}
+
+// NewEventMessageService wraps a MessageService so that the changes made through it are published on bus
+func NewEventMessageService(inner MessageService, bus *EventBus) MessageService {
+	return &eventMessageService{inner, bus}
+}
+
+// MessageCreate creates a message and publishes a message.created event
+func (s *eventMessageService) MessageCreate(g *models.Message) (*models.Message, error) {
+	m, err := s.MessageService.MessageCreate(g)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(&models.Event{Type: models.EventMessageCreated, Message: m})
+	return m, nil
+}
+
+// MessageUpdate edits a message and publishes a message.edited event
+func (s *eventMessageService) MessageUpdate(g *models.Message) (*models.Message, error) {
+	m, err := s.MessageService.MessageUpdate(g)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(&models.Event{Type: models.EventMessageEdited, Message: m})
+	return m, nil
+}
+
+// MessageDelete deletes a message and publishes a message.deleted event
+func (s *eventMessageService) MessageDelete(g *models.Message) (*models.Message, error) {
+	m, err := s.MessageService.MessageDelete(g)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(&models.Event{Type: models.EventMessageDeleted, Message: m})
+	return m, nil
+}
+
+// eventGroupMembershipService is a GroupMembershipService that publishes an event for every member joining or leaving a group
+type eventGroupMembershipService struct {
+	GroupMembershipService
+	bus *EventBus
+}
+
+// NewEventGroupMembershipService wraps a GroupMembershipService so that the changes made through it are published on bus
+func NewEventGroupMembershipService(inner GroupMembershipService, bus *EventBus) GroupMembershipService {
+	return &eventGroupMembershipService{inner, bus}
+}
+
+// GroupMembershipCreate adds a member to a group and publishes a member.joined event
+func (s *eventGroupMembershipService) GroupMembershipCreate(g *models.GroupMembership) (*models.GroupMembership, error) {
+	gm, err := s.GroupMembershipService.GroupMembershipCreate(g)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(&models.Event{Type: models.EventMemberJoined, GroupId: gm.GroupId, Member: gm})
+	return gm, nil
+}
+
+// GroupMembershipDelete removes a member from a group and publishes a member.left event
+func (s *eventGroupMembershipService) GroupMembershipDelete(g *models.GroupMembership) (*models.GroupMembership, error) {
+	gm, err := s.GroupMembershipService.GroupMembershipDelete(g)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(&models.Event{Type: models.EventMemberLeft, GroupId: gm.GroupId, Member: gm})
+	return gm, nil
+}
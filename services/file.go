@@ -0,0 +1,26 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"io"
+)
+
+// FileService is an interface used to manage the relevant file and upload doc controllers
+type FileService interface {
+	MaxFileSize() int64
+	UploadCreate(u *models.Upload) (*models.Upload, error)
+	UploadFind(u *models.Upload) (*models.Upload, error)
+	UploadChunkWrite(u *models.Upload, c *models.UploadChunk, data []byte) (*models.Upload, error)
+	UploadComplete(u *models.Upload) (*models.File, error)
+	UploadDelete(u *models.Upload) (*models.Upload, error)
+	PurgeExpiredUploads() (int, error)
+	ScanPendingFiles() (int, error)
+	FileCreate(f *models.File, data []byte) (*models.File, error)
+	FileFind(f *models.File) (*models.File, error)
+	FileAttach(f *models.File, messageId string) error
+	FileDownload(f *models.File, w io.Writer) (*models.File, error)
+	ThumbnailDownload(f *models.File, t *models.FileThumbnail, w io.Writer) error
+	FileDelete(f *models.File) (*models.File, error)
+	StorageFind(s *models.Storage) (*models.Storage, error)
+	StorageUpdate(s *models.Storage) (*models.Storage, error)
+}
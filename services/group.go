@@ -9,5 +9,6 @@ type GroupService interface {
 	GroupsFind() ([]*models.Group, error)
 	GroupDelete(g *models.Group) (*models.Group, error)
 	GroupUpdate(g *models.Group) (*models.Group, error)
+	GroupImageSet(g *models.Group) (*models.Group, error)
 	GroupDocInsert(g *models.Group) (*models.Group, error)
 }
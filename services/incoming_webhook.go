@@ -0,0 +1,13 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// IncomingWebhookService is an interface used to manage the relevant incoming webhook doc controllers
+type IncomingWebhookService interface {
+	IncomingWebhookCreate(h *models.IncomingWebhook) (*models.IncomingWebhook, error)
+	IncomingWebhooksFind(h *models.IncomingWebhook) ([]*models.IncomingWebhook, error)
+	IncomingWebhookDelete(h *models.IncomingWebhook) (*models.IncomingWebhook, error)
+	IncomingWebhookVerify(token string) (*models.IncomingWebhook, error)
+}
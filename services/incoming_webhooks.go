@@ -0,0 +1,69 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultIncomingWebhookRateLimit is how many messages an incoming webhook may post per minute
+const defaultIncomingWebhookRateLimit = 30
+
+// incomingWebhookRateLimit returns how many messages an incoming webhook may post per minute, configured with
+// INCOMING_WEBHOOK_RATE_LIMIT
+func incomingWebhookRateLimit() int {
+	limit, err := strconv.Atoi(os.Getenv("INCOMING_WEBHOOK_RATE_LIMIT"))
+	if err != nil || limit <= 0 {
+		return defaultIncomingWebhookRateLimit
+	}
+	return limit
+}
+
+// CreateIncomingWebhook adds an incoming webhook to a group conversation along with the integration User its messages
+// are attributed to, the token is only returned here
+func (a *TokenService) CreateIncomingWebhook(actorId string, c *models.Conversation, name string) (*models.IncomingWebhook, error) {
+	if !c.Group || len(c.ParticipantsIds) == 0 {
+		return nil, models.ErrIncomingWebhookConversation
+	}
+	h := &models.IncomingWebhook{
+		ConversationId: c.Id,
+		GroupId:        c.ParticipantsIds[0],
+		UserId:         utilities.GenerateObjectID(),
+		Name:           name,
+		CreatedBy:      actorId,
+	}
+	err := h.Validate("create")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = a.createBotUser(h.UserId, name); err != nil {
+		return nil, err
+	}
+	return a.ihService.IncomingWebhookCreate(h)
+}
+
+// IncomingWebhooks outputs the incoming webhooks of a conversation
+func (a *TokenService) IncomingWebhooks(conversationId string) ([]*models.IncomingWebhook, error) {
+	return a.ihService.IncomingWebhooksFind(&models.IncomingWebhook{ConversationId: conversationId})
+}
+
+// RevokeIncomingWebhook revokes an incoming webhook of a conversation, its integration User stays around as the sender
+// of the messages it posted
+func (a *TokenService) RevokeIncomingWebhook(conversationId string, hookId string) (*models.IncomingWebhook, error) {
+	return a.ihService.IncomingWebhookDelete(&models.IncomingWebhook{Id: hookId, ConversationId: conversationId})
+}
+
+// VerifyIncomingWebhook looks up the incoming webhook of a token and applies its rate limit, returning how long to
+// wait when it is exceeded
+func (a *TokenService) VerifyIncomingWebhook(token string) (*models.IncomingWebhook, time.Duration, error) {
+	h, err := a.ihService.IncomingWebhookVerify(token)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ok, retryAfter := a.limiter.allow("incoming_webhook:"+h.Id, incomingWebhookRateLimit(), time.Minute); !ok {
+		return nil, retryAfter, models.ErrIncomingWebhookRateLimited
+	}
+	return h, 0, nil
+}
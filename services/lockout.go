@@ -0,0 +1,49 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+// signInAttempts returns the login attempt records a sign in is tracked against, skipping the ones that are unknown
+func signInAttempts(email string, ip string) []*models.LoginAttempt {
+	var ls []*models.LoginAttempt
+	if email != "" {
+		ls = append(ls, models.NewLoginAttempt(models.LoginAttemptAccount, email))
+	}
+	if ip != "" {
+		ls = append(ls, models.NewLoginAttempt(models.LoginAttemptIP, ip))
+	}
+	return ls
+}
+
+// SignInLocked returns how long until a sign in for an email from an address is accepted again, zero when it is not locked out
+func (a *TokenService) SignInLocked(email string, ip string) (time.Duration, error) {
+	return a.lService.LoginAttemptsLocked(signInAttempts(email, ip)...)
+}
+
+// SignInFailed records a failed sign in against both the email and the address it came from
+func (a *TokenService) SignInFailed(email string, ip string) error {
+	for _, l := range signInAttempts(email, ip) {
+		if _, err := a.lService.LoginAttemptFailed(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SignInSucceeded forgets the failed sign ins of an email once its password was accepted.
+// The address keeps its count so one valid account can not be used to reset guessing at others.
+func (a *TokenService) SignInSucceeded(email string) error {
+	return a.lService.LoginAttemptsClear(models.NewLoginAttempt(models.LoginAttemptAccount, email))
+}
+
+// Lockouts outputs the accounts and addresses that are currently locked out of signing in
+func (a *TokenService) Lockouts() ([]*models.LoginAttempt, error) {
+	return a.lService.LoginAttemptsFind()
+}
+
+// ClearLockout lets a locked out account or address sign in again
+func (a *TokenService) ClearLockout(id string) (*models.LoginAttempt, error) {
+	return a.lService.LoginAttemptDelete(&models.LoginAttempt{Id: id})
+}
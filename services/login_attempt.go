@@ -0,0 +1,15 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+// LoginAttemptService is an interface used to manage the failed sign in records behind sign in lockouts
+type LoginAttemptService interface {
+	LoginAttemptsLocked(ls ...*models.LoginAttempt) (time.Duration, error)
+	LoginAttemptFailed(l *models.LoginAttempt) (*models.LoginAttempt, error)
+	LoginAttemptsClear(l *models.LoginAttempt) error
+	LoginAttemptsFind() ([]*models.LoginAttempt, error)
+	LoginAttemptDelete(l *models.LoginAttempt) (*models.LoginAttempt, error)
+}
@@ -0,0 +1,11 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// MailService is an interface used to manage the mail outbox
+type MailService interface {
+	MailQueue(m *models.Mail) (*models.Mail, error)
+	MailDeliverPending() (int, error)
+}
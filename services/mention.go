@@ -0,0 +1,11 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// MentionService is an interface used to manage the relevant mention doc controllers
+type MentionService interface {
+	MentionCreate(m *models.Mention) (*models.Mention, error)
+	MentionsFind(m *models.Mention) ([]*models.Mention, error)
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// hereWindow is how recently a participant has to have used a session to be reached by @here
+const hereWindow = 5 * time.Minute
+
+// mentionPattern matches @username, @here and @all when the @ starts a word, so email addresses are not mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.-]*\w)`)
+
+// mentionAllRole returns the group role allowed to mention @all, configured with MENTION_ALL_ROLE
+func mentionAllRole() string {
+	if os.Getenv("MENTION_ALL_ROLE") == models.MentionAllMembers {
+		return models.MentionAllMembers
+	}
+	return models.MentionAllAdmins
+}
+
+// parseMentions returns the usernames mentioned in content, along with whether it mentions @here and @all
+func parseMentions(content string) (usernames []string, here bool, all bool) {
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := match[1]
+		switch strings.ToLower(name) {
+		case "here":
+			here = true
+		case "all":
+			all = true
+		default:
+			if !seen[name] {
+				seen[name] = true
+				usernames = append(usernames, name)
+			}
+		}
+	}
+	return
+}
+
+// mentionMessageService is a MessageService that resolves the mentions of the messages created through it
+type mentionMessageService struct {
+	MessageService
+	uService  UserService
+	gmService GroupMembershipService
+	cService  ConversationService
+	sService  SessionService
+	mnService MentionService
+}
+
+// NewMentionMessageService wraps a MessageService so that the @username, @here and @all mentions of new messages are
+// resolved against the participants of their conversation, stored on them and recorded for every mentioned User
+func NewMentionMessageService(inner MessageService, uService UserService, gmService GroupMembershipService, cService ConversationService, sService SessionService, mnService MentionService) MessageService {
+	return &mentionMessageService{inner, uService, gmService, cService, sService, mnService}
+}
+
+// MessageCreate resolves the mentions of a message and creates it
+func (s *mentionMessageService) MessageCreate(g *models.Message) (*models.Message, error) {
+	mentions, err := s.resolve(g)
+	if err != nil {
+		return nil, err
+	}
+	g.Mentions = mentions
+	m, err := s.MessageService.MessageCreate(g)
+	if err != nil || m.Mentions == nil {
+		return m, err
+	}
+	groupId := ""
+	if m.Group {
+		groupId = m.ReceiverID
+	}
+	for _, userId := range m.Mentions.UserIds {
+		// the message is already stored, a mention that fails to record only leaves it out of the user's mentions
+		s.mnService.MentionCreate(&models.Mention{
+			MessageId:      m.Id,
+			UserId:         userId,
+			SenderId:       m.SenderID,
+			ConversationId: m.ConversationID,
+			GroupId:        groupId,
+		})
+	}
+	return m, nil
+}
+
+// participants returns the ids of the users who can read a message, along with the id of its group if it has one
+func participants(m *models.Message, gmService GroupMembershipService, cService ConversationService) ([]string, string, error) {
+	groupId := ""
+	if m.Group {
+		groupId = m.ReceiverID
+	} else if m.ConversationID != "" {
+		c, err := cService.ConversationFind(&models.Conversation{Id: m.ConversationID})
+		if err != nil {
+			return nil, "", err
+		}
+		if !c.Group || len(c.ParticipantsIds) == 0 {
+			return c.ParticipantsIds, "", nil
+		}
+		groupId = c.ParticipantsIds[0] // the only participant of a group conversation is the group
+	} else {
+		return []string{m.SenderID, m.ReceiverID}, "", nil
+	}
+	gms, err := gmService.GroupMembershipsFind(&models.GroupMembership{GroupId: groupId})
+	if err != nil {
+		return nil, "", err
+	}
+	var ids []string
+	for _, gm := range gms {
+		ids = append(ids, gm.UserId)
+	}
+	return ids, groupId, nil
+}
+
+// active determines whether a User has used a session within hereWindow
+func active(sService SessionService, userId string) bool {
+	sessions, err := sService.SessionsFind(&models.Session{UserId: userId})
+	if err != nil {
+		return false
+	}
+	for _, session := range sessions {
+		if time.Since(session.LastUsedAt) < hereWindow {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the participants a message mentions, other than its sender, or nil when it mentions no one
+func (s *mentionMessageService) resolve(m *models.Message) (*models.Mentions, error) {
+	usernames, here, all := parseMentions(m.Content)
+	if len(usernames) == 0 && !here && !all {
+		return nil, nil
+	}
+	readers, groupId, err := participants(m, s.gmService, s.cService)
+	if err != nil {
+		return nil, err
+	}
+	if all && groupId != "" && mentionAllRole() == models.MentionAllAdmins {
+		gm, err := s.gmService.GroupMembershipFind(&models.GroupMembership{UserId: m.SenderID, GroupId: groupId})
+		if err != nil || !gm.Admin {
+			return nil, models.ErrMentionAllForbidden
+		}
+	}
+	in := make(map[string]bool)
+	for _, id := range readers {
+		in[id] = true
+	}
+	mentions := &models.Mentions{Here: here, All: all}
+	added := map[string]bool{m.SenderID: true}
+	add := func(id string) {
+		if in[id] && !added[id] {
+			added[id] = true
+			mentions.UserIds = append(mentions.UserIds, id)
+		}
+	}
+	for _, name := range usernames {
+		u, err := s.uService.UserFind(&models.User{Username: name})
+		if err != nil || !in[u.Id] {
+			continue // mentions of users who are not in the conversation stay plain text
+		}
+		mentions.Usernames = append(mentions.Usernames, u.Username)
+		add(u.Id)
+	}
+	for _, id := range readers {
+		if all || (here && active(s.sService, id)) {
+			add(id)
+		}
+	}
+	if len(mentions.UserIds) == 0 && len(mentions.Usernames) == 0 && !here && !all {
+		return nil, nil
+	}
+	return mentions, nil
+}
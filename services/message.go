@@ -7,6 +7,7 @@ type MessageService interface {
 	MessageCreate(g *models.Message) (*models.Message, error)
 	MessageFind(g *models.Message) (*models.Message, error)
 	MessagesFind(g *models.Message) ([]*models.Message, error)
+	MessageUpdate(g *models.Message) (*models.Message, error)
 	MessageDelete(g *models.Message) (*models.Message, error)
 	MessageDocInsert(g *models.Message) (*models.Message, error)
 }
@@ -0,0 +1,13 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+// MessageExpiryService is an interface used to track when the messages sent with disappearing messages on expire
+type MessageExpiryService interface {
+	MessageExpiryCreate(e *models.MessageExpiry) (*models.MessageExpiry, error)
+	MessageExpiriesDue(t time.Time) ([]*models.MessageExpiry, error)
+	MessageExpiryDelete(e *models.MessageExpiry) (*models.MessageExpiry, error)
+}
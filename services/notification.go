@@ -0,0 +1,13 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// NotificationService is an interface used to manage the push notification outbox
+type NotificationService interface {
+	NotificationQueue(n *models.Notification) ([]*models.Notification, error)
+	NotificationsFind(n *models.Notification) ([]*models.Notification, error)
+	NotificationDeliver(n *models.Notification) error
+	NotificationDeliverPending() (int, error)
+}
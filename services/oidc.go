@@ -0,0 +1,13 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// OIDCService is an interface used to manage the relevant external sign in and identity doc controllers
+type OIDCService interface {
+	OIDCLoginCreate(l *models.OIDCLogin) (*models.OIDCLogin, error)
+	OIDCLoginConsume(state string) (*models.OIDCLogin, error)
+	IdentityFind(i *models.Identity) (*models.Identity, error)
+	IdentityCreate(i *models.Identity) (*models.Identity, error)
+}
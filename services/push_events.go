@@ -0,0 +1,104 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/utilities"
+	"log"
+	"time"
+)
+
+// pushPreviewLength is how many characters of a message the body of its push notifications shows
+const pushPreviewLength = 140
+
+// PushNotifier queues push notifications of new messages for the participants who are not using the app
+type PushNotifier struct {
+	nService  NotificationService
+	uService  UserService
+	gmService GroupMembershipService
+	cService  ConversationService
+	sService  SessionService
+}
+
+// NewPushNotifier is an exported function used to initialize a new PushNotifier struct
+func NewPushNotifier(nService NotificationService, uService UserService, gmService GroupMembershipService, cService ConversationService, sService SessionService) *PushNotifier {
+	return &PushNotifier{nService, uService, gmService, cService, sService}
+}
+
+// preview shortens the content of a message to the body of a push notification
+func preview(content string) string {
+	runes := []rune(content)
+	if len(runes) <= pushPreviewLength {
+		return content
+	}
+	return string(runes[:pushPreviewLength-1]) + "…"
+}
+
+// mentionedByName determines whether a message mentions a User by their username, which reaches them even in a
+// group they muted
+func (n *PushNotifier) mentionedByName(m *models.Message, userId string) bool {
+	if !m.Mentions.Includes(userId) || len(m.Mentions.Usernames) == 0 {
+		return false
+	}
+	u, err := n.uService.UserFind(&models.User{Id: userId})
+	return err == nil && utilities.IfStrInSlice(u.Username, m.Mentions.Usernames)
+}
+
+// notified determines whether a participant of a message is sent a push notification for it, participants using
+// the app see the message there and members who muted its group are only notified when mentioned by name
+func (n *PushNotifier) notified(m *models.Message, groupId string, userId string) bool {
+	if userId == "" || userId == m.SenderID || active(n.sService, userId) {
+		return false
+	}
+	if groupId != "" {
+		gm, err := n.gmService.GroupMembershipFind(&models.GroupMembership{UserId: userId, GroupId: groupId})
+		if err == nil && gm.Muted(time.Now()) {
+			return n.mentionedByName(m, userId)
+		}
+	}
+	return true
+}
+
+// Notify is an EventHandler that queues a push notification of every new message in the persisted outbox for the
+// devices of its participants before attempting the notifications it queued, notifications that fail are retried by
+// NotificationDeliverPending
+func (n *PushNotifier) Notify(e *models.Event) {
+	if e.Type != models.EventMessageCreated || e.Message == nil {
+		return
+	}
+	go func() {
+		m := e.Message
+		readers, groupId, err := participants(m, n.gmService, n.cService)
+		if err != nil {
+			log.Println("Push notification failed:", err)
+			return
+		}
+		sender := "Someone"
+		if u, err := n.uService.UserFind(&models.User{Id: m.SenderID}); err == nil {
+			sender = u.Username
+		}
+		body := preview(m.Content)
+		if body == "" {
+			body = "Sent an attachment"
+		}
+		var queued []*models.Notification
+		for _, userId := range readers {
+			if !n.notified(m, groupId, userId) {
+				continue
+			}
+			title := sender
+			if m.Mentions.Includes(userId) {
+				title = sender + " mentioned you"
+			}
+			notifications, err := n.nService.NotificationQueue(&models.Notification{UserId: userId, MessageId: m.Id, Title: title, Body: body})
+			if err != nil {
+				log.Println("Push notification enqueue failed:", err)
+			}
+			queued = append(queued, notifications...)
+		}
+		for _, notification := range queued {
+			if err = n.nService.NotificationDeliver(notification); err != nil {
+				log.Println("Push notification delivery failed:", err)
+			}
+		}
+	}()
+}
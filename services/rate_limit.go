@@ -0,0 +1,35 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is an in-memory sliding window rate limiter keyed by an arbitrary string such as an integration id
+type rateLimiter struct {
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+// newRateLimiter returns an empty rateLimiter
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{hits: make(map[string][]time.Time)}
+}
+
+// allow records a hit for key unless it already had limit hits within window, in which case it returns how long to
+// wait until the oldest of those hits expires
+func (l *rateLimiter) allow(key string, limit int, window time.Duration) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	hits := l.hits[key]
+	for len(hits) > 0 && now.Sub(hits[0]) >= window {
+		hits = hits[1:]
+	}
+	if len(hits) >= limit {
+		l.hits[key] = hits
+		return false, window - now.Sub(hits[0])
+	}
+	l.hits[key] = append(hits, now)
+	return true, 0
+}
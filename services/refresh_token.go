@@ -0,0 +1,12 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// RefreshTokenService is an interface used to manage the relevant refresh token doc controllers
+type RefreshTokenService interface {
+	RefreshTokenCreate(userId string, familyId string) (string, error)
+	RefreshTokenRotate(token string) (*models.RefreshToken, string, error)
+	RefreshTokenFamilyRevoke(familyId string) error
+}
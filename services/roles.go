@@ -0,0 +1,47 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// ChangeRole promotes or demotes a User on behalf of a root admin and records the change in the audit trail.
+// Admins can not change their own role, which keeps at least one root admin around, and bots keep the bot role.
+func (a *TokenService) ChangeRole(actorId string, userId string, role string) (*models.User, error) {
+	if !models.AssignableRole(role) {
+		return nil, models.ErrInvalidRole
+	}
+	if actorId == userId {
+		return nil, models.ErrRoleChangeForbidden
+	}
+	u, err := a.uService.UserFind(&models.User{Id: userId})
+	if err != nil {
+		return nil, err
+	}
+	if u.HasRole(models.RoleBot) {
+		return nil, models.ErrRoleChangeForbidden
+	}
+	if u.HasRole(role) {
+		return u, nil
+	}
+	from := u.Role
+	u, err = a.uService.UserRoleSet(&models.User{Id: u.Id, Role: role})
+	if err != nil {
+		return nil, err
+	}
+	_, err = a.eService.AuditEventCreate(&models.AuditEvent{
+		Action:   models.AuditRoleChange,
+		ActorId:  actorId,
+		TargetId: u.Id,
+		From:     from,
+		To:       role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// AuditEvents outputs the audit trail, optionally only the events of one target or action
+func (a *TokenService) AuditEvents(filter *models.AuditEvent) ([]*models.AuditEvent, error) {
+	return a.eService.AuditEventsFind(filter)
+}
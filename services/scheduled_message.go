@@ -0,0 +1,18 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"time"
+)
+
+// ScheduledMessageService is an interface used to manage the messages users scheduled to be sent later
+type ScheduledMessageService interface {
+	ScheduledMessageCreate(s *models.ScheduledMessage) (*models.ScheduledMessage, error)
+	ScheduledMessagesFind(s *models.ScheduledMessage) ([]*models.ScheduledMessage, error)
+	ScheduledMessageFind(s *models.ScheduledMessage) (*models.ScheduledMessage, error)
+	ScheduledMessageUpdate(s *models.ScheduledMessage) (*models.ScheduledMessage, error)
+	ScheduledMessageDelete(s *models.ScheduledMessage) (*models.ScheduledMessage, error)
+	ScheduledMessagesDue(t time.Time) ([]*models.ScheduledMessage, error)
+	ScheduledMessageClaim(s *models.ScheduledMessage) (*models.ScheduledMessage, error)
+	ScheduledMessageDeliveryUpdate(s *models.ScheduledMessage) error
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"github.com/ablancas22/messenger-backend/models"
+	"log"
+	"time"
+)
+
+// scheduledMessageMaxAttempts is how many times a scheduled message is tried before it is marked as failed
+const scheduledMessageMaxAttempts = 5
+
+// Scheduler delivers the messages users scheduled once their send time passes
+type Scheduler struct {
+	smService ScheduledMessageService
+	tService  MessageService
+}
+
+// NewScheduler is an exported function used to initialize a new Scheduler struct, scheduled messages are delivered
+// through tService so that they are handled like the messages users send right away
+func NewScheduler(smService ScheduledMessageService, tService MessageService) *Scheduler {
+	return &Scheduler{smService, tService}
+}
+
+// deliver creates the Message a scheduled message is delivered as. The Message has the id of the scheduled message,
+// so a Message an earlier attempt or another server instance already created is not created again.
+func (s *Scheduler) deliver(sm *models.ScheduledMessage) error {
+	sm.Attempts++
+	_, err := s.tService.MessageFind(&models.Message{Id: sm.Id})
+	if err != nil {
+		_, err = s.tService.MessageCreate(sm.Message())
+	}
+	switch {
+	case err == nil || errors.Is(err, models.ErrMessageExists):
+		sm.Status = models.ScheduledSent
+		sm.SentAt = time.Now().UTC()
+		return nil
+	case errors.Is(err, models.ErrMentionAllForbidden):
+		sm.Status = models.ScheduledFailed
+	case sm.Attempts >= scheduledMessageMaxAttempts:
+		sm.Status = models.ScheduledFailed
+	default:
+		sm.Status = models.ScheduledPending
+	}
+	sm.LastError = err.Error()
+	return err
+}
+
+// DeliverDue delivers the scheduled messages whose send time has passed, including the ones that came due while
+// the app was not running, returning how many were delivered. Every message is claimed before it is delivered, so it
+// is delivered with its latest edit and can not be edited while it is being delivered.
+func (s *Scheduler) DeliverDue() (int, error) {
+	due, err := s.smService.ScheduledMessagesDue(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, sm := range due {
+		if sm, err = s.smService.ScheduledMessageClaim(sm); err != nil {
+			return delivered, err
+		}
+		if sm == nil {
+			continue
+		}
+		if err = s.deliver(sm); err == nil {
+			delivered++
+		} else {
+			log.Println("Scheduled message "+sm.Id+" failed:", err)
+		}
+		if err = s.smService.ScheduledMessageDeliveryUpdate(sm); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
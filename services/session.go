@@ -0,0 +1,15 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// SessionService is an interface used to manage the relevant session doc controllers
+type SessionService interface {
+	SessionCreate(s *models.Session) (*models.Session, error)
+	SessionFind(s *models.Session) (*models.Session, error)
+	SessionFindCached(s *models.Session) (*models.Session, error)
+	SessionsFind(s *models.Session) ([]*models.Session, error)
+	SessionTouch(s *models.Session) error
+	SessionRevoke(s *models.Session) (*models.Session, error)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"github.com/ablancas22/messenger-backend/models"
+	"github.com/ablancas22/messenger-backend/oidc"
+	"os"
+	"strings"
+	"time"
+)
+
+// oidcTimeout bounds the requests made to an external provider during a sign in
+const oidcTimeout = 30 * time.Second
+
+// OIDCProviders outputs the names of the external providers users can sign in with
+func (a *TokenService) OIDCProviders() ([]string, error) {
+	return oidc.Names()
+}
+
+// StartOIDCLogin begins a sign in with an external provider, returning the URL the user is sent to and the
+// pending sign in, whose state the user comes back with
+func (a *TokenService) StartOIDCLogin(provider string) (string, *models.OIDCLogin, error) {
+	p, err := oidc.Lookup(provider)
+	if err != nil {
+		return "", nil, err
+	}
+	l := &models.OIDCLogin{Provider: p.Name}
+	if l.State, err = oidc.RandomString(); err != nil {
+		return "", nil, err
+	}
+	if l.Nonce, err = oidc.RandomString(); err != nil {
+		return "", nil, err
+	}
+	if l.Verifier, err = oidc.RandomString(); err != nil {
+		return "", nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+	authURL, err := p.AuthCodeURL(ctx, l.State, l.Nonce, l.Verifier)
+	if err != nil {
+		return "", nil, err
+	}
+	l, err = a.oService.OIDCLoginCreate(l)
+	if err != nil {
+		return "", nil, err
+	}
+	return authURL, l, nil
+}
+
+// CompleteOIDCLogin redeems the authorization code an external provider sent the user back with and returns the
+// User the provider account belongs to. Provider accounts that are not linked yet are linked to the User with the
+// same email when both the provider and the User verified it, otherwise a new User is created when REGISTRATION is on.
+func (a *TokenService) CompleteOIDCLogin(provider string, code string, state string) (*models.User, error) {
+	p, err := oidc.Lookup(provider)
+	if err != nil {
+		return nil, err
+	}
+	l, err := a.oService.OIDCLoginConsume(state)
+	if err != nil {
+		return nil, err
+	}
+	if l.Provider != p.Name {
+		return nil, models.ErrOIDCLoginInvalid
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+	claims, err := p.Exchange(ctx, code, l.Verifier, l.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := a.oService.IdentityFind(&models.Identity{Provider: p.Name, Subject: claims.Subject})
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		return a.uService.UserFind(&models.User{Id: identity.UserId})
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, models.ErrIdentityEmailUnverified
+	}
+	u, err := a.oidcUser(claims)
+	if err != nil {
+		return nil, err
+	}
+	_, err = a.oService.IdentityCreate(&models.Identity{UserId: u.Id, Provider: p.Name, Subject: claims.Subject, Email: claims.Email})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// oidcUser returns the User a new provider account is linked to, creating one when no User has its email.
+// An existing User is only linked once they verified the email, so nobody can claim an account by registering
+// its email address at a provider before the owner does.
+func (a *TokenService) oidcUser(claims *oidc.Claims) (*models.User, error) {
+	users, err := a.uService.UsersFind(&models.User{Email: claims.Email})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) > 0 {
+		if !users[0].EmailVerified() {
+			return nil, models.ErrIdentityEmailUnverified
+		}
+		return users[0], nil
+	}
+	if os.Getenv("REGISTRATION") == "OFF" {
+		return nil, models.ErrIdentityRegistrationClosed
+	}
+	// the user signs in with the provider, the random password only exists to satisfy the password policy
+	pw, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	username := claims.PreferredUsername
+	if username == "" {
+		username = strings.Split(claims.Email, "@")[0]
+	}
+	u, err := a.uService.UserCreate(&models.User{Username: username, Email: claims.Email, Password: pw})
+	if err != nil {
+		return nil, err
+	}
+	return a.uService.UserVerifyEmail(u)
+}
@@ -4,36 +4,159 @@ import (
 	"github.com/ablancas22/messenger-backend/auth"
 	"github.com/ablancas22/messenger-backend/models"
 	"github.com/ablancas22/messenger-backend/utilities"
+	"log"
 	"net/http"
+	"os"
 	"time"
 )
 
+// defaultAccessTokenTTL is the lifetime of a session token, clients renew it with their refresh token
+const defaultAccessTokenTTL = 15 * time.Minute
+
+// challengeTTL is how long a user has to enter their second factor once their password was accepted
+const challengeTTL = 5 * time.Minute
+
 // TokenService is used by the app to manage db auth functionality
 type TokenService struct {
-	uService UserService
-	gService GroupService
-	bService BlacklistService
+	uService  UserService
+	gService  GroupService
+	bService  BlacklistService
+	rService  RefreshTokenService
+	sService  SessionService
+	kService  APIKeyService
+	tService  TwoFactorService
+	mService  MailService
+	lService  LoginAttemptService
+	oService  OIDCService
+	eService  AuditEventService
+	btService BotService
+	ihService IncomingWebhookService
+	limiter   *rateLimiter
 }
 
 // NewTokenService is an exported function used to initialize a new authService struct
-func NewTokenService(uService UserService, gService GroupService, bService BlacklistService) *TokenService {
-	return &TokenService{uService, gService, bService}
+func NewTokenService(uService UserService, gService GroupService, bService BlacklistService, rService RefreshTokenService, sService SessionService, kService APIKeyService, tService TwoFactorService, mService MailService, lService LoginAttemptService, oService OIDCService, eService AuditEventService, btService BotService, ihService IncomingWebhookService) *TokenService {
+	return &TokenService{uService, gService, bService, rService, sService, kService, tService, mService, lService, oService, eService, btService, ihService, newRateLimiter()}
+}
+
+// accessTokenTTL returns the lifetime of a session token, configured with ACCESS_TOKEN_TTL
+func accessTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultAccessTokenTTL
+	}
+	return ttl
+}
+
+// requireAdminTwoFactor determines whether the REQUIRE_ADMIN_2FA policy is turned on
+func requireAdminTwoFactor() bool {
+	return os.Getenv("REQUIRE_ADMIN_2FA") == "true"
+}
+
+// roleTypeAllowed determines whether the role of a User may use the routes of a middleware roleType,
+// admin routes are kept for root admins and support routes for root admins and support staff
+func roleTypeAllowed(roleType string, u *models.User) bool {
+	switch roleType {
+	case "Admin":
+		return u.HasRole(models.RoleRootAdmin)
+	case "Support":
+		return u.HasRole(models.RoleRootAdmin, models.RoleSupport)
+	}
+	return true
+}
+
+// verifyAdminTwoFactor enforces the admin policy, which keeps users off admin and support routes until they enable 2FA
+func (a *TokenService) verifyAdminTwoFactor(userId string) error {
+	if !requireAdminTwoFactor() {
+		return nil
+	}
+	enabled, err := a.TwoFactorEnabled(userId)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return models.ErrTwoFactorRequired
+	}
+	return nil
+}
+
+// verifyTokenUser verifies Token's User, returning the User so routes check their current role instead of the one
+// the Token was issued with, and records that the User is active. The User is read through a short-lived cache.
+func (a *TokenService) verifyTokenUser(decodedToken *auth.TokenData) (*models.User, error) {
+	user, err := a.uService.UserFindCached(decodedToken.ToUser())
+	if err != nil {
+		return nil, err
+	}
+	if err = a.uService.UserTouch(user); err != nil {
+		log.Println("User activity update failed:", err)
+	}
+	return user, nil
 }
 
-// verifyTokenUser verifies Token's User
-func (a *TokenService) verifyTokenUser(decodedToken *auth.TokenData) (bool, string) {
-	tUser := decodedToken.ToUser()
-	_, err := a.uService.UserFind(tUser)
+// verifyTokenSession verifies that the session a Token was issued for has not been signed out. Tokens without a session
+// are rejected, as they were issued before sessions and signing out could never revoke them.
+func (a *TokenService) verifyTokenSession(decodedToken *auth.TokenData) error {
+	if decodedToken.SessionId == "" {
+		return models.ErrTokenNoSession
+	}
+	session, err := a.sService.SessionFindCached(&models.Session{Id: decodedToken.SessionId, UserId: decodedToken.UserId})
 	if err != nil {
-		return false, err.Error()
+		return models.ErrSessionRevoked
 	}
-	return true, "No Error"
+	if !session.Active() {
+		return models.ErrSessionRevoked
+	}
+	return a.sService.SessionTouch(session)
 }
 
-// tokenVerifyMiddleWare inputs the route handler function along with User roleType to verify User token and permissions
-func (a *TokenService) tokenVerifyMiddleWare(roleType string, next http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+// apiKeyVerifyMiddleWare verifies an API-Key header holds every scope a route requires before calling the route handler
+func (a *TokenService) apiKeyVerifyMiddleWare(roleType string, scopes []string, next http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	if len(scopes) == 0 {
+		utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: "api keys can not access this route"})
+		return
+	}
+	key, err := a.kService.APIKeyVerify(r.Header.Get("API-Key"))
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if !key.HasScopes(scopes...) {
+		utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: "api key is missing the required scope"})
+		return
+	}
+	user, err := a.uService.UserFindCached(&models.User{Id: key.UserId})
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	if !roleTypeAllowed(roleType, user) {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: "Invalid Token"})
+		return
+	}
+	if roleType != "Member" {
+		if err = a.verifyAdminTwoFactor(user.Id); err != nil {
+			utilities.RespondWithError(w, http.StatusForbidden, utilities.JWTError{Message: err.Error()})
+			return
+		}
+	}
+	tokenData, err := auth.InitUserToken(user)
+	if err != nil {
+		utilities.RespondWithError(w, http.StatusUnauthorized, utilities.JWTError{Message: err.Error()})
+		return
+	}
+	tokenData.APIKeyId = key.Id
+	next.ServeHTTP(w, auth.WithTokenData(r, tokenData))
+}
+
+// tokenVerifyMiddleWare inputs the route handler function along with User roleType to verify User token and permissions.
+// Requests authenticated with an API-Key instead of an Auth-Token are only accepted when the route lists scopes.
+func (a *TokenService) tokenVerifyMiddleWare(roleType string, scopes []string, next http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
 	var errorObject utilities.JWTError
 	authToken := r.Header.Get("Auth-Token")
+	if authToken == "" && r.Header.Get("API-Key") != "" {
+		a.apiKeyVerifyMiddleWare(roleType, scopes, next, w, r)
+		return
+	}
 	if a.bService.CheckTokenBlacklist(authToken) {
 		errorObject.Message = "Invalid Token"
 		utilities.RespondWithError(w, http.StatusUnauthorized, errorObject)
@@ -45,54 +168,233 @@ func (a *TokenService) tokenVerifyMiddleWare(roleType string, next http.HandlerF
 		utilities.RespondWithError(w, http.StatusUnauthorized, errorObject)
 		return
 	}
-	verified, verifyMsg := a.verifyTokenUser(decodedToken)
-	if verified {
-		if roleType == "Admin" && decodedToken.RootAdmin {
-			next.ServeHTTP(w, r)
-		} else if roleType != "Admin" {
-			next.ServeHTTP(w, r)
-		} else {
-			errorObject.Message = "Invalid Token"
-			utilities.RespondWithError(w, http.StatusUnauthorized, errorObject)
-			return
-		}
-	} else {
-		errorObject.Message = verifyMsg
+	if err = a.verifyTokenSession(decodedToken); err != nil {
+		errorObject.Message = err.Error()
 		utilities.RespondWithError(w, http.StatusUnauthorized, errorObject)
 		return
 	}
+	user, err := a.verifyTokenUser(decodedToken)
+	if err != nil {
+		errorObject.Message = err.Error()
+		utilities.RespondWithError(w, http.StatusUnauthorized, errorObject)
+		return
+	}
+	// promotions and demotions apply to tokens that were issued before them
+	decodedToken.Role, decodedToken.RootAdmin = user.Role, user.RootAdmin
+	if !roleTypeAllowed(roleType, user) {
+		errorObject.Message = "Invalid Token"
+		utilities.RespondWithError(w, http.StatusUnauthorized, errorObject)
+		return
+	}
+	if roleType != "Member" {
+		if err = a.verifyAdminTwoFactor(decodedToken.UserId); err != nil {
+			errorObject.Message = err.Error()
+			utilities.RespondWithError(w, http.StatusForbidden, errorObject)
+			return
+		}
+	}
+	next.ServeHTTP(w, auth.WithTokenData(r, decodedToken))
 }
 
-// GenerateToken outputs an auth token string for an inputted User
-func (a *TokenService) GenerateToken(u *models.User, tType string) (string, error) {
-	expDT := time.Now().Add(time.Hour * 1).Unix() // Default 1 hour expiration for session token
-	if tType == "api" {
-		expDT = time.Now().Add(time.Hour * 4380).Unix() // 6 month expiration for api key
-	}
+// GenerateSessionToken outputs a session token bound to a device session for an inputted User
+func (a *TokenService) GenerateSessionToken(u *models.User, sessionId string) (string, error) {
 	tData, err := auth.InitUserToken(u)
 	if err != nil {
 		return "", err
 	}
-	return tData.CreateToken(expDT)
+	tData.SessionId = sessionId
+	return tData.CreateToken(time.Now().Add(accessTokenTTL()).Unix())
+}
+
+// CreateSession records a new device session for an inputted User and outputs its session and refresh tokens
+func (a *TokenService) CreateSession(u *models.User, s *models.Session) (string, string, error) {
+	s.UserId = u.Id
+	session, err := a.sService.SessionCreate(s)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := a.rService.RefreshTokenCreate(u.Id, session.Id)
+	if err != nil {
+		return "", "", err
+	}
+	authToken, err := a.GenerateSessionToken(u, session.Id)
+	if err != nil {
+		return "", "", err
+	}
+	return authToken, refreshToken, nil
+}
+
+// TwoFactorEnabled determines whether a User has to enter a second factor to sign in
+func (a *TokenService) TwoFactorEnabled(userId string) (bool, error) {
+	t, err := a.tService.TwoFactorFind(userId)
+	if err != nil {
+		return false, err
+	}
+	return t.Enabled, nil
+}
+
+// CreateTwoFactorChallenge outputs a short-lived challenge token for a User whose password was accepted,
+// which CompleteTwoFactorSignIn exchanges for a session along with a second factor. The challenge is invalidated once
+// too many wrong codes were entered for the User.
+func (a *TokenService) CreateTwoFactorChallenge(u *models.User) (string, time.Time, error) {
+	check, err := a.tService.TwoFactorChallenge(u.Id)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(challengeTTL)
+	challenge := &auth.ActionToken{UserId: u.Id, Action: auth.ActionTwoFactor, Check: check}
+	challengeToken, err := challenge.CreateToken(expiresAt.Unix())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return challengeToken, expiresAt, nil
+}
+
+// CompleteTwoFactorSignIn checks the second factor of a sign in challenge and records a new device session
+func (a *TokenService) CompleteTwoFactorSignIn(challengeToken string, code string, s *models.Session) (*models.User, string, string, error) {
+	challenge, err := auth.DecodeActionToken(challengeToken, auth.ActionTwoFactor)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err = a.tService.TwoFactorVerifyChallenge(challenge.UserId, challenge.Check, code); err != nil {
+		return nil, "", "", err
+	}
+	u, err := a.uService.UserFind(&models.User{Id: challenge.UserId})
+	if err != nil {
+		return nil, "", "", err
+	}
+	authToken, refreshToken, err := a.CreateSession(u, s)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return u, authToken, refreshToken, nil
 }
 
-// AdminTokenVerifyMiddleWare is used to verify that the requester is a valid admin
-func (a *TokenService) AdminTokenVerifyMiddleWare(next http.HandlerFunc) http.HandlerFunc {
+// TwoFactor outputs the two-factor status of a User
+func (a *TokenService) TwoFactor(userId string) (*models.TwoFactor, error) {
+	return a.tService.TwoFactorFind(userId)
+}
+
+// EnrollTwoFactor starts a TOTP enrollment for a User, labelled with their email in authenticator apps
+func (a *TokenService) EnrollTwoFactor(userId string) (*models.TwoFactor, error) {
+	u, err := a.uService.UserFind(&models.User{Id: userId})
+	if err != nil {
+		return nil, err
+	}
+	return a.tService.TwoFactorEnroll(u.Id, u.Email)
+}
+
+// ConfirmTwoFactor enables two-factor authentication for a User and outputs their recovery codes
+func (a *TokenService) ConfirmTwoFactor(userId string, code string) (*models.TwoFactor, error) {
+	return a.tService.TwoFactorConfirm(userId, code)
+}
+
+// DisableTwoFactor turns off two-factor authentication for a User, which the admin policy does not allow root admins
+// and support staff
+func (a *TokenService) DisableTwoFactor(u *models.User, code string) error {
+	if u.HasRole(models.RoleRootAdmin, models.RoleSupport) && requireAdminTwoFactor() {
+		return models.ErrTwoFactorRequired
+	}
+	return a.tService.TwoFactorDisable(u.Id, code)
+}
+
+// RefreshAccessToken rotates a refresh token and outputs a new session token along with the replacement refresh token
+func (a *TokenService) RefreshAccessToken(refreshToken string) (string, string, error) {
+	rt, newRefreshToken, err := a.rService.RefreshTokenRotate(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	session, err := a.sService.SessionFind(&models.Session{Id: rt.FamilyId, UserId: rt.UserId})
+	if err != nil || !session.Active() {
+		_ = a.rService.RefreshTokenFamilyRevoke(rt.FamilyId)
+		return "", "", models.ErrSessionRevoked
+	}
+	if err = a.sService.SessionTouch(session); err != nil {
+		return "", "", err
+	}
+	u, err := a.uService.UserFind(&models.User{Id: rt.UserId})
+	if err != nil {
+		return "", "", err
+	}
+	authToken, err := a.GenerateSessionToken(u, session.Id)
+	if err != nil {
+		return "", "", err
+	}
+	return authToken, newRefreshToken, nil
+}
+
+// Sessions outputs the active device sessions of a User
+func (a *TokenService) Sessions(userId string) ([]*models.Session, error) {
+	return a.sService.SessionsFind(&models.Session{UserId: userId})
+}
+
+// RevokeSession signs out a device session, invalidating its session tokens and refresh tokens
+func (a *TokenService) RevokeSession(s *models.Session) error {
+	session, err := a.sService.SessionRevoke(s)
+	if err != nil {
+		return err
+	}
+	return a.rService.RefreshTokenFamilyRevoke(session.Id)
+}
+
+// RevokeSessions signs out every device session of a User
+func (a *TokenService) RevokeSessions(userId string) error {
+	sessions, err := a.Sessions(userId)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err = a.RevokeSession(session); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdminTokenVerifyMiddleWare is used to verify that the requester is a valid admin, optionally through an API key with the listed scopes
+func (a *TokenService) AdminTokenVerifyMiddleWare(next http.HandlerFunc, scopes ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.tokenVerifyMiddleWare("Admin", scopes, next, w, r)
+		return
+	}
+}
+
+// SupportTokenVerifyMiddleWare is used to verify that the requester is a valid admin or support staff, optionally through an API key with the listed scopes
+func (a *TokenService) SupportTokenVerifyMiddleWare(next http.HandlerFunc, scopes ...string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		a.tokenVerifyMiddleWare("Admin", next, w, r)
+		a.tokenVerifyMiddleWare("Support", scopes, next, w, r)
 		return
 	}
 }
 
-// MemberTokenVerifyMiddleWare is used to verify that a requester is authenticated
-func (a *TokenService) MemberTokenVerifyMiddleWare(next http.HandlerFunc) http.HandlerFunc {
+// MemberTokenVerifyMiddleWare is used to verify that a requester is authenticated, optionally through an API key with the listed scopes
+func (a *TokenService) MemberTokenVerifyMiddleWare(next http.HandlerFunc, scopes ...string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		a.tokenVerifyMiddleWare("Member", next, w, r)
+		a.tokenVerifyMiddleWare("Member", scopes, next, w, r)
 		return
 	}
 }
 
-// BlacklistAuthToken is used to blacklist an unexpired token
+// CreateAPIKey creates a new named and scoped API key for a User
+func (a *TokenService) CreateAPIKey(k *models.APIKey) (*models.APIKey, error) {
+	return a.kService.APIKeyCreate(k)
+}
+
+// APIKeys outputs the API keys of a User
+func (a *TokenService) APIKeys(userId string) ([]*models.APIKey, error) {
+	return a.kService.APIKeysFind(&models.APIKey{UserId: userId})
+}
+
+// RevokeAPIKey revokes one of a Users API keys
+func (a *TokenService) RevokeAPIKey(k *models.APIKey) (*models.APIKey, error) {
+	return a.kService.APIKeyDelete(k)
+}
+
+// BlacklistAuthToken is used to blacklist an unexpired token until it expires
 func (a *TokenService) BlacklistAuthToken(authToken string) error {
-	return a.bService.BlacklistAuthToken(authToken)
+	expiresAt, err := auth.TokenExpiry(authToken)
+	if err != nil {
+		return err
+	}
+	return a.bService.BlacklistAuthToken(authToken, expiresAt)
 }
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// TwoFactorService is an interface used to manage the relevant two factor doc controllers
+type TwoFactorService interface {
+	TwoFactorFind(userId string) (*models.TwoFactor, error)
+	TwoFactorEnroll(userId string, account string) (*models.TwoFactor, error)
+	TwoFactorConfirm(userId string, code string) (*models.TwoFactor, error)
+	TwoFactorVerify(userId string, code string) error
+	TwoFactorChallenge(userId string) (string, error)
+	TwoFactorVerifyChallenge(userId string, challenge string, code string) error
+	TwoFactorDisable(userId string, code string) error
+}
@@ -12,6 +12,12 @@ type UserService interface {
 	UserDelete(u *models.User) (*models.User, error)
 	UsersFind(u *models.User) ([]*models.User, error)
 	UserFind(u *models.User) (*models.User, error)
+	UserFindCached(u *models.User) (*models.User, error)
 	UserUpdate(u *models.User) (*models.User, error)
+	UserImageSet(u *models.User) (*models.User, error)
+	UserVerifyEmail(u *models.User) (*models.User, error)
+	UserPasswordReset(u *models.User, newPassword string) (*models.User, error)
+	UserRoleSet(u *models.User) (*models.User, error)
+	UserTouch(u *models.User) error
 	UserDocInsert(u *models.User) (*models.User, error)
 }
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+)
+
+// WebhookService is an interface used to manage group webhooks and the queue of their deliveries
+type WebhookService interface {
+	WebhookCreate(w *models.Webhook) (*models.Webhook, error)
+	WebhooksFind(w *models.Webhook) ([]*models.Webhook, error)
+	WebhookFind(w *models.Webhook) (*models.Webhook, error)
+	WebhookUpdate(w *models.Webhook) (*models.Webhook, error)
+	WebhookDelete(w *models.Webhook) (*models.Webhook, error)
+	WebhookDeliveriesFind(d *models.WebhookDelivery) ([]*models.WebhookDelivery, error)
+	WebhookEnqueue(e *models.Event) ([]*models.WebhookDelivery, error)
+	WebhookDeliver(d *models.WebhookDelivery) error
+	WebhookDeliverPending() (int, error)
+}
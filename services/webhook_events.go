@@ -0,0 +1,56 @@
+package services
+
+import (
+	"github.com/ablancas22/messenger-backend/models"
+	"log"
+)
+
+// WebhookNotifier queues the events of groups and their conversations for delivery to the webhooks of the group
+type WebhookNotifier struct {
+	whService WebhookService
+	cService  ConversationService
+}
+
+// NewWebhookNotifier is an exported function used to initialize a new WebhookNotifier struct
+func NewWebhookNotifier(whService WebhookService, cService ConversationService) *WebhookNotifier {
+	return &WebhookNotifier{whService, cService}
+}
+
+// groupId returns the id of the group an event happened in, or an empty string for direct messages
+func (n *WebhookNotifier) groupId(e *models.Event) string {
+	if e.GroupId != "" || e.Message == nil {
+		return e.GroupId
+	}
+	if e.Message.Group {
+		return e.Message.ReceiverID
+	}
+	if e.Message.ConversationID != "" {
+		c, err := n.cService.ConversationFind(&models.Conversation{Id: e.Message.ConversationID})
+		if err == nil && c.Group && len(c.ParticipantsIds) > 0 {
+			return c.ParticipantsIds[0]
+		}
+	}
+	return ""
+}
+
+// Notify is an EventHandler that queues group events in the persisted delivery queue before attempting the deliveries
+// it queued, deliveries that fail are retried by WebhookDeliverPending
+func (n *WebhookNotifier) Notify(e *models.Event) {
+	go func() {
+		groupId := n.groupId(e)
+		if groupId == "" {
+			return
+		}
+		ev := *e
+		ev.GroupId = groupId
+		queued, err := n.whService.WebhookEnqueue(&ev)
+		if err != nil {
+			log.Println("Webhook enqueue failed:", err)
+		}
+		for _, d := range queued {
+			if err = n.whService.WebhookDeliver(d); err != nil {
+				log.Println("Webhook delivery failed:", err)
+			}
+		}
+	}()
+}
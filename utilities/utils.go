@@ -26,20 +26,20 @@ func GenerateObjectID() string {
 // HandleOptionsRequest handles incoming OPTIONS request
 func HandleOptionsRequest(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Add("Access-Control-Allow-Headers", "Content-Type, Auth-Token, API-Key")
-	w.Header().Add("Access-Control-Expose-Headers", "Content-Type, Auth-Token, API-Key")
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type, Auth-Token, API-Key, Refresh-Token, Upload-Checksum")
+	w.Header().Add("Access-Control-Expose-Headers", "Content-Type, Auth-Token, API-Key, Refresh-Token, Upload-Offset")
 	w.Header().Add("Access-Control-Allow-Origin", "*")
-	w.Header().Add("Access-Control-Allow-Methods", "GET,DELETE,POST,PATCH")
+	w.Header().Add("Access-Control-Allow-Methods", "GET,DELETE,POST,PUT,PATCH")
 	w.WriteHeader(http.StatusOK)
 }
 
 // SetResponseHeaders sets the response headers being sent back to the client
 func SetResponseHeaders(w http.ResponseWriter, authToken string, apiKey string) http.ResponseWriter {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Add("Access-Control-Allow-Headers", "Content-Type, Auth-Token, API-Key")
-	w.Header().Add("Access-Control-Expose-Headers", "Content-Type, Auth-Token, API-Key")
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type, Auth-Token, API-Key, Refresh-Token, Upload-Checksum")
+	w.Header().Add("Access-Control-Expose-Headers", "Content-Type, Auth-Token, API-Key, Refresh-Token, Upload-Offset")
 	w.Header().Add("Access-Control-Allow-Origin", "*")
-	w.Header().Add("Access-Control-Allow-Methods", "GET,DELETE,POST,PATCH")
+	w.Header().Add("Access-Control-Allow-Methods", "GET,DELETE,POST,PUT,PATCH")
 	if authToken != "" {
 		w.Header().Add("Auth-Token", authToken)
 	}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a delivery would connect to a loopback, private, link-local or otherwise
+// internal address
+var ErrForbiddenAddress = errors.New("webhook address is not allowed")
+
+// internalNetworks are the ranges not covered by the net.IP helpers that must not be reachable from a delivery
+var internalNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("64:ff9b::/96"),
+}
+
+// mustParseCIDR parses a CIDR literal, panicking if it is malformed
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// forbidden determines whether ip is an internal address a delivery must not connect to, this covers the cloud
+// metadata address 169.254.169.254 as it is link-local
+func forbidden(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, n := range internalNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// guard is a net.Dialer Control function that refuses to connect to internal addresses. It runs after the host has
+// been resolved, so a name that resolves to an internal address, or is rebound to one, is refused as well. Internal
+// addresses are allowed when WEBHOOK_ALLOW_PRIVATE_NETWORKS is true.
+func guard(network, address string, _ syscall.RawConn) error {
+	if os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || forbidden(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// NewClient returns an http.Client for deliveries to user supplied URLs that refuses to connect to internal addresses.
+// It does not use a proxy, redirects are followed but go through the same check.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Control: guard}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
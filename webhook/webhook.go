@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the timestamp, a dot and the body
+	SignatureHeader = "X-Messenger-Signature"
+	// TimestampHeader carries the unix time the delivery was signed at
+	TimestampHeader = "X-Messenger-Timestamp"
+	// EventHeader carries the type of the event in the body
+	EventHeader = "X-Messenger-Event"
+	// maxResponseSize caps how much of a response body Call reads
+	maxResponseSize = 65536
+)
+
+// ErrInvalidSignature is returned by Verify when a signature does not match the body
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// NewSecret returns a random hex secret for signing webhook deliveries
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign returns the value of the signature header for a body sent at timestamp ts
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers of a delivery against its body
+func Verify(secret string, header http.Header, body []byte) error {
+	ts, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	sig := header.Get(SignatureHeader)
+	if !strings.HasPrefix(sig, "sha256=") || !hmac.Equal([]byte(sig), []byte(Sign(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Post sends a signed JSON body to url, any response outside of 2xx is returned as an error
+func Post(ctx context.Context, client *http.Client, url string, secret string, eventType string, body []byte) error {
+	_, err := Call(ctx, client, url, secret, eventType, body)
+	return err
+}
+
+// Call sends a signed JSON body to url like Post and returns up to maxResponseSize bytes of the response body
+func Call(ctx context.Context, client *http.Client, url string, secret string, eventType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, ts, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, errors.New("webhook responded with status " + strconv.Itoa(resp.StatusCode))
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+}
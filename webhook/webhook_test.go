@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"type":"message.created"}`)
+	sig := Sign("secret", 1700000000, body)
+	tests := []struct {
+		name    string
+		secret  string
+		ts      string
+		sig     string
+		body    []byte
+		wantErr bool
+	}{
+		{"valid", "secret", "1700000000", sig, body, false},
+		{"wrong secret", "other", "1700000000", sig, body, true},
+		{"wrong timestamp", "secret", "1700000001", sig, body, true},
+		{"tampered body", "secret", "1700000000", sig, []byte(`{"type":"message.deleted"}`), true},
+		{"missing timestamp", "secret", "", sig, body, true},
+		{"missing signature", "secret", "1700000000", "", body, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(TimestampHeader, tt.ts)
+			h.Set(SignatureHeader, tt.sig)
+			if err := Verify(tt.secret, h, tt.body); (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPost(t *testing.T) {
+	status := http.StatusNoContent
+	got := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if r.Header.Get(EventHeader) != "message.created" {
+			t.Errorf("Post() event header = %q", r.Header.Get(EventHeader))
+		}
+		got <- Verify("secret", r.Header, b)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Post(ctx, srv.Client(), srv.URL, "secret", "message.created", []byte(`{}`)); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if err := <-got; err != nil {
+		t.Errorf("Post() signature error = %v", err)
+	}
+	status = http.StatusInternalServerError
+	if err := Post(ctx, srv.Client(), srv.URL, "secret", "message.created", []byte(`{}`)); err == nil {
+		t.Errorf("Post() expected an error for a 500 response")
+	}
+	<-got
+}
+
+func TestCall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if err := Verify("secret", r.Header, b); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"text":"pong"}`))
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	body, err := Call(ctx, srv.Client(), srv.URL, "secret", "command.invoked", []byte(`{}`))
+	if err != nil || string(body) != `{"text":"pong"}` {
+		t.Fatalf("Call() = %q, %v", body, err)
+	}
+	if _, err = Call(ctx, srv.Client(), srv.URL, "wrong", "command.invoked", []byte(`{}`)); err == nil {
+		t.Errorf("Call() expected an error for a 401 response")
+	}
+}
+
+func TestForbidden(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+	}
+	for _, tt := range tests {
+		if got := forbidden(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("forbidden(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false")
+	if err := Post(ctx, NewClient(5*time.Second), srv.URL, "secret", "message.created", []byte(`{}`)); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Post() to a loopback address error = %v, want %v", err, ErrForbiddenAddress)
+	}
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "true")
+	if err := Post(ctx, NewClient(5*time.Second), srv.URL, "secret", "message.created", []byte(`{}`)); err != nil {
+		t.Errorf("Post() with private networks allowed error = %v", err)
+	}
+}